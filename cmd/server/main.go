@@ -10,8 +10,20 @@ import (
 	"time"
 
 	"wa-server-go/internal/api"
+	"wa-server-go/internal/apikey"
 	"wa-server-go/internal/config"
+	"wa-server-go/internal/contacts"
+	"wa-server-go/internal/features/backup"
+	"wa-server-go/internal/features/blog"
+	"wa-server-go/internal/features/monitor"
+	"wa-server-go/internal/features/reminder"
+	"wa-server-go/internal/features/scheduler"
 	"wa-server-go/internal/firestore"
+	"wa-server-go/internal/metrics"
+	"wa-server-go/internal/outbox"
+	"wa-server-go/internal/sqlite"
+	"wa-server-go/internal/storage"
+	"wa-server-go/internal/webhook"
 	"wa-server-go/internal/whatsapp"
 )
 
@@ -20,8 +32,6 @@ func main() {
 	fmt.Println("=========================================")
 	fmt.Printf("📌 Running as Go/whatsmeow (socket-based)\n")
 	fmt.Printf("📌 Session: SQLite (local)\n")
-	fmt.Printf("📌 Business Data: Firestore\n")
-	fmt.Println("=========================================\n")
 
 	// Load configuration
 	cfg := config.Load()
@@ -29,44 +39,206 @@ func main() {
 	// Create context for app lifecycle
 	ctx := context.Background()
 
-	// Initialize Firestore
-	fsClient, err := firestore.NewClient(ctx, cfg.GoogleCredentials, cfg.FirebaseProjectID)
+	// Local SQLite database (outbox, and business data when STORAGE_BACKEND=sqlite)
+	localDB, err := sqlite.NewClient(ctx, cfg.SQLitePath)
 	if err != nil {
-		log.Printf("⚠️ Failed to initialize Firestore: %v", err)
+		log.Fatalf("Failed to initialize SQLite database: %v", err)
 	}
-	defer fsClient.Close()
+	defer localDB.Close()
+
+	// Initialize business data storage
+	store := openStorage(ctx, cfg, localDB)
+	defer store.Close()
+
+	var chatsRepo storage.ChatsRepository
+	if store != nil {
+		fmt.Printf("📌 Business Data: %s\n", store.Backend)
+		store.Chats = metrics.InstrumentChats(store.Chats, store.Backend)
+		chatsRepo = store.Chats
 
-	var chatsRepo *firestore.ChatsRepository
-	if fsClient != nil {
-		chatsRepo = firestore.NewChatsRepository(fsClient)
+		// Chats and messages stored before sessions were recorded belong to the bot session
+		if n, err := chatsRepo.AssignSession(ctx, cfg.BotClientID); err != nil {
+			log.Printf("⚠️ Failed to assign stored chats to session %s: %v", cfg.BotClientID, err)
+		} else if n > 0 {
+			log.Printf("✅ Assigned %d stored chats and messages to session %s", n, cfg.BotClientID)
+		}
+	} else {
+		fmt.Printf("📌 Business Data: disabled\n")
+	}
+	fmt.Println("=========================================")
+
+	// Media used to be cached in the public uploads directory
+	if err := whatsapp.MoveLegacyMediaDir(); err != nil {
+		log.Printf("⚠️ Failed to move cached media to %s: %v", whatsapp.MediaDir, err)
 	}
 
 	// Create WhatsApp manager
 	waManager := whatsapp.NewManager(chatsRepo)
 
-	// Create bot client
-	err = waManager.CreateClient(ctx, cfg.BotClientID, "session-bot.db")
+	waManager.Registry = sqlite.NewSessionRepository(localDB)
+
+	// LID to phone number mappings, including those of the old lid_mapping.json cache
+	waManager.LIDs = whatsapp.NewLIDResolver(sqlite.NewLIDRepository(localDB))
+	if n, err := waManager.LIDs.ImportFile(ctx, "lid_mapping.json"); err != nil {
+		log.Printf("⚠️ Failed to import lid_mapping.json: %v", err)
+	} else if n > 0 {
+		log.Printf("✅ Imported %d LID mappings from lid_mapping.json", n)
+	}
+
+	// Start registered sessions; the bot session is always registered and connected
+	err = waManager.RestoreSessions(ctx, whatsapp.SessionRecord{
+		ID:          cfg.BotClientID,
+		Role:        whatsapp.RoleSender,
+		AutoConnect: true,
+	})
 	if err != nil {
-		log.Fatalf("Failed to create bot client: %v", err)
+		log.Fatalf("Failed to start sessions: %v", err)
+	}
+
+	// Persistent outbound message queue
+	queueCfg := outbox.DefaultConfig()
+	queueCfg.Workers = cfg.OutboxWorkers
+	queueCfg.RatePerMinute = cfg.OutboxRatePerMinute
+	queueCfg.PerChatInterval = time.Duration(cfg.OutboxChatIntervalSec) * time.Second
+	queue := outbox.NewQueue(sqlite.NewOutboxRepository(localDB), queueCfg)
+
+	// Signed webhooks for WhatsApp events
+	webhookCfg := webhook.DefaultConfig()
+	if len(cfg.WebhookURLs) > 0 && cfg.WebhookSecret == "" {
+		log.Fatalf("WEBHOOK_URLS is set without WEBHOOK_SECRET: receivers could not verify deliveries")
+	}
+	webhookCfg.Secret = cfg.WebhookSecret
+	webhookCfg.MaxAttempts = cfg.WebhookMaxAttempts
+	for _, url := range cfg.WebhookURLs {
+		webhookCfg.Endpoints = append(webhookCfg.Endpoints, webhook.Endpoint{URL: url, Events: cfg.WebhookEvents})
 	}
+	webhooks := webhook.NewDispatcher(webhookCfg, sqlite.NewWebhookDeadLetterRepository(localDB))
+
+	// Named, scoped API keys; API_KEY keeps working as an admin key
+	keys := apikey.NewService(sqlite.NewAPIKeyRepository(localDB), cfg.APIKey)
+	keys.StartAuditCleanup(ctx, cfg.AuditRetentionDays)
+	if !keys.Enabled(ctx) {
+		log.Printf("⚠️ No API_KEY set and no API keys minted: all endpoints answer 401 until a key is created via POST /admin/api-keys from this host")
+	}
+
+	// Scheduled messages, fired into the outbox
+	schedules := scheduler.NewSchedulerService(sqlite.NewScheduledMessageRepository(localDB), cfg.ScheduleTimezone)
 
-	// Set up event handlers
-	err = waManager.SetupEventHandlers(cfg.BotClientID)
+	// Invoice reminders and overdue notices, driven by due dates
+	reminderCfg := reminder.DefaultConfig()
+	reminderCfg.Timezone = cfg.ScheduleTimezone
+	reminderCfg.SendHour = cfg.InvoiceReminderHour
+	reminderCfg.OverdueDays = cfg.InvoiceOverdueDays
+	reminders, err := reminder.NewInvoiceReminderService(sqlite.NewInvoiceRepository(localDB), reminderCfg)
 	if err != nil {
-		log.Fatalf("Failed to setup event handlers: %v", err)
+		log.Fatalf("Failed to create invoice reminders: %v", err)
 	}
 
-	// Connect bot client
-	go func() {
-		err := waManager.Connect(ctx, cfg.BotClientID)
+	// Daily data backup sent to BACKUP_PHONE
+	var backups *backup.BackupService
+	if cfg.BackupPhone != "" {
+		backups = backup.NewBackupService(waManager, cfg.BotClientID, cfg.WebURL, cfg.BackupPhone, sqlite.NewBackupRunRepository(localDB))
+		if cfg.BackupDir != "" {
+			key, err := backup.ParseKey(cfg.BackupEncryptionKey)
+			if err != nil {
+				log.Fatalf("Invalid BACKUP_ENCRYPTION_KEY: %v", err)
+			}
+			if key == nil {
+				log.Fatalf("BACKUP_DIR is set without BACKUP_ENCRYPTION_KEY: archived backups would be stored unencrypted")
+			}
+			dirStore, err := backup.NewDirStore(cfg.BackupDir)
+			if err != nil {
+				log.Fatalf("Failed to open backup archive: %v", err)
+			}
+			backups.SetArchive(&backup.Archive{
+				Store:     dirStore,
+				Key:       key,
+				Retention: backup.Retention{KeepDaily: cfg.BackupKeepDaily, KeepWeekly: cfg.BackupKeepWeekly},
+			})
+		}
+	} else {
+		log.Printf("⚠️ BACKUP_PHONE not set, backups disabled")
+	}
+
+	// Health monitor for the web app and any extra targets
+	targets := []monitor.Target{monitor.HealthTarget(cfg.WebURL)}
+	if cfg.MonitorTargets != "" {
+		if targets, err = monitor.ParseTargets(cfg.MonitorTargets); err != nil {
+			log.Fatalf("Invalid MONITOR_TARGETS: %v", err)
+		}
+	}
+	monitors := monitor.NewMonitorService(waManager, sqlite.NewMonitorCheckRepository(localDB), monitor.Config{
+		Targets:     targets,
+		Recipients:  cfg.MonitorAlertRecipients,
+		Session:     cfg.BotClientID,
+		Interval:    time.Duration(cfg.MonitorIntervalSeconds) * time.Second,
+		HistoryDays: cfg.MonitorHistoryDays,
+	})
+
+	// Blog automator: chat completion article, stock cover image, Contentful entry
+	var blogs *blog.Automator
+	if cfg.GroqAPIKey != "" && cfg.ContentfulManagementToken != "" && cfg.ContentfulSpaceID != "" {
+		var images blog.ImageSearcher
+		if cfg.PexelsAPIKey != "" {
+			images = &blog.PexelsSearcher{BaseURL: cfg.PexelsBaseURL, APIKey: cfg.PexelsAPIKey}
+		}
+		postURL := cfg.BlogPostURL
+		if postURL == "" {
+			postURL = cfg.WebURL + "/blog/{slug}"
+		}
+		blogs, err = blog.NewAutomator(
+			&blog.ChatCompletionWriter{BaseURL: cfg.GroqBaseURL, APIKey: cfg.GroqAPIKey, Model: cfg.GroqModel},
+			images,
+			&blog.ContentfulPublisher{
+				BaseURL:     cfg.ContentfulBaseURL,
+				Token:       cfg.ContentfulManagementToken,
+				SpaceID:     cfg.ContentfulSpaceID,
+				Environment: cfg.ContentfulEnvironment,
+				ContentType: cfg.ContentfulContentType,
+				Locale:      cfg.ContentfulLocale,
+			},
+			sqlite.NewBlogTopicRepository(localDB),
+			waManager,
+			blog.Config{
+				Cron:       cfg.BlogCron,
+				Timezone:   cfg.ScheduleTimezone,
+				DraftOnly:  cfg.BlogDraftOnly,
+				PostURL:    postURL,
+				AdminPhone: cfg.BlogAdminPhone,
+				Session:    cfg.BotClientID,
+			},
+		)
 		if err != nil {
-			log.Printf("❌ Failed to connect bot client: %v", err)
+			log.Fatalf("Failed to create blog automator: %v", err)
 		}
-	}()
+	} else {
+		log.Printf("⚠️ GROQ_API_KEY or Contentful credentials not set, blog automator disabled")
+	}
 
+	// Contact directory: session contact stores plus our own names and notes
+	directory := contacts.NewDirectory(sqlite.NewContactRepository(localDB), waManager.LIDs)
 
 	// Create and start HTTP server
-	server := api.NewServer(cfg, waManager, chatsRepo, fsClient)
+	server := api.NewServer(cfg, waManager, store, queue, webhooks, keys, schedules, reminders, backups, monitors, blogs, directory)
+	queue.Start(ctx)
+	webhooks.Start(ctx)
+	schedules.Start(ctx)
+	if err := reminders.Start(ctx); err != nil {
+		log.Printf("⚠️ %v", err)
+	}
+	if backups != nil {
+		if err := backups.Start(); err != nil {
+			log.Printf("⚠️ %v", err)
+		}
+	}
+	if err := monitors.Start(); err != nil {
+		log.Printf("⚠️ %v", err)
+	}
+	if blogs != nil {
+		if err := blogs.Start(); err != nil {
+			log.Printf("⚠️ %v", err)
+		}
+	}
 
 	// Handle graceful shutdown
 	go func() {
@@ -76,6 +248,9 @@ func main() {
 
 		fmt.Println("\n⚠️ Shutdown signal received...")
 		waManager.Close()
+		webhooks.Stop()
+		store.Close()
+		localDB.Close()
 		fmt.Println("✅ Cleanup complete. Goodbye!")
 		os.Exit(0)
 	}()
@@ -100,3 +275,23 @@ func main() {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
+
+// openStorage opens the configured storage backend.
+// If Firestore cannot be initialized, the local SQLite database is used instead so chat history is kept.
+func openStorage(ctx context.Context, cfg *config.Config, localDB *sqlite.Client) *storage.Store {
+	switch cfg.StorageBackend {
+	case storage.BackendSQLite:
+		// handled below
+	case storage.BackendFirestore:
+		fsClient, err := firestore.NewClient(ctx, cfg.GoogleCredentials, cfg.FirebaseProjectID)
+		if err == nil {
+			return firestore.NewStore(fsClient)
+		}
+		log.Printf("⚠️ Failed to initialize Firestore: %v", err)
+		log.Printf("⚠️ Falling back to local SQLite storage at %s", cfg.SQLitePath)
+	default:
+		log.Printf("⚠️ Unknown STORAGE_BACKEND %q, using SQLite", cfg.StorageBackend)
+	}
+
+	return sqlite.NewStore(localDB)
+}
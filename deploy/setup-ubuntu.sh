@@ -30,8 +30,8 @@ fi
 
 # 3. Create Directories
 echo "📂 Creating project directories..."
-mkdir -p uploads/media
-chmod 755 uploads/media
+mkdir -p media
+chmod 700 media
 
 # 4. Build Project
 echo "🏗️ Building WA Server..."
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"wa-server-go/internal/api/middleware"
+	"wa-server-go/internal/apikey"
+	"wa-server-go/internal/whatsapp"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateAPIKeyRequest represents the request body for POST /admin/api-keys
+type CreateAPIKeyRequest struct {
+	Name      string         `json:"name" binding:"required"`
+	Scopes    []apikey.Scope `json:"scopes" binding:"required"`
+	Sessions  []string       `json:"sessions,omitempty"`  // restrict the key to these sessions
+	ExpiresAt *time.Time     `json:"expiresAt,omitempty"` // RFC3339
+}
+
+// CreateAPIKey handles POST /admin/api-keys
+// The secret is only returned in this response
+func (h *Handler) CreateAPIKey(c *gin.Context) {
+	if h.APIKeys == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "API keys are not configured"})
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	for _, session := range req.Sessions {
+		if !whatsapp.ValidSessionID(session) {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid session ID: " + session})
+			return
+		}
+	}
+
+	key, secret, err := h.APIKeys.Mint(c.Request.Context(), req.Name, req.Scopes, req.Sessions, req.ExpiresAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"key":     key,
+		"secret":  secret,
+		"message": "Store the secret now, it cannot be shown again",
+	})
+}
+
+// ListAPIKeys handles GET /admin/api-keys
+func (h *Handler) ListAPIKeys(c *gin.Context) {
+	if h.APIKeys == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "API keys are not configured"})
+		return
+	}
+
+	keys, err := h.APIKeys.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "keys": keys})
+}
+
+// RevokeAPIKey handles DELETE /admin/api-keys/:id
+func (h *Handler) RevokeAPIKey(c *gin.Context) {
+	if h.APIKeys == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "API keys are not configured"})
+		return
+	}
+
+	ok, err := h.APIKeys.Revoke(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "API key not found or already revoked"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "API key revoked"})
+}
+
+// GetAuditLog handles GET /admin/audit?keyId=&limit=
+func (h *Handler) GetAuditLog(c *gin.Context) {
+	if h.APIKeys == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "API keys are not configured"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	entries, err := h.APIKeys.AuditLog(c.Request.Context(), c.Query("keyId"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "entries": entries})
+}
+
+// IssueWSToken handles POST /ws/token
+// The token authenticates one WebSocket connection as ?token= for a minute, so browsers need not put a long-lived key in the URL
+func (h *Handler) IssueWSToken(c *gin.Context) {
+	if h.APIKeys == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "API keys are not configured"})
+		return
+	}
+
+	token, expiresAt, err := h.APIKeys.IssueToken(middleware.CurrentKey(c), apikey.DefaultTokenTTL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "token": token, "expiresAt": expiresAt})
+}
@@ -2,25 +2,72 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"wa-server-go/internal/api/middleware"
+	"wa-server-go/internal/api/websocket"
+	"wa-server-go/internal/storage"
+	"wa-server-go/internal/utils"
+	"wa-server-go/internal/whatsapp"
 
 	"github.com/gin-gonic/gin"
 	"go.mau.fi/whatsmeow"
 	"go.mau.fi/whatsmeow/types"
 )
 
+// Page sizes of the chat list and message history
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
 // GetChats handles GET /get-chats
+// Lists the chats of the session (?session=, default bot). Supports limit, cursor (nextCursor of the previous page), before/since (RFC3339 or unix seconds)
+// and the unread, invoice, otp and groups filters.
 func (h *Handler) GetChats(c *gin.Context) {
 	if h.Repo == nil {
 		c.JSON(http.StatusServiceUnavailable, gin.H{
 			"success": false,
-			"error":   "Chat storage (Firestore) is not configured",
+			"error":   "Chat storage is not configured",
 		})
 		return
 	}
 
-	chats, err := h.Repo.GetRecentChats(c.Request.Context(), 50)
+	session, ok := h.sessionParam(c)
+	if !ok {
+		return
+	}
+	page, ok := pageParams(c)
+	if !ok {
+		return
+	}
+	filter := storage.ChatFilter{
+		Session:    session,
+		Limit:      page.Limit,
+		Cursor:     page.Cursor,
+		Before:     page.Before,
+		Since:      page.Since,
+		UnreadOnly: c.Query("unread") == "true",
+		HasInvoice: c.Query("invoice") == "true",
+		OTP:        c.Query("otp") == "true",
+	}
+	if groups := c.Query("groups"); groups != "" {
+		onlyGroups := groups == "true"
+		filter.Groups = &onlyGroups
+	}
+
+	chats, nextCursor, err := h.Repo.ListChats(c.Request.Context(), filter)
+	if errors.Is(err, storage.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -34,7 +81,7 @@ func (h *Handler) GetChats(c *gin.Context) {
 	mappedChats := make([]map[string]interface{}, 0)
 
 	// Fetch profile pics for those missing them (Async)
-	botClient, _ := h.WAManager.GetClient("bot")
+	botClient, _ := h.WAManager.GetClient(session)
 	canFetch := botClient != nil && botClient.IsReady()
 
 	for _, chat := range chats {
@@ -56,11 +103,11 @@ func (h *Handler) GetChats(c *gin.Context) {
 				if err == nil && pic != nil && pic.URL != "" {
 					fmt.Printf("📸 Profile pic fetched for %s\n", jidStr)
 					// Update DB so next fetch has it
-					_ = h.Repo.UpdateChatProfilePic(context.Background(), jidStr, pic.URL)
+					_ = h.Repo.UpdateChatProfilePic(context.Background(), session, jidStr, pic.URL)
 					
 					// Broadcast update to frontend for instant display
 					if h.WSHub != nil {
-						h.WSHub.Broadcast("chat-update", gin.H{
+						h.WSHub.Publish("chat-update", websocket.Topic{Session: session, Chat: jidStr}, gin.H{
 							"id":            jidStr,
 							"profilePicUrl": pic.URL,
 						})
@@ -71,35 +118,29 @@ func (h *Handler) GetChats(c *gin.Context) {
 
 		// Improve Name Resolution
 		displayName := chat.Name
-		if (displayName == "" || displayName == chat.Number || displayName == "Unknown") && canFetch {
-			// Try to resolve name from contact store
-			jid, _ := types.ParseJID(chat.JID)
-			contact, err := botClient.WAClient.Store.Contacts.GetContact(c.Request.Context(), jid)
-			if err == nil && contact.Found {
-				newName := ""
-				if contact.PushName != "" {
-					newName = contact.PushName
-				} else if contact.FullName != "" {
-					newName = contact.FullName
-				} else if contact.BusinessName != "" {
-					newName = contact.BusinessName
+		if displayName == "" && chat.IsGroup && canFetch {
+			// Groups are named by their subject, cached once fetched
+			if jid, err := types.ParseJID(chat.JID); err == nil {
+				if group := botClient.CachedGroup(jid); group != nil {
+					displayName = group.Name
 				}
-
-				if newName != "" {
-					displayName = newName
-					// Update DB async
-					go func(id, name string) {
-						// h.Repo.UpdateChatName(context.Background(), id, name) // Assuming method exists or just let it cache next time
-						// For now we don't have UpdateChatName expose? 
-						// Actually we just want live update on frontend.
-						
-						if h.WSHub != nil {
-							h.WSHub.Broadcast("chat-update", gin.H{
-								"id":   id,
-								"name": name,
-							})
-						}
-					}(chat.JID, newName)
+			}
+		}
+		if !chat.IsGroup && botClient != nil {
+			// Custom names always win; store names only fill in chats named by their number
+			if jid, err := types.ParseJID(chat.JID); err == nil {
+				contact, err := h.Contacts.Get(c.Request.Context(), botClient, jid)
+				if err == nil && contact.CustomName != "" {
+					displayName = contact.CustomName
+				} else if err == nil && contact.Name != "" && (displayName == "" || displayName == chat.Number || displayName == "Unknown") {
+					displayName = contact.Name
+					// Live update for the frontend
+					if h.WSHub != nil {
+						go h.WSHub.Publish("chat-update", websocket.Topic{Session: session, Chat: chat.JID}, gin.H{
+							"id":   chat.JID,
+							"name": displayName,
+						})
+					}
 				}
 			}
 		}
@@ -108,6 +149,7 @@ func (h *Handler) GetChats(c *gin.Context) {
 			"id":            chat.JID,
 			"name":          displayName,
 			"number":        chat.Number,
+			"isGroup":       chat.IsGroup,
 			"unreadCount":   chat.UnreadCount,
 			"profilePicUrl": profilePic,
 			"timestamp":     chat.LastMessageAt.Unix(),
@@ -121,23 +163,38 @@ func (h *Handler) GetChats(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"chats":   mappedChats,
-		"total":   len(mappedChats),
+		"chats":      mappedChats,
+		"total":      len(mappedChats),
+		"nextCursor": nextCursor,
 	})
 }
 
 // GetMessages handles GET /get-messages/:chatId
+// Lists the messages of the session's chat (?session=, default bot). Supports limit, cursor (nextCursor of the previous page) and before/since (RFC3339 or unix seconds).
 func (h *Handler) GetMessages(c *gin.Context) {
 	if h.Repo == nil {
 		c.JSON(http.StatusServiceUnavailable, gin.H{
 			"success": false,
-			"error":   "Chat storage (Firestore) is not configured",
+			"error":   "Chat storage is not configured",
 		})
 		return
 	}
 
+	session, ok := h.sessionParam(c)
+	if !ok {
+		return
+	}
+	page, ok := pageParams(c)
+	if !ok {
+		return
+	}
+
 	chatId := c.Param("chatId")
-	messages, err := h.Repo.GetChatMessages(c.Request.Context(), chatId, 50)
+	messages, nextCursor, err := h.Repo.ListChatMessages(c.Request.Context(), session, chatId, page)
+	if errors.Is(err, storage.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
 	if err != nil {
 		fmt.Printf("❌ Failed to fetch messages for %s: %v\n", chatId, err)
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -148,49 +205,183 @@ func (h *Handler) GetMessages(c *gin.Context) {
 		return
 	}
 
-	// Map to frontend format
-	mappedMessages := make([]map[string]interface{}, 0)
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"messages":   mapMessages(messages),
+		"nextCursor": nextCursor,
+	})
+}
+
+// pageParams reads the limit, cursor, before and since query parameters, responding 400 if one is invalid
+func pageParams(c *gin.Context) (storage.MessageFilter, bool) {
+	page := storage.MessageFilter{Limit: defaultPageSize, Cursor: c.Query("cursor")}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid limit"})
+			return page, false
+		}
+		page.Limit = min(limit, maxPageSize)
+	}
+
+	var err error
+	if page.Before, err = parseTimeParam(c.Query("before")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid before: " + err.Error()})
+		return page, false
+	}
+	if page.Since, err = parseTimeParam(c.Query("since")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid since: " + err.Error()})
+		return page, false
+	}
+	return page, true
+}
+
+// parseTimeParam parses an RFC3339 time or unix seconds; an empty value yields the zero time
+func parseTimeParam(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// mapMessages maps stored messages to the frontend format
+func mapMessages(messages []storage.WAMessage) []map[string]interface{} {
+	mappedMessages := make([]map[string]interface{}, 0, len(messages))
 	for _, msg := range messages {
 		mappedMessages = append(mappedMessages, map[string]interface{}{
-			"id":        msg.MessageID,
-			"body":      msg.Body,
-			"fromMe":    msg.FromMe,
-			"timestamp": msg.Timestamp.Unix(),
-			"type":      msg.Type,
-			"ack":       msg.Ack,
-			"hasMedia":  msg.HasMedia,
-			"mediaUrl":  msg.MediaURL,
+			"id":         msg.MessageID,
+			"body":       msg.Body,
+			"fromMe":     msg.FromMe,
+			"timestamp":  msg.Timestamp.Unix(),
+			"type":       msg.Type,
+			"ack":        msg.Ack,
+			"ackStatus":  storage.AckName(msg.Ack),
+			"hasMedia":   msg.HasMedia,
+			"mediaUrl":   mediaURL(msg),
+			"senderJid":  msg.SenderJID,
+			"senderName": msg.SenderName,
 		})
 	}
+	return mappedMessages
+}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success":  true,
-		"messages": mappedMessages,
-	})
+// mediaURL returns the /get-media URL of a message's media, which checks the API key's sessions
+func mediaURL(msg storage.WAMessage) string {
+	if !msg.HasMedia {
+		return ""
+	}
+	return "/get-media/" + url.PathEscape(msg.MessageID) + "?session=" + url.QueryEscape(msg.Session)
 }
 
 // GetMedia handles GET /get-media/:messageId
+// Serves the cached file of the session's message (?session=, default bot) when present, otherwise downloads
+// it again from WhatsApp through that session using the stored media keys. Range requests are supported so
+// video can be seeked.
 func (h *Handler) GetMedia(c *gin.Context) {
-	// ... (Media download logic needs whatsmeow helper not just firestore)
-	c.JSON(http.StatusNotImplemented, gin.H{
-		"success": false,
-		"error":   "Media download is being implemented",
-	})
+	if h.Repo == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"error":   "Chat storage is not configured",
+		})
+		return
+	}
+
+	session, ok := h.sessionParam(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	messageID := c.Param("messageId")
+
+	msg, err := h.Repo.GetMessage(ctx, session, messageID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if msg == nil || !msg.HasMedia {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Media not found"})
+		return
+	}
+
+	// 1. Serve the locally cached file
+	if msg.MediaURL != "" {
+		localPath := whatsapp.MediaPath(msg.MediaURL)
+		if _, err := os.Stat(localPath); err == nil {
+			serveMediaFile(c, localPath, msg.MediaType)
+			return
+		}
+	}
+
+	// 2. Re-download from WhatsApp (requests a re-upload from the phone if the CDN copy expired)
+	data, err := h.WAManager.DownloadMedia(ctx, session, msg)
+	if err != nil {
+		status := http.StatusBadGateway
+		if errors.Is(err, whatsapp.ErrNoMediaKeys) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"success": false, "error": fmt.Sprintf("Failed to download media: %v", err)})
+		return
+	}
+
+	ext := whatsapp.MediaExtension(msg.Type, msg.MediaType, "")
+	fileName, err := whatsapp.WriteMediaFile(msg.MessageID, ext, data)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	// Persist the cached file and the (possibly refreshed) direct path
+	msg.MediaURL = fileName
+	if err := h.Repo.UpdateMessageMedia(ctx, msg); err != nil {
+		fmt.Printf("⚠️ Failed to update media for %s: %v\n", msg.MessageID, err)
+	}
+
+	serveMediaFile(c, whatsapp.MediaPath(fileName), msg.MediaType)
+}
+
+// serveMediaFile streams a cached media file with Range support
+func serveMediaFile(c *gin.Context, path, mimeType string) {
+	f, err := os.Open(path)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if mimeType != "" {
+		c.Header("Content-Type", mimeType)
+	}
+	http.ServeContent(c.Writer, c.Request, info.Name(), info.ModTime(), f)
 }
 
 // GetInvoiceChats handles GET /get-invoice-chats
+// Lists the session's chats (?session=, default bot) that contain invoices
 func (h *Handler) GetInvoiceChats(c *gin.Context) {
 	if h.Repo == nil {
 		c.JSON(http.StatusServiceUnavailable, gin.H{
 			"success": false,
-			"error":   "Chat storage (Firestore) is not configured",
+			"error":   "Chat storage is not configured",
 		})
 		return
 	}
 
+	session, ok := h.sessionParam(c)
+	if !ok {
+		return
+	}
+
 	// For now, reuse GetRecentChats (filtering should happen in Repo or here)
 	// Ideally we filter for chats containing "INV-"
-	chats, err := h.Repo.GetInvoiceChats(c.Request.Context(), 50)
+	chats, err := h.Repo.GetInvoiceChats(c.Request.Context(), session, 50)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -222,3 +413,143 @@ func (h *Handler) GetInvoiceChats(c *gin.Context) {
 		"chats":   mappedChats,
 	})
 }
+
+// MarkChatReadRequest represents the optional request body for POST /chats/:chatId/read
+type MarkChatReadRequest struct {
+	MessageIDs []string `json:"messageIds,omitempty"` // defaults to the chat's unread incoming messages
+	Sender     string   `json:"sender,omitempty"`     // sender of messageIds in a group
+}
+
+// MarkChatRead handles POST /chats/:chatId/read
+// Sends read receipts through the session (?session=, default bot), resets the unread counter and publishes a chat-update
+func (h *Handler) MarkChatRead(c *gin.Context) {
+	var req MarkChatReadRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+	}
+
+	session, ok := h.sessionParam(c)
+	if !ok {
+		return
+	}
+	chatID := c.Param("chatId")
+	middleware.SetAuditRecipient(c, chatID)
+
+	client, err := h.sendingClient(middleware.CurrentKey(c), session)
+	if err != nil {
+		c.JSON(statusOf(err), gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	marked, err := h.markChatRead(c.Request.Context(), client, chatID, req.MessageIDs, req.Sender)
+	if err != nil {
+		c.JSON(statusOf(err), gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "chatId": chatID, "marked": marked})
+}
+
+// markChatRead sends read receipts for messageIDs, or for the chat's unread incoming messages, resets its
+// unread counter and publishes a chat-update. It returns the number of messages marked.
+func (h *Handler) markChatRead(ctx context.Context, client *whatsapp.Client, chatID string, messageIDs []string, sender string) (int, error) {
+	chat, err := chatJID(chatID)
+	if err != nil {
+		return 0, err
+	}
+
+	var stored *storage.WAChat
+	if h.Repo != nil {
+		if stored, err = h.Repo.GetChat(ctx, client.ID, chat.String()); err != nil {
+			return 0, err
+		}
+	}
+
+	// Receipts are sent per sender, which only differs from the chat in groups
+	bySender := make(map[types.JID][]types.MessageID)
+	if len(messageIDs) > 0 {
+		senderJID := chat
+		if sender != "" {
+			if senderJID, err = chatJID(sender); err != nil {
+				return 0, err
+			}
+		}
+		bySender[senderJID] = messageIDs
+	} else {
+		if h.Repo == nil {
+			return 0, &requestError{http.StatusBadRequest, "messageIds is required without chat storage"}
+		}
+		if stored == nil {
+			return 0, &requestError{http.StatusNotFound, "Chat not found: " + chatID}
+		}
+		unread, err := h.unreadMessages(ctx, client.ID, chat.String(), stored.UnreadCount)
+		if err != nil {
+			return 0, err
+		}
+		for _, msg := range unread {
+			senderJID := chat
+			if from, err := types.ParseJID(msg.From); err == nil && chat.Server == types.GroupServer {
+				senderJID = from.ToNonAD()
+			}
+			bySender[senderJID] = append(bySender[senderJID], msg.MessageID)
+		}
+	}
+
+	marked := 0
+	for senderJID, ids := range bySender {
+		if err := client.WAClient.MarkRead(ctx, ids, time.Now(), chat, senderJID); err != nil {
+			return marked, fmt.Errorf("failed to send read receipts: %w", err)
+		}
+		marked += len(ids)
+	}
+
+	if stored != nil {
+		if err := h.Repo.MarkChatAsRead(ctx, client.ID, chat.String()); err != nil {
+			return marked, err
+		}
+		if h.WSHub != nil {
+			h.WSHub.Publish("chat-update", websocket.Topic{Session: client.ID, Chat: chat.String()}, gin.H{
+				"id":          chat.String(),
+				"unreadCount": 0,
+			})
+		}
+	}
+	return marked, nil
+}
+
+// unreadMessages returns the latest count incoming messages of a session's chat, at most maxPageSize
+func (h *Handler) unreadMessages(ctx context.Context, session, chatID string, count int) ([]storage.WAMessage, error) {
+	count = min(count, maxPageSize)
+	var unread []storage.WAMessage
+	cursor := ""
+	for len(unread) < count {
+		messages, next, err := h.Repo.ListChatMessages(ctx, session, chatID, storage.MessageFilter{Limit: maxPageSize, Cursor: cursor})
+		if err != nil {
+			return nil, err
+		}
+		for _, msg := range messages {
+			if !msg.FromMe && len(unread) < count {
+				unread = append(unread, msg)
+			}
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	return unread, nil
+}
+
+// chatJID accepts a full JID (including groups) or a phone number
+func chatJID(chat string) (types.JID, error) {
+	if strings.Contains(chat, "@") {
+		jid, err := types.ParseJID(chat)
+		if err != nil {
+			return types.JID{}, &requestError{http.StatusBadRequest, "Invalid chat ID: " + chat}
+		}
+		return jid, nil
+	}
+	return utils.PhoneToJID(chat), nil
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"wa-server-go/internal/api/middleware"
+	"wa-server-go/internal/apikey"
+	"wa-server-go/internal/sqlite"
+	"wa-server-go/internal/storage"
+	"wa-server-go/internal/whatsapp"
+
+	"github.com/gin-gonic/gin"
+)
+
+var testEpoch = time.Date(2026, 1, 2, 9, 0, 0, 0, time.UTC)
+
+// testServer serves the chat routes from a fresh SQLite database, behind the API key middleware
+type testServer struct {
+	router *gin.Engine
+	repo   *sqlite.ChatsRepository
+	keys   *apikey.Service
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	client, err := sqlite.NewClient(context.Background(), filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	repo := sqlite.NewChatsRepository(client)
+	keys := apikey.NewService(sqlite.NewAPIKeyRepository(client), "")
+	h := NewHandler(whatsapp.NewManager(repo), repo, nil)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.SecurityMiddleware(keys, nil))
+	readChats := router.Group("", middleware.RequireScope(apikey.ScopeReadChats))
+	readChats.GET("/get-chats", h.GetChats)
+	readChats.GET("/get-messages/:chatId", h.GetMessages)
+	readChats.GET("/get-media/:messageId", h.GetMedia)
+
+	return &testServer{router: router, repo: repo, keys: keys}
+}
+
+// mint creates a read-chats key, restricted to sessions when any are given
+func (s *testServer) mint(t *testing.T, sessions ...string) string {
+	t.Helper()
+	_, secret, err := s.keys.Mint(context.Background(), "test", []apikey.Scope{apikey.ScopeReadChats}, sessions, nil)
+	if err != nil {
+		t.Fatalf("Mint: %v", err)
+	}
+	return secret
+}
+
+func (s *testServer) save(t *testing.T, msg storage.WAMessage) {
+	t.Helper()
+	msg.Type = "text"
+	msg.Ack = storage.AckServer
+	if err := s.repo.SaveMessage(context.Background(), &msg); err != nil {
+		t.Fatalf("SaveMessage %s: %v", msg.MessageID, err)
+	}
+}
+
+// get performs a request with the key and decodes the JSON response
+func (s *testServer) get(t *testing.T, key, path string) (int, map[string]interface{}) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("x-api-key", key)
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("GET %s: invalid JSON %q", path, w.Body.String())
+	}
+	return w.Code, body
+}
+
+// ids returns the "id" of every object in a response list
+func ids(t *testing.T, list interface{}) []string {
+	t.Helper()
+	items, ok := list.([]interface{})
+	if !ok {
+		t.Fatalf("not a list: %#v", list)
+	}
+	out := []string{}
+	for _, item := range items {
+		out = append(out, item.(map[string]interface{})["id"].(string))
+	}
+	return out
+}
+
+func TestGetMessagesPages(t *testing.T) {
+	s := newTestServer(t)
+	key := s.mint(t)
+	chat := "62811@s.whatsapp.net"
+	for i, id := range []string{"m1", "m2", "m3"} {
+		s.save(t, storage.WAMessage{Session: "bot", MessageID: id, ChatID: chat, Body: id, Timestamp: testEpoch.Add(time.Duration(i) * time.Minute)})
+	}
+
+	status, body := s.get(t, key, "/get-messages/"+chat+"?limit=2")
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, body %v", status, body)
+	}
+	if got := ids(t, body["messages"]); len(got) != 2 || got[0] != "m3" || got[1] != "m2" {
+		t.Fatalf("first page = %v, want [m3 m2]", got)
+	}
+	cursor, _ := body["nextCursor"].(string)
+	if cursor == "" {
+		t.Fatal("first page has no nextCursor")
+	}
+
+	status, body = s.get(t, key, "/get-messages/"+chat+"?limit=2&cursor="+cursor)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, body %v", status, body)
+	}
+	if got := ids(t, body["messages"]); len(got) != 1 || got[0] != "m1" {
+		t.Fatalf("second page = %v, want [m1]", got)
+	}
+	if cursor, _ := body["nextCursor"].(string); cursor != "" {
+		t.Errorf("last page nextCursor = %q, want empty", cursor)
+	}
+
+	if status, _ := s.get(t, key, "/get-messages/"+chat+"?cursor=bogus"); status != http.StatusBadRequest {
+		t.Errorf("invalid cursor status = %d, want 400", status)
+	}
+}
+
+func TestGetChatsSessionRestrictedKey(t *testing.T) {
+	s := newTestServer(t)
+	s.save(t, storage.WAMessage{Session: "bot", MessageID: "b1", ChatID: "62811@s.whatsapp.net", Timestamp: testEpoch})
+	s.save(t, storage.WAMessage{Session: "cs", MessageID: "c1", ChatID: "62822@s.whatsapp.net", Timestamp: testEpoch})
+	key := s.mint(t, "cs")
+
+	// The key may not read the default (bot) session
+	if status, _ := s.get(t, key, "/get-chats"); status != http.StatusForbidden {
+		t.Fatalf("bot session status = %d, want 403", status)
+	}
+	if status, _ := s.get(t, key, "/get-messages/62811@s.whatsapp.net"); status != http.StatusForbidden {
+		t.Fatalf("bot messages status = %d, want 403", status)
+	}
+
+	status, body := s.get(t, key, "/get-chats?session=cs")
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, body %v", status, body)
+	}
+	if got := ids(t, body["chats"]); len(got) != 1 || got[0] != "62822@s.whatsapp.net" {
+		t.Fatalf("cs chats = %v", got)
+	}
+}
+
+func TestGetMessagesMediaURL(t *testing.T) {
+	s := newTestServer(t)
+	key := s.mint(t)
+	chat := "62811@s.whatsapp.net"
+	s.save(t, storage.WAMessage{Session: "bot", MessageID: "img1", ChatID: chat, Timestamp: testEpoch, HasMedia: true, MediaType: "image/jpeg"})
+	s.save(t, storage.WAMessage{Session: "bot", MessageID: "txt1", ChatID: chat, Timestamp: testEpoch.Add(time.Minute)})
+
+	_, body := s.get(t, key, "/get-messages/"+chat)
+	urls := map[string]string{}
+	for _, item := range body["messages"].([]interface{}) {
+		msg := item.(map[string]interface{})
+		urls[msg["id"].(string)] = msg["mediaUrl"].(string)
+	}
+	if want := "/get-media/img1?session=bot"; urls["img1"] != want {
+		t.Errorf("img1 mediaUrl = %q, want %q", urls["img1"], want)
+	}
+	if urls["txt1"] != "" {
+		t.Errorf("txt1 mediaUrl = %q, want empty", urls["txt1"])
+	}
+
+	// A text message has no media; another session's copy does not exist
+	if status, _ := s.get(t, key, "/get-media/txt1"); status != http.StatusNotFound {
+		t.Errorf("text message media status = %d, want 404", status)
+	}
+	if status, _ := s.get(t, key, "/get-media/img1?session=cs"); status != http.StatusNotFound {
+		t.Errorf("other session media status = %d, want 404", status)
+	}
+}
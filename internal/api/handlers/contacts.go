@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"wa-server-go/internal/api/middleware"
+	"wa-server-go/internal/api/websocket"
+	"wa-server-go/internal/contacts"
+	"wa-server-go/internal/whatsapp"
+
+	"github.com/gin-gonic/gin"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// UpdateContactRequest represents the request body for PATCH /contacts/:id
+type UpdateContactRequest struct {
+	CustomName *string `json:"customName"` // empty clears it
+	Notes      *string `json:"notes"`
+}
+
+// ResolveLIDsRequest represents the request body for POST /lid/resolve
+type ResolveLIDsRequest struct {
+	LIDs    []string `json:"lids" binding:"required,min=1,max=500"` // LIDs, with or without @lid
+	Network bool     `json:"network,omitempty"`                     // ask WhatsApp for the LIDs not known locally
+	Session string   `json:"session,omitempty"`
+}
+
+// GetContacts handles GET /contacts
+// Contacts of the session (?session=, default bot) merged with our custom names and notes, by name; ?q= filters by name or number
+func (h *Handler) GetContacts(c *gin.Context) {
+	client, ok := h.contactsClient(c)
+	if !ok {
+		return
+	}
+
+	list, err := h.Contacts.List(c.Request.Context(), client, c.Query("q"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to list contacts",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "contacts": list, "total": len(list)})
+}
+
+// GetContact handles GET /contacts/:id
+// A contact by phone number, JID or LID. The profile picture is fetched when unknown or with ?refresh=true.
+func (h *Handler) GetContact(c *gin.Context) {
+	jid, ok := contactParam(c)
+	if !ok {
+		return
+	}
+	client, ok := h.contactsClient(c)
+	if !ok {
+		return
+	}
+
+	contact, err := h.Contacts.Get(c.Request.Context(), client, jid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to fetch contact",
+			"details": err.Error(),
+		})
+		return
+	}
+	if client.IsReady() && (contact.ProfilePicURL == "" || c.Query("refresh") == "true") {
+		if err := h.Contacts.RefreshPicture(c.Request.Context(), client, contact); err != nil {
+			log.Printf("⚠️ Failed to fetch profile picture of %s: %v", contact.ID, err)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "contact": contact})
+}
+
+// UpdateContact handles PATCH /contacts/:id
+// Sets our own name and notes of a contact; the custom name is used everywhere the contact is listed
+func (h *Handler) UpdateContact(c *gin.Context) {
+	jid, ok := contactParam(c)
+	if !ok {
+		return
+	}
+	var req UpdateContactRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if req.CustomName == nil && req.Notes == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "customName or notes is required"})
+		return
+	}
+	client, ok := h.contactsClient(c)
+	if !ok {
+		return
+	}
+
+	contact, err := h.Contacts.Update(c.Request.Context(), client, jid, contacts.Update{
+		CustomName: req.CustomName,
+		Notes:      req.Notes,
+	})
+	if errors.Is(err, contacts.ErrNoStorage) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to update contact",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if h.WSHub != nil {
+		h.WSHub.Publish("chat-update", websocket.Topic{Session: client.ID, Chat: contact.ID}, gin.H{
+			"id":   contact.ID,
+			"name": contact.Name,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "contact": contact})
+}
+
+// contactParam parses the :id of a contact: a phone number, a phone number JID or a LID
+func contactParam(c *gin.Context) (types.JID, bool) {
+	jid, err := chatJID(c.Param("id"))
+	if err == nil && jid.Server != types.DefaultUserServer && jid.Server != types.HiddenUserServer {
+		err = &requestError{http.StatusBadRequest, "Invalid contact ID: " + c.Param("id")}
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return jid, false
+	}
+	middleware.SetAuditRecipient(c, jid.String())
+	return jid, true
+}
+
+// contactsClient returns the client of the ?session= whose contact store is read; it does not need to be connected
+func (h *Handler) contactsClient(c *gin.Context) (*whatsapp.Client, bool) {
+	session, ok := h.sessionParam(c)
+	if !ok {
+		return nil, false
+	}
+	client, exists := h.WAManager.GetClient(session)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Session not found: " + session})
+		return nil, false
+	}
+	return client, true
+}
+
+// ResolveLIDs handles POST /lid/resolve
+// Maps LIDs to phone numbers from the session's LID store and the learned mappings and, with
+// network, from WhatsApp one LID at a time until it rate limits the session
+func (h *Handler) ResolveLIDs(c *gin.Context) {
+	var req ResolveLIDsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	lids := make([]types.JID, 0, len(req.LIDs))
+	for _, value := range req.LIDs {
+		jid := types.NewJID(value, types.HiddenUserServer)
+		if strings.Contains(value, "@") {
+			var err error
+			if jid, err = types.ParseJID(value); err != nil || jid.Server != types.HiddenUserServer {
+				c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid LID: " + value})
+				return
+			}
+		}
+		lids = append(lids, jid)
+	}
+
+	session := req.Session
+	if session == "" {
+		session = h.DefaultSession
+	}
+	if !middleware.SessionAllowed(c, session) {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "error": "API key is not allowed to use session " + session})
+		return
+	}
+	client, exists := h.WAManager.GetClient(session)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Session not found: " + session})
+		return
+	}
+	if req.Network && !client.IsReady() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "Session " + session + " is not connected"})
+		return
+	}
+
+	results, err := h.WAManager.LIDs.Resolve(c.Request.Context(), client, lids, req.Network)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to resolve LIDs",
+			"details": err.Error(),
+		})
+		return
+	}
+	resolved := 0
+	for _, result := range results {
+		if result.Phone != "" {
+			resolved++
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "results": results, "resolved": resolved, "total": len(results)})
+}
@@ -1,14 +1,29 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+
+	"wa-server-go/internal/features/backup"
+	"wa-server-go/internal/features/blog"
 
 	"github.com/gin-gonic/gin"
 )
 
 // TriggerBackup handles POST /trigger-backup
+// Starts a backup in the background and returns its run ID; progress is published as backup-update
 func (h *Handler) TriggerBackup(c *gin.Context) {
-	botClient, ok := h.WAManager.GetClient("bot")
+	if h.Backups == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "Backups are not configured (BACKUP_PHONE is not set)"})
+		return
+	}
+
+	session, ok := h.sessionParam(c)
+	if !ok {
+		return
+	}
+	botClient, ok := h.WAManager.GetClient(session)
 	if !ok || !botClient.IsReady() {
 		c.JSON(http.StatusServiceUnavailable, gin.H{
 			"success": false,
@@ -17,28 +32,169 @@ func (h *Handler) TriggerBackup(c *gin.Context) {
 		return
 	}
 
-	// TODO: Implement async backup trigger
-	// - Fetch data from WEB_URL/api/backup/data-dump
-	// - Generate Excel file using excelize
-	// - Send via WhatsApp to BACKUP_PHONE
+	run, err := h.Backups.Trigger(c.Request.Context(), backup.TriggerManual, session)
+	if errors.Is(err, backup.ErrRunning) {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{
+	c.JSON(http.StatusAccepted, gin.H{
 		"success": true,
-		"message": "Backup triggered (implementation pending)",
+		"jobId":   run.ID,
+		"status":  run.Status,
 	})
 }
 
+// ListBackups handles GET /backups?limit=
+func (h *Handler) ListBackups(c *gin.Context) {
+	if h.Backups == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "Backups are not configured (BACKUP_PHONE is not set)"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "30"))
+	runs, err := h.Backups.List(c.Request.Context(), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "backups": runs})
+}
+
+// RestoreBackupRequest represents the optional request body for POST /backups/:id/restore
+type RestoreBackupRequest struct {
+	DryRun bool `json:"dryRun"` // only compare the backup with the live data
+}
+
+// RestoreBackup handles POST /backups/:id/restore
+func (h *Handler) RestoreBackup(c *gin.Context) {
+	if h.Backups == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "Backups are not configured (BACKUP_PHONE is not set)"})
+		return
+	}
+
+	var req RestoreBackupRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+	}
+
+	result, err := h.Backups.Restore(c.Request.Context(), c.Param("id"), req.DryRun)
+	switch {
+	case errors.Is(err, backup.ErrNotArchived):
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": err.Error()})
+	case err != nil && result != nil:
+		// The restore endpoint rejected the backup
+		c.JSON(http.StatusBadGateway, gin.H{"success": false, "error": err.Error(), "result": result})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+	case result == nil:
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Backup not found"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"success": true, "result": result})
+	}
+}
+
+// BlogTopicRequest represents the request body for POST /api/blog/manual-trigger and POST /api/blog/topics
+type BlogTopicRequest struct {
+	Topic string `json:"topic"`
+	Draft bool   `json:"draft"` // create the entry without publishing it
+}
+
 // TriggerBlog handles POST /api/blog/manual-trigger
+// Generates a post for the given topic, or the oldest queued one, in the background; progress is published as blog-update
 func (h *Handler) TriggerBlog(c *gin.Context) {
-	// TODO: Implement blog automation trigger
-	// - Generate content using Groq API
-	// - Fetch images from Pexels
-	// - Publish to Contentful
+	if h.Blog == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "Blog automator is not configured"})
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "Blog generation triggered (implementation pending)",
-	})
+	var req BlogTopicRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+	}
+
+	topic, err := h.Blog.Trigger(c.Request.Context(), req.Topic, req.Draft)
+	switch {
+	case errors.Is(err, blog.ErrRunning):
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": err.Error()})
+	case errors.Is(err, blog.ErrQueueEmpty):
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+	default:
+		c.JSON(http.StatusAccepted, gin.H{"success": true, "topic": topic})
+	}
+}
+
+// QueueBlogTopic handles POST /api/blog/topics
+func (h *Handler) QueueBlogTopic(c *gin.Context) {
+	if h.Blog == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "Blog automator is not configured"})
+		return
+	}
+
+	var req BlogTopicRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	topic, err := h.Blog.Enqueue(c.Request.Context(), req.Topic, req.Draft)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "topic": topic})
+}
+
+// RetryBlogTopic handles POST /api/blog/topics/:id/retry
+// Runs a failed topic again in the background; an entry created by the failed run is published instead of written again
+func (h *Handler) RetryBlogTopic(c *gin.Context) {
+	if h.Blog == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "Blog automator is not configured"})
+		return
+	}
+
+	topic, err := h.Blog.Retry(c.Request.Context(), c.Param("id"))
+	switch {
+	case errors.Is(err, blog.ErrRunning), errors.Is(err, blog.ErrNotFailed):
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+	case topic == nil:
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Topic not found"})
+	default:
+		c.JSON(http.StatusAccepted, gin.H{"success": true, "topic": topic})
+	}
+}
+
+// ListBlogTopics handles GET /api/blog/topics?status=&limit=
+func (h *Handler) ListBlogTopics(c *gin.Context) {
+	if h.Blog == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "Blog automator is not configured"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	topics, err := h.Blog.List(c.Request.Context(), blog.TopicStatus(c.Query("status")), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "topics": topics})
 }
 
 // SyncInvoices handles POST /sync-invoices
@@ -46,7 +202,7 @@ func (h *Handler) SyncInvoices(c *gin.Context) {
 	if h.Repo == nil {
 		c.JSON(http.StatusServiceUnavailable, gin.H{
 			"success": false,
-			"error":   "Chat storage (Firestore) is not configured",
+			"error":   "Chat storage is not configured",
 		})
 		return
 	}
@@ -0,0 +1,435 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+	"log"
+	"net/http"
+
+	"wa-server-go/internal/api/middleware"
+	"wa-server-go/internal/api/websocket"
+	"wa-server-go/internal/storage"
+	"wa-server-go/internal/whatsapp"
+
+	"github.com/gin-gonic/gin"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// GetGroups handles GET /groups
+// Lists the groups the session (?session=, default bot) is a member of, without their participants
+func (h *Handler) GetGroups(c *gin.Context) {
+	session, ok := h.sessionParam(c)
+	if !ok {
+		return
+	}
+	client, err := h.connectedClient(middleware.CurrentKey(c), session)
+	if err != nil {
+		c.JSON(statusOf(err), gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	groups, err := client.JoinedGroups(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"success": false, "error": "Failed to fetch groups: " + err.Error()})
+		return
+	}
+
+	summaries := make([]whatsapp.Group, 0, len(groups))
+	for _, group := range groups {
+		summary := *group
+		summary.Participants = nil
+		summaries = append(summaries, summary)
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "groups": summaries, "total": len(summaries)})
+}
+
+// GetGroup handles GET /groups/:jid
+// Subject, description and participants of a group, cached for an hour unless ?refresh=true
+func (h *Handler) GetGroup(c *gin.Context) {
+	client, jid, ok := h.groupClient(c)
+	if !ok {
+		return
+	}
+
+	group, err := client.GroupInfo(c.Request.Context(), jid, c.Query("refresh") == "true")
+	if err != nil {
+		c.JSON(groupErrorStatus(err), gin.H{"success": false, "error": "Failed to fetch group: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "group": group})
+}
+
+// CreateGroupRequest represents the request body for POST /groups
+type CreateGroupRequest struct {
+	Name         string   `json:"name" binding:"required,max=25"` // WhatsApp rejects longer subjects
+	Participants []string `json:"participants"`                   // phone numbers or JIDs; the session is added as admin
+	Session      string   `json:"session,omitempty"`
+}
+
+// GroupParticipantsRequest represents the request body for POST /groups/:jid/participants
+type GroupParticipantsRequest struct {
+	Action       string   `json:"action" binding:"required,oneof=add remove promote demote"`
+	Participants []string `json:"participants" binding:"required,min=1"`
+}
+
+// GroupSubjectRequest represents the request body for PUT /groups/:jid/subject
+type GroupSubjectRequest struct {
+	Subject string `json:"subject" binding:"required,max=25"`
+}
+
+// GroupDescriptionRequest represents the request body for PUT /groups/:jid/description; empty removes it
+type GroupDescriptionRequest struct {
+	Description string `json:"description"`
+}
+
+// GroupPictureRequest represents the request body for PUT /groups/:jid/picture
+type GroupPictureRequest struct {
+	URL string `json:"url" binding:"required"` // JPEG or PNG image
+}
+
+// GroupSettingsRequest represents the request body for PUT /groups/:jid/settings; omitted settings are left as they are
+type GroupSettingsRequest struct {
+	Announce *bool `json:"announce"` // only admins can send messages
+	Locked   *bool `json:"locked"`   // only admins can edit the group info
+}
+
+// CreateGroup handles POST /groups
+func (h *Handler) CreateGroup(c *gin.Context) {
+	var req CreateGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	session := req.Session
+	if session == "" {
+		session = h.DefaultSession
+	}
+	if !middleware.SessionAllowed(c, session) {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "error": "API key is not allowed to use session " + session})
+		return
+	}
+	client, err := h.connectedClient(nil, session)
+	if err != nil {
+		c.JSON(statusOf(err), gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if !client.Role.CanSend() {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "error": "Session " + session + " is read-only"})
+		return
+	}
+	participants, err := participantJIDs(req.Participants)
+	if err != nil {
+		c.JSON(statusOf(err), gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	middleware.SetAuditAction(c, "group.create", jidStrings(participants)...)
+
+	group, err := client.CreateGroup(c.Request.Context(), req.Name, participants)
+	if err != nil {
+		c.JSON(groupErrorStatus(err), gin.H{"success": false, "error": "Failed to create group: " + err.Error()})
+		return
+	}
+	middleware.SetAuditRecipient(c, group.JID)
+	h.saveGroupChat(c.Request.Context(), client, group)
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "group": group})
+}
+
+// UpdateGroupParticipants handles POST /groups/:jid/participants
+// Adds, removes, promotes or demotes members; the result lists each participant with WhatsApp's error code (0 on success)
+func (h *Handler) UpdateGroupParticipants(c *gin.Context) {
+	var req GroupParticipantsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	client, jid, ok := h.groupClient(c)
+	if !ok {
+		return
+	}
+	participants, err := participantJIDs(req.Participants)
+	if err != nil {
+		c.JSON(statusOf(err), gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	middleware.SetAuditAction(c, "group.participants."+req.Action, jidStrings(participants)...)
+
+	changed, err := client.WAClient.UpdateGroupParticipants(c.Request.Context(), jid, participants, whatsmeow.ParticipantChange(req.Action))
+	if err != nil {
+		c.JSON(groupErrorStatus(err), gin.H{"success": false, "error": "Failed to " + req.Action + " participants: " + err.Error()})
+		return
+	}
+	results := make([]gin.H, 0, len(changed))
+	for _, p := range changed {
+		results = append(results, gin.H{"jid": p.JID.String(), "error": p.Error})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "participants": results, "group": h.groupChanged(c, client, jid)})
+}
+
+// SetGroupSubject handles PUT /groups/:jid/subject
+func (h *Handler) SetGroupSubject(c *gin.Context) {
+	var req GroupSubjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	middleware.SetAuditAction(c, "group.subject", req.Subject)
+	h.changeGroup(c, "change subject", func(ctx context.Context, client *whatsapp.Client, jid types.JID) error {
+		return client.WAClient.SetGroupName(ctx, jid, req.Subject)
+	})
+}
+
+// SetGroupDescription handles PUT /groups/:jid/description
+func (h *Handler) SetGroupDescription(c *gin.Context) {
+	var req GroupDescriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	middleware.SetAuditAction(c, "group.description", req.Description)
+	h.changeGroup(c, "change description", func(ctx context.Context, client *whatsapp.Client, jid types.JID) error {
+		return client.WAClient.SetGroupTopic(ctx, jid, "", "", req.Description)
+	})
+}
+
+// SetGroupPicture handles PUT /groups/:jid/picture
+func (h *Handler) SetGroupPicture(c *gin.Context) {
+	var req GroupPictureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	// The session and key are checked before anything is downloaded
+	client, jid, ok := h.groupClient(c)
+	if !ok {
+		return
+	}
+	middleware.SetAuditAction(c, "group.picture", req.URL)
+	data, contentType, err := downloadImage(req.URL)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Failed to download picture: " + err.Error()})
+		return
+	}
+	if data, err = jpegImage(data, contentType); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	h.applyGroupChange(c, client, jid, "change picture", func(ctx context.Context, client *whatsapp.Client, jid types.JID) error {
+		if _, err := client.WAClient.SetGroupPhoto(ctx, jid, data); err != nil {
+			return err
+		}
+		h.clearChatPicture(ctx, client, jid)
+		return nil
+	})
+}
+
+// DeleteGroupPicture handles DELETE /groups/:jid/picture
+func (h *Handler) DeleteGroupPicture(c *gin.Context) {
+	middleware.SetAuditAction(c, "group.picture.remove")
+	h.changeGroup(c, "remove picture", func(ctx context.Context, client *whatsapp.Client, jid types.JID) error {
+		if _, err := client.WAClient.SetGroupPhoto(ctx, jid, nil); err != nil {
+			return err
+		}
+		h.clearChatPicture(ctx, client, jid)
+		return nil
+	})
+}
+
+// SetGroupSettings handles PUT /groups/:jid/settings
+func (h *Handler) SetGroupSettings(c *gin.Context) {
+	var req GroupSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if req.Announce == nil && req.Locked == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "announce or locked is required"})
+		return
+	}
+	var settings []string
+	if req.Announce != nil {
+		settings = append(settings, fmt.Sprintf("announce=%t", *req.Announce))
+	}
+	if req.Locked != nil {
+		settings = append(settings, fmt.Sprintf("locked=%t", *req.Locked))
+	}
+	middleware.SetAuditAction(c, "group.settings", settings...)
+	h.changeGroup(c, "change settings", func(ctx context.Context, client *whatsapp.Client, jid types.JID) error {
+		if req.Announce != nil {
+			if err := client.WAClient.SetGroupAnnounce(ctx, jid, *req.Announce); err != nil {
+				return err
+			}
+		}
+		if req.Locked != nil {
+			return client.WAClient.SetGroupLocked(ctx, jid, *req.Locked)
+		}
+		return nil
+	})
+}
+
+// GetGroupInviteLink handles GET /groups/:jid/invite-link
+func (h *Handler) GetGroupInviteLink(c *gin.Context) {
+	h.groupInviteLink(c, false)
+}
+
+// RevokeGroupInviteLink handles POST /groups/:jid/invite-link/revoke
+// The old link stops working and a new one is returned
+func (h *Handler) RevokeGroupInviteLink(c *gin.Context) {
+	h.groupInviteLink(c, true)
+}
+
+func (h *Handler) groupInviteLink(c *gin.Context, reset bool) {
+	client, jid, ok := h.groupClient(c)
+	if !ok {
+		return
+	}
+	if reset {
+		middleware.SetAuditAction(c, "group.invite-link.revoke")
+	}
+	link, err := client.WAClient.GetGroupInviteLink(c.Request.Context(), jid, reset)
+	if err != nil {
+		c.JSON(groupErrorStatus(err), gin.H{"success": false, "error": "Failed to get invite link: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "jid": jid.String(), "inviteLink": link})
+}
+
+// groupClient resolves the :jid group and the connected session (?session=, default bot) acting on it,
+// responding with an error if either is invalid or, for anything but a GET, the session cannot send.
+// The group is recorded as the audit recipient.
+func (h *Handler) groupClient(c *gin.Context) (*whatsapp.Client, types.JID, bool) {
+	jid, err := types.ParseJID(c.Param("jid"))
+	if err != nil || !storage.IsGroupJID(jid.String()) {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid group JID: " + c.Param("jid")})
+		return nil, jid, false
+	}
+	middleware.SetAuditRecipient(c, jid.String())
+
+	session, ok := h.sessionParam(c)
+	if !ok {
+		return nil, jid, false
+	}
+	client, err := h.connectedClient(middleware.CurrentKey(c), session)
+	if err != nil {
+		c.JSON(statusOf(err), gin.H{"success": false, "error": err.Error()})
+		return nil, jid, false
+	}
+	if c.Request.Method != http.MethodGet && !client.Role.CanSend() {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "error": "Session " + session + " is read-only"})
+		return nil, jid, false
+	}
+	return client, jid, true
+}
+
+// changeGroup applies a change to the :jid group and responds with the group as it is afterwards
+func (h *Handler) changeGroup(c *gin.Context, what string, change func(ctx context.Context, client *whatsapp.Client, jid types.JID) error) {
+	client, jid, ok := h.groupClient(c)
+	if !ok {
+		return
+	}
+	h.applyGroupChange(c, client, jid, what, change)
+}
+
+// applyGroupChange applies a change to a group through a resolved client and responds with the group as it is afterwards
+func (h *Handler) applyGroupChange(c *gin.Context, client *whatsapp.Client, jid types.JID, what string, change func(ctx context.Context, client *whatsapp.Client, jid types.JID) error) {
+	if err := change(c.Request.Context(), client, jid); err != nil {
+		c.JSON(groupErrorStatus(err), gin.H{"success": false, "error": "Failed to " + what + ": " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "group": h.groupChanged(c, client, jid)})
+}
+
+// groupChanged refetches a group after a change and updates its stored chat; a failed refetch
+// is only logged since the change itself went through
+func (h *Handler) groupChanged(c *gin.Context, client *whatsapp.Client, jid types.JID) *whatsapp.Group {
+	group, err := client.GroupInfo(c.Request.Context(), jid, true)
+	if err != nil {
+		log.Printf("⚠️ Failed to refresh group %s: %v", jid, err)
+		return nil
+	}
+	h.saveGroupChat(c.Request.Context(), client, group)
+	return group
+}
+
+// saveGroupChat stores the group's chat record under its subject and tells WebSocket clients
+func (h *Handler) saveGroupChat(ctx context.Context, client *whatsapp.Client, group *whatsapp.Group) {
+	if h.Repo != nil {
+		if err := h.Repo.UpsertChat(ctx, client.ID, group.JID, group.Name); err != nil {
+			log.Printf("⚠️ Failed to store group chat %s: %v", group.JID, err)
+		}
+	}
+	if h.WSHub != nil {
+		h.WSHub.Publish("chat-update", websocket.Topic{Session: client.ID, Chat: group.JID}, gin.H{
+			"id":      group.JID,
+			"name":    group.Name,
+			"isGroup": true,
+		})
+	}
+}
+
+// clearChatPicture drops the stored picture of the session's chat so the chat list fetches the current one
+func (h *Handler) clearChatPicture(ctx context.Context, client *whatsapp.Client, jid types.JID) {
+	if h.Repo != nil {
+		_ = h.Repo.UpdateChatProfilePic(ctx, client.ID, jid.String(), "")
+	}
+}
+
+// participantJIDs parses phone numbers or JIDs of group members
+func participantJIDs(participants []string) ([]types.JID, error) {
+	jids := make([]types.JID, 0, len(participants))
+	for _, p := range participants {
+		jid, err := chatJID(p)
+		if err != nil {
+			return nil, err
+		}
+		jids = append(jids, jid)
+	}
+	return jids, nil
+}
+
+// jidStrings formats JIDs for the audit log
+func jidStrings(jids []types.JID) []string {
+	s := make([]string, len(jids))
+	for i, jid := range jids {
+		s[i] = jid.String()
+	}
+	return s
+}
+
+// groupErrorStatus maps a whatsmeow group error to the response status
+func groupErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, whatsmeow.ErrGroupNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, whatsmeow.ErrNotInGroup), errors.Is(err, whatsmeow.ErrIQForbidden),
+		errors.Is(err, whatsmeow.ErrIQNotAuthorized), errors.Is(err, whatsmeow.ErrGroupInviteLinkUnauthorized):
+		return http.StatusForbidden
+	case errors.Is(err, whatsmeow.ErrInvalidImageFormat), errors.Is(err, whatsmeow.ErrIQNotAcceptable):
+		return http.StatusBadRequest
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+// jpegImage returns the image as JPEG, which is the only format WhatsApp accepts for group pictures
+func jpegImage(data []byte, contentType string) ([]byte, error) {
+	if contentType == "image/jpeg" {
+		return data, nil
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.New("picture must be a JPEG or PNG image")
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
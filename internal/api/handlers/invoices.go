@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"wa-server-go/internal/api/middleware"
+	"wa-server-go/internal/features/reminder"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SaveInvoiceRequest represents the request body for POST /invoices
+type SaveInvoiceRequest struct {
+	Number     string `json:"number" binding:"required"`
+	ClientName string `json:"clientName"`
+	Phone      string `json:"phone" binding:"required"`
+	DueDate    string `json:"dueDate" binding:"required"` // YYYY-MM-DD in the reminder timezone, or RFC3339
+	Amount     int64  `json:"amount"`                     // remaining amount in Rupiah
+	Status     string `json:"status,omitempty"`           // unpaid (default for new invoices), partial, paid, cancelled; kept when omitted
+	PdfURL     string `json:"pdfUrl,omitempty"`           // attached to every reminder
+	Session    string `json:"session,omitempty"`
+}
+
+// MarkInvoicePaidRequest represents the optional request body for POST /invoices/:id/paid
+type MarkInvoicePaidRequest struct {
+	Notify bool `json:"notify"` // send the "paid" confirmation
+}
+
+// RegisterInvoiceReminders lets the reminder engine send invoice messages through the outbox
+func (h *Handler) RegisterInvoiceReminders(reminders *reminder.InvoiceReminderService) {
+	h.Reminders = reminders
+	reminders.SetSender(h.enqueueInvoiceReminder)
+}
+
+// enqueueInvoiceReminder queues an invoice message (with its PDF) and returns the outbox job ID
+func (h *Handler) enqueueInvoiceReminder(ctx context.Context, invoice *reminder.Invoice, message string) (string, error) {
+	if h.Outbox == nil {
+		return "", fmt.Errorf("outbox is not configured")
+	}
+	client, ok := h.WAManager.GetClient(invoice.Session)
+	if !ok {
+		return "", fmt.Errorf("session %s is not running", invoice.Session)
+	}
+	if !client.Role.CanSend() {
+		return "", fmt.Errorf("session %s is read-only", invoice.Session)
+	}
+
+	payload := SendInvoiceRequest{
+		Number:     invoice.Phone,
+		Message:    message,
+		PdfURL:     invoice.PdfURL,
+		ClientName: invoice.ClientName,
+		Session:    invoice.Session,
+	}
+	if invoice.PdfURL != "" {
+		payload.FileName = "Invoice-" + strings.ReplaceAll(invoice.Number, "/", "-") + ".pdf"
+	}
+
+	job, err := h.Outbox.Enqueue(ctx, jobKindInvoice, invoice.Session, invoice.Phone, payload)
+	if err != nil {
+		return "", err
+	}
+	return job.ID, nil
+}
+
+// SaveInvoice handles POST /invoices
+// Creates the invoice, or updates the invoice with the same number
+func (h *Handler) SaveInvoice(c *gin.Context) {
+	if h.Reminders == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "Invoice reminders are not configured"})
+		return
+	}
+
+	var req SaveInvoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	dueDate, err := time.ParseInLocation("2006-01-02", req.DueDate, h.Reminders.Location())
+	if err != nil {
+		if dueDate, err = time.Parse(time.RFC3339, req.DueDate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "dueDate must be YYYY-MM-DD or RFC3339"})
+			return
+		}
+	}
+
+	session := req.Session
+	if session == "" {
+		session = h.DefaultSession
+	}
+	middleware.SetAuditRecipient(c, req.Phone)
+	if !middleware.SessionAllowed(c, session) {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "error": "API key is not allowed to use session " + session})
+		return
+	}
+	if client, ok := h.WAManager.GetClient(session); ok && !client.Role.CanSend() {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "error": "Session " + session + " is read-only"})
+		return
+	}
+
+	// The number may belong to an invoice of a session the key cannot see
+	existing, err := h.Reminders.GetByNumber(c.Request.Context(), req.Number)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if key := middleware.CurrentKey(c); existing != nil && key != nil && !key.AllowsSession(existing.Session) {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "error": "API key is not allowed to change invoice " + req.Number})
+		return
+	}
+
+	invoice := &reminder.Invoice{
+		Number:     req.Number,
+		ClientName: req.ClientName,
+		Phone:      req.Phone,
+		DueDate:    dueDate,
+		Amount:     req.Amount,
+		Status:     reminder.InvoiceStatus(req.Status),
+		PdfURL:     req.PdfURL,
+		Session:    session,
+	}
+	if err := h.Reminders.Save(c.Request.Context(), invoice); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "invoice": invoice})
+}
+
+// ListInvoices handles GET /invoices?status=&limit=
+func (h *Handler) ListInvoices(c *gin.Context) {
+	if h.Reminders == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "Invoice reminders are not configured"})
+		return
+	}
+
+	// Keys restricted to some sessions only see their invoices
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	invoices, err := h.Reminders.List(c.Request.Context(), reminder.InvoiceStatus(c.Query("status")), keySessions(c), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "invoices": invoices})
+}
+
+// GetInvoice handles GET /invoices/:id
+// Includes the dunning history of the invoice
+func (h *Handler) GetInvoice(c *gin.Context) {
+	invoice, ok := h.loadInvoice(c)
+	if !ok {
+		return
+	}
+
+	steps, err := h.Reminders.Steps(c.Request.Context(), invoice.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "invoice": invoice, "steps": steps})
+}
+
+// MarkInvoicePaid handles POST /invoices/:id/paid
+// Stops further reminders; {"notify": true} also sends the "paid" confirmation
+func (h *Handler) MarkInvoicePaid(c *gin.Context) {
+	invoice, ok := h.loadInvoice(c)
+	if !ok {
+		return
+	}
+
+	var req MarkInvoicePaidRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+	}
+
+	middleware.SetAuditRecipient(c, invoice.Phone)
+	updated, err := h.Reminders.MarkPaid(c.Request.Context(), invoice.ID, req.Notify)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "invoice": updated})
+}
+
+// loadInvoice fetches the invoice named in the URL and checks the API key may see it
+func (h *Handler) loadInvoice(c *gin.Context) (*reminder.Invoice, bool) {
+	if h.Reminders == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "Invoice reminders are not configured"})
+		return nil, false
+	}
+
+	invoice, err := h.Reminders.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return nil, false
+	}
+	if invoice == nil || !middleware.SessionAllowed(c, invoice.Session) {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Invoice not found"})
+		return nil, false
+	}
+	return invoice, true
+}
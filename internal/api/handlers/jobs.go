@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"wa-server-go/internal/api/middleware"
+	"wa-server-go/internal/apikey"
+	"wa-server-go/internal/outbox"
+	"wa-server-go/internal/whatsapp"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterJobHandlers registers the outbox delivery functions for each send kind
+func (h *Handler) RegisterJobHandlers(queue *outbox.Queue) {
+	h.Outbox = queue
+	queue.Register(jobKindText, h.deliverText)
+	queue.Register(jobKindInvoice, h.deliverInvoice)
+	queue.Register(jobKindMedia, h.deliverMedia)
+}
+
+// GetJob handles GET /jobs/:id
+// A job of a session the API key may not use is reported as missing
+func (h *Handler) GetJob(c *gin.Context) {
+	if h.Outbox == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "Outbox is not configured"})
+		return
+	}
+
+	job, err := h.Outbox.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if job == nil || !middleware.SessionAllowed(c, job.ClientID) {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Job not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "job": job})
+}
+
+// enqueueSend queues a send on the given session (the default session if empty) and responds with the job ID
+func (h *Handler) enqueueSend(c *gin.Context, kind, session, recipient string, payload interface{}) {
+	if session == "" {
+		session = h.DefaultSession
+	}
+	middleware.SetAuditRecipient(c, recipient)
+	if !middleware.SessionAllowed(c, session) {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "error": "API key is not allowed to use session " + session})
+		return
+	}
+
+	job, err := h.queueSend(c.Request.Context(), middleware.CurrentKey(c), kind, session, recipient, payload)
+	if err != nil {
+		c.JSON(statusOf(err), gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"message": "Message queued",
+		"jobId":   job.ID,
+		"session": session,
+		"status":  job.Status,
+	})
+}
+
+// queueSend checks that key may send on the session and queues the send.
+// It is shared by the REST endpoints and WebSocket commands.
+func (h *Handler) queueSend(ctx context.Context, key *apikey.Key, kind, session, recipient string, payload interface{}) (*outbox.Job, error) {
+	if h.Outbox == nil {
+		return nil, &requestError{http.StatusServiceUnavailable, "Outbox is not configured"}
+	}
+	if key != nil && !key.AllowsSession(session) {
+		return nil, &requestError{http.StatusForbidden, "API key is not allowed to use session " + session}
+	}
+	client, ok := h.WAManager.GetClient(session)
+	if !ok {
+		return nil, &requestError{http.StatusNotFound, "Session not found: " + session}
+	}
+	if !client.Role.CanSend() {
+		return nil, &requestError{http.StatusForbidden, "Session " + session + " is read-only"}
+	}
+	return h.Outbox.Enqueue(ctx, kind, session, recipient, payload)
+}
+
+// requestError is an error caused by the request, with the HTTP status it maps to
+type requestError struct {
+	status  int
+	message string
+}
+
+func (e *requestError) Error() string { return e.message }
+
+// StatusCode returns the HTTP status of the error
+func (e *requestError) StatusCode() int { return e.status }
+
+// statusOf returns the HTTP status of err: the request error's own, or 500
+func statusOf(err error) int {
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		return reqErr.status
+	}
+	return http.StatusInternalServerError
+}
+
+// readyClient returns the job's WhatsApp client, or ErrClientNotReady to postpone the job
+func (h *Handler) readyClient(clientID string) (*whatsapp.Client, error) {
+	client, ok := h.WAManager.GetClient(clientID)
+	if !ok || !client.IsReady() {
+		return nil, outbox.ErrClientNotReady
+	}
+	return client, nil
+}
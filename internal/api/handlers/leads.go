@@ -7,8 +7,7 @@ import (
 	"net/http"
 	"strings"
 	"sync"
-	"time"
-	"wa-server-go/internal/utils"
+	"wa-server-go/internal/whatsapp"
 
 	"github.com/gin-gonic/gin"
 	"go.mau.fi/whatsmeow"
@@ -20,21 +19,14 @@ import (
 // Fetches contacts from Number B (Leads) filtered by "Leads for Web" label
 func (h *Handler) SyncContacts(c *gin.Context) {
 	// Auto-Start 'leads' client logic
-	clientID := "leads"
-	client, exists := h.WAManager.GetClient(clientID)
+	client, exists := h.WAManager.GetClient(h.LeadsSession)
 
 	if !exists {
 		// Create and Connect
-		err := h.WAManager.CreateClient(context.Background(), clientID, "session-leads.db")
-		if err != nil {
+		if err := h.startLeadsSession(); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
 			return
 		}
-		_ = h.WAManager.SetupEventHandlers(clientID)
-		
-		go func() {
-			_ = h.WAManager.Connect(context.Background(), clientID)
-		}()
 
 		c.JSON(http.StatusOK, gin.H{
 			"success":    false,
@@ -80,7 +72,7 @@ func (h *Handler) SyncContacts(c *gin.Context) {
 	fmt.Printf("🏷️ [leads] App state fetch completed. Labels in store: %d\n", len(h.WAManager.LabelStore.GetAllLabels()))
 
 	// Get all contacts first
-	contacts, err := client.WAClient.Store.Contacts.GetAllContacts(ctx)
+	contacts, err := h.Contacts.List(ctx, client, "")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to fetch contacts"})
 		return
@@ -135,39 +127,20 @@ func (h *Handler) SyncContacts(c *gin.Context) {
 			
 			name := jid.User
 			displayID := jid.User
-			
-			// Try to get contact info first
-			contact, err := client.WAClient.Store.Contacts.GetContact(ctx, jid)
-			if err == nil && contact.Found {
-				if contact.FullName != "" {
-					name = contact.FullName
-				} else if contact.PushName != "" {
-					name = contact.PushName
-				} else if contact.BusinessName != "" {
-					name = contact.BusinessName
+
+			// Name and phone number from the contact directory
+			if contact, err := h.Contacts.Get(ctx, client, jid); err == nil {
+				if contact.Phone != "" {
+					displayID = contact.Phone
 				}
-			}
-			
-			// Resolve LID to Phone Number using helper
-			if jid.Server == "lid" {
-				resolvedNum, err := utils.ResolveLIDToPhoneNumber(client.WAClient, jid)
-				if err == nil && resolvedNum != "" {
-					displayID = resolvedNum
-					// If name is still user ID, try to use resolved phone as name
-					if name == jid.User {
-						name = displayID
-					}
+				if contact.Name != "" {
+					name = contact.Name
+				} else {
+					// Fall back to the resolved phone number as the name
+					name = displayID
 				}
-			} else {
-				displayID = strings.Replace(displayID, "@s.whatsapp.net", "", -1)
-			}
-			
-			// Only add if we have a valid phone number or LID
-			if jid.Server == "lid" && displayID == jid.User {
-				// We allow LIDs now as they are valid for sending, but log it
-				// fmt.Printf("ℹ️  Unresolved LID: %s\n", jid.User)
 			}
-			
+
 			// Get Profile Picture (Sync version) - simplified for non-streaming
 			var profilePicUrl string
 			pic, _ := client.WAClient.GetProfilePictureInfo(ctx, jid, &whatsmeow.GetProfilePictureParams{Preview: true})
@@ -187,21 +160,18 @@ func (h *Handler) SyncContacts(c *gin.Context) {
 		// STRATEGY B: Fallback to iterating all contacts if no label found (or filtering disabled)
 		fmt.Printf("🏷️ Filtering mode: Iterating through all %d contacts\n", len(contacts))
 		
-		for jid, contact := range contacts {
-			if contact.FullName == "" && contact.PushName == "" {
+		for _, contact := range contacts {
+			if contact.Name == "" {
 				continue
 			}
-			name := contact.FullName
-			if name == "" {
-				name = contact.PushName
+			formattedID := contact.Phone
+			if formattedID == "" {
+				formattedID = strings.TrimSuffix(contact.ID, "@"+types.HiddenUserServer)
 			}
 
-			// Clean up ID
-			formattedID := strings.Replace(jid.User, "@s.whatsapp.net", "", -1)
-
 			result = append(result, map[string]interface{}{
 				"id":    formattedID,
-				"name":  name,
+				"name":  contact.Name,
 				"phone": formattedID, // Added for frontend compatibility
 				"type":  "user",
 			})
@@ -220,10 +190,8 @@ func (h *Handler) SyncContacts(c *gin.Context) {
 
 // StartLeadsClient handles POST /start-leads-client
 func (h *Handler) StartLeadsClient(c *gin.Context) {
-	clientID := "leads"
-	
 	// Check if already exists
-	if client, exists := h.WAManager.GetClient(clientID); exists {
+	if client, exists := h.WAManager.GetClient(h.LeadsSession); exists {
 		if client.IsReady() {
 			c.JSON(http.StatusOK, gin.H{
 				"success": true,
@@ -235,10 +203,9 @@ func (h *Handler) StartLeadsClient(c *gin.Context) {
 		// For now assume if exists we just let it be or user should stop first
 	}
 
-	// Create client
+	// Create the client and connect in background
 	// Use a separate DB for leads session
-	err := h.WAManager.CreateClient(context.Background(), clientID, "session-leads.db")
-	if err != nil {
+	if err := h.startLeadsSession(); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"error":   "Failed to create leads client: " + err.Error(),
@@ -246,37 +213,23 @@ func (h *Handler) StartLeadsClient(c *gin.Context) {
 		return
 	}
 
-	// Setup events
-	err = h.WAManager.SetupEventHandlers(clientID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"error":   "Failed to setup event handlers: " + err.Error(),
-		})
-		return
-	}
-
-	// Connect in background
-	go func() {
-		// Use background context for long running connection
-		err := h.WAManager.Connect(context.Background(), clientID)
-		if err != nil {
-			// Log error (can't write to response anymore)
-			fmt.Printf("❌ Failed to connect leads client: %v\n", err)
-		}
-	}()
-
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "Leads client starting... please scan QR code",
 	})
 }
 
+// startLeadsSession starts the on-demand contact sync session in privacy mode
+func (h *Handler) startLeadsSession() error {
+	return h.WAManager.StartSession(context.Background(), whatsapp.SessionRecord{
+		ID:   h.LeadsSession,
+		Role: whatsapp.RolePrivacy,
+	})
+}
+
 // StopLeadsClient handles POST /stop-leads-client
 func (h *Handler) StopLeadsClient(c *gin.Context) {
-	clientID := "leads"
-	
-	err := h.WAManager.DestroyClient(clientID)
+	err := h.WAManager.DestroyClient(h.LeadsSession)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -316,21 +269,14 @@ func (h *Handler) SyncContactsStream(c *gin.Context) {
 	}
 
 	// Auto-Start 'leads' client logic
-	clientID := "leads"
-	client, exists := h.WAManager.GetClient(clientID)
+	client, exists := h.WAManager.GetClient(h.LeadsSession)
 
 	if !exists {
 		// Create and Connect
-		err := h.WAManager.CreateClient(context.Background(), clientID, "session-leads.db")
-		if err != nil {
+		if err := h.startLeadsSession(); err != nil {
 			sendEvent("error", gin.H{"error": err.Error()})
 			return
 		}
-		_ = h.WAManager.SetupEventHandlers(clientID)
-
-		go func() {
-			_ = h.WAManager.Connect(context.Background(), clientID)
-		}()
 
 		sendEvent("status", gin.H{"status": "requiresQR", "message": "Session started. Please scan QR code."})
 		return
@@ -393,14 +339,9 @@ func (h *Handler) SyncContactsStream(c *gin.Context) {
 	// Process regular JIDs first (no rate limiting needed)
 	processedCount := 0
 	for _, jid := range regularJIDs {
-		contact, _ := client.WAClient.Store.Contacts.GetContact(ctx, jid)
 		name := jid.User
-		if contact.Found {
-			if contact.FullName != "" {
-				name = contact.FullName
-			} else if contact.PushName != "" {
-				name = contact.PushName
-			}
+		if contact, err := h.Contacts.Get(ctx, client, jid); err == nil && contact.Name != "" {
+			name = contact.Name
 		}
 
 		displayID := strings.Replace(jid.User, "@s.whatsapp.net", "", -1)
@@ -414,95 +355,31 @@ func (h *Handler) SyncContactsStream(c *gin.Context) {
 		processedCount++
 	}
 
-	// Initialize Cache (Global)
-	cachePath := "lid_mapping.json"
-	utils.InitGlobalCache(cachePath)
-	lidCache := utils.GlobalLIDCache // Use the singleton
-
-	// Process LIDs
-	fmt.Printf("🏷️ Processing %d LIDs (Smart Cache Mode)...\n", len(lidJIDs))
-	
-	// Separate Cached vs Uncached
-	var uncachedLIDs []types.JID
+	// Process LIDs: the LID store and learned mappings first, then WhatsApp for the rest
+	fmt.Printf("🏷️ Processing %d LIDs...\n", len(lidJIDs))
 	lidToResolvedID := make(map[string]string)
-	var pendingPics []types.JID
 
-	for _, lidJID := range lidJIDs {
+	// Lookups stop when the client goes away
+	resolved, err := h.WAManager.LIDs.Resolve(c.Request.Context(), client, lidJIDs, true)
+	if err != nil {
+		fmt.Printf("⚠️ Failed to resolve LIDs: %v\n", err)
+	}
+	for i, lidJID := range lidJIDs {
 		// Default to LID
 		lidToResolvedID[lidJID.User] = lidJID.User
-		
-		// Check Cache
-		if phone, found := lidCache.Get(lidJID.User); found {
-			lidToResolvedID[lidJID.User] = phone
-		} else {
-			uncachedLIDs = append(uncachedLIDs, lidJID)
-		}
-		pendingPics = append(pendingPics, lidJID)
-	}
-	
-	fmt.Printf("📦 Cached: %d, Needing Resolution: %d\n", len(lidJIDs)-len(uncachedLIDs), len(uncachedLIDs))
-
-	// Batch process ONLY uncached LIDs
-	if len(uncachedLIDs) > 0 {
-		batchSize := 1 // Single item for safety
-		networkDisabled := false // Circuit breaker
-		
-		for i := 0; i < len(uncachedLIDs); i += batchSize {
-			if networkDisabled {
-				break // Stop processing network requests
-			}
-
-			end := i + batchSize
-			if end > len(uncachedLIDs) {
-				end = len(uncachedLIDs)
+		if resolved != nil && resolved[i].Phone != "" {
+			if phone, err := types.ParseJID(resolved[i].Phone); err == nil {
+				lidToResolvedID[lidJID.User] = phone.User
 			}
-			batch := uncachedLIDs[i:end]
-			
-			// Try Network Resolution
-			resp, err := client.WAClient.GetUserInfo(ctx, batch)
-			if err != nil {
-				if strings.Contains(err.Error(), "429") || strings.Contains(err.Error(), "rate-overlimit") {
-					fmt.Printf("⚠️ Rate Limit Hit (429). Circuit Breaker Activated. Switching to local-only for remaining %d LIDs.\n", len(uncachedLIDs)-i)
-					networkDisabled = true
-					continue
-				}
-				// Other network error - just log and continue (maybe wait a bit)
-				fmt.Printf("⚠️ Network Error on batch: %v\n", err)
-				time.Sleep(1 * time.Second) // Light delay
-				continue
-			}
-				
-			// Success
-			for lidJID, info := range resp {
-				for _, device := range info.Devices {
-					if device.Server == "s.whatsapp.net" {
-						// Update Map & Cache
-						lidToResolvedID[lidJID.User] = device.User
-						lidCache.Set(lidJID.User, device.User)
-						fmt.Printf("   ✅ Resolved: %s -> %s\n", lidJID.User, device.User)
-						break
-					}
-				}
-			}
-			
-			// Respectful delay between successful requests
-			time.Sleep(2 * time.Second)
 		}
 	}
 
 	// Send Events
 	for _, lidJID := range lidJIDs {
 		// Get local name info
-		contact, _ := client.WAClient.Store.Contacts.GetContact(ctx, lidJID)
 		name := lidJID.User
-		if contact.Found {
-			if contact.PushName != "" {
-				name = contact.PushName
-			} else if contact.FullName != "" {
-				name = contact.FullName
-			} else if contact.BusinessName != "" {
-				name = contact.BusinessName
-			}
+		if contact, err := h.Contacts.Get(ctx, client, lidJID); err == nil && contact.Name != "" {
+			name = contact.Name
 		}
 		
 		displayID := lidToResolvedID[lidJID.User]
@@ -533,12 +410,12 @@ func (h *Handler) SyncContactsStream(c *gin.Context) {
 	
 	// Fetch profile pictures concurrently (but block complete signal)
 	sendEvent("progress", gin.H{
-		"message": fmt.Sprintf("Fetching profile pictures for %d contacts...", len(pendingPics)),
+		"message": fmt.Sprintf("Fetching profile pictures for %d contacts...", len(lidJIDs)),
 	})
 	
 	// Worker pool for profile pics
 	workerCount := 5
-	jobs := make(chan types.JID, len(pendingPics))
+	jobs := make(chan types.JID, len(lidJIDs))
 	var wg sync.WaitGroup
 	
 	for w := 0; w < workerCount; w++ {
@@ -577,7 +454,7 @@ func (h *Handler) SyncContactsStream(c *gin.Context) {
 		ID  string
 		URL string
 	}
-	results := make(chan PicResult, len(pendingPics))
+	results := make(chan PicResult, len(lidJIDs))
 	
 	// Re-spawn workers to write to results channel
 	// Actually let's rewrite the worker part simpler:
@@ -586,7 +463,7 @@ func (h *Handler) SyncContactsStream(c *gin.Context) {
 		localWg := sync.WaitGroup{}
 		semaphore := make(chan struct{}, 5) // Limit 5 concurrent requests
 		
-		for _, jid := range pendingPics {
+		for _, jid := range lidJIDs {
 			localWg.Add(1)
 			go func(targetJID types.JID) {
 				defer localWg.Done()
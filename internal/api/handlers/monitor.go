@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetMonitor handles GET /monitor
+func (h *Handler) GetMonitor(c *gin.Context) {
+	if h.Monitor == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "Monitor is not configured"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "targets": h.Monitor.GetStatus()})
+}
+
+// GetMonitorHistory handles GET /monitor/:target/history?limit=
+func (h *Handler) GetMonitorHistory(c *gin.Context) {
+	if h.Monitor == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "Monitor is not configured"})
+		return
+	}
+
+	target := c.Param("target")
+	if !h.Monitor.HasTarget(target) {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Monitor target not found"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	checks, err := h.Monitor.History(c.Request.Context(), target, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "target": target, "checks": checks})
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"wa-server-go/internal/api/middleware"
+	"wa-server-go/internal/features/scheduler"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateScheduledMessageRequest represents the request body for POST /scheduled-messages
+type CreateScheduledMessageRequest struct {
+	Number    string     `json:"number" binding:"required"`
+	Message   string     `json:"message,omitempty"` // text, or the caption of media
+	MediaURL  string     `json:"mediaUrl,omitempty"`
+	MediaType string     `json:"mediaType,omitempty"`
+	SendAt    *time.Time `json:"sendAt,omitempty"` // RFC3339, for a one-shot message
+	Cron      string     `json:"cron,omitempty"`   // e.g. "0 9 1 * *" for monthly reminders
+	Timezone  string     `json:"timezone,omitempty"`
+	Session   string     `json:"session,omitempty"`
+}
+
+// RegisterScheduler lets the scheduler deliver due messages through the outbox
+func (h *Handler) RegisterScheduler(schedules *scheduler.SchedulerService) {
+	h.Scheduler = schedules
+	schedules.SetSender(h.enqueueScheduled)
+}
+
+// enqueueScheduled queues one run of a scheduled message and returns the outbox job ID
+func (h *Handler) enqueueScheduled(ctx context.Context, msg *scheduler.ScheduledMessage) (string, error) {
+	if h.Outbox == nil {
+		return "", fmt.Errorf("outbox is not configured")
+	}
+	client, ok := h.WAManager.GetClient(msg.Session)
+	if !ok {
+		return "", fmt.Errorf("session %s is not running", msg.Session)
+	}
+	if !client.Role.CanSend() {
+		return "", fmt.Errorf("session %s is read-only", msg.Session)
+	}
+
+	var kind string
+	var payload interface{}
+	switch msg.Kind {
+	case scheduler.KindMedia:
+		kind = jobKindMedia
+		payload = SendMediaRequest{Number: msg.Recipient, MediaURL: msg.MediaURL, Caption: msg.Message,
+			MediaType: msg.MediaType, Session: msg.Session}
+	default:
+		kind = jobKindText
+		payload = SendMessageRequest{Number: msg.Recipient, Message: msg.Message, Session: msg.Session}
+	}
+
+	job, err := h.Outbox.Enqueue(ctx, kind, msg.Session, msg.Recipient, payload)
+	if err != nil {
+		return "", err
+	}
+	return job.ID, nil
+}
+
+// CreateScheduledMessage handles POST /scheduled-messages
+func (h *Handler) CreateScheduledMessage(c *gin.Context) {
+	if h.Scheduler == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "Scheduler is not configured"})
+		return
+	}
+
+	var req CreateScheduledMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if req.Message == "" && req.MediaURL == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "message or mediaUrl is required"})
+		return
+	}
+
+	session := req.Session
+	if session == "" {
+		session = h.DefaultSession
+	}
+	middleware.SetAuditRecipient(c, req.Number)
+	if !middleware.SessionAllowed(c, session) {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "error": "API key is not allowed to use session " + session})
+		return
+	}
+	if client, ok := h.WAManager.GetClient(session); ok && !client.Role.CanSend() {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "error": "Session " + session + " is read-only"})
+		return
+	}
+
+	msg := &scheduler.ScheduledMessage{
+		Session:   session,
+		Recipient: req.Number,
+		Kind:      scheduler.KindText,
+		Message:   req.Message,
+		MediaURL:  req.MediaURL,
+		MediaType: req.MediaType,
+		SendAt:    req.SendAt,
+		Cron:      req.Cron,
+		Timezone:  req.Timezone,
+	}
+	if req.MediaURL != "" {
+		msg.Kind = scheduler.KindMedia
+	}
+
+	if err := h.Scheduler.Create(c.Request.Context(), msg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "schedule": msg})
+}
+
+// ListScheduledMessages handles GET /scheduled-messages?status=&limit=
+func (h *Handler) ListScheduledMessages(c *gin.Context) {
+	if h.Scheduler == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "Scheduler is not configured"})
+		return
+	}
+
+	// Keys restricted to some sessions only see their schedules
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	schedules, err := h.Scheduler.List(c.Request.Context(), scheduler.Status(c.Query("status")), keySessions(c), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "schedules": schedules})
+}
+
+// GetScheduledMessage handles GET /scheduled-messages/:id
+// Includes the most recent executions (?limit=, default 50)
+func (h *Handler) GetScheduledMessage(c *gin.Context) {
+	msg, ok := h.loadScheduledMessage(c)
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	executions, err := h.Scheduler.Executions(c.Request.Context(), msg.ID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "schedule": msg, "executions": executions})
+}
+
+// PauseScheduledMessage handles POST /scheduled-messages/:id/pause
+func (h *Handler) PauseScheduledMessage(c *gin.Context) {
+	h.transitionScheduledMessage(c, h.Scheduler.Pause)
+}
+
+// ResumeScheduledMessage handles POST /scheduled-messages/:id/resume
+func (h *Handler) ResumeScheduledMessage(c *gin.Context) {
+	h.transitionScheduledMessage(c, h.Scheduler.Resume)
+}
+
+// CancelScheduledMessage handles DELETE /scheduled-messages/:id
+func (h *Handler) CancelScheduledMessage(c *gin.Context) {
+	h.transitionScheduledMessage(c, h.Scheduler.Cancel)
+}
+
+func (h *Handler) transitionScheduledMessage(c *gin.Context, transition func(context.Context, string) (*scheduler.ScheduledMessage, error)) {
+	msg, ok := h.loadScheduledMessage(c)
+	if !ok {
+		return
+	}
+
+	updated, err := transition(c.Request.Context(), msg.ID)
+	if errors.Is(err, scheduler.ErrInvalidTransition) {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": fmt.Sprintf("Schedule is %s", msg.Status)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "schedule": updated})
+}
+
+// loadScheduledMessage fetches the schedule named in the URL and checks the API key may see it
+func (h *Handler) loadScheduledMessage(c *gin.Context) (*scheduler.ScheduledMessage, bool) {
+	if h.Scheduler == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "Scheduler is not configured"})
+		return nil, false
+	}
+
+	msg, err := h.Scheduler.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return nil, false
+	}
+	if msg == nil || !middleware.SessionAllowed(c, msg.Session) {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Scheduled message not found"})
+		return nil, false
+	}
+	return msg, true
+}
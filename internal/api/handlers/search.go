@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"wa-server-go/internal/api/middleware"
+	"wa-server-go/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SearchMessages handles GET /search/messages
+// Full-text search over stored messages (?q=), newest first, filtered by session, chatId, before, since, fromMe, type and hasMedia.
+// Without ?session= it searches every session the API key may use.
+func (h *Handler) SearchMessages(c *gin.Context) {
+	if h.Repo == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"error":   "Chat storage is not configured",
+		})
+		return
+	}
+
+	query := c.Query("q")
+	if len(storage.SearchTokens(query)) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "q must contain at least one word"})
+		return
+	}
+
+	page, ok := pageParams(c)
+	if !ok {
+		return
+	}
+	filter := storage.SearchFilter{
+		Query:  query,
+		Type:   c.Query("type"),
+		Before: page.Before,
+		Since:  page.Since,
+		Limit:  page.Limit,
+		Cursor: page.Cursor,
+	}
+	if c.Query("session") != "" {
+		session, ok := h.sessionParam(c)
+		if !ok {
+			return
+		}
+		filter.Sessions = []string{session}
+	} else if key := middleware.CurrentKey(c); key != nil && len(key.Sessions) > 0 {
+		filter.Sessions = key.Sessions
+	}
+	if chatID := c.Query("chatId"); chatID != "" {
+		jid, err := chatJID(chatID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+		filter.ChatID = jid.String()
+	}
+	var err error
+	if filter.FromMe, err = boolParam(c, "fromMe"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if filter.HasMedia, err = boolParam(c, "hasMedia"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	hits, nextCursor, err := h.Repo.SearchMessages(c.Request.Context(), filter)
+	if errors.Is(err, storage.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to search messages",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	results := make([]map[string]interface{}, 0, len(hits))
+	for _, hit := range hits {
+		result := mapMessages([]storage.WAMessage{hit.Message})[0]
+		result["chatId"] = hit.Message.ChatID
+		result["snippet"] = hit.Snippet
+		results = append(results, result)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"messages":   results,
+		"nextCursor": nextCursor,
+	})
+}
+
+// boolParam parses an optional true/false query parameter; nil means it was not given
+func boolParam(c *gin.Context, name string) (*bool, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, &requestError{http.StatusBadRequest, "Invalid " + name}
+	}
+	return &b, nil
+}
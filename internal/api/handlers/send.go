@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io"
 	"mime"
@@ -10,7 +11,8 @@ import (
 	"os"
 	"time"
 
-	"wa-server-go/internal/firestore"
+	"wa-server-go/internal/outbox"
+	"wa-server-go/internal/storage"
 	"wa-server-go/internal/utils"
 	"wa-server-go/internal/whatsapp"
 
@@ -23,12 +25,13 @@ import (
 
 // SendInvoiceRequest represents the request body for /send-invoice
 type SendInvoiceRequest struct {
-	Number    string `json:"number" binding:"required"`
-	Message   string `json:"message" binding:"required"`
-	PdfURL    string `json:"pdfUrl,omitempty"`
-	PdfBase64 string `json:"pdfBase64,omitempty"`
-	FileName  string `json:"fileName,omitempty"`
+	Number     string `json:"number" binding:"required"`
+	Message    string `json:"message" binding:"required"`
+	PdfURL     string `json:"pdfUrl,omitempty"`
+	PdfBase64  string `json:"pdfBase64,omitempty"`
+	FileName   string `json:"fileName,omitempty"`
 	ClientName string `json:"clientName,omitempty"`
+	Session    string `json:"session,omitempty"` // sending session, defaults to the bot session
 }
 
 // SendMessageRequest represents the request body for /send-message
@@ -36,6 +39,7 @@ type SendMessageRequest struct {
 	Number  string `json:"number,omitempty"`
 	Phone   string `json:"phone,omitempty"`
 	Message string `json:"message" binding:"required"`
+	Session string `json:"session,omitempty"` // sending session, defaults to the bot session
 }
 
 // SendMediaRequest represents the request body for /send-media
@@ -44,8 +48,19 @@ type SendMediaRequest struct {
 	MediaURL  string `json:"mediaUrl" binding:"required"`
 	Caption   string `json:"caption,omitempty"`
 	MediaType string `json:"mediaType,omitempty"`
+	Session   string `json:"session,omitempty"` // sending session, defaults to the bot session
 }
 
+// Job kinds delivered by the outbox
+const (
+	jobKindText    = "text"
+	jobKindInvoice = "invoice"
+	jobKindMedia   = "media"
+)
+
+// downloadClient fetches PDFs and media to send; a URL that never answers must not hold an outbox worker
+var downloadClient = &http.Client{Timeout: 2 * time.Minute}
+
 // SendInvoice handles POST /send-invoice
 func (h *Handler) SendInvoice(c *gin.Context) {
 	var req SendInvoiceRequest
@@ -54,27 +69,64 @@ func (h *Handler) SendInvoice(c *gin.Context) {
 		return
 	}
 
-	botClient, ok := h.WAManager.GetClient("bot")
-	if !ok || !botClient.IsReady() {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"success": false,
-			"error":   "WhatsApp Bot client is not ready",
-		})
-		return
+	h.enqueueSend(c, jobKindInvoice, req.Session, req.Number, req)
+}
+
+// deliverInvoice sends a queued invoice: the text message followed by the optional PDF.
+// The result of a failed attempt records the text message if it went out, so a retry only sends the PDF.
+func (h *Handler) deliverInvoice(ctx context.Context, job *outbox.Job) (map[string]interface{}, error) {
+	var req SendInvoiceRequest
+	if err := json.Unmarshal(job.Payload, &req); err != nil {
+		return nil, fmt.Errorf("invalid invoice payload: %w", err)
 	}
 
-	ctx := context.Background()
+	botClient, err := h.readyClient(job.ClientID)
+	if err != nil {
+		return nil, err
+	}
 
 	// Format phone number and create JID
 	jid := utils.PhoneToJID(req.Number)
 
+	// Update Chat Name if provided
+	chatName := jid.User // Default to phone number
+	if req.ClientName != "" {
+		chatName = req.ClientName
+		if h.Repo != nil {
+			_ = h.Repo.UpdateChatName(ctx, botClient.ID, jid.String(), req.ClientName)
+		}
+	}
+
+	messageID, _ := job.Result["messageId"].(string)
+	if messageID == "" {
+		if messageID, err = h.sendInvoiceText(ctx, botClient, jid, req.Message, chatName); err != nil {
+			return nil, err
+		}
+	}
+	result := map[string]interface{}{
+		"messageId": messageID,
+		"pdfSent":   false,
+	}
+
+	// Send PDF if provided
+	if req.PdfBase64 != "" || req.PdfURL != "" {
+		if err := h.sendPDF(ctx, botClient, jid, req.PdfBase64, req.PdfURL, req.FileName, chatName); err != nil {
+			return result, fmt.Errorf("failed to send PDF: %w", err)
+		}
+		result["pdfSent"] = true
+	}
+	return result, nil
+}
+
+// sendInvoiceText sends the text message of an invoice after a typing indicator and returns its message ID
+func (h *Handler) sendInvoiceText(ctx context.Context, botClient *whatsapp.Client, jid types.JID, message, chatName string) (string, error) {
 	// Normalize message newlines
-	normalizedMessage := utils.NormalizeNewlines(req.Message)
+	normalizedMessage := utils.NormalizeNewlines(message)
 
 	// Anti-bot: Simulate typing indicator to appear more human-like
 	// 1. Send "composing" (typing) presence
 	_ = botClient.WAClient.SendChatPresence(ctx, jid, types.ChatPresenceComposing, types.ChatPresenceMediaText)
-	
+
 	// 2. Wait based on message length (simulates typing time)
 	typingDelay := len(normalizedMessage) * 50 // ~50ms per character
 	if typingDelay < 2000 {
@@ -84,7 +136,7 @@ func (h *Handler) SendInvoice(c *gin.Context) {
 		typingDelay = 8000
 	}
 	utils.HumanizeDelay(typingDelay, typingDelay+2000)
-	
+
 	// 3. Stop typing indicator
 	_ = botClient.WAClient.SendChatPresence(ctx, jid, types.ChatPresencePaused, types.ChatPresenceMediaText)
 
@@ -93,104 +145,71 @@ func (h *Handler) SendInvoice(c *gin.Context) {
 		Conversation: proto.String(normalizedMessage),
 	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"error":   fmt.Sprintf("Failed to send message: %v", err),
-		})
-		return
-	}
-
-	// Update Chat Name if provided
-	chatName := jid.User // Default to phone number
-	if req.ClientName != "" {
-		chatName = req.ClientName
-		if h.Repo != nil {
-			_ = h.Repo.UpdateChatName(ctx, jid.String(), req.ClientName)
-		}
+		return "", fmt.Errorf("failed to send message: %w", err)
 	}
 
 	// Manual Save & Broadcast for Text (Fail-safe)
-	go func() {
-		dbMsg := &firestore.WAMessage{
-			MessageID: resp.ID,
-			ChatID:    jid.String(),
-			From:      botClient.WAClient.Store.ID.ToNonAD().String(),
-			To:        jid.String(),
-			Body:      normalizedMessage,
-			Timestamp: resp.Timestamp,
-			FromMe:    true,
-			HasMedia:  false,
-			Type:      "text",
-			Ack:       1,
-		}
-		if h.Repo != nil {
-			_ = h.Repo.SaveMessage(context.Background(), dbMsg)
-			_ = h.Repo.SetChatHasInvoice(context.Background(), jid.String(), true)
+	dbMsg := &storage.WAMessage{
+		Session:   botClient.ID,
+		MessageID: resp.ID,
+		ChatID:    jid.String(),
+		From:      botClient.WAClient.Store.ID.ToNonAD().String(),
+		To:        jid.String(),
+		Body:      normalizedMessage,
+		Timestamp: resp.Timestamp,
+		FromMe:    true,
+		HasMedia:  false,
+		Type:      "text",
+		Ack:       storage.AckServer,
+	}
+	if h.Repo != nil {
+		if err := h.WAManager.SaveSentMessage(context.Background(), dbMsg); err != nil {
+			fmt.Printf("⚠️ Failed to save sent message %s: %v\n", dbMsg.MessageID, err)
 		}
-
-		h.WAManager.BroadcastMessage(whatsapp.NewMessageEvent{
-			Client:    "bot",
-			ID:        resp.ID,
-			From:      dbMsg.From,
-			To:        dbMsg.To,
-			Body:      dbMsg.Body,
-			Timestamp: resp.Timestamp.Unix(),
-			FromMe:    true,
-			ChatID:    jid.String(),
-			ChatName:  chatName,
-			HasMedia:  false,
-			Type:      "text",
-		})
-	}()
-
-	// Send PDF if provided
-	pdfSent := false
-	if req.PdfBase64 != "" || req.PdfURL != "" {
-		pdfSent = h.sendPDF(ctx, botClient, jid, req.PdfBase64, req.PdfURL, req.FileName, chatName)
+		_ = h.Repo.SetChatHasInvoice(context.Background(), dbMsg.Session, jid.String(), true)
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "Invoice sent successfully",
-		"pdfSent": pdfSent,
+	h.WAManager.BroadcastMessage(whatsapp.NewMessageEvent{
+		Client:    botClient.ID,
+		ID:        resp.ID,
+		From:      dbMsg.From,
+		To:        dbMsg.To,
+		Body:      dbMsg.Body,
+		Timestamp: resp.Timestamp.Unix(),
+		FromMe:    true,
+		ChatID:    jid.String(),
+		ChatName:  chatName,
+		HasMedia:  false,
+		Type:      "text",
 	})
+	return resp.ID, nil
 }
 
 // sendPDF uploads and sends a PDF document
-func (h *Handler) sendPDF(ctx context.Context, client *whatsapp.Client, jid types.JID, base64Data, url, fileName, chatName string) bool {
+func (h *Handler) sendPDF(ctx context.Context, client *whatsapp.Client, jid types.JID, base64Data, url, fileName, chatName string) error {
 	var pdfData []byte
 	var err error
 
 	if base64Data != "" {
 		pdfData, err = base64.StdEncoding.DecodeString(base64Data)
 		if err != nil {
-			fmt.Printf("❌ Error decoding PDF base64: %v\n", err)
-			return false
+			return fmt.Errorf("invalid PDF base64: %w", err)
 		}
 	} else if url != "" {
-		resp, err := http.Get(url)
+		pdfData, _, err = downloadURL(ctx, url)
 		if err != nil {
-			fmt.Printf("❌ Error downloading PDF from URL: %v\n", err)
-			return false
-		}
-		defer resp.Body.Close()
-
-		pdfData, err = io.ReadAll(resp.Body)
-		if err != nil {
-			fmt.Printf("❌ Error reading PDF response: %v\n", err)
-			return false
+			return fmt.Errorf("failed to download PDF: %w", err)
 		}
 	}
 
 	if len(pdfData) == 0 {
-		return false
+		return fmt.Errorf("PDF is empty")
 	}
 
 	// Upload to WhatsApp
 	uploaded, err := client.WAClient.Upload(ctx, pdfData, whatsmeow.MediaDocument)
 	if err != nil {
-		fmt.Printf("❌ Error uploading PDF: %v\n", err)
-		return false
+		return fmt.Errorf("failed to upload PDF: %w", err)
 	}
 
 	// Set filename
@@ -214,17 +233,15 @@ func (h *Handler) sendPDF(ctx context.Context, client *whatsapp.Client, jid type
 		},
 	})
 	if err != nil {
-		fmt.Printf("❌ Error sending PDF: %v\n", err)
-		return false
+		return err
 	}
 
 	// Save file locally for history display (using Message ID)
 	// ext must match what events.go expects (.pdf for documents with 'pdf' mime/name)
 	localFileName := fmt.Sprintf("%s.pdf", resp.ID)
-	uploadsDir := "./uploads/media"
-	_ = os.MkdirAll(uploadsDir, 0755)
-	localPath := fmt.Sprintf("%s/%s", uploadsDir, localFileName)
-	
+	_ = os.MkdirAll(whatsapp.MediaDir, 0700)
+	localPath := whatsapp.MediaPath(localFileName)
+
 	if err := os.WriteFile(localPath, pdfData, 0644); err != nil {
 		fmt.Printf("⚠️ Failed to save outgoing PDF locally: %v\n", err)
 	} else {
@@ -232,45 +249,51 @@ func (h *Handler) sendPDF(ctx context.Context, client *whatsapp.Client, jid type
 	}
 
 	// Manually Save & Broadcast to ensure visibility (Bypass missing Echo)
-	go func() {
-		// 1. Save to DB
-		dbMsg := &firestore.WAMessage{
-			MessageID: resp.ID,
-			ChatID:    jid.String(),
-			From:      client.WAClient.Store.ID.ToNonAD().String(),
-			To:        jid.String(),
-			Body:      "[Document] " + fileName,
-			Timestamp: resp.Timestamp,
-			FromMe:    true,
-			HasMedia:  true,
-			MediaType: "application/pdf",
-			MediaURL:  fmt.Sprintf("/uploads/media/%s", localFileName),
-			Type:      "document",
-			Ack:       1,
-		}
-		if h.Repo != nil {
-			_ = h.Repo.SaveMessage(context.Background(), dbMsg)
-			_ = h.Repo.SetChatHasInvoice(context.Background(), jid.String(), true)
+	// 1. Save to DB
+	dbMsg := &storage.WAMessage{
+		Session:            client.ID,
+		MessageID:          resp.ID,
+		ChatID:             jid.String(),
+		From:               client.WAClient.Store.ID.ToNonAD().String(),
+		To:                 jid.String(),
+		Body:               "[Document] " + fileName,
+		Timestamp:          resp.Timestamp,
+		FromMe:             true,
+		HasMedia:           true,
+		MediaType:          "application/pdf",
+		MediaURL:           localFileName,
+		Type:               "document",
+		Ack:                storage.AckServer,
+		MediaDirectPath:    uploaded.DirectPath,
+		MediaKey:           uploaded.MediaKey,
+		MediaFileSHA256:    uploaded.FileSHA256,
+		MediaFileEncSHA256: uploaded.FileEncSHA256,
+		MediaFileLength:    uint64(len(pdfData)),
+	}
+	if h.Repo != nil {
+		if err := h.WAManager.SaveSentMessage(context.Background(), dbMsg); err != nil {
+			fmt.Printf("⚠️ Failed to save sent message %s: %v\n", dbMsg.MessageID, err)
 		}
+		_ = h.Repo.SetChatHasInvoice(context.Background(), dbMsg.Session, jid.String(), true)
+	}
 
-		// 2. Broadcast WS
-		h.WAManager.BroadcastMessage(whatsapp.NewMessageEvent{
-			Client:    "bot",
-			ID:        resp.ID,
-			From:      dbMsg.From,
-			To:        dbMsg.To,
-			Body:      dbMsg.Body,
-			Timestamp: resp.Timestamp.Unix(),
-			FromMe:    true,
-			ChatID:    jid.String(),
-			ChatName:  chatName,
-			HasMedia:  true,
-			Type:      "document",
-		})
-	}()
+	// 2. Broadcast WS
+	h.WAManager.BroadcastMessage(whatsapp.NewMessageEvent{
+		Client:    client.ID,
+		ID:        resp.ID,
+		From:      dbMsg.From,
+		To:        dbMsg.To,
+		Body:      dbMsg.Body,
+		Timestamp: resp.Timestamp.Unix(),
+		FromMe:    true,
+		ChatID:    jid.String(),
+		ChatName:  chatName,
+		HasMedia:  true,
+		Type:      "document",
+	})
 
 	fmt.Println("✅ PDF sent successfully")
-	return true
+	return nil
 }
 
 // SendMessage handles POST /send-message
@@ -291,22 +314,28 @@ func (h *Handler) SendMessage(c *gin.Context) {
 		return
 	}
 
-	botClient, ok := h.WAManager.GetClient("bot")
-	if !ok || !botClient.IsReady() {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"success": false,
-			"error":   "WhatsApp Bot client is not ready",
-		})
-		return
+	req.Number = targetPhone
+	h.enqueueSend(c, jobKindText, req.Session, targetPhone, req)
+}
+
+// deliverText sends a queued text message
+func (h *Handler) deliverText(ctx context.Context, job *outbox.Job) (map[string]interface{}, error) {
+	var req SendMessageRequest
+	if err := json.Unmarshal(job.Payload, &req); err != nil {
+		return nil, fmt.Errorf("invalid message payload: %w", err)
+	}
+
+	botClient, err := h.readyClient(job.ClientID)
+	if err != nil {
+		return nil, err
 	}
 
-	ctx := context.Background()
-	jid := utils.PhoneToJID(targetPhone)
+	jid := utils.PhoneToJID(req.Number)
 	normalizedMessage := utils.NormalizeNewlines(req.Message)
 
 	// Anti-bot: Simulate typing indicator to appear more human-like
 	_ = botClient.WAClient.SendChatPresence(ctx, jid, types.ChatPresenceComposing, types.ChatPresenceMediaText)
-	
+
 	// Wait based on message length
 	typingDelay := len(normalizedMessage) * 40
 	if typingDelay < 1000 {
@@ -316,57 +345,51 @@ func (h *Handler) SendMessage(c *gin.Context) {
 		typingDelay = 5000
 	}
 	utils.HumanizeDelay(typingDelay, typingDelay+1000)
-	
+
 	_ = botClient.WAClient.SendChatPresence(ctx, jid, types.ChatPresencePaused, types.ChatPresenceMediaText)
 
 	resp, err := botClient.WAClient.SendMessage(ctx, jid, &waProto.Message{
 		Conversation: proto.String(normalizedMessage),
 	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"error":   fmt.Sprintf("Failed to send message: %v", err),
-		})
-		return
+		return nil, fmt.Errorf("failed to send message: %w", err)
 	}
 
 	// Manual Save & Broadcast (Ensure "Live" Chat Visibility)
-	go func() {
-		dbMsg := &firestore.WAMessage{
-			MessageID: resp.ID,
-			ChatID:    jid.String(),
-			From:      botClient.WAClient.Store.ID.ToNonAD().String(),
-			To:        jid.String(),
-			Body:      normalizedMessage,
-			Timestamp: resp.Timestamp,
-			FromMe:    true,
-			HasMedia:  false,
-			Type:      "text",
-			Ack:       1,
-		}
-		if h.Repo != nil {
-			_ = h.Repo.SaveMessage(context.Background(), dbMsg)
+	dbMsg := &storage.WAMessage{
+		Session:   botClient.ID,
+		MessageID: resp.ID,
+		ChatID:    jid.String(),
+		From:      botClient.WAClient.Store.ID.ToNonAD().String(),
+		To:        jid.String(),
+		Body:      normalizedMessage,
+		Timestamp: resp.Timestamp,
+		FromMe:    true,
+		HasMedia:  false,
+		Type:      "text",
+		Ack:       storage.AckServer,
+	}
+	if h.Repo != nil {
+		if err := h.WAManager.SaveSentMessage(context.Background(), dbMsg); err != nil {
+			fmt.Printf("⚠️ Failed to save sent message %s: %v\n", dbMsg.MessageID, err)
 		}
+	}
 
-		h.WAManager.BroadcastMessage(whatsapp.NewMessageEvent{
-			Client:    "bot",
-			ID:        resp.ID,
-			From:      dbMsg.From,
-			To:        dbMsg.To,
-			Body:      dbMsg.Body,
-			Timestamp: resp.Timestamp.Unix(),
-			FromMe:    true,
-			ChatID:    jid.String(),
-			ChatName:  utils.JIDToPhoneNumber(jid), // Use phone as fallback name
-			HasMedia:  false,
-			Type:      "text",
-		})
-	}()
-
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "Message sent successfully",
+	h.WAManager.BroadcastMessage(whatsapp.NewMessageEvent{
+		Client:    botClient.ID,
+		ID:        resp.ID,
+		From:      dbMsg.From,
+		To:        dbMsg.To,
+		Body:      dbMsg.Body,
+		Timestamp: resp.Timestamp.Unix(),
+		FromMe:    true,
+		ChatID:    jid.String(),
+		ChatName:  utils.JIDToPhoneNumber(jid), // Use phone as fallback name
+		HasMedia:  false,
+		Type:      "text",
 	})
+
+	return map[string]interface{}{"messageId": resp.ID}, nil
 }
 
 // SendMedia handles POST /send-media
@@ -377,33 +400,29 @@ func (h *Handler) SendMedia(c *gin.Context) {
 		return
 	}
 
-	botClient, ok := h.WAManager.GetClient("bot")
-	if !ok || !botClient.IsReady() {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"success": false,
-			"error":   "WhatsApp Bot client is not ready",
-		})
-		return
-	}
+	h.enqueueSend(c, jobKindMedia, req.Session, req.Number, req)
+}
 
-	ctx := context.Background()
-	jid := utils.PhoneToJID(req.Number)
+// deliverMedia downloads and sends a queued image or document
+func (h *Handler) deliverMedia(ctx context.Context, job *outbox.Job) (map[string]interface{}, error) {
+	var req SendMediaRequest
+	if err := json.Unmarshal(job.Payload, &req); err != nil {
+		return nil, fmt.Errorf("invalid media payload: %w", err)
+	}
 
-	// Download media from URL
-	resp, err := http.Get(req.MediaURL)
+	botClient, err := h.readyClient(job.ClientID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to download media"})
-		return
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	mediaData, err := io.ReadAll(resp.Body)
+	jid := utils.PhoneToJID(req.Number)
+
+	// Download media from URL
+	mediaData, contentType, err := downloadURL(ctx, req.MediaURL)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to read media"})
-		return
+		return nil, fmt.Errorf("failed to download media: %w", err)
 	}
-
-	contentType := resp.Header.Get("Content-Type")
+	var messageID string
 
 	// Anti-bot: Simulate media upload/typing presence
 	_ = botClient.WAClient.SendChatPresence(ctx, jid, types.ChatPresenceComposing, types.ChatPresenceMediaText)
@@ -417,8 +436,7 @@ func (h *Handler) SendMedia(c *gin.Context) {
 	if isImageMime(contentType) {
 		uploaded, err := botClient.WAClient.Upload(ctx, mediaData, whatsmeow.MediaImage)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to upload image"})
-			return
+			return nil, fmt.Errorf("failed to upload image: %w", err)
 		}
 
 		resp, err := botClient.WAClient.SendMessage(ctx, jid, &waProto.Message{
@@ -434,59 +452,63 @@ func (h *Handler) SendMedia(c *gin.Context) {
 			},
 		})
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to send image"})
-			return
+			return nil, fmt.Errorf("failed to send image: %w", err)
 		}
+		messageID = resp.ID
 
 		// Save locally (ID.jpg)
 		localFileName := fmt.Sprintf("%s.jpg", resp.ID)
-		uploadsDir := "./uploads/media"
-		_ = os.MkdirAll(uploadsDir, 0755)
-		localPath := fmt.Sprintf("%s/%s", uploadsDir, localFileName)
+		_ = os.MkdirAll(whatsapp.MediaDir, 0700)
+		localPath := whatsapp.MediaPath(localFileName)
 		_ = os.WriteFile(localPath, mediaData, 0644)
 
 		// Manual Save & Broadcast for Image
-		go func() {
-			dbMsg := &firestore.WAMessage{
-				MessageID: resp.ID,
-				ChatID:    jid.String(),
-				From:      botClient.WAClient.Store.ID.ToNonAD().String(),
-				To:        jid.String(),
-				Body:      "[Image] " + req.Caption,
-				Timestamp: resp.Timestamp,
-				FromMe:    true,
-				HasMedia:  true,
-				MediaType: contentType,
-				MediaURL:  fmt.Sprintf("/uploads/media/%s", localFileName),
-				Type:      "image",
-				Ack:       1,
-			}
-			if h.Repo != nil {
-				_ = h.Repo.SaveMessage(context.Background(), dbMsg)
+		dbMsg := &storage.WAMessage{
+			Session:            botClient.ID,
+			MessageID:          resp.ID,
+			ChatID:             jid.String(),
+			From:               botClient.WAClient.Store.ID.ToNonAD().String(),
+			To:                 jid.String(),
+			Body:               "[Image] " + req.Caption,
+			Timestamp:          resp.Timestamp,
+			FromMe:             true,
+			HasMedia:           true,
+			MediaType:          contentType,
+			MediaURL:           localFileName,
+			Type:               "image",
+			Ack:                storage.AckServer,
+			MediaDirectPath:    uploaded.DirectPath,
+			MediaKey:           uploaded.MediaKey,
+			MediaFileSHA256:    uploaded.FileSHA256,
+			MediaFileEncSHA256: uploaded.FileEncSHA256,
+			MediaFileLength:    uint64(len(mediaData)),
+		}
+		if h.Repo != nil {
+			if err := h.WAManager.SaveSentMessage(context.Background(), dbMsg); err != nil {
+				fmt.Printf("⚠️ Failed to save sent message %s: %v\n", dbMsg.MessageID, err)
 			}
-			h.WAManager.BroadcastMessage(whatsapp.NewMessageEvent{
-				Client:    "bot",
-				ID:        resp.ID,
-				From:      dbMsg.From,
-				To:        dbMsg.To,
-				Body:      dbMsg.Body,
-				Timestamp: resp.Timestamp.Unix(),
-				FromMe:    true,
-				ChatID:    jid.String(),
-				ChatName:  utils.JIDToPhoneNumber(jid),
-				HasMedia:  true,
-				Type:      "image",
-			})
-		}()
+		}
+		h.WAManager.BroadcastMessage(whatsapp.NewMessageEvent{
+			Client:    botClient.ID,
+			ID:        resp.ID,
+			From:      dbMsg.From,
+			To:        dbMsg.To,
+			Body:      dbMsg.Body,
+			Timestamp: resp.Timestamp.Unix(),
+			FromMe:    true,
+			ChatID:    jid.String(),
+			ChatName:  utils.JIDToPhoneNumber(jid),
+			HasMedia:  true,
+			Type:      "image",
+		})
 	} else {
 		// Send as document
 		uploaded, err := botClient.WAClient.Upload(ctx, mediaData, whatsmeow.MediaDocument)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to upload document"})
-			return
+			return nil, fmt.Errorf("failed to upload document: %w", err)
 		}
 
-	resp, err := botClient.WAClient.SendMessage(ctx, jid, &waProto.Message{
+		resp, err := botClient.WAClient.SendMessage(ctx, jid, &waProto.Message{
 			DocumentMessage: &waProto.DocumentMessage{
 				URL:           proto.String(uploaded.URL),
 				Mimetype:      proto.String(contentType),
@@ -499,9 +521,9 @@ func (h *Handler) SendMedia(c *gin.Context) {
 			},
 		})
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to send document"})
-			return
+			return nil, fmt.Errorf("failed to send document: %w", err)
 		}
+		messageID = resp.ID
 
 		// Save locally - try to guess ext based on content-type or default to .bin
 		// events.go tries to guess from filename or mimetype
@@ -510,54 +532,79 @@ func (h *Handler) SendMedia(c *gin.Context) {
 		if len(exts) > 0 {
 			ext = exts[0]
 		}
-		
+
 		localFileName := fmt.Sprintf("%s%s", resp.ID, ext)
-		uploadsDir := "./uploads/media"
-		_ = os.MkdirAll(uploadsDir, 0755)
-		localPath := fmt.Sprintf("%s/%s", uploadsDir, localFileName)
+		_ = os.MkdirAll(whatsapp.MediaDir, 0700)
+		localPath := whatsapp.MediaPath(localFileName)
 		_ = os.WriteFile(localPath, mediaData, 0644)
 
 		// Manual Save & Broadcast for Document
-		go func() {
-			dbMsg := &firestore.WAMessage{
-				MessageID: resp.ID,
-				ChatID:    jid.String(),
-				From:      botClient.WAClient.Store.ID.ToNonAD().String(),
-				To:        jid.String(),
-				Body:      "[Document] " + req.Caption,
-				Timestamp: resp.Timestamp,
-				FromMe:    true,
-				HasMedia:  true,
-				MediaType: contentType,
-				MediaURL:  fmt.Sprintf("/uploads/media/%s", localFileName),
-				Type:      "document",
-				Ack:       1,
-			}
-			if h.Repo != nil {
-				_ = h.Repo.SaveMessage(context.Background(), dbMsg)
+		dbMsg := &storage.WAMessage{
+			Session:            botClient.ID,
+			MessageID:          resp.ID,
+			ChatID:             jid.String(),
+			From:               botClient.WAClient.Store.ID.ToNonAD().String(),
+			To:                 jid.String(),
+			Body:               "[Document] " + req.Caption,
+			Timestamp:          resp.Timestamp,
+			FromMe:             true,
+			HasMedia:           true,
+			MediaType:          contentType,
+			MediaURL:           localFileName,
+			Type:               "document",
+			Ack:                storage.AckServer,
+			MediaDirectPath:    uploaded.DirectPath,
+			MediaKey:           uploaded.MediaKey,
+			MediaFileSHA256:    uploaded.FileSHA256,
+			MediaFileEncSHA256: uploaded.FileEncSHA256,
+			MediaFileLength:    uint64(len(mediaData)),
+		}
+		if h.Repo != nil {
+			if err := h.WAManager.SaveSentMessage(context.Background(), dbMsg); err != nil {
+				fmt.Printf("⚠️ Failed to save sent message %s: %v\n", dbMsg.MessageID, err)
 			}
-			h.WAManager.BroadcastMessage(whatsapp.NewMessageEvent{
-				Client:    "bot",
-				ID:        resp.ID,
-				From:      dbMsg.From,
-				To:        dbMsg.To,
-				Body:      dbMsg.Body,
-				Timestamp: resp.Timestamp.Unix(),
-				FromMe:    true,
-				ChatID:    jid.String(),
-				ChatName:  utils.JIDToPhoneNumber(jid),
-				HasMedia:  true,
-				Type:      "document",
-			})
-		}()
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "Media sent successfully",
-	})
+		}
+		h.WAManager.BroadcastMessage(whatsapp.NewMessageEvent{
+			Client:    botClient.ID,
+			ID:        resp.ID,
+			From:      dbMsg.From,
+			To:        dbMsg.To,
+			Body:      dbMsg.Body,
+			Timestamp: resp.Timestamp.Unix(),
+			FromMe:    true,
+			ChatID:    jid.String(),
+			ChatName:  utils.JIDToPhoneNumber(jid),
+			HasMedia:  true,
+			Type:      "document",
+		})
+	}
+
+	return map[string]interface{}{"messageId": messageID}, nil
 }
 
 func isImageMime(mime string) bool {
 	return mime == "image/jpeg" || mime == "image/png" || mime == "image/gif" || mime == "image/webp"
 }
+
+// downloadURL fetches a file to send and returns its body and Content-Type.
+// Non-2xx responses are errors, so an error page is never sent as the file.
+func downloadURL(ctx context.Context, url string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := downloadClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, "", fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, resp.Header.Get("Content-Type"), nil
+}
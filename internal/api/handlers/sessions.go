@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"net/http"
+
+	"wa-server-go/internal/api/middleware"
+	"wa-server-go/internal/whatsapp"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateSessionRequest represents the request body for POST /sessions
+type CreateSessionRequest struct {
+	ID          string        `json:"id" binding:"required"`
+	Role        whatsapp.Role `json:"role,omitempty"`        // sender (default), sync or privacy
+	AutoConnect *bool         `json:"autoConnect,omitempty"` // connect on server start, default true
+}
+
+// ListSessions handles GET /sessions
+// Keys restricted to sessions only see those sessions and their QR codes
+func (h *Handler) ListSessions(c *gin.Context) {
+	sessions, err := h.WAManager.Sessions(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if key := middleware.CurrentKey(c); key != nil {
+		visible := sessions[:0]
+		for _, session := range sessions {
+			if key.AllowsSession(session.ID) {
+				visible = append(visible, session)
+			}
+		}
+		sessions = visible
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":        true,
+		"defaultSession": h.DefaultSession,
+		"sessions":       sessions,
+	})
+}
+
+// CreateSession handles POST /sessions
+// Registers a new session and starts it; the QR code is published over WebSocket and GET /sessions
+func (h *Handler) CreateSession(c *gin.Context) {
+	var req CreateSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if !whatsapp.ValidSessionID(req.ID) {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "id must be 1-32 letters, digits, '-' or '_'"})
+		return
+	}
+	if req.Role == "" {
+		req.Role = whatsapp.RoleSender
+	}
+	if !req.Role.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "role must be sender, sync or privacy"})
+		return
+	}
+	if _, exists := h.WAManager.GetClient(req.ID); exists {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "Session already running: " + req.ID})
+		return
+	}
+
+	rec := whatsapp.SessionRecord{ID: req.ID, Role: req.Role, AutoConnect: true}
+	if req.AutoConnect != nil {
+		rec.AutoConnect = *req.AutoConnect
+	}
+	if err := h.WAManager.StartSession(c.Request.Context(), rec); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Session starting... please scan QR code",
+		"session": rec,
+	})
+}
+
+// DeleteSession handles DELETE /sessions/:id
+// Logs the device out, stops the client and deletes its session database
+func (h *Handler) DeleteSession(c *gin.Context) {
+	id := c.Param("id")
+	if id == h.DefaultSession {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "The default session cannot be deleted"})
+		return
+	}
+	if !whatsapp.ValidSessionID(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid session ID"})
+		return
+	}
+
+	if err := h.WAManager.DeleteSession(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Session deleted"})
+}
+
+// LogoutSession handles POST /sessions/:id/logout
+// Unlinks the device and restarts the session with a fresh QR code
+func (h *Handler) LogoutSession(c *gin.Context) {
+	id := c.Param("id")
+	if _, exists := h.WAManager.GetClient(id); !exists {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Session not running: " + id})
+		return
+	}
+
+	if err := h.WAManager.LogoutSession(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Logged out, scan the new QR code to link again"})
+}
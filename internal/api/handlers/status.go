@@ -4,8 +4,18 @@ import (
 	"net/http"
 	"time"
 
+	"wa-server-go/internal/api/middleware"
 	"wa-server-go/internal/api/websocket"
-	"wa-server-go/internal/firestore"
+	"wa-server-go/internal/apikey"
+	"wa-server-go/internal/contacts"
+	"wa-server-go/internal/features/backup"
+	"wa-server-go/internal/features/blog"
+	"wa-server-go/internal/features/monitor"
+	"wa-server-go/internal/features/reminder"
+	"wa-server-go/internal/features/scheduler"
+	"wa-server-go/internal/outbox"
+	"wa-server-go/internal/storage"
+	"wa-server-go/internal/webhook"
 	"wa-server-go/internal/whatsapp"
 
 	"github.com/gin-gonic/gin"
@@ -14,17 +24,54 @@ import (
 // Handler holds dependencies for HTTP handlers
 type Handler struct {
 	WAManager *whatsapp.Manager
-	Repo      *firestore.ChatsRepository
+	Repo      storage.ChatsRepository
 	WSHub     *websocket.Hub
+	Outbox    *outbox.Queue
+	Webhooks  *webhook.Dispatcher
+	APIKeys   *apikey.Service
+	Scheduler *scheduler.SchedulerService
+	Reminders *reminder.InvoiceReminderService
+	Backups   *backup.BackupService
+	Monitor   *monitor.MonitorService
+	Blog      *blog.Automator
+	Contacts  *contacts.Directory
+
+	DefaultSession string // session used when a request does not name one
+	LeadsSession   string // on-demand contact sync session
 }
 
 // NewHandler creates a new handler with dependencies
-func NewHandler(waManager *whatsapp.Manager, repo *firestore.ChatsRepository, wsHub *websocket.Hub) *Handler {
+func NewHandler(waManager *whatsapp.Manager, repo storage.ChatsRepository, wsHub *websocket.Hub) *Handler {
 	return &Handler{
-		WAManager: waManager,
-		Repo:      repo,
-		WSHub:     wsHub,
+		WAManager:      waManager,
+		Repo:           repo,
+		WSHub:          wsHub,
+		Contacts:       contacts.NewDirectory(nil, waManager.LIDs),
+		DefaultSession: "bot",
+		LeadsSession:   "leads",
+	}
+}
+
+// sessionParam returns the session named by the "session" query parameter, or the default session.
+// It responds 403 and returns false if the API key is restricted to other sessions.
+func (h *Handler) sessionParam(c *gin.Context) (string, bool) {
+	session := c.Query("session")
+	if session == "" {
+		session = h.DefaultSession
+	}
+	if !middleware.SessionAllowed(c, session) {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "error": "API key is not allowed to use session " + session})
+		return "", false
+	}
+	return session, true
+}
+
+// keySessions returns the sessions the API key is restricted to, or nil if it may use every session
+func keySessions(c *gin.Context) []string {
+	if key := middleware.CurrentKey(c); key != nil {
+		return key.Sessions
 	}
+	return nil
 }
 
 // HealthCheck handles GET /
@@ -33,9 +80,10 @@ func (h *Handler) HealthCheck(c *gin.Context) {
 }
 
 // GetStatus handles GET /status
+// The route is public, so it only reports whether a session waits for a scan; the QR code itself is served by GET /sessions.
 func (h *Handler) GetStatus(c *gin.Context) {
-	botClient, botExists := h.WAManager.GetClient("bot")
-	leadsClient, leadsExists := h.WAManager.GetClient("leads")
+	botClient, botExists := h.WAManager.GetClient(h.DefaultSession)
+	leadsClient, leadsExists := h.WAManager.GetClient(h.LeadsSession)
 
 	botStatus := map[string]interface{}{
 		"ready":   false,
@@ -44,7 +92,7 @@ func (h *Handler) GetStatus(c *gin.Context) {
 	}
 	if botExists {
 		botStatus["ready"] = botClient.IsReady()
-		botStatus["qr"] = botClient.GetQRCode()
+		botStatus["awaitingScan"] = botClient.GetQRCode() != ""
 	}
 
 	leadsStatus := map[string]interface{}{
@@ -56,15 +104,15 @@ func (h *Handler) GetStatus(c *gin.Context) {
 	}
 	if leadsExists {
 		leadsStatus["ready"] = leadsClient.IsReady()
-		leadsStatus["qr"] = leadsClient.GetQRCode()
+		leadsStatus["awaitingScan"] = leadsClient.GetQRCode() != ""
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"status": "running",
 		"mode":   "low-ram-optimized",
 		"sessions": gin.H{
-			"bot":   botStatus,
-			"leads": leadsStatus,
+			h.DefaultSession: botStatus,
+			h.LeadsSession:   leadsStatus,
 		},
 		"timestamp": time.Now().Format(time.RFC3339),
 	})
@@ -72,7 +120,7 @@ func (h *Handler) GetStatus(c *gin.Context) {
 
 // GetSyncStatus handles GET /sync-status
 func (h *Handler) GetSyncStatus(c *gin.Context) {
-	leadsClient, leadsExists := h.WAManager.GetClient("leads")
+	leadsClient, leadsExists := h.WAManager.GetClient(h.LeadsSession)
 
 	response := gin.H{
 		"clientLeadsReady":        false,
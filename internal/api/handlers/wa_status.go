@@ -8,7 +8,7 @@ import (
 	"sync"
 	"time"
 
-	"wa-server-go/internal/firestore"
+	"wa-server-go/internal/storage"
 
 	"github.com/gin-gonic/gin"
 	"go.mau.fi/whatsmeow"
@@ -27,10 +27,10 @@ type SyncWAStatusRequest struct {
 }
 
 // WAStatusRepo is the repository for WA status management (injected via Handler setup)
-var waStatusRepo *firestore.WAStatusRepository
+var waStatusRepo storage.WAStatusRepository
 
 // InitWAStatusRepo sets up the WA status repository
-func InitWAStatusRepo(repo *firestore.WAStatusRepository) {
+func InitWAStatusRepo(repo storage.WAStatusRepository) {
 	waStatusRepo = repo
 }
 
@@ -49,7 +49,11 @@ func (h *Handler) SyncWAStatus(c *gin.Context) {
 	}
 
 	// Get bot client
-	botClient, ok := h.WAManager.GetClient("bot")
+	session, ok := h.sessionParam(c)
+	if !ok {
+		return
+	}
+	botClient, ok := h.WAManager.GetClient(session)
 	if !ok || !botClient.IsReady() {
 		c.JSON(http.StatusServiceUnavailable, gin.H{
 			"success": false,
@@ -88,7 +92,7 @@ func (h *Handler) SyncWAStatus(c *gin.Context) {
 	statusJID := types.JID{User: "status", Server: "broadcast"}
 
 	var (
-		entries []firestore.WAStatusEntry
+		entries []storage.WAStatusEntry
 		mu      sync.Mutex
 		posted  int
 	)
@@ -140,7 +144,7 @@ func (h *Handler) SyncWAStatus(c *gin.Context) {
 		}
 
 		mu.Lock()
-		entries = append(entries, firestore.WAStatusEntry{
+		entries = append(entries, storage.WAStatusEntry{
 			MessageID: resp.ID,
 			BannerURL: banner.URL,
 			Caption:   caption,
@@ -157,10 +161,10 @@ func (h *Handler) SyncWAStatus(c *gin.Context) {
 		}
 	}
 
-	// Save status IDs to Firestore
+	// Save status IDs to storage
 	if waStatusRepo != nil && len(entries) > 0 {
 		if err := waStatusRepo.SaveStatusIDs(ctx, entries, activeNumber); err != nil {
-			fmt.Printf("⚠️ [WA Status] Failed to save status IDs to Firestore: %v\n", err)
+			fmt.Printf("⚠️ [WA Status] Failed to save status IDs: %v\n", err)
 		}
 	}
 
@@ -176,7 +180,11 @@ func (h *Handler) SyncWAStatus(c *gin.Context) {
 // ClearWAStatus handles DELETE /clear-wa-status
 // Revokes/deletes all previously posted WA statuses
 func (h *Handler) ClearWAStatus(c *gin.Context) {
-	botClient, ok := h.WAManager.GetClient("bot")
+	session, ok := h.sessionParam(c)
+	if !ok {
+		return
+	}
+	botClient, ok := h.WAManager.GetClient(session)
 	if !ok || !botClient.IsReady() {
 		c.JSON(http.StatusServiceUnavailable, gin.H{
 			"success": false,
@@ -188,10 +196,10 @@ func (h *Handler) ClearWAStatus(c *gin.Context) {
 	ctx := context.Background()
 	revoked := h.revokeOldStatuses(ctx, botClient.WAClient)
 
-	// Clear from Firestore
+	// Clear from storage
 	if waStatusRepo != nil {
 		if err := waStatusRepo.ClearStatusIDs(ctx); err != nil {
-			fmt.Printf("⚠️ [WA Status] Failed to clear status IDs from Firestore: %v\n", err)
+			fmt.Printf("⚠️ [WA Status] Failed to clear status IDs: %v\n", err)
 		}
 	}
 
@@ -202,7 +210,7 @@ func (h *Handler) ClearWAStatus(c *gin.Context) {
 	})
 }
 
-// revokeOldStatuses revokes all previously posted WA statuses stored in Firestore
+// revokeOldStatuses revokes all previously posted WA statuses recorded in storage
 // Returns the number of successfully revoked statuses
 func (h *Handler) revokeOldStatuses(ctx context.Context, waClient interface{ RevokeMessage(context.Context, types.JID, types.MessageID) (whatsmeow.SendResponse, error) }) int {
 	if waStatusRepo == nil {
@@ -297,7 +305,7 @@ func (h *Handler) SyncWAStatusFromScheduler(ctx context.Context) {
 
 	fmt.Println("⏰ [WA Status Scheduler] Status expired or empty, fetching banners and re-posting...")
 
-	// Fetch banners from Firestore
+	// Fetch banners from settings
 	banners, err := waStatusRepo.GetBannerSettings(ctx)
 	if err != nil || len(banners) == 0 {
 		fmt.Printf("⚠️ [WA Status Scheduler] No banners found: %v\n", err)
@@ -305,7 +313,7 @@ func (h *Handler) SyncWAStatusFromScheduler(ctx context.Context) {
 	}
 
 	// Get bot client
-	botClient, ok := h.WAManager.GetClient("bot")
+	botClient, ok := h.WAManager.GetClient(h.DefaultSession)
 	if !ok || !botClient.IsReady() {
 		fmt.Println("⚠️ [WA Status Scheduler] Bot client not ready, skipping")
 		return
@@ -325,7 +333,7 @@ func (h *Handler) SyncWAStatusFromScheduler(ctx context.Context) {
 		banners = banners[:10]
 	}
 
-	var entries []firestore.WAStatusEntry
+	var entries []storage.WAStatusEntry
 
 	for i, banner := range banners {
 		url := banner["url"]
@@ -368,7 +376,7 @@ func (h *Handler) SyncWAStatusFromScheduler(ctx context.Context) {
 			continue
 		}
 
-		entries = append(entries, firestore.WAStatusEntry{
+		entries = append(entries, storage.WAStatusEntry{
 			MessageID: resp.ID,
 			BannerURL: url,
 			Caption:   caption,
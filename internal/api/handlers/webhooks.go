@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetWebhookDeadLetters handles GET /webhooks/dead-letters
+func (h *Handler) GetWebhookDeadLetters(c *gin.Context) {
+	if h.Webhooks == nil || h.Webhooks.DeadLetters() == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "Webhooks are not configured"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	letters, err := h.Webhooks.DeadLetters().List(c.Request.Context(), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "deadLetters": letters})
+}
@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"wa-server-go/internal/api/websocket"
+	"wa-server-go/internal/apikey"
+	"wa-server-go/internal/storage"
+	"wa-server-go/internal/whatsapp"
+
+	"github.com/gin-gonic/gin/binding"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// MarkReadRequest represents the data of the mark-read command
+type MarkReadRequest struct {
+	ChatID     string   `json:"chatId" binding:"required"` // JID or phone number
+	MessageIDs []string `json:"messageIds,omitempty"`      // defaults to the latest stored incoming messages
+	Sender     string   `json:"sender,omitempty"`          // sender of messageIds in a group
+	Session    string   `json:"session,omitempty"`
+}
+
+// TypingRequest represents the data of the typing-start and typing-stop commands
+type TypingRequest struct {
+	ChatID  string `json:"chatId" binding:"required"`
+	Session string `json:"session,omitempty"`
+}
+
+// FetchHistoryRequest represents the data of the fetch-history command
+type FetchHistoryRequest struct {
+	ChatID   string `json:"chatId" binding:"required"`
+	Limit    int    `json:"limit,omitempty"`
+	Cursor   string `json:"cursor,omitempty"`   // nextCursor of the previous page
+	MarkRead bool   `json:"markRead,omitempty"` // mark the chat read when its first page is opened
+	Session  string `json:"session,omitempty"`  // session of the chat, which sends the read receipts
+}
+
+// wsCall is a command being handled; session and recipient are recorded in the audit log
+type wsCall struct {
+	*websocket.CommandRequest
+	session   string
+	recipient string
+}
+
+// RegisterWSCommands registers the commands WebSocket clients can send, with the scope of the matching REST endpoint
+func (h *Handler) RegisterWSCommands(hub *websocket.Hub) {
+	hub.HandleCommand("send-text", apikey.ScopeSend, h.wsCommand(h.wsSendText))
+	hub.HandleCommand("send-media", apikey.ScopeSend, h.wsCommand(h.wsSendMedia))
+	hub.HandleCommand("typing-start", apikey.ScopeSend, h.wsCommand(h.wsTyping(types.ChatPresenceComposing)))
+	hub.HandleCommand("typing-stop", apikey.ScopeSend, h.wsCommand(h.wsTyping(types.ChatPresencePaused)))
+	hub.HandleCommand("mark-read", apikey.ScopeReadChats, h.wsCommand(h.wsMarkRead))
+	hub.HandleCommand("fetch-history", apikey.ScopeReadChats, h.wsCommand(h.wsFetchHistory))
+}
+
+// wsCommand adapts a command to the hub and writes it to the audit log like a REST request
+func (h *Handler) wsCommand(fn func(ctx context.Context, call *wsCall) (interface{}, error)) websocket.CommandFunc {
+	return func(ctx context.Context, req *websocket.CommandRequest) (interface{}, error) {
+		call := &wsCall{CommandRequest: req}
+		result, err := fn(ctx, call)
+
+		if h.APIKeys != nil && req.Key != nil {
+			status := http.StatusOK
+			if err != nil {
+				status = statusOf(err)
+			}
+			h.APIKeys.Audit(context.Background(), &apikey.AuditEntry{
+				KeyID:     req.Key.ID,
+				KeyName:   req.Key.Name,
+				Method:    "WS",
+				Path:      "/ws#" + req.Type,
+				Status:    status,
+				Session:   call.session,
+				Recipient: call.recipient,
+				IP:        req.RemoteAddr,
+			})
+		}
+		return result, err
+	}
+}
+
+// bindCommand decodes and validates the data of a command like a REST request body
+func bindCommand(call *wsCall, obj interface{}) error {
+	if len(call.Data) == 0 {
+		return &requestError{http.StatusBadRequest, "data is required"}
+	}
+	if err := binding.JSON.BindBody(call.Data, obj); err != nil {
+		return &requestError{http.StatusBadRequest, err.Error()}
+	}
+	return nil
+}
+
+func (h *Handler) wsSendText(ctx context.Context, call *wsCall) (interface{}, error) {
+	var req SendMessageRequest
+	if err := bindCommand(call, &req); err != nil {
+		return nil, err
+	}
+	if req.Number == "" {
+		req.Number = req.Phone
+	}
+	if req.Number == "" {
+		return nil, &requestError{http.StatusBadRequest, "number or phone is required"}
+	}
+	return h.wsQueue(ctx, call, jobKindText, req.Session, req.Number, req)
+}
+
+func (h *Handler) wsSendMedia(ctx context.Context, call *wsCall) (interface{}, error) {
+	var req SendMediaRequest
+	if err := bindCommand(call, &req); err != nil {
+		return nil, err
+	}
+	return h.wsQueue(ctx, call, jobKindMedia, req.Session, req.Number, req)
+}
+
+// wsQueue queues a send through the outbox, exactly like the REST send endpoints
+func (h *Handler) wsQueue(ctx context.Context, call *wsCall, kind, session, recipient string, payload interface{}) (interface{}, error) {
+	if session == "" {
+		session = h.DefaultSession
+	}
+	call.session, call.recipient = session, recipient
+
+	job, err := h.queueSend(ctx, call.Key, kind, session, recipient, payload)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"jobId": job.ID, "session": session, "status": job.Status}, nil
+}
+
+// wsTyping returns a command that sets the chat presence shown to the recipient
+func (h *Handler) wsTyping(state types.ChatPresence) func(ctx context.Context, call *wsCall) (interface{}, error) {
+	return func(ctx context.Context, call *wsCall) (interface{}, error) {
+		var req TypingRequest
+		if err := bindCommand(call, &req); err != nil {
+			return nil, err
+		}
+		call.recipient = req.ChatID
+
+		client, err := h.sendingClient(call.Key, req.Session)
+		if err != nil {
+			return nil, err
+		}
+		call.session = client.ID
+
+		jid, err := chatJID(req.ChatID)
+		if err != nil {
+			return nil, err
+		}
+		if err := client.WAClient.SendChatPresence(ctx, jid, state, types.ChatPresenceMediaText); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"chatId": jid.String(), "state": state}, nil
+	}
+}
+
+func (h *Handler) wsMarkRead(ctx context.Context, call *wsCall) (interface{}, error) {
+	var req MarkReadRequest
+	if err := bindCommand(call, &req); err != nil {
+		return nil, err
+	}
+	call.recipient = req.ChatID
+
+	client, err := h.sendingClient(call.Key, req.Session)
+	if err != nil {
+		return nil, err
+	}
+	call.session = client.ID
+
+	marked, err := h.markChatRead(ctx, client, req.ChatID, req.MessageIDs, req.Sender)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"chatId": req.ChatID, "marked": marked}, nil
+}
+
+func (h *Handler) wsFetchHistory(ctx context.Context, call *wsCall) (interface{}, error) {
+	var req FetchHistoryRequest
+	if err := bindCommand(call, &req); err != nil {
+		return nil, err
+	}
+	call.recipient = req.ChatID
+	if h.Repo == nil {
+		return nil, &requestError{http.StatusServiceUnavailable, "Chat storage is not configured"}
+	}
+	session := req.Session
+	if session == "" {
+		session = h.DefaultSession
+	}
+	call.session = session
+	if call.Key != nil && !call.Key.AllowsSession(session) {
+		return nil, &requestError{http.StatusForbidden, "API key is not allowed to use session " + session}
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	messages, nextCursor, err := h.Repo.ListChatMessages(ctx, session, req.ChatID, storage.MessageFilter{
+		Limit:  min(limit, maxPageSize),
+		Cursor: req.Cursor,
+	})
+	if errors.Is(err, storage.ErrInvalidCursor) {
+		return nil, &requestError{http.StatusBadRequest, err.Error()}
+	}
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]interface{}{"chatId": req.ChatID, "messages": mapMessages(messages), "nextCursor": nextCursor}
+
+	// Auto-read: a failed receipt must not fail opening the chat
+	if req.MarkRead && req.Cursor == "" {
+		client, err := h.sendingClient(call.Key, session)
+		if err == nil {
+			call.session = client.ID
+			result["marked"], err = h.markChatRead(ctx, client, req.ChatID, nil, "")
+		}
+		if err != nil {
+			log.Printf("⚠️ Failed to mark %s read: %v", req.ChatID, err)
+			result["markReadError"] = err.Error()
+		}
+	}
+	return result, nil
+}
+
+// sendingClient is connectedClient for commands that act on WhatsApp (presence, read receipts);
+// sessions that cannot send are refused
+func (h *Handler) sendingClient(key *apikey.Key, session string) (*whatsapp.Client, error) {
+	client, err := h.connectedClient(key, session)
+	if err != nil {
+		return nil, err
+	}
+	if !client.Role.CanSend() {
+		return nil, &requestError{http.StatusForbidden, "Session " + client.ID + " is read-only"}
+	}
+	return client, nil
+}
+
+// connectedClient returns the session's client (the default session if empty) if key may use it and it is connected
+func (h *Handler) connectedClient(key *apikey.Key, session string) (*whatsapp.Client, error) {
+	if session == "" {
+		session = h.DefaultSession
+	}
+	if key != nil && !key.AllowsSession(session) {
+		return nil, &requestError{http.StatusForbidden, "API key is not allowed to use session " + session}
+	}
+	client, ok := h.WAManager.GetClient(session)
+	if !ok {
+		return nil, &requestError{http.StatusNotFound, "Session not found: " + session}
+	}
+	if !client.IsReady() {
+		return nil, &requestError{http.StatusServiceUnavailable, "Session " + session + " is not connected"}
+	}
+	return client, nil
+}
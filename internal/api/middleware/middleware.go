@@ -1,10 +1,14 @@
 package middleware
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"strings"
 
+	"wa-server-go/internal/apikey"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -22,7 +26,6 @@ func CORSMiddleware(allowedDomains []string) gin.HandlerFunc {
 			}
 		}
 
-
 		// Allow requests with no origin (server-to-server, curl, etc.)
 		if origin == "" {
 			allowed = true
@@ -36,7 +39,7 @@ func CORSMiddleware(allowedDomains []string) gin.HandlerFunc {
 
 		c.Header("Access-Control-Allow-Credentials", "true")
 		c.Header("Access-Control-Allow-Headers", "Content-Type, x-api-key, Origin, Referer, Authorization")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 
 		// Handle preflight
 		if c.Request.Method == "OPTIONS" {
@@ -48,8 +51,24 @@ func CORSMiddleware(allowedDomains []string) gin.HandlerFunc {
 	}
 }
 
-// SecurityMiddleware checks API key and origin/referer
-func SecurityMiddleware(apiKey string, allowedDomains []string) gin.HandlerFunc {
+// Context keys set by SecurityMiddleware and handlers
+const (
+	apiKeyContextKey  = "apiKey"
+	auditSessionKey   = "auditSession"
+	auditRecipientKey = "auditRecipient"
+	auditActionKey    = "auditAction"
+	auditDetailsKey   = "auditDetails"
+	anonymousKeyID    = "anonymous"
+	apiKeyQueryParam  = "api_key"
+	tokenQueryParam   = "token"
+	bearerPrefix      = "Bearer "
+)
+
+// SecurityMiddleware authenticates every request (except health checks) with an API key and writes the audit log.
+// Origin/Referer never grant access; a browser Origin outside the allowed domains is rejected outright.
+// WebSocket upgrades may instead present a short-lived token (?token=) issued by POST /ws/token.
+// Until API_KEY is set or a key is minted, only the first key can be minted, from the server itself (see bootstrapRequest).
+func SecurityMiddleware(keys *apikey.Service, allowedDomains []string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		path := c.Request.URL.Path
 
@@ -65,62 +84,134 @@ func SecurityMiddleware(apiKey string, allowedDomains []string) gin.HandlerFunc
 			return
 		}
 
-		origin := c.GetHeader("Origin")
-		referer := c.GetHeader("Referer")
-		reqAPIKey := c.GetHeader("x-api-key")
-
-		// Check API key
-		isValidAPIKey := apiKey != "" && reqAPIKey == apiKey
-
-		// Check origin
-		isAllowedOrigin := false
-		for _, domain := range allowedDomains {
-			if domain == "*" || origin == domain {
-				isAllowedOrigin = true
-				break
-			}
+		if origin := c.GetHeader("Origin"); origin != "" && !OriginAllowed(origin, allowedDomains) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			return
 		}
 
-		// Check referer
-		isAllowedReferer := false
-		for _, domain := range allowedDomains {
-			if strings.HasPrefix(referer, domain) {
-				isAllowedReferer = true
-				break
+		ctx := c.Request.Context()
+		secret := requestSecret(c)
+
+		token := c.Query(tokenQueryParam)
+		var key *apikey.Key
+		var err error
+		switch {
+		case secret == "" && !keys.Enabled(ctx):
+			if !bootstrapRequest(c) {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: no API keys configured; mint one with POST /admin/api-keys from the server itself"})
+				return
 			}
+			key = &apikey.Key{ID: anonymousKeyID, Name: "anonymous", Scopes: []apikey.Scope{apikey.ScopeAdmin}}
+		case secret == "" && token != "" && isWebSocketUpgrade(c.Request):
+			key, err = keys.VerifyToken(ctx, token)
+		default:
+			key, err = keys.Authenticate(ctx, secret)
 		}
-
-		// Allow if any check passes
-		if isValidAPIKey || isAllowedOrigin || isAllowedReferer {
-			c.Next()
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
 			return
 		}
+		c.Set(apiKeyContextKey, key)
 
-		// Block unauthorized access
-		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		c.Next()
+
+		// The request context is cancelled once a streaming client disconnects
+		keys.Audit(context.Background(), &apikey.AuditEntry{
+			KeyID:     key.ID,
+			KeyName:   key.Name,
+			Method:    c.Request.Method,
+			Path:      c.FullPath(),
+			Status:    c.Writer.Status(),
+			Session:   c.GetString(auditSessionKey),
+			Recipient: c.GetString(auditRecipientKey),
+			Action:    c.GetString(auditActionKey),
+			Details:   c.GetString(auditDetailsKey),
+			IP:        c.ClientIP(),
+		})
 	}
 }
 
-// APIKeyRequired requires a valid API key for the endpoint
-func APIKeyRequired(apiKey string) gin.HandlerFunc {
+// RequireScope rejects requests whose API key lacks the given scope
+func RequireScope(scope apikey.Scope) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if apiKey == "" {
-			// No API key configured, allow through
-			c.Next()
+		key := CurrentKey(c)
+		if key == nil || !key.HasScope(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden: API key lacks scope " + string(scope)})
 			return
 		}
+		c.Next()
+	}
+}
 
-		// Check header first, then query parameter (for EventSource/SSE)
-		reqAPIKey := c.GetHeader("x-api-key")
-		if reqAPIKey == "" {
-			reqAPIKey = c.Query("api_key")
+// CurrentKey returns the API key that authenticated the request, if any
+func CurrentKey(c *gin.Context) *apikey.Key {
+	if v, ok := c.Get(apiKeyContextKey); ok {
+		if key, ok := v.(*apikey.Key); ok {
+			return key
 		}
-		
-		if reqAPIKey != apiKey {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Invalid API Key"})
-			return
+	}
+	return nil
+}
+
+// SessionAllowed reports whether the request's API key may act on the session, and records it in the audit log
+func SessionAllowed(c *gin.Context, session string) bool {
+	c.Set(auditSessionKey, session)
+	key := CurrentKey(c)
+	return key == nil || key.AllowsSession(session)
+}
+
+// SetAuditRecipient records the message recipient of the request in the audit log
+func SetAuditRecipient(c *gin.Context, recipient string) {
+	c.Set(auditRecipientKey, recipient)
+}
+
+// SetAuditAction records what the request changes in the audit log, with details such as the participants affected
+func SetAuditAction(c *gin.Context, action string, details ...string) {
+	c.Set(auditActionKey, action)
+	c.Set(auditDetailsKey, strings.Join(details, ", "))
+}
+
+// bootstrapRequest reports whether the request may mint the first API key without one: only POST /admin/api-keys
+// from a loopback address, and not relayed by a reverse proxy (whose own address would be loopback)
+func bootstrapRequest(c *gin.Context) bool {
+	if c.Request.Method != http.MethodPost || c.FullPath() != "/admin/api-keys" {
+		return false
+	}
+	for _, header := range []string{"X-Forwarded-For", "X-Real-IP", "Forwarded"} {
+		if c.GetHeader(header) != "" {
+			return false
 		}
+	}
+	host, _, err := net.SplitHostPort(c.Request.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
 
-		c.Next()
+// requestSecret reads the API key from the x-api-key header, a bearer token or the api_key query parameter (for EventSource/WebSocket)
+func requestSecret(c *gin.Context) string {
+	if secret := c.GetHeader("x-api-key"); secret != "" {
+		return secret
+	}
+	if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, bearerPrefix) {
+		return strings.TrimSpace(strings.TrimPrefix(auth, bearerPrefix))
 	}
+	return c.Query(apiKeyQueryParam)
+}
+
+// OriginAllowed reports whether a browser Origin is one of the allowed domains
+func OriginAllowed(origin string, allowedDomains []string) bool {
+	for _, domain := range allowedDomains {
+		if domain == "*" || origin == domain {
+			return true
+		}
+	}
+	return false
+}
+
+// isWebSocketUpgrade reports whether the request opens a WebSocket
+func isWebSocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
 }
@@ -1,17 +1,30 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"time"
 
 	"wa-server-go/internal/api/handlers"
 	"wa-server-go/internal/api/middleware"
 	"wa-server-go/internal/api/websocket"
+	"wa-server-go/internal/apikey"
 	"wa-server-go/internal/config"
-	"wa-server-go/internal/firestore"
+	"wa-server-go/internal/contacts"
+	"wa-server-go/internal/features/backup"
+	"wa-server-go/internal/features/blog"
+	"wa-server-go/internal/features/monitor"
+	"wa-server-go/internal/features/reminder"
+	"wa-server-go/internal/features/scheduler"
+	"wa-server-go/internal/metrics"
+	"wa-server-go/internal/outbox"
+	"wa-server-go/internal/storage"
+	"wa-server-go/internal/webhook"
 	"wa-server-go/internal/whatsapp"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // Server represents the HTTP server
@@ -21,11 +34,20 @@ type Server struct {
 	WSHub     *websocket.Hub
 	WAManager *whatsapp.Manager
 	Handler   *handlers.Handler
-	Repo      *firestore.ChatsRepository
+	Store     *storage.Store
+	Outbox    *outbox.Queue
+	Webhooks  *webhook.Dispatcher
+	APIKeys   *apikey.Service
+	Scheduler *scheduler.SchedulerService
+	Reminders *reminder.InvoiceReminderService
+	Backups   *backup.BackupService
+	Monitor   *monitor.MonitorService
+	Blog      *blog.Automator
+	Contacts  *contacts.Directory
 }
 
 // NewServer creates a new HTTP server
-func NewServer(cfg *config.Config, waManager *whatsapp.Manager, repo *firestore.ChatsRepository, fsClient *firestore.Client) *Server {
+func NewServer(cfg *config.Config, waManager *whatsapp.Manager, store *storage.Store, queue *outbox.Queue, webhooks *webhook.Dispatcher, keys *apikey.Service, schedules *scheduler.SchedulerService, reminders *reminder.InvoiceReminderService, backups *backup.BackupService, monitors *monitor.MonitorService, blogs *blog.Automator, directory *contacts.Directory) *Server {
 	// Set Gin mode
 	gin.SetMode(gin.ReleaseMode)
 
@@ -34,16 +56,102 @@ func NewServer(cfg *config.Config, waManager *whatsapp.Manager, repo *firestore.
 	router.Use(gin.Logger())
 
 	// Create WebSocket hub
-	wsHub := websocket.NewHub()
+	wsHub := websocket.NewHub(cfg.AllowedDomains, cfg.WSReplaySize)
+	metrics.RegisterWebSocketClients(wsHub.ClientCount)
+	if queue != nil {
+		metrics.RegisterOutbox(func() map[string]int {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			depth, err := queue.Depth(ctx)
+			if err != nil {
+				log.Printf("⚠️ [METRICS] Failed to count outbox jobs: %v", err)
+				return nil
+			}
+			counts := make(map[string]int, len(depth))
+			for status, n := range depth {
+				counts[string(status)] = n
+			}
+			return counts
+		})
+	}
+	if webhooks.Enabled() {
+		metrics.RegisterWebhookQueue(func() map[string]int {
+			queued, retrying := webhooks.Pending()
+			return map[string]int{"queued": queued, "retrying": retrying}
+		})
+	}
 
 	// Create handlers
+	var repo storage.ChatsRepository
+	if store != nil {
+		repo = store.Chats
+	}
 	handler := handlers.NewHandler(waManager, repo, wsHub)
+	handler.Webhooks = webhooks
+	handler.APIKeys = keys
+	handler.DefaultSession = cfg.BotClientID
+	handler.LeadsSession = cfg.LeadsClientID
+
+	// Let WebSocket clients send, type, mark read and fetch history over the socket
+	handler.RegisterWSCommands(wsHub)
+
+	// Deliver queued sends and publish their state transitions
+	if queue != nil {
+		handler.RegisterJobHandlers(queue)
+		queue.OnUpdate(func(job outbox.Job) {
+			wsHub.Publish("job-update", websocket.Topic{Session: job.ClientID, Chat: job.Recipient}, job)
+		})
+	}
+
+	// Fire scheduled messages through the outbox and publish their state
+	if schedules != nil {
+		handler.RegisterScheduler(schedules)
+		schedules.OnUpdate(func(msg scheduler.ScheduledMessage) {
+			wsHub.Publish("schedule-update", websocket.Topic{Session: msg.Session, Chat: msg.Recipient}, msg)
+		})
+	}
+
+	// Send invoice reminders through the outbox and publish each dunning step
+	if reminders != nil {
+		handler.RegisterInvoiceReminders(reminders)
+		reminders.OnUpdate(func(invoice reminder.Invoice, step reminder.DunningStep) {
+			wsHub.Publish("invoice-reminder", websocket.Topic{Session: invoice.Session, Chat: invoice.Phone}, gin.H{"invoice": invoice, "step": step})
+		})
+	}
+
+	// Publish backup progress and results
+	if backups != nil {
+		handler.Backups = backups
+		backups.OnUpdate(func(run backup.Run) {
+			wsHub.Publish("backup-update", websocket.Topic{Session: run.Session}, run)
+		})
+	}
+
+	// Publish monitor status changes
+	if monitors != nil {
+		handler.Monitor = monitors
+		monitors.OnUpdate(func(status monitor.TargetStatus) {
+			wsHub.Broadcast("monitor-update", status)
+		})
+	}
+
+	// Publish blog topic progress
+	if blogs != nil {
+		handler.Blog = blogs
+		blogs.OnUpdate(func(topic blog.Topic) {
+			wsHub.Broadcast("blog-update", topic)
+		})
+	}
+
+	// Contact names and notes of our own
+	if directory != nil {
+		handler.Contacts = directory
+	}
 
 	// Initialize WA Status repository
-	if fsClient != nil {
-		waStatusRepo := firestore.NewWAStatusRepository(fsClient)
-		handlers.InitWAStatusRepo(waStatusRepo)
-		log.Println("✅ WA Status repository initialized")
+	if store != nil && store.WAStatus != nil {
+		handlers.InitWAStatusRepo(store.WAStatus)
+		log.Printf("✅ WA Status repository initialized (%s)", store.Backend)
 	}
 
 	server := &Server{
@@ -52,12 +160,18 @@ func NewServer(cfg *config.Config, waManager *whatsapp.Manager, repo *firestore.
 		WSHub:     wsHub,
 		WAManager: waManager,
 		Handler:   handler,
-		Repo:      repo,
+		Store:     store,
+		Outbox:    queue,
+		Webhooks:  webhooks,
+		APIKeys:   keys,
+		Scheduler: schedules,
+		Reminders: reminders,
+		Backups:   backups,
+		Monitor:   monitors,
+		Blog:      blogs,
+		Contacts:  handler.Contacts,
 	}
 
-	// Serve static files (uploads)
-	router.Static("/uploads", "./uploads")
-
 	server.setupRoutes()
 
 	return server
@@ -67,47 +181,129 @@ func NewServer(cfg *config.Config, waManager *whatsapp.Manager, repo *firestore.
 func (s *Server) setupRoutes() {
 	// Apply global middleware
 	s.Router.Use(middleware.CORSMiddleware(s.Config.AllowedDomains))
-	s.Router.Use(middleware.SecurityMiddleware(s.Config.APIKey, s.Config.AllowedDomains))
+	s.Router.Use(middleware.SecurityMiddleware(s.APIKeys, s.Config.AllowedDomains))
 
 	// Health check endpoints (no auth required)
 	s.Router.GET("/", s.Handler.HealthCheck)
 	s.Router.GET("/status", s.Handler.GetStatus)
 
-	// WebSocket endpoint
-	s.Router.GET("/ws", s.WSHub.HandleWebSocket)
+	// Every other route requires an API key with the scope of its group
 
-	// Sync endpoints
-	s.Router.GET("/sync-status", s.Handler.GetSyncStatus)
+	// Sending endpoints
+	send := s.Router.Group("", middleware.RequireScope(apikey.ScopeSend))
+	{
+		send.POST("/send-invoice", s.Handler.SendInvoice)
+		send.POST("/send-message", s.Handler.SendMessage)
+		send.POST("/send-media", s.Handler.SendMedia)
+		send.GET("/jobs/:id", s.Handler.GetJob)
+
+		// Scheduled messages
+		send.POST("/scheduled-messages", s.Handler.CreateScheduledMessage)
+		send.GET("/scheduled-messages", s.Handler.ListScheduledMessages)
+		send.GET("/scheduled-messages/:id", s.Handler.GetScheduledMessage)
+		send.POST("/scheduled-messages/:id/pause", s.Handler.PauseScheduledMessage)
+		send.POST("/scheduled-messages/:id/resume", s.Handler.ResumeScheduledMessage)
+		send.DELETE("/scheduled-messages/:id", s.Handler.CancelScheduledMessage)
+
+		// Invoice reminders
+		send.POST("/invoices", s.Handler.SaveInvoice)
+		send.GET("/invoices", s.Handler.ListInvoices)
+		send.GET("/invoices/:id", s.Handler.GetInvoice)
+		send.POST("/invoices/:id/paid", s.Handler.MarkInvoicePaid)
+	}
 
-	// Protected endpoints (require API key per route)
-	protected := s.Router.Group("")
-	protected.Use(middleware.APIKeyRequired(s.Config.APIKey))
+	// Chat endpoints
+	readChats := s.Router.Group("", middleware.RequireScope(apikey.ScopeReadChats))
 	{
-		// Sending endpoints
-		protected.POST("/send-invoice", s.Handler.SendInvoice)
-		protected.POST("/send-message", s.Handler.SendMessage)
-		protected.POST("/send-media", s.Handler.SendMedia)
-
-		// Chat endpoints
-		protected.GET("/get-chats", s.Handler.GetChats)
-		protected.GET("/get-messages/:chatId", s.Handler.GetMessages)
-		protected.GET("/get-media/:messageId", s.Handler.GetMedia)
-		protected.GET("/get-invoice-chats", s.Handler.GetInvoiceChats)
-
-		// Sync endpoints
-		protected.POST("/sync-contacts", s.Handler.SyncContacts)
-		protected.GET("/sync-contacts-stream", s.Handler.SyncContactsStream) // SSE streaming
-		protected.POST("/start-leads-client", s.Handler.StartLeadsClient)
-		protected.POST("/stop-leads-client", s.Handler.StopLeadsClient)
-
-		// Feature endpoints
-		protected.POST("/trigger-backup", s.Handler.TriggerBackup)
-		protected.POST("/api/blog/manual-trigger", s.Handler.TriggerBlog)
-		protected.POST("/sync-invoices", s.Handler.SyncInvoices)
-
-		// WhatsApp Status sync endpoints
-		protected.POST("/sync-wa-status", s.Handler.SyncWAStatus)
-		protected.DELETE("/clear-wa-status", s.Handler.ClearWAStatus)
+		// WebSocket endpoint (browsers pass a token from POST /ws/token as ?token=, or the key as ?api_key=)
+		readChats.GET("/ws", s.WSHub.HandleWebSocket)
+		readChats.POST("/ws/token", s.Handler.IssueWSToken)
+
+		readChats.GET("/get-chats", s.Handler.GetChats)
+		readChats.GET("/get-messages/:chatId", s.Handler.GetMessages)
+		readChats.GET("/get-media/:messageId", s.Handler.GetMedia)
+		readChats.GET("/get-invoice-chats", s.Handler.GetInvoiceChats)
+		readChats.POST("/chats/:chatId/read", s.Handler.MarkChatRead)
+		readChats.GET("/search/messages", s.Handler.SearchMessages)
+		readChats.GET("/groups", s.Handler.GetGroups)
+		readChats.GET("/groups/:jid", s.Handler.GetGroup)
+		readChats.GET("/contacts", s.Handler.GetContacts)
+		readChats.GET("/contacts/:id", s.Handler.GetContact)
+	}
+
+	// Contact editing endpoints
+	contactsEdit := s.Router.Group("", middleware.RequireScope(apikey.ScopeContacts))
+	{
+		contactsEdit.PATCH("/contacts/:id", s.Handler.UpdateContact)
+	}
+
+	// Group administration endpoints
+	groups := s.Router.Group("", middleware.RequireScope(apikey.ScopeGroups))
+	{
+		groups.POST("/groups", s.Handler.CreateGroup)
+		groups.POST("/groups/:jid/participants", s.Handler.UpdateGroupParticipants)
+		groups.PUT("/groups/:jid/subject", s.Handler.SetGroupSubject)
+		groups.PUT("/groups/:jid/description", s.Handler.SetGroupDescription)
+		groups.PUT("/groups/:jid/picture", s.Handler.SetGroupPicture)
+		groups.DELETE("/groups/:jid/picture", s.Handler.DeleteGroupPicture)
+		groups.PUT("/groups/:jid/settings", s.Handler.SetGroupSettings)
+		groups.GET("/groups/:jid/invite-link", s.Handler.GetGroupInviteLink)
+		groups.POST("/groups/:jid/invite-link/revoke", s.Handler.RevokeGroupInviteLink)
+	}
+
+	// Leads sync endpoints
+	leadsSync := s.Router.Group("", middleware.RequireScope(apikey.ScopeLeadsSync))
+	{
+		leadsSync.GET("/sync-status", s.Handler.GetSyncStatus)
+		leadsSync.POST("/sync-contacts", s.Handler.SyncContacts)
+		leadsSync.GET("/sync-contacts-stream", s.Handler.SyncContactsStream) // SSE streaming
+		leadsSync.POST("/lid/resolve", s.Handler.ResolveLIDs)
+		leadsSync.POST("/start-leads-client", s.Handler.StartLeadsClient)
+		leadsSync.POST("/stop-leads-client", s.Handler.StopLeadsClient)
+	}
+
+	// Status and feature read endpoints
+	status := s.Router.Group("", middleware.RequireScope(apikey.ScopeStatus))
+	{
+		status.GET("/sessions", s.Handler.ListSessions)
+		status.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+		status.GET("/backups", s.Handler.ListBackups)
+		status.GET("/monitor", s.Handler.GetMonitor)
+		status.GET("/monitor/:target/history", s.Handler.GetMonitorHistory)
+		status.GET("/api/blog/topics", s.Handler.ListBlogTopics)
+	}
+
+	// Backup, blog, invoice sync and WhatsApp Status tasks
+	tasks := s.Router.Group("", middleware.RequireScope(apikey.ScopeTasks))
+	{
+		tasks.POST("/trigger-backup", s.Handler.TriggerBackup)
+		tasks.POST("/api/blog/manual-trigger", s.Handler.TriggerBlog)
+		tasks.POST("/api/blog/topics", s.Handler.QueueBlogTopic)
+		tasks.POST("/api/blog/topics/:id/retry", s.Handler.RetryBlogTopic)
+		tasks.POST("/sync-invoices", s.Handler.SyncInvoices)
+
+		tasks.POST("/sync-wa-status", s.Handler.SyncWAStatus)
+		tasks.DELETE("/clear-wa-status", s.Handler.ClearWAStatus)
+	}
+
+	// Admin endpoints
+	admin := s.Router.Group("", middleware.RequireScope(apikey.ScopeAdmin))
+	{
+		// Session management
+		admin.POST("/sessions", s.Handler.CreateSession)
+		admin.DELETE("/sessions/:id", s.Handler.DeleteSession)
+		admin.POST("/sessions/:id/logout", s.Handler.LogoutSession)
+
+		// API keys and audit log
+		admin.GET("/admin/api-keys", s.Handler.ListAPIKeys)
+		admin.POST("/admin/api-keys", s.Handler.CreateAPIKey)
+		admin.DELETE("/admin/api-keys/:id", s.Handler.RevokeAPIKey)
+		admin.GET("/admin/audit", s.Handler.GetAuditLog)
+
+		admin.GET("/webhooks/dead-letters", s.Handler.GetWebhookDeadLetters)
+
+		admin.POST("/backups/:id/restore", s.Handler.RestoreBackup)
 	}
 }
 
@@ -124,18 +320,29 @@ func (s *Server) Start() error {
 	return s.Router.Run(addr)
 }
 
-// forwardEvents forwards WhatsApp events to WebSocket clients
+// forwardEvents forwards WhatsApp events to WebSocket clients and webhooks
 func (s *Server) forwardEvents() {
 	for {
 		select {
 		case qr := <-s.WAManager.QRChannel():
-			s.WSHub.Broadcast("qr-image", qr)
+			s.WSHub.Publish("qr-image", websocket.Topic{Session: qr.Client}, qr)
+			s.Webhooks.Dispatch("qr-image", qr)
 
 		case status := <-s.WAManager.StatusChannel():
-			s.WSHub.Broadcast("status-update", status)
+			s.WSHub.Publish("status-update", websocket.Topic{Session: status.Client}, status)
+			s.Webhooks.Dispatch("status-update", status)
 
 		case msg := <-s.WAManager.MessageChannel():
-			s.WSHub.Broadcast("new-message", msg)
+			s.WSHub.Publish("new-message", websocket.Topic{Session: msg.Client, Chat: msg.ChatID}, msg)
+			s.Webhooks.Dispatch("new-message", msg)
+
+		case receipt := <-s.WAManager.ReceiptChannel():
+			s.WSHub.Publish("message-ack", websocket.Topic{Session: receipt.Client, Chat: receipt.ChatID}, receipt)
+			s.Webhooks.Dispatch("message-ack", receipt)
+
+		case group := <-s.WAManager.GroupChannel():
+			s.WSHub.Publish("group-update", websocket.Topic{Session: group.Client, Chat: group.ChatID}, group)
+			s.Webhooks.Dispatch("group-update", group)
 		}
 	}
 }
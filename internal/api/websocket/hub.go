@@ -1,23 +1,77 @@
 package websocket
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"wa-server-go/internal/api/middleware"
+	"wa-server-go/internal/apikey"
+
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		// TODO: Validate origin against allowed domains
-		return true
-	},
+// Topic identifies what an event is about, so it only reaches interested clients.
+// Empty fields mean the event is not tied to a session or chat.
+type Topic struct {
+	Session string
+	Chat    string // chat JID or phone number
+}
+
+// evictedReason is the close reason sent to a client that cannot keep up
+const evictedReason = "send buffer full, reconnect with lastSeq to resume"
+
+// message is an event waiting to be fanned out; data is the encoded frame once seq is assigned
+type message struct {
+	seq   uint64
+	event string
+	topic Topic
+	data  []byte
+}
+
+// envelope is the frame sent to clients. Events carry a sequence number; replies to a client's frames do not.
+type envelope struct {
+	Seq   uint64      `json:"seq,omitempty"`
+	Event string      `json:"event"`
+	Data  interface{} `json:"data"`
+}
+
+// commandTimeout bounds how long a command frame may run
+const commandTimeout = 30 * time.Second
+
+// CommandRequest is a command frame sent by a client, e.g.
+// {"type":"send-text","id":"r1","data":{"number":"628123456789","message":"Hi"}}
+type CommandRequest struct {
+	ID         string          // request ID chosen by the client, echoed in the reply
+	Type       string          // command name
+	Data       json.RawMessage // command arguments
+	Key        *apikey.Key     // key the connection authenticated with; nil when auth is disabled
+	RemoteAddr string
+}
+
+// CommandFunc runs a command; its result or error is sent back in a reply frame.
+// Errors with a StatusCode() int method report that HTTP-style status.
+type CommandFunc func(ctx context.Context, req *CommandRequest) (interface{}, error)
+
+type command struct {
+	scope apikey.Scope
+	fn    CommandFunc
+}
+
+// resumeRequest asks the hub to replay the events after lastSeq to a client
+type resumeRequest struct {
+	client  *Client
+	lastSeq uint64
 }
 
 // Client represents a WebSocket client connection
@@ -25,24 +79,80 @@ type Client struct {
 	hub  *Hub
 	conn *websocket.Conn
 	send chan []byte
+	key  *apikey.Key // key the connection authenticated with; nil when auth is disabled
+	addr string
+
+	resumeFrom *uint64 // lastSeq given on connect
+	closeCode  int     // close frame sent when the hub drops the client
+	closeText  string
+
+	mu   sync.Mutex
+	subs subscription
+}
+
+// subscription filters the events a client receives. A nil set matches everything;
+// unsubscribing from every entry of a set leaves it empty, matching nothing.
+type subscription struct {
+	events   map[string]bool
+	sessions map[string]bool
+	chats    map[string]bool
+}
+
+// frame is an inbound client message, e.g.
+// {"type":"subscribe","events":["new-message"],"sessions":["bot"],"chats":["628123456789"]}
+// {"type":"resume","lastSeq":42}
+// Any other type is a command (see CommandRequest).
+type frame struct {
+	Type     string          `json:"type"`
+	Events   []string        `json:"events"`
+	Sessions []string        `json:"sessions"`
+	Chats    []string        `json:"chats"`
+	LastSeq  *uint64         `json:"lastSeq"`
+	ID       string          `json:"id"`
+	Data     json.RawMessage `json:"data"`
 }
 
 // Hub manages WebSocket connections and broadcasts
 type Hub struct {
 	clients    map[*Client]bool
-	broadcast  chan []byte
+	broadcast  chan message
 	register   chan *Client
 	unregister chan *Client
+	resume     chan resumeRequest
 	mu         sync.RWMutex
+	upgrader   websocket.Upgrader
+	commands   map[string]command
+
+	// Owned by Run
+	seq     uint64
+	history []message // ring buffer of the most recent events
+	next    int       // next write position in history
+	count   int       // events held in history
 }
 
-// NewHub creates a new WebSocket hub
-func NewHub() *Hub {
+// NewHub creates a new WebSocket hub accepting browser connections from the allowed domains.
+// The last replaySize events are kept for clients resuming after a reconnect.
+func NewHub(allowedDomains []string, replaySize int) *Hub {
+	if replaySize < 0 {
+		replaySize = 0
+	}
 	return &Hub{
 		clients:    make(map[*Client]bool),
-		broadcast:  make(chan []byte, 256),
+		broadcast:  make(chan message, 256),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
+		resume:     make(chan resumeRequest),
+		commands:   make(map[string]command),
+		history:    make([]message, replaySize),
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			CheckOrigin: func(r *http.Request) bool {
+				// Non-browser clients send no Origin
+				origin := r.Header.Get("Origin")
+				return origin == "" || middleware.OriginAllowed(origin, allowedDomains)
+			},
+		},
 	}
 }
 
@@ -53,8 +163,19 @@ func (h *Hub) Run() {
 		case client := <-h.register:
 			h.mu.Lock()
 			h.clients[client] = true
+			// Missed events go out before any live event
+			if client.resumeFrom != nil {
+				h.replay(client, *client.resumeFrom)
+			}
+			h.mu.Unlock()
+			log.Printf("✅ WebSocket client connected. Total: %d", h.ClientCount())
+
+		case req := <-h.resume:
+			h.mu.Lock()
+			if h.clients[req.client] {
+				h.replay(req.client, req.lastSeq)
+			}
 			h.mu.Unlock()
-			log.Printf("✅ WebSocket client connected. Total: %d", len(h.clients))
 
 		case client := <-h.unregister:
 			h.mu.Lock()
@@ -63,35 +184,103 @@ func (h *Hub) Run() {
 				close(client.send)
 			}
 			h.mu.Unlock()
-			log.Printf("❌ WebSocket client disconnected. Total: %d", len(h.clients))
+			log.Printf("❌ WebSocket client disconnected. Total: %d", h.ClientCount())
+
+		case msg := <-h.broadcast:
+			h.seq++
+			msg.seq = h.seq
+			data, err := json.Marshal(envelope{Seq: msg.seq, Event: msg.event, Data: json.RawMessage(msg.data)})
+			if err != nil {
+				log.Printf("Error marshaling broadcast: %v", err)
+				continue
+			}
+			msg.data = data
+			h.remember(msg)
 
-		case message := <-h.broadcast:
-			h.mu.RLock()
+			h.mu.Lock()
 			for client := range h.clients {
-				select {
-				case client.send <- message:
-				default:
-					close(client.send)
-					delete(h.clients, client)
+				if client.wants(msg) {
+					h.deliver(client, msg.data)
 				}
 			}
-			h.mu.RUnlock()
+			h.mu.Unlock()
 		}
 	}
 }
 
-// Broadcast sends a message to all connected clients
-func (h *Hub) Broadcast(event string, data interface{}) {
-	msg := map[string]interface{}{
-		"event": event,
-		"data":  data,
+// remember adds an event to the replay buffer, overwriting the oldest one when full
+func (h *Hub) remember(msg message) {
+	if len(h.history) == 0 {
+		return
 	}
-	jsonData, err := json.Marshal(msg)
+	h.history[h.next] = msg
+	h.next = (h.next + 1) % len(h.history)
+	if h.count < len(h.history) {
+		h.count++
+	}
+}
+
+// replay sends the buffered events after lastSeq that the client wants, followed by a resumed event.
+// resumed.complete is false when events after lastSeq have already left the buffer, or the sequence
+// restarted with the server; the client should then reload its state over REST.
+// Must be called from Run with h.mu held.
+func (h *Hub) replay(client *Client, lastSeq uint64) {
+	oldest := h.seq - uint64(h.count) // newest event no longer buffered
+	var batch [][]byte
+	for i := 0; i < h.count; i++ {
+		msg := h.history[(h.next-h.count+i+len(h.history))%len(h.history)]
+		if msg.seq > lastSeq && client.wants(msg) {
+			batch = append(batch, msg.data)
+		}
+	}
+
+	resumed, err := encode("resumed", map[string]interface{}{
+		"lastSeq":  lastSeq,
+		"seq":      h.seq,
+		"replayed": len(batch),
+		"complete": lastSeq >= oldest && lastSeq <= h.seq,
+	})
+	if err != nil {
+		log.Printf("Error marshaling resume: %v", err)
+		return
+	}
+	// One send, so the replay stays contiguous and cannot be interleaved with live events
+	h.deliver(client, bytes.Join(append(batch, resumed), []byte{'\n'}))
+}
+
+// deliver queues data for a client, dropping the client when its buffer is full.
+// Must be called from Run with h.mu held.
+func (h *Hub) deliver(client *Client, data []byte) {
+	select {
+	case client.send <- data:
+	default:
+		log.Printf("⚠️ WebSocket client too slow, dropping it at seq %d", h.seq)
+		client.closeCode = websocket.CloseTryAgainLater
+		client.closeText = evictedReason
+		close(client.send)
+		delete(h.clients, client)
+	}
+}
+
+// Broadcast sends an event that is not tied to a session or chat to all subscribed clients
+func (h *Hub) Broadcast(event string, data interface{}) {
+	h.Publish(event, Topic{}, data)
+}
+
+// Publish sends an event to the clients subscribed to it whose API key may see the topic's session
+func (h *Hub) Publish(event string, topic Topic, data interface{}) {
+	jsonData, err := json.Marshal(data)
 	if err != nil {
 		log.Printf("Error marshaling broadcast: %v", err)
 		return
 	}
-	h.broadcast <- jsonData
+	h.broadcast <- message{event: event, topic: topic, data: jsonData}
+}
+
+// HandleCommand registers a command clients may send, allowed for keys with the given scope.
+// Commands must be registered before the server starts accepting connections.
+func (h *Hub) HandleCommand(name string, scope apikey.Scope, fn CommandFunc) {
+	h.commands[name] = command{scope: scope, fn: fn}
 }
 
 // ClientCount returns the number of connected clients
@@ -101,19 +290,40 @@ func (h *Hub) ClientCount() int {
 	return len(h.clients)
 }
 
-// HandleWebSocket handles WebSocket upgrade requests
+// HandleWebSocket handles WebSocket upgrade requests.
+// The initial subscription may be given as comma-separated events, sessions and chats query parameters,
+// and lastSeq replays the events missed since a previous connection.
 func (h *Hub) HandleWebSocket(c *gin.Context) {
-	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	client := &Client{
+		hub:  h,
+		key:  middleware.CurrentKey(c),
+		addr: c.ClientIP(),
+	}
+	if value := c.Query("lastSeq"); value != "" {
+		lastSeq, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid lastSeq"})
+			return
+		}
+		client.resumeFrom = &lastSeq
+	}
+	err := client.subscribe(frame{
+		Events:   splitQuery(c.Query("events")),
+		Sessions: splitQuery(c.Query("sessions")),
+		Chats:    splitQuery(c.Query("chats")),
+	})
 	if err != nil {
-		log.Printf("WebSocket upgrade error: %v", err)
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "error": err.Error()})
 		return
 	}
 
-	client := &Client{
-		hub:  h,
-		conn: conn,
-		send: make(chan []byte, 256),
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("WebSocket upgrade error: %v", err)
+		return
 	}
+	client.conn = conn
+	client.send = make(chan []byte, 256)
 
 	h.register <- client
 
@@ -122,7 +332,152 @@ func (h *Hub) HandleWebSocket(c *gin.Context) {
 	go client.readPump()
 }
 
-// readPump reads messages from the WebSocket connection
+// wants reports whether the client should receive msg
+func (c *Client) wants(msg message) bool {
+	if msg.topic.Session != "" && c.key != nil && !c.key.AllowsSession(msg.topic.Session) {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.subs.events != nil && !c.subs.events[msg.event] {
+		return false
+	}
+	if msg.topic.Session != "" && c.subs.sessions != nil && !c.subs.sessions[msg.topic.Session] {
+		return false
+	}
+	if msg.topic.Chat != "" && c.subs.chats != nil && !c.subs.chats[chatKey(msg.topic.Chat)] {
+		return false
+	}
+	return true
+}
+
+// subscribe adds the frame's events, sessions and chats to the client's filters
+func (c *Client) subscribe(f frame) error {
+	for _, session := range f.Sessions {
+		if c.key != nil && !c.key.AllowsSession(session) {
+			return fmt.Errorf("API key may not access session %s", session)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.subs.events = addAll(c.subs.events, f.Events, nil)
+	c.subs.sessions = addAll(c.subs.sessions, f.Sessions, nil)
+	c.subs.chats = addAll(c.subs.chats, f.Chats, chatKey)
+	return nil
+}
+
+// unsubscribe removes the frame's events, sessions and chats; an empty frame clears every filter
+func (c *Client) unsubscribe(f frame) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(f.Events) == 0 && len(f.Sessions) == 0 && len(f.Chats) == 0 {
+		c.subs = subscription{}
+		return
+	}
+	for _, event := range f.Events {
+		delete(c.subs.events, event)
+	}
+	for _, session := range f.Sessions {
+		delete(c.subs.sessions, session)
+	}
+	for _, chat := range f.Chats {
+		delete(c.subs.chats, chatKey(chat))
+	}
+}
+
+// subscriptions returns the client's current filters
+func (c *Client) subscriptions() gin.H {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return gin.H{
+		"events":   keys(c.subs.events),
+		"sessions": keys(c.subs.sessions),
+		"chats":    keys(c.subs.chats),
+	}
+}
+
+// handleFrame applies an inbound frame and replies to the client
+func (c *Client) handleFrame(data []byte) {
+	var f frame
+	if err := json.Unmarshal(data, &f); err != nil {
+		c.reply("error", gin.H{"error": "Invalid frame: " + err.Error()})
+		return
+	}
+
+	switch f.Type {
+	case "subscribe":
+		if err := c.subscribe(f); err != nil {
+			c.reply("error", gin.H{"error": err.Error()})
+			return
+		}
+	case "unsubscribe":
+		c.unsubscribe(f)
+	case "resume":
+		if f.LastSeq == nil {
+			c.reply("error", gin.H{"error": "lastSeq is required"})
+			return
+		}
+		c.hub.resume <- resumeRequest{client: c, lastSeq: *f.LastSeq}
+		return
+	default:
+		cmd, ok := c.hub.commands[f.Type]
+		if !ok {
+			c.reply("error", gin.H{"id": f.ID, "error": fmt.Sprintf("Unknown frame type %q", f.Type)})
+			return
+		}
+		// Commands run concurrently; replies are matched to requests by ID
+		go c.runCommand(cmd, &CommandRequest{ID: f.ID, Type: f.Type, Data: f.Data, Key: c.key, RemoteAddr: c.addr})
+		return
+	}
+	c.reply("subscriptions", c.subscriptions())
+}
+
+// runCommand runs a command if the client's key has its scope and replies with the outcome
+func (c *Client) runCommand(cmd command, req *CommandRequest) {
+	if c.key != nil && !c.key.HasScope(cmd.scope) {
+		c.reply("reply", gin.H{"id": req.ID, "type": req.Type, "success": false, "status": http.StatusForbidden,
+			"error": "Forbidden: API key lacks scope " + string(cmd.scope)})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+	result, err := cmd.fn(ctx, req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		var coded interface{ StatusCode() int }
+		if errors.As(err, &coded) {
+			status = coded.StatusCode()
+		}
+		c.reply("reply", gin.H{"id": req.ID, "type": req.Type, "success": false, "status": status, "error": err.Error()})
+		return
+	}
+	c.reply("reply", gin.H{"id": req.ID, "type": req.Type, "success": true, "result": result})
+}
+
+// reply sends an event to this client only
+func (c *Client) reply(event string, data interface{}) {
+	jsonData, err := encode(event, data)
+	if err != nil {
+		log.Printf("Error marshaling reply: %v", err)
+		return
+	}
+
+	// The hub closes send when it drops the client, always under its write lock
+	c.hub.mu.RLock()
+	defer c.hub.mu.RUnlock()
+	if !c.hub.clients[c] {
+		return
+	}
+	select {
+	case c.send <- jsonData:
+	default:
+	}
+}
+
+// readPump reads subscription, resume and command frames from the WebSocket connection
 func (c *Client) readPump() {
 	defer func() {
 		c.hub.unregister <- c
@@ -137,14 +492,14 @@ func (c *Client) readPump() {
 	})
 
 	for {
-		_, _, err := c.conn.ReadMessage()
+		_, data, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("WebSocket error: %v", err)
 			}
 			break
 		}
-		// Handle incoming messages if needed
+		c.handleFrame(data)
 	}
 }
 
@@ -161,7 +516,11 @@ func (c *Client) writePump() {
 		case message, ok := <-c.send:
 			c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
 			if !ok {
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				var reason []byte
+				if c.closeCode != 0 {
+					reason = websocket.FormatCloseMessage(c.closeCode, c.closeText)
+				}
+				c.conn.WriteMessage(websocket.CloseMessage, reason)
 				return
 			}
 
@@ -190,3 +549,49 @@ func (c *Client) writePump() {
 		}
 	}
 }
+
+// encode builds a frame without a sequence number
+func encode(event string, data interface{}) ([]byte, error) {
+	return json.Marshal(envelope{Event: event, Data: data})
+}
+
+// chatKey normalises a chat JID or phone number to its user part, so 628123 matches 628123@s.whatsapp.net
+func chatKey(chat string) string {
+	user, _, _ := strings.Cut(chat, "@")
+	return user
+}
+
+func addAll(set map[string]bool, values []string, normalise func(string) string) map[string]bool {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+		if normalise != nil {
+			value = normalise(value)
+		}
+		if set == nil {
+			set = make(map[string]bool)
+		}
+		set[value] = true
+	}
+	return set
+}
+
+func keys(set map[string]bool) []string {
+	result := make([]string, 0, len(set))
+	for value := range set {
+		result = append(result, value)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func splitQuery(value string) []string {
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
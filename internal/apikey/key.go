@@ -0,0 +1,117 @@
+package apikey
+
+import (
+	"context"
+	"time"
+)
+
+// Scope is a permission granted to an API key
+type Scope string
+
+const (
+	ScopeSend      Scope = "send"       // queue outbound messages
+	ScopeReadChats Scope = "read-chats" // read chats, messages and media
+	ScopeLeadsSync Scope = "leads-sync" // run the contact sync session
+	ScopeStatus    Scope = "status"     // read session state, metrics, backups, monitor and blog topics
+	ScopeTasks     Scope = "tasks"      // run backups, blog posts and invoice and WA status syncs
+	ScopeGroups    Scope = "groups"     // create groups and manage their members and settings
+	ScopeContacts  Scope = "contacts"   // edit custom contact names and notes
+	ScopeAdmin     Scope = "admin"      // manage sessions and API keys; implies every scope
+)
+
+// AllScopes lists every known scope
+var AllScopes = []Scope{ScopeSend, ScopeReadChats, ScopeLeadsSync, ScopeStatus, ScopeTasks, ScopeGroups, ScopeContacts, ScopeAdmin}
+
+// Valid reports whether s is a known scope
+func (s Scope) Valid() bool {
+	for _, scope := range AllScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Key is a named API key. Only the SHA-256 hash of the secret is stored.
+type Key struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"` // first characters of the secret, to recognise a key
+	Hash       string     `json:"-"`
+	Scopes     []Scope    `json:"scopes"`
+	Sessions   []string   `json:"sessions,omitempty"` // allowed sessions; empty means all
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+}
+
+// HasScope reports whether the key grants scope; admin keys grant every scope.
+// Admin spans every session and key, so a key restricted to sessions never holds it.
+func (k *Key) HasScope(scope Scope) bool {
+	for _, s := range k.Scopes {
+		if s == ScopeAdmin && len(k.Sessions) > 0 {
+			continue
+		}
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsSession reports whether the key may act on the given WhatsApp session
+func (k *Key) AllowsSession(session string) bool {
+	if len(k.Sessions) == 0 {
+		return true
+	}
+	for _, s := range k.Sessions {
+		if s == session {
+			return true
+		}
+	}
+	return false
+}
+
+// Active reports whether the key is neither revoked nor expired
+func (k *Key) Active(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+// AuditEntry records one authenticated request
+type AuditEntry struct {
+	ID        int64     `json:"id"`
+	KeyID     string    `json:"keyId"`
+	KeyName   string    `json:"keyName"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Status    int       `json:"status"`
+	Session   string    `json:"session,omitempty"`
+	Recipient string    `json:"recipient,omitempty"`
+	Action    string    `json:"action,omitempty"`  // what the request changed, e.g. group.participants.remove
+	Details   string    `json:"details,omitempty"` // what it was changed to, e.g. the participants removed
+	IP        string    `json:"ip"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Repository persists API keys and the audit log
+type Repository interface {
+	Create(ctx context.Context, key *Key) error
+	// GetByHash returns the key with the given secret hash, or nil if none exists
+	GetByHash(ctx context.Context, hash string) (*Key, error)
+	// Get returns the key with the given ID, or nil if none exists
+	Get(ctx context.Context, id string) (*Key, error)
+	List(ctx context.Context) ([]Key, error)
+	// Revoke marks a key revoked; it reports false if the key does not exist or is already revoked
+	Revoke(ctx context.Context, id string, at time.Time) (bool, error)
+	Touch(ctx context.Context, id string, at time.Time) error
+
+	AddAudit(ctx context.Context, entry *AuditEntry) error
+	// ListAudit returns the most recent entries first, optionally for a single key
+	ListAudit(ctx context.Context, keyID string, limit int) ([]AuditEntry, error)
+	// PruneAudit deletes entries older than before
+	PruneAudit(ctx context.Context, before time.Time) (int64, error)
+}
@@ -0,0 +1,213 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// secretPrefix marks minted keys so they are easy to spot in logs and configs
+const secretPrefix = "wak_"
+
+// touchInterval limits how often a key's last-used time is written
+const touchInterval = time.Minute
+
+// auditCleanupInterval is how often audit entries past their retention are deleted
+const auditCleanupInterval = 24 * time.Hour
+
+// LegacyKeyID identifies requests authenticated with the API_KEY environment variable
+const LegacyKeyID = "legacy"
+
+var (
+	ErrInvalidKey = errors.New("invalid API key")
+	ErrExpiredKey = errors.New("API key expired or revoked")
+)
+
+// Service mints, verifies and revokes API keys
+type Service struct {
+	repo        Repository
+	legacyKey   string
+	tokenSecret []byte // signs short-lived tokens; regenerated on every start
+}
+
+// NewService creates a key service. A non-empty legacyKey (API_KEY) keeps working as an admin key.
+func NewService(repo Repository, legacyKey string) *Service {
+	tokenSecret := make([]byte, 32)
+	rand.Read(tokenSecret)
+	return &Service{repo: repo, legacyKey: legacyKey, tokenSecret: tokenSecret}
+}
+
+// Enabled reports whether authentication is enforced: API_KEY is set or at least one key has been minted
+func (s *Service) Enabled(ctx context.Context) bool {
+	if s.legacyKey != "" {
+		return true
+	}
+	keys, err := s.repo.List(ctx)
+	if err != nil {
+		// Fail closed
+		return true
+	}
+	return len(keys) > 0
+}
+
+// Mint creates a new key and returns it together with its secret, which is not stored and cannot be shown again
+func (s *Service) Mint(ctx context.Context, name string, scopes []Scope, sessions []string, expiresAt *time.Time) (*Key, string, error) {
+	if name == "" {
+		return nil, "", fmt.Errorf("name is required")
+	}
+	if len(scopes) == 0 {
+		return nil, "", fmt.Errorf("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !scope.Valid() {
+			return nil, "", fmt.Errorf("unknown scope %q", scope)
+		}
+	}
+	for _, scope := range scopes {
+		if scope == ScopeAdmin && len(sessions) > 0 {
+			return nil, "", fmt.Errorf("admin keys cannot be restricted to sessions")
+		}
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, "", fmt.Errorf("expiresAt must be in the future")
+	}
+
+	id, err := randomHex(8)
+	if err != nil {
+		return nil, "", err
+	}
+	random, err := randomHex(24)
+	if err != nil {
+		return nil, "", err
+	}
+	secret := secretPrefix + random
+
+	key := &Key{
+		ID:        id,
+		Name:      name,
+		Prefix:    secret[:len(secretPrefix)+6],
+		Hash:      HashSecret(secret),
+		Scopes:    scopes,
+		Sessions:  sessions,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}
+	if err := s.repo.Create(ctx, key); err != nil {
+		return nil, "", fmt.Errorf("failed to store API key: %w", err)
+	}
+
+	log.Printf("🔑 [APIKEY] Minted key %s (%s) with scopes %v", key.ID, key.Name, key.Scopes)
+	return key, secret, nil
+}
+
+// Authenticate resolves a presented secret to its key
+func (s *Service) Authenticate(ctx context.Context, secret string) (*Key, error) {
+	if secret == "" {
+		return nil, ErrInvalidKey
+	}
+
+	if s.legacyKey != "" && subtle.ConstantTimeCompare([]byte(secret), []byte(s.legacyKey)) == 1 {
+		return &Key{ID: LegacyKeyID, Name: "API_KEY", Scopes: []Scope{ScopeAdmin}}, nil
+	}
+
+	key, err := s.repo.GetByHash(ctx, HashSecret(secret))
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, ErrInvalidKey
+	}
+
+	now := time.Now()
+	if !key.Active(now) {
+		return nil, ErrExpiredKey
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > touchInterval {
+		if err := s.repo.Touch(ctx, key.ID, now); err != nil {
+			log.Printf("⚠️ [APIKEY] Failed to update last use of key %s: %v", key.ID, err)
+		}
+	}
+	return key, nil
+}
+
+// List returns all keys, including revoked ones
+func (s *Service) List(ctx context.Context) ([]Key, error) {
+	return s.repo.List(ctx)
+}
+
+// Revoke disables a key immediately
+func (s *Service) Revoke(ctx context.Context, id string) (bool, error) {
+	ok, err := s.repo.Revoke(ctx, id, time.Now())
+	if err == nil && ok {
+		log.Printf("🔒 [APIKEY] Revoked key %s", id)
+	}
+	return ok, err
+}
+
+// Audit records an authenticated request
+func (s *Service) Audit(ctx context.Context, entry *AuditEntry) {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	if err := s.repo.AddAudit(ctx, entry); err != nil {
+		log.Printf("⚠️ [APIKEY] Failed to write audit entry: %v", err)
+	}
+}
+
+// AuditLog returns the most recent audit entries, optionally for a single key
+func (s *Service) AuditLog(ctx context.Context, keyID string, limit int) ([]AuditEntry, error) {
+	return s.repo.ListAudit(ctx, keyID, limit)
+}
+
+// StartAuditCleanup deletes audit entries older than retentionDays now and then daily, until ctx is done.
+// A retentionDays of 0 or less keeps the audit log forever.
+func (s *Service) StartAuditCleanup(ctx context.Context, retentionDays int) {
+	if retentionDays <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(auditCleanupInterval)
+		defer ticker.Stop()
+		for {
+			s.pruneAudit(ctx, retentionDays)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (s *Service) pruneAudit(ctx context.Context, retentionDays int) {
+	deleted, err := s.repo.PruneAudit(ctx, time.Now().AddDate(0, 0, -retentionDays))
+	if err != nil {
+		log.Printf("⚠️ [APIKEY] Failed to prune audit log: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("🗑️ [APIKEY] Pruned %d old audit entries", deleted)
+	}
+}
+
+// HashSecret returns the stored hash of a key secret.
+// Secrets are 192-bit random values, so a fast hash is sufficient.
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,88 @@
+package apikey
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tokenPrefix marks short-lived tokens
+const tokenPrefix = "wat_"
+
+// DefaultTokenTTL is how long a token issued for a WebSocket connection stays valid
+const DefaultTokenTTL = time.Minute
+
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// IssueToken signs a short-lived token standing in for key, so a browser can open a
+// WebSocket without putting a long-lived secret in the URL. Tokens are not stored;
+// they expire after ttl and are invalidated by a restart or by revoking the key.
+func (s *Service) IssueToken(key *Key, ttl time.Duration) (string, time.Time, error) {
+	if key == nil || key.ID == "" {
+		return "", time.Time{}, ErrInvalidKey
+	}
+	if ttl <= 0 {
+		ttl = DefaultTokenTTL
+	}
+
+	expiresAt := time.Now().Add(ttl).Truncate(time.Second)
+	payload := key.ID + "." + strconv.FormatInt(expiresAt.Unix(), 10)
+	token := tokenPrefix + base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		base64.RawURLEncoding.EncodeToString(s.signToken(payload))
+	return token, expiresAt, nil
+}
+
+// VerifyToken resolves a token issued by IssueToken to its key
+func (s *Service) VerifyToken(ctx context.Context, token string) (*Key, error) {
+	encoded, signature, ok := strings.Cut(strings.TrimPrefix(token, tokenPrefix), ".")
+	if !ok || !strings.HasPrefix(token, tokenPrefix) {
+		return nil, ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, s.signToken(string(payload))) {
+		return nil, ErrInvalidToken
+	}
+
+	id, expiry, ok := strings.Cut(string(payload), ".")
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	unix, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || !time.Now().Before(time.Unix(unix, 0)) {
+		return nil, ErrInvalidToken
+	}
+
+	if id == LegacyKeyID {
+		if s.legacyKey == "" {
+			return nil, ErrExpiredKey
+		}
+		return &Key{ID: LegacyKeyID, Name: "API_KEY", Scopes: []Scope{ScopeAdmin}}, nil
+	}
+
+	key, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, ErrInvalidToken
+	}
+	if !key.Active(time.Now()) {
+		return nil, ErrExpiredKey
+	}
+	return key, nil
+}
+
+func (s *Service) signToken(payload string) []byte {
+	mac := hmac.New(sha256.New, s.tokenSecret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/joho/godotenv"
@@ -18,8 +19,46 @@ type Config struct {
 	LeadsClientID string
 
 	// Security
-	APIKey         string
-	AllowedDomains []string
+	APIKey             string
+	AllowedDomains     []string
+	AuditRetentionDays int // days of API key audit log kept; 0 keeps it forever
+
+	// WebSocket
+	WSReplaySize int // recent events kept for clients resuming after a reconnect
+
+	// Storage
+	StorageBackend string // firestore or sqlite
+	SQLitePath     string
+
+	// Outbox
+	OutboxWorkers         int
+	OutboxRatePerMinute   int
+	OutboxChatIntervalSec int
+
+	// Webhooks
+	WebhookURLs        []string
+	WebhookSecret      string
+	WebhookEvents      []string // empty means all events
+	WebhookMaxAttempts int
+
+	// Scheduled messages
+	ScheduleTimezone string // default timezone of schedules
+
+	// Invoice reminders
+	InvoiceReminderHour int   // local hour reminders are sent
+	InvoiceOverdueDays  []int // days after the due date an overdue notice is sent
+
+	// Backup archive
+	BackupDir           string // local directory backups are kept in; empty (default) disables the archive
+	BackupEncryptionKey string // 32-byte AES key, hex or base64
+	BackupKeepDaily     int
+	BackupKeepWeekly    int
+
+	// Monitor
+	MonitorTargets         string   // JSON array of targets; default is WEB_URL/api/health
+	MonitorAlertRecipients []string // phone numbers or group JIDs
+	MonitorIntervalSeconds int
+	MonitorHistoryDays     int
 
 	// Firestore
 	FirebaseProjectID string
@@ -32,10 +71,20 @@ type Config struct {
 
 	// Blog Automator
 	GroqAPIKey                 string
+	GroqBaseURL                string // OpenAI-compatible chat completion API
+	GroqModel                  string
 	PexelsAPIKey               string
+	PexelsBaseURL              string
 	ContentfulManagementToken  string
 	ContentfulSpaceID          string
 	ContentfulEnvironment      string
+	ContentfulBaseURL          string
+	ContentfulContentType      string
+	ContentfulLocale           string
+	BlogCron                   string // schedule of queued topics; empty disables it
+	BlogDraftOnly              bool
+	BlogPostURL                string // link of a published post, {slug} is replaced
+	BlogAdminPhone             string
 }
 
 // Load reads configuration from environment variables
@@ -54,8 +103,46 @@ func Load() *Config {
 		LeadsClientID: getEnv("WA_LEADS_CLIENT_ID", "leads"),
 
 		// Security
-		APIKey:         getEnv("API_KEY", ""),
-		AllowedDomains: parseAllowedDomains(getEnv("ALLOWED_DOMAINS", "http://localhost:3000,https://valprointertech.com,https://valprointertech.vercel.app")),
+		APIKey:             getEnv("API_KEY", ""),
+		AllowedDomains:     parseAllowedDomains(getEnv("ALLOWED_DOMAINS", "http://localhost:3000,https://valprointertech.com,https://valprointertech.vercel.app")),
+		AuditRetentionDays: getEnvInt("AUDIT_RETENTION_DAYS", 90),
+
+		// WebSocket
+		WSReplaySize: getEnvInt("WS_REPLAY_SIZE", 1000),
+
+		// Storage
+		StorageBackend: strings.ToLower(getEnv("STORAGE_BACKEND", "firestore")),
+		SQLitePath:     getEnv("SQLITE_PATH", "storage.db"),
+
+		// Outbox
+		OutboxWorkers:         getEnvInt("OUTBOX_WORKERS", 2),
+		OutboxRatePerMinute:   getEnvInt("OUTBOX_RATE_PER_MINUTE", 20),
+		OutboxChatIntervalSec: getEnvInt("OUTBOX_CHAT_INTERVAL", 5),
+
+		// Webhooks
+		WebhookURLs:        parseList(getEnv("WEBHOOK_URLS", "")),
+		WebhookSecret:      getEnv("WEBHOOK_SECRET", ""),
+		WebhookEvents:      parseList(getEnv("WEBHOOK_EVENTS", "")),
+		WebhookMaxAttempts: getEnvInt("WEBHOOK_MAX_ATTEMPTS", 6),
+
+		// Scheduled messages
+		ScheduleTimezone: getEnv("SCHEDULE_TIMEZONE", "Asia/Jakarta"),
+
+		// Invoice reminders
+		InvoiceReminderHour: getEnvInt("INVOICE_REMINDER_HOUR", 9),
+		InvoiceOverdueDays:  parseIntList("INVOICE_OVERDUE_DAYS", []int{1, 3, 7, 14, 30}),
+
+		// Backup archive
+		BackupDir:           getEnv("BACKUP_DIR", ""),
+		BackupEncryptionKey: getEnv("BACKUP_ENCRYPTION_KEY", ""),
+		BackupKeepDaily:     getEnvInt("BACKUP_KEEP_DAILY", 7),
+		BackupKeepWeekly:    getEnvInt("BACKUP_KEEP_WEEKLY", 4),
+
+		// Monitor
+		MonitorTargets:         getEnv("MONITOR_TARGETS", ""),
+		MonitorAlertRecipients: parseList(getEnv("MONITOR_ALERT_RECIPIENTS", "")),
+		MonitorIntervalSeconds: getEnvInt("MONITOR_INTERVAL", 60),
+		MonitorHistoryDays:     getEnvInt("MONITOR_HISTORY_DAYS", 7),
 
 		// Firestore
 		FirebaseProjectID: getEnv("FIREBASE_PROJECT_ID", ""),
@@ -68,10 +155,20 @@ func Load() *Config {
 
 		// Blog Automator
 		GroqAPIKey:                 getEnv("GROQ_API_KEY", ""),
+		GroqBaseURL:                getEnv("GROQ_BASE_URL", "https://api.groq.com/openai/v1"),
+		GroqModel:                  getEnv("GROQ_MODEL", "llama-3.3-70b-versatile"),
 		PexelsAPIKey:               getEnv("PEXELS_API_KEY", ""),
+		PexelsBaseURL:              getEnv("PEXELS_BASE_URL", "https://api.pexels.com/v1"),
 		ContentfulManagementToken:  getEnv("CONTENTFUL_MANAGEMENT_TOKEN", ""),
 		ContentfulSpaceID:          getEnv("CONTENTFUL_SPACE_ID", ""),
 		ContentfulEnvironment:      getEnv("CONTENTFUL_ENVIRONMENT", "master"),
+		ContentfulBaseURL:          getEnv("CONTENTFUL_BASE_URL", "https://api.contentful.com"),
+		ContentfulContentType:      getEnv("CONTENTFUL_CONTENT_TYPE", "blogPost"),
+		ContentfulLocale:           getEnv("CONTENTFUL_LOCALE", "en-US"),
+		BlogCron:                   getEnv("BLOG_CRON", ""),
+		BlogDraftOnly:              getEnvBool("BLOG_DRAFT_ONLY", false),
+		BlogPostURL:                getEnv("BLOG_POST_URL", ""),
+		BlogAdminPhone:             getEnv("BLOG_ADMIN_PHONE", ""),
 	}
 
 	return cfg
@@ -84,14 +181,60 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		n, err := strconv.Atoi(value)
+		if err == nil {
+			return n
+		}
+		log.Printf("Invalid %s=%q, using %d", key, value, defaultValue)
+	}
+	return defaultValue
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		b, err := strconv.ParseBool(value)
+		if err == nil {
+			return b
+		}
+		log.Printf("Invalid %s=%q, using %t", key, value, defaultValue)
+	}
+	return defaultValue
+}
+
 func parseAllowedDomains(domainsStr string) []string {
-	domains := strings.Split(domainsStr, ",")
-	result := make([]string, 0, len(domains))
-	for _, d := range domains {
-		trimmed := strings.TrimSpace(d)
+	return parseList(domainsStr)
+}
+
+// parseList splits a comma-separated value, dropping empty entries
+func parseList(value string) []string {
+	items := strings.Split(value, ",")
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		trimmed := strings.TrimSpace(item)
 		if trimmed != "" {
 			result = append(result, trimmed)
 		}
 	}
 	return result
 }
+
+// parseIntList reads a comma-separated list of positive integers in ascending order
+func parseIntList(key string, defaultValue []int) []int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var result []int
+	for _, item := range parseList(value) {
+		n, err := strconv.Atoi(item)
+		if err != nil || n <= 0 || (len(result) > 0 && n <= result[len(result)-1]) {
+			log.Printf("Invalid %s=%q, using %v", key, value, defaultValue)
+			return defaultValue
+		}
+		result = append(result, n)
+	}
+	return result
+}
@@ -0,0 +1,318 @@
+package contacts
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"wa-server-go/internal/whatsapp"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// ErrNoStorage is returned when changing a contact of a directory without a repository
+var ErrNoStorage = errors.New("contact storage is not configured")
+
+// Record is what we keep about a contact ourselves, keyed by the contact's ID
+type Record struct {
+	JID           string
+	CustomName    string
+	Notes         string
+	ProfilePicURL string
+	UpdatedAt     time.Time
+}
+
+// Repository persists contact records
+type Repository interface {
+	// Get returns the record of a contact, or nil if there is none
+	Get(ctx context.Context, jid string) (*Record, error)
+	List(ctx context.Context) ([]Record, error)
+	Save(ctx context.Context, record *Record) error
+	Delete(ctx context.Context, jid string) error
+}
+
+// Contact is the merged view of a contact: the session's contact store, the LID it is known by
+// and our own custom name, notes and profile picture
+type Contact struct {
+	ID            string     `json:"id"`              // phone number JID, or the LID while the number is unknown
+	Phone         string     `json:"phone,omitempty"` // phone number without the server
+	LID           string     `json:"lid,omitempty"`
+	Name          string     `json:"name"` // custom name, else the address book, push or business name
+	CustomName    string     `json:"customName,omitempty"`
+	FullName      string     `json:"fullName,omitempty"`
+	FirstName     string     `json:"firstName,omitempty"`
+	PushName      string     `json:"pushName,omitempty"`
+	BusinessName  string     `json:"businessName,omitempty"`
+	Notes         string     `json:"notes,omitempty"`
+	ProfilePicURL string     `json:"profilePicUrl,omitempty"`
+	InAddressBook bool       `json:"inAddressBook"`
+	UpdatedAt     *time.Time `json:"updatedAt,omitempty"` // last change to the custom name or notes
+}
+
+// Update changes our own data of a contact; nil fields are left as they are
+type Update struct {
+	CustomName *string
+	Notes      *string
+}
+
+// Directory is the single place contacts are looked up: chat listing and contact sync read names
+// and phone numbers from it
+type Directory struct {
+	repo Repository
+	lids *whatsapp.LIDResolver
+}
+
+// NewDirectory creates a contact directory. Without a repository, contacts only have the session's store data.
+func NewDirectory(repo Repository, lids *whatsapp.LIDResolver) *Directory {
+	if lids == nil {
+		lids = whatsapp.NewLIDResolver(nil)
+	}
+	return &Directory{repo: repo, lids: lids}
+}
+
+// Resolve returns the phone number JID and the LID of jid, either of which may be empty when unknown
+func (d *Directory) Resolve(ctx context.Context, client *whatsapp.Client, jid types.JID) (phone, lid types.JID) {
+	jid = jid.ToNonAD()
+	if jid.Server != types.HiddenUserServer {
+		return jid, d.lids.LID(ctx, client, jid)
+	}
+	phone, _ = d.lids.Phone(ctx, client, jid)
+	return phone, jid
+}
+
+// Get returns a contact by phone number JID or LID
+func (d *Directory) Get(ctx context.Context, client *whatsapp.Client, jid types.JID) (*Contact, error) {
+	phone, lid := d.Resolve(ctx, client, jid)
+	contact := newContact(phone, lid)
+	for _, id := range []types.JID{phone, lid} {
+		if id.IsEmpty() || client == nil {
+			continue
+		}
+		info, err := client.WAClient.Store.Contacts.GetContact(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		contact.merge(info)
+	}
+
+	record, err := d.record(ctx, phone, lid)
+	if err != nil {
+		return nil, err
+	}
+	contact.apply(record)
+	return contact, nil
+}
+
+// List returns every contact of the session's store and every contact we keep a record of, by name.
+// A non-empty query keeps the contacts whose name or phone number contains it.
+func (d *Directory) List(ctx context.Context, client *whatsapp.Client, query string) ([]Contact, error) {
+	byID := make(map[string]*Contact)
+	if client != nil {
+		infos, err := client.WAClient.Store.Contacts.GetAllContacts(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for jid, info := range infos {
+			phone, lid := d.Resolve(ctx, client, jid)
+			contact := newContact(phone, lid)
+			if existing, ok := byID[contact.ID]; ok {
+				// The same person stored under both their number and their LID
+				if existing.LID == "" {
+					existing.LID = contact.LID
+				}
+				contact = existing
+			}
+			contact.merge(info)
+			byID[contact.ID] = contact
+		}
+	}
+
+	if d.repo != nil {
+		records, err := d.repo.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for i := range records {
+			jid, err := types.ParseJID(records[i].JID)
+			if err != nil {
+				continue
+			}
+			contact := newContact(d.Resolve(ctx, client, jid))
+			if existing, ok := byID[contact.ID]; ok {
+				contact = existing
+			}
+			contact.apply(&records[i])
+			byID[contact.ID] = contact
+		}
+	}
+
+	query = strings.ToLower(strings.TrimSpace(query))
+	contacts := make([]Contact, 0, len(byID))
+	for _, contact := range byID {
+		if query != "" && !strings.Contains(strings.ToLower(contact.Name), query) && !strings.Contains(contact.Phone, query) {
+			continue
+		}
+		contacts = append(contacts, *contact)
+	}
+	sort.Slice(contacts, func(i, j int) bool {
+		a, b := strings.ToLower(contacts[i].Name), strings.ToLower(contacts[j].Name)
+		if a != b {
+			// Unnamed contacts last
+			return b == "" || (a != "" && a < b)
+		}
+		return contacts[i].ID < contacts[j].ID
+	})
+	return contacts, nil
+}
+
+// Update saves our own name and notes of a contact and returns the merged contact
+func (d *Directory) Update(ctx context.Context, client *whatsapp.Client, jid types.JID, update Update) (*Contact, error) {
+	record, err := d.editRecord(ctx, client, jid)
+	if err != nil {
+		return nil, err
+	}
+	if update.CustomName != nil {
+		record.CustomName = strings.TrimSpace(*update.CustomName)
+	}
+	if update.Notes != nil {
+		record.Notes = *update.Notes
+	}
+	record.UpdatedAt = time.Now()
+	if err := d.repo.Save(ctx, record); err != nil {
+		return nil, err
+	}
+	return d.Get(ctx, client, jid)
+}
+
+// RefreshPicture fetches the contact's profile picture from WhatsApp and keeps its URL.
+// A contact without a visible picture keeps an empty URL.
+func (d *Directory) RefreshPicture(ctx context.Context, client *whatsapp.Client, contact *Contact) error {
+	jid, err := types.ParseJID(contact.ID)
+	if err != nil {
+		return err
+	}
+	pic, err := client.WAClient.GetProfilePictureInfo(ctx, jid, &whatsmeow.GetProfilePictureParams{Preview: true})
+	if errors.Is(err, whatsmeow.ErrProfilePictureNotSet) || errors.Is(err, whatsmeow.ErrProfilePictureUnauthorized) {
+		pic, err = nil, nil
+	}
+	if err != nil {
+		return err
+	}
+	url := ""
+	if pic != nil {
+		url = pic.URL
+	}
+	if url == contact.ProfilePicURL {
+		return nil
+	}
+	contact.ProfilePicURL = url
+
+	if d.repo == nil {
+		return nil
+	}
+	record, err := d.editRecord(ctx, client, jid)
+	if err != nil {
+		return err
+	}
+	record.ProfilePicURL = url
+	return d.repo.Save(ctx, record)
+}
+
+// record returns the record of a contact, kept under its phone number or, before the number was known, its LID
+func (d *Directory) record(ctx context.Context, phone, lid types.JID) (*Record, error) {
+	if d.repo == nil {
+		return nil, nil
+	}
+	for _, id := range []types.JID{phone, lid} {
+		if id.IsEmpty() {
+			continue
+		}
+		record, err := d.repo.Get(ctx, id.String())
+		if err != nil || record != nil {
+			return record, err
+		}
+	}
+	return nil, nil
+}
+
+// editRecord returns the record of a contact to change, moved from its LID to its phone number once that is known
+func (d *Directory) editRecord(ctx context.Context, client *whatsapp.Client, jid types.JID) (*Record, error) {
+	if d.repo == nil {
+		return nil, ErrNoStorage
+	}
+	phone, lid := d.Resolve(ctx, client, jid)
+	id := newContact(phone, lid).ID
+	record, err := d.record(ctx, phone, lid)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return &Record{JID: id}, nil
+	}
+	if record.JID != id {
+		if err := d.repo.Delete(ctx, record.JID); err != nil {
+			return nil, err
+		}
+		record.JID = id
+	}
+	return record, nil
+}
+
+func newContact(phone, lid types.JID) *Contact {
+	contact := &Contact{}
+	if !phone.IsEmpty() {
+		contact.ID = phone.String()
+		contact.Phone = phone.User
+	}
+	if !lid.IsEmpty() {
+		contact.LID = lid.String()
+		if contact.ID == "" {
+			contact.ID = contact.LID
+		}
+	}
+	return contact
+}
+
+// merge fills the names the contact does not have yet from a store entry
+func (c *Contact) merge(info types.ContactInfo) {
+	if !info.Found {
+		return
+	}
+	fill := func(field *string, value string) {
+		if *field == "" {
+			*field = value
+		}
+	}
+	fill(&c.FullName, info.FullName)
+	fill(&c.FirstName, info.FirstName)
+	fill(&c.PushName, info.PushName)
+	fill(&c.BusinessName, info.BusinessName)
+	c.InAddressBook = c.FullName != ""
+	c.Name = c.displayName()
+}
+
+// apply overlays our own record on the contact
+func (c *Contact) apply(record *Record) {
+	if record != nil {
+		c.CustomName = record.CustomName
+		c.Notes = record.Notes
+		c.ProfilePicURL = record.ProfilePicURL
+		if !record.UpdatedAt.IsZero() {
+			updatedAt := record.UpdatedAt
+			c.UpdatedAt = &updatedAt
+		}
+	}
+	c.Name = c.displayName()
+}
+
+func (c *Contact) displayName() string {
+	for _, name := range []string{c.CustomName, c.FullName, c.PushName, c.BusinessName} {
+		if name != "" {
+			return name
+		}
+	}
+	return ""
+}
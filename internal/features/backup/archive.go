@@ -0,0 +1,188 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// encryptedMagic prefixes files encrypted with AES-256-GCM; it is followed by the nonce and ciphertext
+var encryptedMagic = []byte("WABK1\n")
+
+// Store keeps backup files; keys look like "<runID>/<fileName>".
+// DirStore keeps them on local disk; an S3-compatible store can implement the same interface.
+type Store interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete removes every file stored under prefix
+	Delete(ctx context.Context, prefix string) error
+}
+
+// DirStore stores backup files in a local directory
+type DirStore struct {
+	Dir string
+}
+
+// NewDirStore creates the directory if needed
+func NewDirStore(dir string) (*DirStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	return &DirStore{Dir: dir}, nil
+}
+
+// Put writes a file atomically
+func (d *DirStore) Put(ctx context.Context, key string, data []byte) error {
+	path, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Get reads a file
+func (d *DirStore) Get(ctx context.Context, key string) ([]byte, error) {
+	path, err := d.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+// Delete removes a file or directory
+func (d *DirStore) Delete(ctx context.Context, prefix string) error {
+	path, err := d.path(prefix)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path resolves a key inside the directory, rejecting keys that escape it
+func (d *DirStore) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if clean == "/" || strings.Contains(key, "..") {
+		return "", fmt.Errorf("invalid backup key %q", key)
+	}
+	return filepath.Join(d.Dir, clean), nil
+}
+
+// Retention controls how many archived backups are kept.
+// The newest backup of each of the last KeepDaily days and KeepWeekly ISO weeks is kept.
+type Retention struct {
+	KeepDaily  int
+	KeepWeekly int
+}
+
+// Archive stores a copy of every backup, optionally encrypted
+type Archive struct {
+	Store     Store
+	Key       []byte // AES-256 key; nil stores files unencrypted
+	Retention Retention
+}
+
+// ParseKey decodes a 32-byte AES key given as hex or base64
+func ParseKey(value string) ([]byte, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if key, err := hex.DecodeString(value); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(value); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	return nil, errors.New("backup encryption key must be 32 bytes, hex or base64 encoded")
+}
+
+// fileKey returns the store key of a run's file
+func fileKey(run *Run, name string) string {
+	return run.ID + "/" + name
+}
+
+// put encrypts (if configured) and stores a file, returning its stored name
+func (a *Archive) put(ctx context.Context, run *Run, name string, data []byte) (string, error) {
+	if a.Key != nil {
+		encrypted, err := encrypt(a.Key, data)
+		if err != nil {
+			return "", err
+		}
+		data = encrypted
+		name += ".enc"
+	}
+	return name, a.Store.Put(ctx, fileKey(run, name), data)
+}
+
+// get loads and decrypts a stored file
+func (a *Archive) get(ctx context.Context, run *Run, storedName string) ([]byte, error) {
+	data, err := a.Store.Get(ctx, fileKey(run, storedName))
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(data, encryptedMagic) {
+		return data, nil
+	}
+	if a.Key == nil {
+		return nil, errors.New("backup is encrypted but no encryption key is configured")
+	}
+	return decrypt(a.Key, data)
+}
+
+func encrypt(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := append([]byte{}, encryptedMagic...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, plaintext, encryptedMagic), nil
+}
+
+func decrypt(key, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	data = data[len(encryptedMagic):]
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("encrypted backup is truncated")
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, encryptedMagic)
+	if err != nil {
+		return nil, errors.New("failed to decrypt backup (wrong key?)")
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
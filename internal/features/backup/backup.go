@@ -1,45 +1,80 @@
 package backup
 
 import (
-	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
+	"wa-server-go/internal/templates"
+	"wa-server-go/internal/whatsapp"
+
 	"github.com/robfig/cron/v3"
-	"github.com/xuri/excelize/v2"
 	"go.mau.fi/whatsmeow"
 	waProto "go.mau.fi/whatsmeow/proto/waE2E"
 	"go.mau.fi/whatsmeow/types"
 	"google.golang.org/protobuf/proto"
 )
 
+// ErrRunning is returned when a backup is triggered while another one is running
+var ErrRunning = errors.New("a backup is already running")
+
+// wib is the timezone backups are scheduled and exported in
+var wib = loadWIB()
+
+func loadWIB() *time.Location {
+	loc, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		return time.FixedZone("WIB", 7*60*60)
+	}
+	return loc
+}
+
 // BackupService handles scheduled backup tasks
 type BackupService struct {
-	waClient    *whatsmeow.Client
+	waManager   *whatsapp.Manager
+	session     string // session used by scheduled backups
 	webURL      string
 	backupPhone string
+	repo        Repository
+	archive     *Archive
 	cron        *cron.Cron
+	onUpdate    func(Run)
+
+	mu      sync.Mutex
+	running bool
 }
 
-// NewBackupService creates a new backup service
-func NewBackupService(waClient *whatsmeow.Client, webURL, backupPhone string) *BackupService {
+// NewBackupService creates a new backup service sending through the given session
+func NewBackupService(waManager *whatsapp.Manager, session, webURL, backupPhone string, repo Repository) *BackupService {
 	return &BackupService{
-		waClient:    waClient,
+		waManager:   waManager,
+		session:     session,
 		webURL:      webURL,
 		backupPhone: backupPhone,
-		cron:        cron.New(),
+		repo:        repo,
+		cron:        cron.New(cron.WithLocation(wib)),
 	}
 }
 
+// OnUpdate sets a callback invoked whenever a run changes stage or finishes
+func (s *BackupService) OnUpdate(fn func(Run)) {
+	s.onUpdate = fn
+}
+
 // Start starts the backup cron job (runs daily at 23:00 WIB)
 func (s *BackupService) Start() error {
 	_, err := s.cron.AddFunc("0 23 * * *", func() {
 		log.Println("🔄 [BACKUP] Starting scheduled backup...")
-		if err := s.RunBackup(); err != nil {
+		if _, err := s.Trigger(context.Background(), TriggerScheduled, s.session); err != nil {
 			log.Printf("❌ [BACKUP] Failed: %v", err)
 		}
 	})
@@ -57,29 +92,111 @@ func (s *BackupService) Stop() {
 	s.cron.Stop()
 }
 
-// RunBackup executes the backup process
-func (s *BackupService) RunBackup() error {
-	ctx := context.Background()
-	timestamp := time.Now()
-	dateStr := timestamp.Format("20060102")
+// Trigger records a new run and executes it in the background.
+// An empty session uses the default one. Only one backup runs at a time.
+func (s *BackupService) Trigger(ctx context.Context, trigger, session string) (*Run, error) {
+	if session == "" {
+		session = s.session
+	}
 
-	// 1. Fetch data from web API
-	dataURL := fmt.Sprintf("%s/api/backup/data-dump", s.webURL)
-	log.Printf("📥 [BACKUP] Fetching data from %s", dataURL)
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return nil, ErrRunning
+	}
+	s.running = true
+	s.mu.Unlock()
 
-	resp, err := http.Get(dataURL)
+	id, err := newRunID()
 	if err != nil {
-		return fmt.Errorf("failed to fetch backup data: %w", err)
+		s.finish()
+		return nil, err
 	}
-	defer resp.Body.Close()
+	run := &Run{
+		ID:        id,
+		Trigger:   trigger,
+		Session:   session,
+		Status:    RunRunning,
+		Stage:     "fetching",
+		Files:     []File{},
+		StartedAt: time.Now(),
+	}
+	if err := s.repo.Save(ctx, run); err != nil {
+		s.finish()
+		return nil, fmt.Errorf("failed to record backup run: %w", err)
+	}
+	s.publish(run)
+
+	snapshot := *run
+	go func() {
+		defer s.finish()
+		s.execute(context.Background(), run)
+	}()
+	return &snapshot, nil
+}
+
+// Get returns a run by ID, or nil if it does not exist
+func (s *BackupService) Get(ctx context.Context, id string) (*Run, error) {
+	return s.repo.Get(ctx, id)
+}
+
+// List returns the most recent runs first
+func (s *BackupService) List(ctx context.Context, limit int) ([]Run, error) {
+	return s.repo.List(ctx, limit)
+}
+
+func (s *BackupService) finish() {
+	s.mu.Lock()
+	s.running = false
+	s.mu.Unlock()
+}
 
-	var backupData map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&backupData); err != nil {
-		return fmt.Errorf("failed to parse backup data: %w", err)
+// execute runs the backup, records its outcome and notifies the backup phone
+func (s *BackupService) execute(ctx context.Context, run *Run) {
+	err := s.RunBackup(ctx, run)
+
+	now := time.Now()
+	run.FinishedAt = &now
+	run.Stage = "done"
+	if err != nil {
+		run.Status = RunFailed
+		run.Error = err.Error()
+		log.Printf("❌ [BACKUP] Run %s failed: %v", run.ID, err)
+	} else {
+		run.Status = RunSuccess
+		log.Printf("✅ [BACKUP] Completed successfully at %s", now.Format("15:04:05"))
+	}
+	s.update(run)
+	if err == nil {
+		s.applyRetention(ctx)
+	}
+
+	names := make([]string, 0, len(run.Files))
+	for _, file := range run.Files {
+		names = append(names, file.Name)
+	}
+	notification := templates.GenerateBackupNotification(err == nil, strings.Join(names, ", "), run.StartedAt)
+	if sendErr := s.sendText(ctx, run.Session, notification); sendErr != nil {
+		log.Printf("⚠️ [BACKUP] Failed to send notification: %v", sendErr)
+	}
+}
+
+// RunBackup executes the backup process, recording progress on run
+func (s *BackupService) RunBackup(ctx context.Context, run *Run) error {
+	dateStr := run.StartedAt.In(wib).Format("20060102")
+
+	// 1. Fetch data from web API
+	log.Printf("📥 [BACKUP] Fetching data from %s/api/backup/data-dump", s.webURL)
+	backupData, err := s.fetchData(ctx)
+	if err != nil {
+		return err
 	}
 
 	// 2. Generate Excel file
-	excelData, err := s.generateExcel(backupData, dateStr)
+	run.Stage = "generating"
+	s.update(run)
+
+	excelData, err := s.generateExcel(backupData)
 	if err != nil {
 		return fmt.Errorf("failed to generate Excel: %w", err)
 	}
@@ -90,94 +207,213 @@ func (s *BackupService) RunBackup() error {
 		return fmt.Errorf("failed to generate JSON: %w", err)
 	}
 
-	// 4. Send Excel via WhatsApp
 	excelFileName := fmt.Sprintf("BACKUP_DATA_%s.xlsx", dateStr)
-	if err := s.sendFile(ctx, excelData, excelFileName, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"); err != nil {
-		log.Printf("⚠️ [BACKUP] Failed to send Excel: %v", err)
-	} else {
-		log.Println("✅ [BACKUP] Excel file sent")
+	jsonFileName := fmt.Sprintf("BACKUP_RESTORE_%s.json", dateStr)
+	run.Files = []File{
+		{Name: excelFileName, Size: len(excelData)},
+		{Name: jsonFileName, Size: len(jsonData)},
 	}
 
-	// 5. Send JSON via WhatsApp
-	jsonFileName := fmt.Sprintf("BACKUP_RESTORE_%s.json", dateStr)
-	if err := s.sendFile(ctx, jsonData, jsonFileName, "application/json"); err != nil {
-		log.Printf("⚠️ [BACKUP] Failed to send JSON: %v", err)
-	} else {
-		log.Println("✅ [BACKUP] JSON file sent")
+	contents := [][]byte{excelData, jsonData}
+	var errs []string
+
+	// 4. Keep a copy in the archive, even if sending fails
+	if s.archive != nil {
+		run.Stage = "storing"
+		s.update(run)
+
+		for i := range run.Files {
+			file := &run.Files[i]
+			stored, err := s.archive.put(ctx, run, file.Name, contents[i])
+			if err != nil {
+				log.Printf("⚠️ [BACKUP] Failed to store %s: %v", file.Name, err)
+				errs = append(errs, fmt.Sprintf("store %s: %v", file.Name, err))
+				continue
+			}
+			file.Stored = stored
+		}
 	}
 
-	// 6. Send completion notification
-	notification := fmt.Sprintf("✅ *BACKUP BERHASIL*\n\n📁 Files: %s, %s\n🕐 Waktu: %s\n\nBackup data harian telah berhasil dikirim.",
-		excelFileName, jsonFileName, timestamp.Format("02 Jan 2006 15:04 WIB"))
-	jid := types.NewJID(s.backupPhone, types.DefaultUserServer)
-	_, _ = s.waClient.SendMessage(ctx, jid, &waProto.Message{
-		Conversation: proto.String(notification),
-	})
+	// 5. Send Excel and JSON via WhatsApp
+	run.Stage = "sending"
+	s.update(run)
 
-	log.Printf("✅ [BACKUP] Completed successfully at %s", timestamp.Format("15:04:05"))
+	mimeTypes := []string{"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "application/json"}
+	for i := range run.Files {
+		file := &run.Files[i]
+		if err := s.sendFile(ctx, run.Session, contents[i], file.Name, mimeTypes[i]); err != nil {
+			log.Printf("⚠️ [BACKUP] Failed to send %s: %v", file.Name, err)
+			errs = append(errs, fmt.Sprintf("send %s: %v", file.Name, err))
+			continue
+		}
+		file.Sent = true
+		log.Printf("✅ [BACKUP] %s sent", file.Name)
+		s.update(run)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("backup incomplete: %s", strings.Join(errs, "; "))
+	}
 	return nil
 }
 
-// generateExcel creates an Excel file from backup data
-func (s *BackupService) generateExcel(data map[string]interface{}, dateStr string) ([]byte, error) {
-	f := excelize.NewFile()
-	defer f.Close()
+// SetArchive keeps an (optionally encrypted) copy of every backup and enables restores
+func (s *BackupService) SetArchive(archive *Archive) {
+	s.archive = archive
+}
+
+// applyRetention deletes archived backups outside the retention policy
+func (s *BackupService) applyRetention(ctx context.Context) {
+	if s.archive == nil {
+		return
+	}
+	runs, err := s.repo.List(ctx, 0)
+	if err != nil {
+		log.Printf("⚠️ [BACKUP] Retention skipped: %v", err)
+		return
+	}
+
+	keep := retainedRuns(runs, s.archive.Retention)
+	for i := range runs {
+		run := &runs[i]
+		if keep[run.ID] || !archived(run) {
+			continue
+		}
+		if err := s.archive.Store.Delete(ctx, run.ID); err != nil {
+			log.Printf("⚠️ [BACKUP] Failed to prune run %s: %v", run.ID, err)
+			continue
+		}
+		for j := range run.Files {
+			run.Files[j].Stored = ""
+		}
+		if err := s.repo.Save(ctx, run); err != nil {
+			log.Printf("⚠️ [BACKUP] Failed to record pruned run %s: %v", run.ID, err)
+		}
+		log.Printf("🗑️ [BACKUP] Pruned archived run %s (%s)", run.ID, run.StartedAt.In(wib).Format("2006-01-02"))
+	}
+}
 
-	// Create sheets for each data type
-	for sheetName, sheetData := range data {
-		// Create sheet
-		_, err := f.NewSheet(sheetName)
-		if err != nil {
+// retainedRuns returns the newest successful archived run of each of the last KeepDaily days
+// and KeepWeekly ISO weeks. runs must be newest first.
+func retainedRuns(runs []Run, policy Retention) map[string]bool {
+	keep := make(map[string]bool)
+	days := make(map[string]bool)
+	weeks := make(map[string]bool)
+	for _, run := range runs {
+		if run.Status != RunSuccess || !archived(&run) {
 			continue
 		}
+		t := run.StartedAt.In(wib)
+		day := t.Format("2006-01-02")
+		year, week := t.ISOWeek()
+		weekKey := fmt.Sprintf("%d-W%02d", year, week)
 
-		// Write data based on type
-		if arr, ok := sheetData.([]interface{}); ok {
-			for i, row := range arr {
-				if rowMap, ok := row.(map[string]interface{}); ok {
-					col := 'A'
-					for key, value := range rowMap {
-						if i == 0 {
-							// Write header
-							headerCell := fmt.Sprintf("%c%d", col, 1)
-							f.SetCellValue(sheetName, headerCell, key)
-						}
-						dataCell := fmt.Sprintf("%c%d", col, i+2)
-						f.SetCellValue(sheetName, dataCell, fmt.Sprintf("%v", value))
-						col++
-					}
-				}
-			}
+		if !days[day] && len(days) < policy.KeepDaily {
+			days[day] = true
+			keep[run.ID] = true
+		}
+		if !weeks[weekKey] && len(weeks) < policy.KeepWeekly {
+			weeks[weekKey] = true
+			keep[run.ID] = true
 		}
 	}
+	return keep
+}
 
-	// Delete default sheet
-	f.DeleteSheet("Sheet1")
+func archived(run *Run) bool {
+	for _, file := range run.Files {
+		if file.Stored != "" {
+			return true
+		}
+	}
+	return false
+}
 
-	// Write to buffer
-	var buf bytes.Buffer
-	if err := f.Write(&buf); err != nil {
-		return nil, err
+// fetchData downloads the current data dump from the web app
+func (s *BackupService) fetchData(ctx context.Context) (map[string]interface{}, error) {
+	dataURL := fmt.Sprintf("%s/api/backup/data-dump", s.webURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, dataURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch backup data: %w", err)
+	}
+	// A web app that never answers must not keep the backup running forever
+	resp, err := (&http.Client{Timeout: 5 * time.Minute}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch backup data: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch backup data: HTTP %d", resp.StatusCode)
 	}
 
-	return buf.Bytes(), nil
+	var data map[string]interface{}
+	if err := decodeJSON(resp.Body, &data); err != nil {
+		return nil, fmt.Errorf("failed to parse backup data: %w", err)
+	}
+	return data, nil
+}
+
+// decodeJSON keeps numbers as json.Number so large IDs survive the round trip and integers stay integers
+func decodeJSON(r io.Reader, v interface{}) error {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// update saves the run and publishes it
+func (s *BackupService) update(run *Run) {
+	if err := s.repo.Save(context.Background(), run); err != nil {
+		log.Printf("⚠️ [BACKUP] Failed to record run %s: %v", run.ID, err)
+	}
+	s.publish(run)
+}
+
+func (s *BackupService) publish(run *Run) {
+	if s.onUpdate != nil {
+		snapshot := *run
+		snapshot.Files = append([]File(nil), run.Files...)
+		s.onUpdate(snapshot)
+	}
+}
+
+// waClient returns the connected whatsmeow client of a session
+func (s *BackupService) waClient(session string) (*whatsmeow.Client, error) {
+	client, ok := s.waManager.GetClient(session)
+	if !ok || !client.IsReady() {
+		return nil, fmt.Errorf("WhatsApp session %s is not ready", session)
+	}
+	return client.WAClient, nil
+}
+
+// sendText sends a text message to the backup phone
+func (s *BackupService) sendText(ctx context.Context, session, text string) error {
+	waClient, err := s.waClient(session)
+	if err != nil {
+		return err
+	}
+
+	jid := types.NewJID(s.backupPhone, types.DefaultUserServer)
+	_, err = waClient.SendMessage(ctx, jid, &waProto.Message{
+		Conversation: proto.String(text),
+	})
+	return err
 }
 
 // sendFile uploads and sends a file via WhatsApp
-func (s *BackupService) sendFile(ctx context.Context, data []byte, fileName, mimeType string) error {
-	if s.waClient == nil {
-		return fmt.Errorf("WhatsApp client not available")
+func (s *BackupService) sendFile(ctx context.Context, session string, data []byte, fileName, mimeType string) error {
+	waClient, err := s.waClient(session)
+	if err != nil {
+		return err
 	}
 
 	// Upload file
-	uploaded, err := s.waClient.Upload(ctx, data, whatsmeow.MediaDocument)
+	uploaded, err := waClient.Upload(ctx, data, whatsmeow.MediaDocument)
 	if err != nil {
 		return fmt.Errorf("failed to upload file: %w", err)
 	}
 
 	// Send document
 	jid := types.NewJID(s.backupPhone, types.DefaultUserServer)
-	_, err = s.waClient.SendMessage(ctx, jid, &waProto.Message{
+	_, err = waClient.SendMessage(ctx, jid, &waProto.Message{
 		DocumentMessage: &waProto.DocumentMessage{
 			URL:           proto.String(uploaded.URL),
 			Mimetype:      proto.String(mimeType),
@@ -194,7 +430,11 @@ func (s *BackupService) sendFile(ctx context.Context, data []byte, fileName, mim
 	return err
 }
 
-// TriggerManual runs backup immediately (for API trigger)
-func (s *BackupService) TriggerManual() error {
-	return s.RunBackup()
+// newRunID generates a random backup run ID
+func newRunID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
 }
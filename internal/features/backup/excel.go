@@ -0,0 +1,360 @@
+package backup
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// columnsKey is an optional entry of the data dump declaring the column order of each sheet,
+// e.g. {"_columns": {"invoices": ["number", "client", "amount"]}}. Undeclared columns follow, sorted.
+const columnsKey = "_columns"
+
+const (
+	minColumnWidth = 8
+	maxColumnWidth = 50
+)
+
+// maxSheetTitle is the longest sheet name Excel accepts
+const maxSheetTitle = 31
+
+// defaultSheet is the sheet a new workbook starts with
+const defaultSheet = "Sheet1"
+
+// Date-like strings in the data dump, most specific first
+var dateLayouts = []struct {
+	layout string
+	date   bool // date only, no time of day
+}{
+	{time.RFC3339Nano, false},
+	{"2006-01-02 15:04:05", false},
+	{"2006-01-02T15:04:05", false},
+	{"2006-01-02", true},
+}
+
+// excelStyles holds the style IDs used by the export
+type excelStyles struct {
+	header, date, dateTime int
+}
+
+// generateExcel creates an Excel file from backup data.
+// Sheets are sorted by name and every run produces the same layout for the same data.
+func (s *BackupService) generateExcel(data map[string]interface{}) ([]byte, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	styles, err := newExcelStyles(f)
+	if err != nil {
+		return nil, err
+	}
+	declared := declaredColumns(data[columnsKey])
+
+	sheetNames := make([]string, 0, len(data))
+	for name := range data {
+		if name != columnsKey {
+			sheetNames = append(sheetNames, name)
+		}
+	}
+	sort.Strings(sheetNames)
+
+	titles := make(map[string]bool)
+	for _, sheetName := range sheetNames {
+		rows := sheetRows(data[sheetName])
+		if rows == nil {
+			continue
+		}
+
+		// Create sheet
+		title := sheetTitle(sheetName, titles)
+		if _, err := f.NewSheet(title); err != nil {
+			return nil, fmt.Errorf("sheet %s: %w", sheetName, err)
+		}
+		if err := writeSheet(f, title, rows, sheetColumns(rows, declared[sheetName]), styles); err != nil {
+			return nil, fmt.Errorf("sheet %s: %w", sheetName, err)
+		}
+	}
+
+	// Delete default sheet (unless it is the only one or holds a data set)
+	if len(f.GetSheetList()) > 1 && !titles[strings.ToLower(defaultSheet)] {
+		if err := f.DeleteSheet(defaultSheet); err != nil {
+			return nil, err
+		}
+	}
+
+	// Write to buffer
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// sheetTitle turns a data set name into a sheet name Excel accepts: at most 31 characters, none of []:*?/\
+// and no leading or trailing apostrophe. Names that collide (case-insensitively, like Excel) get a " (2)" suffix.
+// used holds the lowercased names taken so far and is updated.
+func sheetTitle(name string, used map[string]bool) string {
+	title := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	title = strings.Trim(title, "'")
+	if strings.TrimSpace(title) == "" {
+		title = "Sheet"
+	}
+
+	candidate := strings.TrimRight(truncateRunes(title, maxSheetTitle), "'")
+	for n := 2; used[strings.ToLower(candidate)]; n++ {
+		suffix := fmt.Sprintf(" (%d)", n)
+		candidate = truncateRunes(title, maxSheetTitle-len(suffix)) + suffix
+	}
+	used[strings.ToLower(candidate)] = true
+	return candidate
+}
+
+func truncateRunes(value string, limit int) string {
+	if utf8.RuneCountInString(value) <= limit {
+		return value
+	}
+	return string([]rune(value)[:limit])
+}
+
+func newExcelStyles(f *excelize.File) (excelStyles, error) {
+	var styles excelStyles
+	var err error
+	if styles.header, err = f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true},
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"D9E1F2"}},
+	}); err != nil {
+		return styles, err
+	}
+	dateFormat := "yyyy-mm-dd"
+	if styles.date, err = f.NewStyle(&excelize.Style{CustomNumFmt: &dateFormat}); err != nil {
+		return styles, err
+	}
+	dateTimeFormat := "yyyy-mm-dd hh:mm:ss"
+	styles.dateTime, err = f.NewStyle(&excelize.Style{CustomNumFmt: &dateTimeFormat})
+	return styles, err
+}
+
+// declaredColumns reads the optional column order entry of the data dump
+func declaredColumns(value interface{}) map[string][]string {
+	declared := make(map[string][]string)
+	sheets, ok := value.(map[string]interface{})
+	if !ok {
+		return declared
+	}
+	for sheet, columns := range sheets {
+		list, ok := columns.([]interface{})
+		if !ok {
+			continue
+		}
+		for _, column := range list {
+			if name, ok := column.(string); ok {
+				declared[sheet] = append(declared[sheet], name)
+			}
+		}
+	}
+	return declared
+}
+
+// sheetRows converts sheet data to rows: arrays of objects as-is, arrays of scalars as a
+// single "value" column and objects as key/value pairs
+func sheetRows(data interface{}) []map[string]interface{} {
+	switch v := data.(type) {
+	case []interface{}:
+		rows := make([]map[string]interface{}, 0, len(v))
+		for _, item := range v {
+			if row, ok := item.(map[string]interface{}); ok {
+				rows = append(rows, row)
+			} else {
+				rows = append(rows, map[string]interface{}{"value": item})
+			}
+		}
+		return rows
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		rows := make([]map[string]interface{}, 0, len(keys))
+		for _, key := range keys {
+			rows = append(rows, map[string]interface{}{"key": key, "value": v[key]})
+		}
+		return rows
+	}
+	return nil
+}
+
+// sheetColumns returns the declared columns followed by every other key of any row, sorted
+func sheetColumns(rows []map[string]interface{}, declared []string) []string {
+	columns := make([]string, 0, len(declared))
+	seen := make(map[string]bool)
+	for _, column := range declared {
+		if !seen[column] {
+			seen[column] = true
+			columns = append(columns, column)
+		}
+	}
+
+	var extra []string
+	for _, row := range rows {
+		for key := range row {
+			if !seen[key] {
+				seen[key] = true
+				extra = append(extra, key)
+			}
+		}
+	}
+	sort.Strings(extra)
+	return append(columns, extra...)
+}
+
+// writeSheet writes the header and rows with typed cells, then freezes the header,
+// adds an autofilter and sizes the columns
+func writeSheet(f *excelize.File, sheet string, rows []map[string]interface{}, columns []string, styles excelStyles) error {
+	if len(columns) == 0 {
+		return nil
+	}
+	widths := make([]int, len(columns))
+
+	for i, column := range columns {
+		cell, err := excelize.CoordinatesToCellName(i+1, 1)
+		if err != nil {
+			return err
+		}
+		if err := f.SetCellStr(sheet, cell, column); err != nil {
+			return err
+		}
+		widths[i] = utf8.RuneCountInString(column) + 2 // room for the filter button
+	}
+	lastColumn, _ := excelize.ColumnNumberToName(len(columns))
+	if err := f.SetCellStyle(sheet, "A1", lastColumn+"1", styles.header); err != nil {
+		return err
+	}
+
+	for r, row := range rows {
+		for i, column := range columns {
+			value, ok := row[column]
+			if !ok || value == nil {
+				continue
+			}
+			cell, err := excelize.CoordinatesToCellName(i+1, r+2)
+			if err != nil {
+				return err
+			}
+			width, err := setTypedCell(f, sheet, cell, value, styles)
+			if err != nil {
+				return err
+			}
+			if width > widths[i] {
+				widths[i] = width
+			}
+		}
+	}
+
+	if err := f.SetPanes(sheet, &excelize.Panes{
+		Freeze:      true,
+		YSplit:      1,
+		TopLeftCell: "A2",
+		ActivePane:  "bottomLeft",
+	}); err != nil {
+		return err
+	}
+	lastCell, _ := excelize.CoordinatesToCellName(len(columns), len(rows)+1)
+	if err := f.AutoFilter(sheet, "A1:"+lastCell, nil); err != nil {
+		return err
+	}
+
+	for i, width := range widths {
+		name, _ := excelize.ColumnNumberToName(i + 1)
+		width = max(minColumnWidth, min(width+1, maxColumnWidth))
+		if err := f.SetColWidth(sheet, name, name, float64(width)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setTypedCell writes value with its native cell type and returns its display width
+func setTypedCell(f *excelize.File, sheet, cell string, value interface{}, styles excelStyles) (int, error) {
+	switch v := value.(type) {
+	case json.Number:
+		// Integers beyond float64 precision (e.g. IDs) are kept as text
+		if n, err := v.Int64(); err == nil {
+			if n > 1<<53 || n < -(1<<53) {
+				return len(v), f.SetCellStr(sheet, cell, v.String())
+			}
+			return len(v), f.SetCellInt(sheet, cell, n)
+		}
+		if !strings.ContainsAny(v.String(), ".eE") {
+			return len(v), f.SetCellStr(sheet, cell, v.String())
+		}
+		if n, err := v.Float64(); err == nil && !math.IsInf(n, 0) {
+			return len(v), f.SetCellFloat(sheet, cell, n, -1, 64)
+		}
+		return len(v), f.SetCellStr(sheet, cell, v.String())
+	case float64:
+		text := fmt.Sprint(v)
+		return len(text), f.SetCellFloat(sheet, cell, v, -1, 64)
+	case bool:
+		return 5, f.SetCellBool(sheet, cell, v)
+	case string:
+		if t, dateOnly, ok := parseDate(v); ok {
+			style := styles.dateTime
+			width := 19
+			if dateOnly {
+				style, width = styles.date, 10
+			}
+			if err := f.SetCellValue(sheet, cell, t); err != nil {
+				return 0, err
+			}
+			return width, f.SetCellStyle(sheet, cell, cell, style)
+		}
+		return longestLine(v), f.SetCellStr(sheet, cell, v)
+	default:
+		// Nested objects and arrays are kept as JSON
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			encoded = []byte(fmt.Sprintf("%v", v))
+		}
+		return utf8.RuneCount(encoded), f.SetCellStr(sheet, cell, string(encoded))
+	}
+}
+
+// parseDate recognizes date strings and returns their WIB wall-clock time, since Excel dates have no timezone
+func parseDate(value string) (time.Time, bool, bool) {
+	if len(value) < 10 || len(value) > 40 {
+		return time.Time{}, false, false
+	}
+	for _, candidate := range dateLayouts {
+		t, err := time.ParseInLocation(candidate.layout, value, wib)
+		if err != nil {
+			continue
+		}
+		t = t.In(wib)
+		wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+		return wall, candidate.date, true
+	}
+	return time.Time{}, false, false
+}
+
+func longestLine(value string) int {
+	longest := 0
+	for _, line := range strings.Split(value, "\n") {
+		if n := utf8.RuneCountInString(line); n > longest {
+			longest = n
+		}
+	}
+	return longest
+}
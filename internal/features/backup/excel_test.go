@@ -0,0 +1,175 @@
+package backup
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// exportDump runs a JSON data dump through generateExcel and opens the result
+func exportDump(t *testing.T, dump string) *excelize.File {
+	t.Helper()
+	var data map[string]interface{}
+	if err := decodeJSON(strings.NewReader(dump), &data); err != nil {
+		t.Fatalf("decode dump: %v", err)
+	}
+	content, err := (&BackupService{}).generateExcel(data)
+	if err != nil {
+		t.Fatalf("generateExcel: %v", err)
+	}
+	f, err := excelize.OpenReader(bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("open workbook: %v", err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func headerRow(t *testing.T, f *excelize.File, sheet string) []string {
+	t.Helper()
+	rows, err := f.GetRows(sheet)
+	if err != nil {
+		t.Fatalf("GetRows(%s): %v", sheet, err)
+	}
+	if len(rows) == 0 {
+		t.Fatalf("sheet %s is empty", sheet)
+	}
+	return rows[0]
+}
+
+func TestExcelColumnsPastZ(t *testing.T) {
+	var fields []string
+	for i := 0; i < 30; i++ {
+		fields = append(fields, fmt.Sprintf(`"c%02d": %d`, i, i))
+	}
+	f := exportDump(t, `{"wide": [{`+strings.Join(fields, ",")+`}]}`)
+
+	header := headerRow(t, f, "wide")
+	if len(header) != 30 {
+		t.Fatalf("header has %d columns, want 30", len(header))
+	}
+	for cell, want := range map[string]string{"Z1": "c25", "AA1": "c26", "AD1": "c29", "AD2": "29"} {
+		got, err := f.GetCellValue("wide", cell)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("%s = %q, want %q", cell, got, want)
+		}
+	}
+}
+
+func TestExcelHeaderIsUnionOfKeys(t *testing.T) {
+	f := exportDump(t, `{"clients": [{"name": "A", "city": "Jakarta"}, {"name": "B", "phone": "0812"}]}`)
+
+	got := strings.Join(headerRow(t, f, "clients"), ",")
+	if want := "city,name,phone"; got != want {
+		t.Fatalf("header = %s, want %s", got, want)
+	}
+	rows, _ := f.GetRows("clients")
+	if got := strings.Join(rows[2], ","); got != ",B,0812" {
+		t.Errorf("second row = %q, want missing city left blank", got)
+	}
+}
+
+func TestExcelDeclaredColumnOrder(t *testing.T) {
+	f := exportDump(t, `{
+		"_columns": {"invoices": ["number", "client", "amount"]},
+		"invoices": [{"amount": 100, "client": "A", "number": "INV-1", "notes": "x", "due": "soon"}]
+	}`)
+
+	if list := f.GetSheetList(); len(list) != 1 || list[0] != "invoices" {
+		t.Fatalf("sheets = %v, want only invoices", list)
+	}
+	got := strings.Join(headerRow(t, f, "invoices"), ",")
+	if want := "number,client,amount,due,notes"; got != want {
+		t.Fatalf("header = %s, want %s", got, want)
+	}
+}
+
+func TestExcelTypedCells(t *testing.T) {
+	f := exportDump(t, `{"rows": [{
+		"a_int": 42,
+		"b_big": 1234567890123456789,
+		"c_float": 1.5,
+		"d_date": "2026-01-15",
+		"e_time": "2026-01-15T10:30:00+07:00",
+		"f_bool": true,
+		"g_text": "hello"
+	}]}`)
+
+	// Numbers and dates are written without a type attribute, which Excel reads as a number
+	const number = excelize.CellTypeUnset
+	tests := []struct {
+		cell     string
+		cellType excelize.CellType
+		value    string
+	}{
+		{"A2", number, "42"},
+		{"B2", excelize.CellTypeSharedString, "1234567890123456789"},
+		{"C2", number, "1.5"},
+		{"D2", number, "2026-01-15"},
+		{"E2", number, "2026-01-15 10:30:00"},
+		{"F2", excelize.CellTypeBool, "TRUE"},
+		{"G2", excelize.CellTypeSharedString, "hello"},
+	}
+	for _, tt := range tests {
+		cellType, err := f.GetCellType("rows", tt.cell)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cellType != tt.cellType {
+			t.Errorf("%s type = %v, want %v", tt.cell, cellType, tt.cellType)
+		}
+		value, err := f.GetCellValue("rows", tt.cell)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value != tt.value {
+			t.Errorf("%s = %q, want %q", tt.cell, value, tt.value)
+		}
+	}
+}
+
+func TestExcelSheetNames(t *testing.T) {
+	f := exportDump(t, `{
+		"orders/2026: [draft]?": [{"id": 1}],
+		"a_data_set_with_a_very_long_name_1": [{"id": 2}],
+		"a_data_set_with_a_very_long_name_2": [{"id": 3}],
+		"Sheet1": [{"id": 4}],
+		"'quoted'": [{"id": 5}]
+	}`)
+
+	want := []string{"quoted", "Sheet1", "a_data_set_with_a_very_long_nam", "a_data_set_with_a_very_long (2)", "orders_2026_ _draft__"}
+	got := f.GetSheetList()
+	if len(got) != len(want) {
+		t.Fatalf("sheets = %q, want %q", got, want)
+	}
+	for _, name := range want {
+		if idx, _ := f.GetSheetIndex(name); idx < 0 {
+			t.Errorf("sheet %q missing from %q", name, got)
+		}
+	}
+	if value, _ := f.GetCellValue("Sheet1", "A2"); value != "4" {
+		t.Errorf("Sheet1 A2 = %q, want the Sheet1 data set", value)
+	}
+}
+
+func TestSheetTitle(t *testing.T) {
+	used := make(map[string]bool)
+	for _, tt := range []struct{ name, want string }{
+		{"invoices", "invoices"},
+		{"Invoices", "Invoices (2)"},
+		{"INVOICES", "INVOICES (3)"},
+		{"a/b\\c*d", "a_b_c_d"},
+		{"", "Sheet"},
+		{"'", "Sheet (2)"},
+	} {
+		if got := sheetTitle(tt.name, used); got != tt.want {
+			t.Errorf("sheetTitle(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
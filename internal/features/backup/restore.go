@@ -0,0 +1,152 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrNotArchived is returned when restoring a run without an archived JSON backup
+var ErrNotArchived = errors.New("backup has no archived restore file")
+
+// SheetDiff compares one data set of a backup with the live data
+type SheetDiff struct {
+	Name    string `json:"name"`
+	Current int    `json:"current"` // rows in the live data
+	Backup  int    `json:"backup"`  // rows in the backup
+	Added   int    `json:"added"`   // rows the restore brings back
+	Removed int    `json:"removed"` // live rows missing from the backup
+	Changed int    `json:"changed"` // rows with the same ID but different values
+}
+
+// RestoreResult is the outcome of a restore or a dry run
+type RestoreResult struct {
+	RunID      string      `json:"runId"`
+	DryRun     bool        `json:"dryRun"`
+	Diff       []SheetDiff `json:"diff,omitempty"`
+	StatusCode int         `json:"statusCode,omitempty"` // restore endpoint response
+	Response   string      `json:"response,omitempty"`
+}
+
+// Restore pushes the archived JSON of a run to WEB_URL/api/backup/restore.
+// With dryRun it only compares the backup with the current data dump.
+func (s *BackupService) Restore(ctx context.Context, id string, dryRun bool) (*RestoreResult, error) {
+	run, err := s.repo.Get(ctx, id)
+	if err != nil || run == nil {
+		return nil, err
+	}
+	data, err := s.restoreFile(ctx, run)
+	if err != nil {
+		return nil, err
+	}
+	result := &RestoreResult{RunID: run.ID, DryRun: dryRun}
+
+	if dryRun {
+		var backupData map[string]interface{}
+		if err := decodeJSON(bytes.NewReader(data), &backupData); err != nil {
+			return nil, fmt.Errorf("failed to parse backup: %w", err)
+		}
+		current, err := s.fetchData(ctx)
+		if err != nil {
+			return nil, err
+		}
+		result.Diff = diffData(current, backupData)
+		return result, nil
+	}
+
+	restoreURL := fmt.Sprintf("%s/api/backup/restore", s.webURL)
+	log.Printf("📤 [BACKUP] Restoring run %s to %s", run.ID, restoreURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, restoreURL, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := (&http.Client{Timeout: 5 * time.Minute}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to push restore: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+
+	result.StatusCode = resp.StatusCode
+	result.Response = string(body)
+	if resp.StatusCode >= 300 {
+		return result, fmt.Errorf("restore endpoint returned HTTP %d", resp.StatusCode)
+	}
+	log.Printf("✅ [BACKUP] Run %s restored", run.ID)
+	return result, nil
+}
+
+// restoreFile loads the archived JSON backup of a run
+func (s *BackupService) restoreFile(ctx context.Context, run *Run) ([]byte, error) {
+	if s.archive == nil {
+		return nil, ErrNotArchived
+	}
+	for _, file := range run.Files {
+		if strings.HasSuffix(file.Name, ".json") && file.Stored != "" {
+			return s.archive.get(ctx, run, file.Stored)
+		}
+	}
+	return nil, ErrNotArchived
+}
+
+// diffData compares every data set of the backup with the live data, sorted by name
+func diffData(current, backup map[string]interface{}) []SheetDiff {
+	names := make(map[string]bool)
+	for name := range current {
+		names[name] = true
+	}
+	for name := range backup {
+		names[name] = true
+	}
+	delete(names, columnsKey)
+
+	diffs := make([]SheetDiff, 0, len(names))
+	for name := range names {
+		diffs = append(diffs, diffSheet(name, sheetRows(current[name]), sheetRows(backup[name])))
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Name < diffs[j].Name })
+	return diffs
+}
+
+// diffSheet matches rows by "id" (or "_id"); rows without one are compared by content
+func diffSheet(name string, current, backup []map[string]interface{}) SheetDiff {
+	diff := SheetDiff{Name: name, Current: len(current), Backup: len(backup)}
+
+	live := make(map[string]map[string]interface{}, len(current))
+	for _, row := range current {
+		live[rowKey(row)] = row
+	}
+	for _, row := range backup {
+		key := rowKey(row)
+		liveRow, ok := live[key]
+		switch {
+		case !ok:
+			diff.Added++
+		case !reflect.DeepEqual(liveRow, row):
+			diff.Changed++
+		}
+		delete(live, key)
+	}
+	diff.Removed = len(live)
+	return diff
+}
+
+func rowKey(row map[string]interface{}) string {
+	for _, field := range []string{"id", "_id"} {
+		if id, ok := row[field]; ok && id != nil {
+			return fmt.Sprintf("%s:%v", field, id)
+		}
+	}
+	encoded, _ := json.Marshal(row) // map keys are sorted, so equal rows encode equally
+	return "row:" + string(encoded)
+}
@@ -0,0 +1,53 @@
+package backup
+
+import (
+	"context"
+	"time"
+)
+
+// RunStatus is the state of a backup run
+type RunStatus string
+
+const (
+	RunRunning RunStatus = "running"
+	RunSuccess RunStatus = "success"
+	RunFailed  RunStatus = "failed"
+)
+
+// Triggers of a backup run
+const (
+	TriggerScheduled = "scheduled"
+	TriggerManual    = "manual"
+)
+
+// File is a backup file produced by a run
+type File struct {
+	Name string `json:"name"`
+	Size int    `json:"size"` // bytes
+	Sent bool   `json:"sent"`
+	// Stored is the name of the archived copy (with .enc when encrypted); empty when not archived or pruned
+	Stored string `json:"stored,omitempty"`
+}
+
+// Run is one execution of the backup, scheduled or manual
+type Run struct {
+	ID         string     `json:"id"`
+	Trigger    string     `json:"trigger"`
+	Session    string     `json:"session"`
+	Status     RunStatus  `json:"status"`
+	Stage      string     `json:"stage"` // fetching, generating, storing, sending, done
+	Files      []File     `json:"files"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+}
+
+// Repository persists backup runs
+type Repository interface {
+	// Save inserts or updates a run
+	Save(ctx context.Context, run *Run) error
+	// Get returns a run by ID, or nil if it does not exist
+	Get(ctx context.Context, id string) (*Run, error)
+	// List returns the most recent runs first
+	List(ctx context.Context, limit int) ([]Run, error)
+}
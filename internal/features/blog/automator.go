@@ -0,0 +1,357 @@
+package blog
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"wa-server-go/internal/templates"
+	"wa-server-go/internal/whatsapp"
+
+	"github.com/robfig/cron/v3"
+	waProto "go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	// ErrRunning is returned when a run is triggered while another one is in progress
+	ErrRunning = errors.New("a blog post is already being generated")
+	// ErrQueueEmpty is returned when a run is triggered without a topic and none is queued
+	ErrQueueEmpty = errors.New("no topic given and the topic queue is empty")
+	// ErrNotFailed is returned when retrying a topic that did not fail
+	ErrNotFailed = errors.New("only failed topics can be retried")
+)
+
+// Config controls the blog automator
+type Config struct {
+	Cron       string // schedule of queued topics; empty disables it
+	Timezone   string
+	DraftOnly  bool   // never publish, only create drafts
+	PostURL    string // link of a published post, {slug} is replaced
+	AdminPhone string // receives a notification after every run
+	Session    string // session notifications are sent from
+}
+
+// Automator turns queued topics into published blog posts
+type Automator struct {
+	writer    Writer
+	images    ImageSearcher // optional
+	publisher Publisher
+	repo      Repository
+	waManager *whatsapp.Manager
+	cfg       Config
+	cron      *cron.Cron
+	onUpdate  func(Topic)
+
+	mu      sync.Mutex
+	running bool
+}
+
+// NewAutomator creates a blog automator; images may be nil to publish without a cover image
+func NewAutomator(writer Writer, images ImageSearcher, publisher Publisher, repo Repository, waManager *whatsapp.Manager, cfg Config) (*Automator, error) {
+	if cfg.Timezone == "" {
+		cfg.Timezone = "Asia/Jakarta"
+	}
+	loc, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", cfg.Timezone, err)
+	}
+	return &Automator{
+		writer:    writer,
+		images:    images,
+		publisher: publisher,
+		repo:      repo,
+		waManager: waManager,
+		cfg:       cfg,
+		cron:      cron.New(cron.WithLocation(loc)),
+	}, nil
+}
+
+// OnUpdate sets a callback invoked whenever a topic changes state
+func (a *Automator) OnUpdate(fn func(Topic)) {
+	a.onUpdate = fn
+}
+
+// Start requeues topics interrupted by a restart and schedules the next queued topic
+// on the configured cron expression
+func (a *Automator) Start() error {
+	ctx := context.Background()
+	if interrupted, err := a.repo.List(ctx, TopicProcessing, 0); err == nil {
+		for i := range interrupted {
+			interrupted[i].Status = TopicQueued
+			a.update(&interrupted[i])
+		}
+	}
+
+	if a.cfg.Cron == "" {
+		log.Println("ℹ️ [BLOG] No BLOG_CRON set, posts are only generated on manual trigger")
+		return nil
+	}
+	_, err := a.cron.AddFunc(a.cfg.Cron, func() {
+		if _, err := a.Trigger(context.Background(), "", false); err != nil {
+			log.Printf("⚠️ [BLOG] Scheduled run skipped: %v", err)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to schedule blog automator: %w", err)
+	}
+
+	a.cron.Start()
+	log.Printf("✅ [BLOG] Scheduler started (%s %s)", a.cfg.Cron, a.cfg.Timezone)
+	return nil
+}
+
+// Stop stops the blog cron job
+func (a *Automator) Stop() {
+	a.cron.Stop()
+}
+
+// Enqueue adds a topic to the queue
+func (a *Automator) Enqueue(ctx context.Context, topic string, draft bool) (*Topic, error) {
+	topic = strings.TrimSpace(topic)
+	if topic == "" {
+		return nil, fmt.Errorf("topic is required")
+	}
+	id, err := newTopicID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	t := &Topic{
+		ID:        id,
+		Topic:     topic,
+		Status:    TopicQueued,
+		Draft:     draft || a.cfg.DraftOnly,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := a.repo.Create(ctx, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Trigger generates a post in the background: for topic if given, otherwise for the oldest queued topic
+func (a *Automator) Trigger(ctx context.Context, topic string, draft bool) (*Topic, error) {
+	a.mu.Lock()
+	if a.running {
+		a.mu.Unlock()
+		return nil, ErrRunning
+	}
+	a.running = true
+	a.mu.Unlock()
+
+	t, err := a.claim(ctx, topic, draft)
+	if err != nil {
+		a.finish()
+		return nil, err
+	}
+	return a.start(t), nil
+}
+
+// Retry runs a failed topic again in the background. A topic whose entry was created reuses it
+// and only publishes it. It returns nil if the topic does not exist.
+func (a *Automator) Retry(ctx context.Context, id string) (*Topic, error) {
+	a.mu.Lock()
+	if a.running {
+		a.mu.Unlock()
+		return nil, ErrRunning
+	}
+	a.running = true
+	a.mu.Unlock()
+
+	t, err := a.repo.Get(ctx, id)
+	if err == nil && t != nil && t.Status != TopicFailed {
+		err = ErrNotFailed
+	}
+	if err != nil || t == nil {
+		a.finish()
+		return nil, err
+	}
+
+	t.Status = TopicProcessing
+	t.Error = ""
+	a.update(t)
+	return a.start(t), nil
+}
+
+// start processes a claimed topic in the background and returns a snapshot of it
+func (a *Automator) start(t *Topic) *Topic {
+	snapshot := *t
+	go func() {
+		defer a.finish()
+		a.process(context.Background(), t)
+	}()
+	return &snapshot
+}
+
+// claim picks the topic to process and marks it processing
+func (a *Automator) claim(ctx context.Context, topic string, draft bool) (*Topic, error) {
+	var t *Topic
+	var err error
+	if topic != "" {
+		t, err = a.Enqueue(ctx, topic, draft)
+	} else {
+		t, err = a.repo.NextQueued(ctx)
+		if err == nil && t == nil {
+			err = ErrQueueEmpty
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	t.Status = TopicProcessing
+	a.update(t)
+	return t, nil
+}
+
+func (a *Automator) finish() {
+	a.mu.Lock()
+	a.running = false
+	a.mu.Unlock()
+}
+
+// process runs the pipeline for a topic: write, find a cover image, publish, notify
+func (a *Automator) process(ctx context.Context, t *Topic) {
+	log.Printf("✍️ [BLOG] Generating article: %s", t.Topic)
+
+	published, err := a.run(ctx, t)
+	if published != nil {
+		t.EntryID = published.EntryID
+	}
+	if err != nil {
+		t.Status = TopicFailed
+		t.Error = err.Error()
+		log.Printf("❌ [BLOG] %s failed: %v", t.Topic, err)
+	} else {
+		if published.Draft {
+			t.Status = TopicDrafted
+			log.Printf("📝 [BLOG] Draft created: %s (entry %s)", t.Title, t.EntryID)
+		} else {
+			t.Status = TopicPublished
+			log.Printf("✅ [BLOG] Published: %s", t.URL)
+		}
+	}
+	a.update(t)
+	a.notify(ctx, t)
+}
+
+// run writes, illustrates and publishes the article of a topic. A topic whose entry was created by an
+// earlier run only has that entry published. The entry is returned with the error if publishing it failed.
+func (a *Automator) run(ctx context.Context, t *Topic) (*Published, error) {
+	if t.EntryID != "" {
+		if t.Draft {
+			return &Published{EntryID: t.EntryID, Draft: true}, nil
+		}
+		published, err := a.publisher.PublishEntry(ctx, t.EntryID)
+		if err != nil {
+			return &Published{EntryID: t.EntryID}, err
+		}
+		a.setURL(t)
+		return published, nil
+	}
+
+	article, err := a.writer.Write(ctx, t.Topic)
+	if err != nil {
+		return nil, err
+	}
+	t.Title = article.Title
+	t.Slug = article.Slug
+
+	var image *Image
+	if a.images != nil {
+		// A missing cover image does not stop the post
+		if image, err = a.images.Search(ctx, article.ImageQuery); err != nil {
+			log.Printf("⚠️ [BLOG] No cover image for %q: %v", article.ImageQuery, err)
+			image = nil
+		}
+	}
+
+	published, err := a.publisher.Publish(ctx, article, image, t.Draft)
+	if err != nil {
+		return published, err
+	}
+	if !published.Draft {
+		a.setURL(t)
+	}
+	return published, nil
+}
+
+// setURL sets the link of a published topic
+func (a *Automator) setURL(t *Topic) {
+	if a.cfg.PostURL != "" && t.Slug != "" {
+		t.URL = strings.ReplaceAll(a.cfg.PostURL, "{slug}", t.Slug)
+	}
+}
+
+// notify sends the outcome of a run to the admin
+func (a *Automator) notify(ctx context.Context, t *Topic) {
+	if a.cfg.AdminPhone == "" {
+		return
+	}
+	client, ok := a.waManager.GetClient(a.cfg.Session)
+	if !ok || !client.IsReady() {
+		log.Printf("⚠️ [BLOG] Cannot notify admin: session %s is not ready", a.cfg.Session)
+		return
+	}
+
+	title := t.Title
+	if title == "" {
+		title = t.Topic
+	}
+	var message string
+	switch t.Status {
+	case TopicPublished:
+		message = templates.GenerateBlogNotification("published", title, t.URL, time.Now())
+	case TopicDrafted:
+		message = templates.GenerateBlogNotification("draft", title, t.EntryID, time.Now())
+	default:
+		message = templates.GenerateBlogNotification("failed", title, t.Error, time.Now())
+	}
+
+	jid := types.NewJID(a.cfg.AdminPhone, types.DefaultUserServer)
+	if _, err := client.WAClient.SendMessage(ctx, jid, &waProto.Message{
+		Conversation: proto.String(message),
+	}); err != nil {
+		log.Printf("❌ [BLOG] Failed to notify admin: %v", err)
+	}
+}
+
+// update saves the topic and publishes it
+func (a *Automator) update(t *Topic) {
+	t.UpdatedAt = time.Now()
+	if err := a.repo.Update(context.Background(), t); err != nil {
+		log.Printf("⚠️ [BLOG] Failed to save topic %s: %v", t.ID, err)
+	}
+	if a.onUpdate != nil {
+		a.onUpdate(*t)
+	}
+}
+
+// Get returns a topic by ID, or nil if it does not exist
+func (a *Automator) Get(ctx context.Context, id string) (*Topic, error) {
+	return a.repo.Get(ctx, id)
+}
+
+// List returns topics, newest first, optionally filtered by status
+func (a *Automator) List(ctx context.Context, status TopicStatus, limit int) ([]Topic, error) {
+	return a.repo.List(ctx, status, limit)
+}
+
+// newTopicID generates a random topic ID
+func newTopicID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,86 @@
+package blog
+
+import (
+	"context"
+	"time"
+)
+
+// Article is a generated blog post
+type Article struct {
+	Title       string   `json:"title"`
+	Slug        string   `json:"slug"`
+	Description string   `json:"description"`
+	Body        string   `json:"body"` // Markdown
+	Tags        []string `json:"tags"`
+	ImageQuery  string   `json:"imageQuery"` // search terms for the cover image
+}
+
+// Image is a cover image found for an article
+type Image struct {
+	URL             string `json:"url"`
+	Alt             string `json:"alt"`
+	Photographer    string `json:"photographer"`
+	PhotographerURL string `json:"photographerUrl"`
+}
+
+// Published is an entry created in the CMS
+type Published struct {
+	EntryID string `json:"entryId"`
+	Draft   bool   `json:"draft"`
+}
+
+// Writer generates an article about a topic
+type Writer interface {
+	Write(ctx context.Context, topic string) (*Article, error)
+}
+
+// ImageSearcher finds a cover image; it returns nil when nothing matches
+type ImageSearcher interface {
+	Search(ctx context.Context, query string) (*Image, error)
+}
+
+// Publisher creates an entry for the article and publishes it unless draft is set
+type Publisher interface {
+	// Publish returns the created entry along with the error when only publishing it failed
+	Publish(ctx context.Context, article *Article, image *Image, draft bool) (*Published, error)
+	// PublishEntry publishes an entry created by an earlier Publish
+	PublishEntry(ctx context.Context, entryID string) (*Published, error)
+}
+
+// TopicStatus is the state of a queued topic
+type TopicStatus string
+
+const (
+	TopicQueued     TopicStatus = "queued"
+	TopicProcessing TopicStatus = "processing"
+	TopicPublished  TopicStatus = "published"
+	TopicDrafted    TopicStatus = "drafted"
+	TopicFailed     TopicStatus = "failed"
+)
+
+// Topic is a queued blog topic and the outcome of its run
+type Topic struct {
+	ID        string      `json:"id"`
+	Topic     string      `json:"topic"`
+	Status    TopicStatus `json:"status"`
+	Draft     bool        `json:"draft"` // create the entry without publishing it
+	Title     string      `json:"title,omitempty"`
+	Slug      string      `json:"slug,omitempty"`
+	EntryID   string      `json:"entryId,omitempty"` // set once the entry exists, even if publishing it failed
+	URL       string      `json:"url,omitempty"`
+	Error     string      `json:"error,omitempty"`
+	CreatedAt time.Time   `json:"createdAt"`
+	UpdatedAt time.Time   `json:"updatedAt"`
+}
+
+// Repository persists the topic queue
+type Repository interface {
+	Create(ctx context.Context, topic *Topic) error
+	Update(ctx context.Context, topic *Topic) error
+	// Get returns a topic by ID, or nil if it does not exist
+	Get(ctx context.Context, id string) (*Topic, error)
+	// NextQueued returns the oldest queued topic, or nil if the queue is empty
+	NextQueued(ctx context.Context) (*Topic, error)
+	// List returns topics, newest first, optionally filtered by status
+	List(ctx context.Context, status TopicStatus, limit int) ([]Topic, error)
+}
@@ -0,0 +1,189 @@
+package blog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const contentfulMediaType = "application/vnd.contentful.management.v1+json"
+
+// ContentfulPublisher creates blog entries with the Contentful Management API.
+// The content type needs the fields title, slug, description, body, tags and heroImage (asset link).
+type ContentfulPublisher struct {
+	BaseURL      string // e.g. https://api.contentful.com
+	Token        string
+	SpaceID      string
+	Environment  string
+	ContentType  string // e.g. blogPost
+	Locale       string // e.g. en-US
+	PollInterval time.Duration
+	Client       *http.Client
+}
+
+type contentfulSys struct {
+	Sys struct {
+		ID      string `json:"id"`
+		Version int    `json:"version"`
+	} `json:"sys"`
+}
+
+// Publish uploads the cover image as an asset, then creates the entry and publishes both unless draft is set
+func (c *ContentfulPublisher) Publish(ctx context.Context, article *Article, image *Image, draft bool) (*Published, error) {
+	fields := map[string]interface{}{
+		"title":       c.localized(article.Title),
+		"slug":        c.localized(article.Slug),
+		"description": c.localized(article.Description),
+		"body":        c.localized(article.Body),
+		"tags":        c.localized(article.Tags),
+	}
+
+	if image != nil {
+		assetID, err := c.createAsset(ctx, article, image, draft)
+		if err != nil {
+			return nil, fmt.Errorf("failed to upload cover image: %w", err)
+		}
+		fields["heroImage"] = c.localized(map[string]interface{}{
+			"sys": map[string]string{"type": "Link", "linkType": "Asset", "id": assetID},
+		})
+	}
+
+	var entry contentfulSys
+	err := c.do(ctx, http.MethodPost, "entries", 0, map[string]string{"X-Contentful-Content-Type": c.ContentType},
+		map[string]interface{}{"fields": fields}, &entry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create entry: %w", err)
+	}
+
+	published := &Published{EntryID: entry.Sys.ID, Draft: draft}
+	if draft {
+		return published, nil
+	}
+	if err := c.do(ctx, http.MethodPut, "entries/"+entry.Sys.ID+"/published", entry.Sys.Version, nil, nil, nil); err != nil {
+		// The entry exists: return it so a retry publishes it instead of creating another one
+		return published, fmt.Errorf("failed to publish entry %s: %w", entry.Sys.ID, err)
+	}
+	return published, nil
+}
+
+// PublishEntry publishes an entry at its current version
+func (c *ContentfulPublisher) PublishEntry(ctx context.Context, entryID string) (*Published, error) {
+	var entry contentfulSys
+	if err := c.do(ctx, http.MethodGet, "entries/"+entryID, 0, nil, nil, &entry); err != nil {
+		return nil, fmt.Errorf("failed to fetch entry %s: %w", entryID, err)
+	}
+	if err := c.do(ctx, http.MethodPut, "entries/"+entryID+"/published", entry.Sys.Version, nil, nil, nil); err != nil {
+		return nil, fmt.Errorf("failed to publish entry %s: %w", entryID, err)
+	}
+	return &Published{EntryID: entryID}, nil
+}
+
+// createAsset creates an asset from the image URL, waits for Contentful to process it and publishes it
+func (c *ContentfulPublisher) createAsset(ctx context.Context, article *Article, image *Image, draft bool) (string, error) {
+	description := image.Alt
+	if image.Photographer != "" {
+		description = fmt.Sprintf("%s (Photo by %s on Pexels)", image.Alt, image.Photographer)
+	}
+	fileName := path.Base(strings.SplitN(image.URL, "?", 2)[0])
+	if !strings.Contains(fileName, ".") {
+		fileName = article.Slug + ".jpg"
+	}
+
+	var asset contentfulSys
+	err := c.do(ctx, http.MethodPost, "assets", 0, nil, map[string]interface{}{
+		"fields": map[string]interface{}{
+			"title":       c.localized(article.Title),
+			"description": c.localized(description),
+			"file": c.localized(map[string]string{
+				"contentType": "image/jpeg",
+				"fileName":    fileName,
+				"upload":      image.URL,
+			}),
+		},
+	}, &asset)
+	if err != nil {
+		return "", err
+	}
+
+	id := asset.Sys.ID
+	if err := c.do(ctx, http.MethodPut, "assets/"+id+"/files/"+c.Locale+"/process", asset.Sys.Version, nil, nil, nil); err != nil {
+		return "", fmt.Errorf("failed to process asset: %w", err)
+	}
+
+	// Processing is asynchronous: wait until the file has a URL
+	interval := c.PollInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	for attempt := 0; ; attempt++ {
+		var state struct {
+			contentfulSys
+			Fields struct {
+				File map[string]struct {
+					URL string `json:"url"`
+				} `json:"file"`
+			} `json:"fields"`
+		}
+		if err := c.do(ctx, http.MethodGet, "assets/"+id, 0, nil, nil, &state); err != nil {
+			return "", err
+		}
+		if state.Fields.File[c.Locale].URL != "" {
+			asset.Sys.Version = state.Sys.Version
+			break
+		}
+		if attempt >= 30 {
+			return "", fmt.Errorf("asset %s was not processed in time", id)
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+
+	if !draft {
+		if err := c.do(ctx, http.MethodPut, "assets/"+id+"/published", asset.Sys.Version, nil, nil, nil); err != nil {
+			return "", fmt.Errorf("failed to publish asset: %w", err)
+		}
+	}
+	return id, nil
+}
+
+func (c *ContentfulPublisher) localized(value interface{}) map[string]interface{} {
+	return map[string]interface{}{c.Locale: value}
+}
+
+// do calls an environment-scoped endpoint; version > 0 is sent as X-Contentful-Version
+func (c *ContentfulPublisher) do(ctx context.Context, method, resource string, version int, headers map[string]string, body, out interface{}) error {
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	endpoint := fmt.Sprintf("%s/spaces/%s/environments/%s/%s", strings.TrimRight(c.BaseURL, "/"), c.SpaceID, c.Environment, resource)
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("Content-Type", contentfulMediaType)
+	if version > 0 {
+		req.Header.Set("X-Contentful-Version", strconv.Itoa(version))
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	return doJSON(c.Client, req, out)
+}
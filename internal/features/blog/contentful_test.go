@@ -0,0 +1,227 @@
+package blog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeContentful is a Contentful Management API stand-in that records the calls it gets
+type fakeContentful struct {
+	t           *testing.T
+	mu          sync.Mutex
+	calls       []string // "METHOD resource vVERSION"
+	entry       map[string]interface{}
+	assetPolls  int  // GETs of the asset before its file has a URL
+	failPublish bool // PUT entries/:id/published fails
+}
+
+func newFakeContentful(t *testing.T) (*fakeContentful, *ContentfulPublisher) {
+	f := &fakeContentful{t: t, assetPolls: 1}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, &ContentfulPublisher{
+		BaseURL:      srv.URL,
+		Token:        "cma-token",
+		SpaceID:      "space",
+		Environment:  "master",
+		ContentType:  "blogPost",
+		Locale:       "en-US",
+		PollInterval: time.Millisecond,
+	}
+}
+
+func (f *fakeContentful) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if auth := r.Header.Get("Authorization"); auth != "Bearer cma-token" {
+		f.t.Errorf("Authorization = %q", auth)
+	}
+	if ct := r.Header.Get("Content-Type"); ct != contentfulMediaType {
+		f.t.Errorf("Content-Type = %q", ct)
+	}
+	resource, ok := strings.CutPrefix(r.URL.Path, "/spaces/space/environments/master/")
+	if !ok {
+		f.t.Errorf("unexpected path %s", r.URL.Path)
+		http.NotFound(w, r)
+		return
+	}
+	version := r.Header.Get("X-Contentful-Version")
+	f.calls = append(f.calls, strings.TrimSpace(fmt.Sprintf("%s %s %s", r.Method, resource, version)))
+
+	sys := func(id string, version int) map[string]interface{} {
+		return map[string]interface{}{"sys": map[string]interface{}{"id": id, "version": version}}
+	}
+	reply := func(v interface{}) {
+		json.NewEncoder(w).Encode(v)
+	}
+
+	switch r.Method + " " + resource {
+	case "POST assets":
+		w.WriteHeader(http.StatusCreated)
+		reply(sys("asset1", 1))
+	case "PUT assets/asset1/files/en-US/process":
+		w.WriteHeader(http.StatusNoContent)
+	case "GET assets/asset1":
+		state := sys("asset1", 2)
+		if f.assetPolls > 0 {
+			f.assetPolls--
+		} else {
+			state["fields"] = map[string]interface{}{
+				"file": map[string]interface{}{"en-US": map[string]string{"url": "//images.test/cover.jpg"}},
+			}
+		}
+		reply(state)
+	case "PUT assets/asset1/published":
+		reply(sys("asset1", 3))
+	case "POST entries":
+		if ct := r.Header.Get("X-Contentful-Content-Type"); ct != "blogPost" {
+			f.t.Errorf("X-Contentful-Content-Type = %q", ct)
+		}
+		var body struct {
+			Fields map[string]interface{} `json:"fields"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			f.t.Errorf("invalid entry body: %v", err)
+		}
+		f.entry = body.Fields
+		w.WriteHeader(http.StatusCreated)
+		reply(sys("entry1", 1))
+	case "GET entries/entry1":
+		reply(sys("entry1", 4))
+	case "PUT entries/entry1/published":
+		if f.failPublish {
+			w.WriteHeader(http.StatusConflict)
+			reply(map[string]string{"message": "VersionMismatch"})
+			return
+		}
+		reply(sys("entry1", 2))
+	default:
+		f.t.Errorf("unexpected request %s %s", r.Method, resource)
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeContentful) Calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.calls...)
+}
+
+var testArticle = &Article{
+	Title:       "Tips Keamanan Server",
+	Slug:        "tips-keamanan-server",
+	Description: "Deskripsi",
+	Body:        "## Intro",
+	Tags:        []string{"server"},
+}
+
+func TestContentfulPublisherPublish(t *testing.T) {
+	f, c := newFakeContentful(t)
+	image := &Image{URL: "https://img.test/photos/123/server.jpeg?w=1200", Alt: "Server room", Photographer: "Ana"}
+
+	published, err := c.Publish(context.Background(), testArticle, image, false)
+	if err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if *published != (Published{EntryID: "entry1"}) {
+		t.Errorf("published = %+v", published)
+	}
+
+	want := []string{
+		"POST assets",
+		"PUT assets/asset1/files/en-US/process 1",
+		"GET assets/asset1",
+		"GET assets/asset1",
+		"PUT assets/asset1/published 2",
+		"POST entries",
+		"PUT entries/entry1/published 1",
+	}
+	if got := f.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("calls = %q, want %q", got, want)
+	}
+
+	title, _ := f.entry["title"].(map[string]interface{})
+	if title["en-US"] != testArticle.Title {
+		t.Errorf("title field = %v", f.entry["title"])
+	}
+	hero, _ := json.Marshal(f.entry["heroImage"])
+	if !strings.Contains(string(hero), `"id":"asset1"`) || !strings.Contains(string(hero), `"linkType":"Asset"`) {
+		t.Errorf("heroImage field = %s", hero)
+	}
+}
+
+func TestContentfulPublisherDraft(t *testing.T) {
+	f, c := newFakeContentful(t)
+
+	published, err := c.Publish(context.Background(), testArticle, nil, true)
+	if err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if *published != (Published{EntryID: "entry1", Draft: true}) {
+		t.Errorf("published = %+v", published)
+	}
+	if got, want := f.Calls(), []string{"POST entries"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("calls = %q, want %q", got, want)
+	}
+	if _, ok := f.entry["heroImage"]; ok {
+		t.Error("entry without an image has a heroImage")
+	}
+}
+
+func TestContentfulPublisherPublishFailureKeepsEntry(t *testing.T) {
+	f, c := newFakeContentful(t)
+	f.failPublish = true
+
+	published, err := c.Publish(context.Background(), testArticle, nil, false)
+	if err == nil || !strings.Contains(err.Error(), "HTTP 409") {
+		t.Fatalf("error = %v, want the publish conflict", err)
+	}
+	if published == nil || published.EntryID != "entry1" {
+		t.Fatalf("published = %+v, want the created entry", published)
+	}
+
+	// A retry publishes the existing entry at its current version instead of creating another one
+	f.failPublish = false
+	published, err = c.PublishEntry(context.Background(), published.EntryID)
+	if err != nil {
+		t.Fatalf("PublishEntry: %v", err)
+	}
+	if *published != (Published{EntryID: "entry1"}) {
+		t.Errorf("published = %+v", published)
+	}
+	want := []string{
+		"POST entries",
+		"PUT entries/entry1/published 1",
+		"GET entries/entry1",
+		"PUT entries/entry1/published 4",
+	}
+	if got := f.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("calls = %q, want %q", got, want)
+	}
+}
+
+func TestContentfulPublisherAssetNotProcessed(t *testing.T) {
+	f, c := newFakeContentful(t)
+	f.assetPolls = 100
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := c.Publish(ctx, testArticle, &Image{URL: "https://img.test/photo", Alt: "alt"}, false)
+	if err == nil || !strings.Contains(err.Error(), "was not processed in time") {
+		t.Errorf("error = %v, want a processing timeout", err)
+	}
+	for _, call := range f.Calls() {
+		if strings.HasPrefix(call, "POST entries") {
+			t.Error("entry was created without its cover image")
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package blog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultChatModel is the default model of the chat completion writer
+const DefaultChatModel = "llama-3.3-70b-versatile"
+
+const articlePrompt = `You write SEO-friendly blog articles in Indonesian for an IT services company.
+Reply with a single JSON object with these fields:
+"title" (max 70 characters), "slug" (lowercase, hyphenated), "description" (max 160 characters),
+"body" (the article in Markdown, 600-1000 words, with ## headings), "tags" (3-5 lowercase strings)
+and "imageQuery" (2-4 English words to search a stock photo).`
+
+// ChatCompletionWriter writes articles with an OpenAI-compatible chat completion API (e.g. Groq)
+type ChatCompletionWriter struct {
+	BaseURL string // e.g. https://api.groq.com/openai/v1
+	APIKey  string
+	Model   string
+	Client  *http.Client
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model          string            `json:"model"`
+	Messages       []chatMessage     `json:"messages"`
+	Temperature    float64           `json:"temperature"`
+	ResponseFormat map[string]string `json:"response_format"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+// Write generates an article about topic
+func (w *ChatCompletionWriter) Write(ctx context.Context, topic string) (*Article, error) {
+	body, err := json.Marshal(chatRequest{
+		Model: w.Model,
+		Messages: []chatMessage{
+			{Role: "system", Content: articlePrompt},
+			{Role: "user", Content: "Topic: " + topic},
+		},
+		Temperature:    0.7,
+		ResponseFormat: map[string]string{"type": "json_object"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(w.BaseURL, "/")+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+w.APIKey)
+
+	var resp chatResponse
+	if err := doJSON(w.Client, req, &resp); err != nil {
+		return nil, fmt.Errorf("chat completion failed: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("chat completion returned no choices")
+	}
+
+	var article Article
+	if err := json.Unmarshal([]byte(extractJSON(resp.Choices[0].Message.Content)), &article); err != nil {
+		return nil, fmt.Errorf("article is not valid JSON: %w", err)
+	}
+	if article.Title == "" || article.Body == "" {
+		return nil, fmt.Errorf("article has no title or body")
+	}
+	article.Slug = Slugify(article.Slug)
+	if article.Slug == "" {
+		article.Slug = Slugify(article.Title)
+	}
+	if article.ImageQuery == "" {
+		article.ImageQuery = topic
+	}
+	return &article, nil
+}
+
+// extractJSON strips Markdown code fences some models put around JSON
+func extractJSON(content string) string {
+	content = strings.TrimSpace(content)
+	if start, end := strings.Index(content, "{"), strings.LastIndex(content, "}"); start >= 0 && end > start {
+		return content[start : end+1]
+	}
+	return content
+}
+
+var slugInvalid = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Slugify turns a title into a URL slug
+func Slugify(value string) string {
+	slug := strings.Trim(slugInvalid.ReplaceAllString(strings.ToLower(value), "-"), "-")
+	if len(slug) > 80 {
+		slug = strings.TrimRight(slug[:80], "-")
+	}
+	return slug
+}
+
+// doJSON sends a request and decodes a JSON response, turning non-2xx responses into errors
+func doJSON(client *http.Client, req *http.Request, out interface{}) error {
+	if client == nil {
+		client = &http.Client{Timeout: 2 * time.Minute}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet := string(data)
+		if len(snippet) > 300 {
+			snippet = snippet[:300]
+		}
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, snippet)
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
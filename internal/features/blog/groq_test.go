@@ -0,0 +1,105 @@
+package blog
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// chatServer serves one chat completion reply with the given content and records the request
+func chatServer(t *testing.T, status int, content string, got *chatRequest) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/chat/completions" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer secret" {
+			t.Errorf("Authorization = %q", auth)
+		}
+		if got != nil {
+			if err := json.NewDecoder(r.Body).Decode(got); err != nil {
+				t.Errorf("invalid request body: %v", err)
+			}
+		}
+		w.WriteHeader(status)
+		if status != http.StatusOK {
+			w.Write([]byte(content))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []interface{}{
+				map[string]interface{}{"message": map[string]string{"role": "assistant", "content": content}},
+			},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestChatCompletionWriterWrite(t *testing.T) {
+	var req chatRequest
+	content := "```json\n" + `{"title": "Tips Keamanan Server", "slug": "Tips Keamanan Server!", "description": "d",
+		"body": "## Intro\nIsi", "tags": ["server", "keamanan"]}` + "\n```"
+	srv := chatServer(t, http.StatusOK, content, &req)
+
+	w := &ChatCompletionWriter{BaseURL: srv.URL + "/", APIKey: "secret", Model: "test-model"}
+	article, err := w.Write(context.Background(), "server security")
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	if req.Model != "test-model" || len(req.Messages) != 2 || req.Messages[1].Content != "Topic: server security" {
+		t.Errorf("unexpected request: %+v", req)
+	}
+	if req.ResponseFormat["type"] != "json_object" {
+		t.Errorf("response_format = %v", req.ResponseFormat)
+	}
+	if article.Title != "Tips Keamanan Server" || article.Body != "## Intro\nIsi" {
+		t.Errorf("unexpected article: %+v", article)
+	}
+	if article.Slug != "tips-keamanan-server" {
+		t.Errorf("Slug = %q, want tips-keamanan-server", article.Slug)
+	}
+	if article.ImageQuery != "server security" {
+		t.Errorf("ImageQuery = %q, want the topic", article.ImageQuery)
+	}
+}
+
+func TestChatCompletionWriterSlugFromTitle(t *testing.T) {
+	srv := chatServer(t, http.StatusOK, `{"title": "Apa Itu DevOps?", "body": "isi"}`, nil)
+
+	w := &ChatCompletionWriter{BaseURL: srv.URL, APIKey: "secret"}
+	article, err := w.Write(context.Background(), "devops")
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if article.Slug != "apa-itu-devops" {
+		t.Errorf("Slug = %q, want apa-itu-devops", article.Slug)
+	}
+}
+
+func TestChatCompletionWriterErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		content string
+		want    string
+	}{
+		{"http error", http.StatusTooManyRequests, "rate limited", "HTTP 429: rate limited"},
+		{"not json", http.StatusOK, "Sorry, I cannot help with that.", "not valid JSON"},
+		{"no body", http.StatusOK, `{"title": "Judul"}`, "no title or body"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := chatServer(t, tt.status, tt.content, nil)
+			w := &ChatCompletionWriter{BaseURL: srv.URL, APIKey: "secret"}
+			_, err := w.Write(context.Background(), "topic")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package blog
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// PexelsSearcher finds cover images with a Pexels-compatible search API
+type PexelsSearcher struct {
+	BaseURL string // e.g. https://api.pexels.com/v1
+	APIKey  string
+	Client  *http.Client
+}
+
+type pexelsResponse struct {
+	Photos []struct {
+		Alt             string `json:"alt"`
+		Photographer    string `json:"photographer"`
+		PhotographerURL string `json:"photographer_url"`
+		Src             struct {
+			Large2x  string `json:"large2x"`
+			Original string `json:"original"`
+		} `json:"src"`
+	} `json:"photos"`
+}
+
+// Search returns the first landscape photo matching query, or nil if there is none
+func (p *PexelsSearcher) Search(ctx context.Context, query string) (*Image, error) {
+	params := url.Values{"query": {query}, "per_page": {"1"}, "orientation": {"landscape"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(p.BaseURL, "/")+"/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", p.APIKey)
+
+	var resp pexelsResponse
+	if err := doJSON(p.Client, req, &resp); err != nil {
+		return nil, fmt.Errorf("image search failed: %w", err)
+	}
+	if len(resp.Photos) == 0 {
+		return nil, nil
+	}
+
+	photo := resp.Photos[0]
+	image := &Image{
+		URL:             photo.Src.Large2x,
+		Alt:             photo.Alt,
+		Photographer:    photo.Photographer,
+		PhotographerURL: photo.PhotographerURL,
+	}
+	if image.URL == "" {
+		image.URL = photo.Src.Original
+	}
+	if image.Alt == "" {
+		image.Alt = query
+	}
+	return image, nil
+}
@@ -0,0 +1,82 @@
+package blog
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// pexelsServer serves body to searches and checks the query and API key
+func pexelsServer(t *testing.T, body string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/search" {
+			t.Errorf("path = %q, want /search", r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "pexels-key" {
+			t.Errorf("Authorization = %q, want the bare API key", auth)
+		}
+		q := r.URL.Query()
+		if q.Get("query") != "cloud server" || q.Get("per_page") != "1" || q.Get("orientation") != "landscape" {
+			t.Errorf("unexpected query %q", r.URL.RawQuery)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestPexelsSearcherSearch(t *testing.T) {
+	srv := pexelsServer(t, `{"photos": [{"alt": "Server room", "photographer": "Ana",
+		"photographer_url": "https://pexels.test/@ana", "src": {"large2x": "https://img.test/large.jpg", "original": "https://img.test/orig.jpg"}}]}`)
+
+	p := &PexelsSearcher{BaseURL: srv.URL + "/", APIKey: "pexels-key"}
+	image, err := p.Search(context.Background(), "cloud server")
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	want := Image{URL: "https://img.test/large.jpg", Alt: "Server room", Photographer: "Ana", PhotographerURL: "https://pexels.test/@ana"}
+	if image == nil || *image != want {
+		t.Errorf("image = %+v, want %+v", image, want)
+	}
+}
+
+func TestPexelsSearcherFallbacks(t *testing.T) {
+	srv := pexelsServer(t, `{"photos": [{"src": {"original": "https://img.test/orig.jpg"}}]}`)
+
+	p := &PexelsSearcher{BaseURL: srv.URL, APIKey: "pexels-key"}
+	image, err := p.Search(context.Background(), "cloud server")
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if image.URL != "https://img.test/orig.jpg" {
+		t.Errorf("URL = %q, want the original size", image.URL)
+	}
+	if image.Alt != "cloud server" {
+		t.Errorf("Alt = %q, want the query", image.Alt)
+	}
+}
+
+func TestPexelsSearcherNoMatch(t *testing.T) {
+	srv := pexelsServer(t, `{"photos": []}`)
+
+	p := &PexelsSearcher{BaseURL: srv.URL, APIKey: "pexels-key"}
+	image, err := p.Search(context.Background(), "cloud server")
+	if err != nil || image != nil {
+		t.Errorf("Search = %+v, %v; want nil, nil", image, err)
+	}
+}
+
+func TestPexelsSearcherHTTPError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad key", http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	p := &PexelsSearcher{BaseURL: srv.URL, APIKey: "wrong"}
+	if _, err := p.Search(context.Background(), "cloud server"); err == nil {
+		t.Error("Search succeeded, want an error")
+	}
+}
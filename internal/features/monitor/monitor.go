@@ -4,11 +4,14 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"net/http"
+	"strings"
+	"sync"
 	"time"
 
+	"wa-server-go/internal/templates"
+	"wa-server-go/internal/whatsapp"
+
 	"github.com/robfig/cron/v3"
-	"go.mau.fi/whatsmeow"
 	waProto "go.mau.fi/whatsmeow/proto/waE2E"
 	"go.mau.fi/whatsmeow/types"
 	"google.golang.org/protobuf/proto"
@@ -24,40 +27,104 @@ const (
 	StatusRecovery HealthStatus = "recovery"
 )
 
+// Check is the result of one check of a target
+type Check struct {
+	ID         int64        `json:"id"`
+	Target     string       `json:"target"`
+	Status     HealthStatus `json:"status"`
+	LatencyMs  int          `json:"latencyMs"`
+	StatusCode int          `json:"statusCode,omitempty"`
+	Error      string       `json:"error,omitempty"`
+	CheckedAt  time.Time    `json:"checkedAt"`
+}
+
+// Repository persists check history
+type Repository interface {
+	AddCheck(ctx context.Context, check *Check) error
+	// ListChecks returns the most recent checks of a target first
+	ListChecks(ctx context.Context, target string, limit int) ([]Check, error)
+	// PruneChecks deletes checks older than before
+	PruneChecks(ctx context.Context, before time.Time) (int64, error)
+}
+
+// TargetStatus is the live state of a target
+type TargetStatus struct {
+	Target
+	Status     HealthStatus `json:"status"`
+	LatencyMs  int          `json:"latencyMs"`
+	StatusCode int          `json:"statusCode,omitempty"`
+	Error      string       `json:"error,omitempty"`
+	LastCheck  *time.Time   `json:"lastCheck,omitempty"`
+	DownSince  *time.Time   `json:"downSince,omitempty"`
+	SlowCount  int          `json:"slowCount"`
+	AlertSent  bool         `json:"alertSent"` // a down or slow alert is open
+}
+
+// Config controls the monitor
+type Config struct {
+	Targets     []Target
+	Recipients  []string      // phone numbers or group JIDs (…@g.us) receiving alerts
+	Session     string        // session alerts are sent from
+	Interval    time.Duration // time between checks
+	HistoryDays int           // days of check history kept
+}
+
 // MonitorService handles system health monitoring
 type MonitorService struct {
-	waClient     *whatsmeow.Client
-	healthURL    string
-	alertPhone   string
-	cron         *cron.Cron
-	lastStatus   HealthStatus
-	downSince    time.Time
-	alertSent    bool
-	slowCount    int
+	waManager *whatsapp.Manager
+	repo      Repository
+	cfg       Config
+	cron      *cron.Cron
+	onUpdate  func(TargetStatus)
+
+	mu     sync.RWMutex
+	states map[string]*TargetStatus
 }
 
 // NewMonitorService creates a new monitor service
-func NewMonitorService(waClient *whatsmeow.Client, webURL, alertPhone string) *MonitorService {
+func NewMonitorService(waManager *whatsapp.Manager, repo Repository, cfg Config) *MonitorService {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.HistoryDays <= 0 {
+		cfg.HistoryDays = 7
+	}
+
+	states := make(map[string]*TargetStatus, len(cfg.Targets))
+	for _, target := range cfg.Targets {
+		states[target.Name] = &TargetStatus{Target: target, Status: StatusUp}
+	}
 	return &MonitorService{
-		waClient:   waClient,
-		healthURL:  fmt.Sprintf("%s/api/health", webURL),
-		alertPhone: alertPhone,
-		cron:       cron.New(),
-		lastStatus: StatusUp,
+		waManager: waManager,
+		repo:      repo,
+		cfg:       cfg,
+		cron:      cron.New(),
+		states:    states,
 	}
 }
 
-// Start starts the monitor cron job (runs every 5 minutes)
+// OnUpdate sets a callback invoked when a target changes status
+func (s *MonitorService) OnUpdate(fn func(TargetStatus)) {
+	s.onUpdate = fn
+}
+
+// Start starts checking every target on the configured interval, plus a daily history cleanup
 func (s *MonitorService) Start() error {
-	_, err := s.cron.AddFunc("*/5 * * * *", func() {
-		s.checkHealth()
+	_, err := s.cron.AddFunc(fmt.Sprintf("@every %s", s.cfg.Interval), func() {
+		s.checkAll(context.Background())
 	})
 	if err != nil {
 		return fmt.Errorf("failed to schedule monitor: %w", err)
 	}
+	_, err = s.cron.AddFunc("@daily", func() {
+		s.pruneHistory(context.Background())
+	})
+	if err != nil {
+		return fmt.Errorf("failed to schedule monitor cleanup: %w", err)
+	}
 
 	s.cron.Start()
-	log.Println("✅ [MONITOR] Health check scheduler started (runs every 5 mins)")
+	log.Printf("✅ [MONITOR] Health check scheduler started (%d targets, every %s)", len(s.cfg.Targets), s.cfg.Interval)
 	return nil
 }
 
@@ -66,114 +133,171 @@ func (s *MonitorService) Stop() {
 	s.cron.Stop()
 }
 
-// checkHealth performs a health check and sends alerts if needed
-func (s *MonitorService) checkHealth() {
-	ctx := context.Background()
-	now := time.Now()
+// checkAll checks every target concurrently
+func (s *MonitorService) checkAll(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := range s.cfg.Targets {
+		target := s.cfg.Targets[i]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.checkTarget(ctx, &target)
+		}()
+	}
+	wg.Wait()
+}
+
+// checkTarget performs a health check, records it and sends alerts if needed
+func (s *MonitorService) checkTarget(ctx context.Context, target *Target) {
+	result := target.check(ctx)
+	if result.Status != StatusUp {
+		log.Printf("🏥 [MONITOR] %s: %s (latency: %dms) %s", target.Name, result.Status, result.LatencyMs, result.Error)
+	}
+	if err := s.repo.AddCheck(ctx, &result); err != nil {
+		log.Printf("⚠️ [MONITOR] Failed to record check of %s: %v", target.Name, err)
+	}
+
+	alert, changed, snapshot := s.transition(target, result)
+	if alert != "" {
+		s.sendAlert(ctx, snapshot, alert)
+	}
+	if changed && s.onUpdate != nil {
+		s.onUpdate(snapshot)
+	}
+}
+
+// transition applies a check to the target's state and returns the alert to send, if any
+func (s *MonitorService) transition(target *Target, result Check) (HealthStatus, bool, TargetStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	start := time.Now()
-	status, latency := s.pingHealth()
-	log.Printf("🏥 [MONITOR] Health check: %s (latency: %dms)", status, latency)
+	state := s.states[target.Name]
+	lastStatus := state.Status
+	now := result.CheckedAt
+	var alert HealthStatus
 
 	// Handle status transitions
-	switch status {
+	switch result.Status {
 	case StatusDown:
-		if s.lastStatus != StatusDown {
-			s.downSince = now
-			s.alertSent = false
+		if lastStatus != StatusDown {
+			state.DownSince = &now
+			state.AlertSent = false
 		}
-		// Send alert after 5 minutes of downtime
-		if !s.alertSent && now.Sub(s.downSince) > 5*time.Minute {
-			s.sendAlert(ctx, StatusDown, latency)
-			s.alertSent = true
+		state.SlowCount = 0
+		// Alert once the grace period has passed
+		grace := time.Duration(target.DownGraceSeconds) * time.Second
+		if !state.AlertSent && now.Sub(*state.DownSince) >= grace {
+			alert = StatusDown
+			state.AlertSent = true
 		}
 
 	case StatusSlow:
-		s.slowCount++
-		// Alert after 3 consecutive slow checks
-		if s.slowCount >= 3 && s.lastStatus != StatusSlow {
-			s.sendAlert(ctx, StatusSlow, latency)
+		// Reachable again after a down alert
+		if lastStatus == StatusDown && state.AlertSent {
+			alert = StatusRecovery
+			state.AlertSent = false
+		}
+		state.DownSince = nil
+		state.SlowCount++
+		// Alert after consecutive slow checks
+		if state.SlowCount == target.SlowChecks && !state.AlertSent {
+			alert = StatusSlow
+			state.AlertSent = true
 		}
 
 	case StatusUp:
-		// Send recovery alert if was down
-		if s.lastStatus == StatusDown && s.alertSent {
-			s.sendAlert(ctx, StatusRecovery, latency)
+		// Send recovery alert if a down or slow alert was sent
+		if state.AlertSent {
+			alert = StatusRecovery
+			state.AlertSent = false
 		}
-		s.slowCount = 0
-		s.alertSent = false
+		state.DownSince = nil
+		state.SlowCount = 0
 	}
 
-	s.lastStatus = status
-	_ = start // Used for latency calculation
+	state.Status = result.Status
+	state.LatencyMs = result.LatencyMs
+	state.StatusCode = result.StatusCode
+	state.Error = result.Error
+	state.LastCheck = &now
+	return alert, lastStatus != result.Status, *state
 }
 
-// pingHealth performs the actual health check
-func (s *MonitorService) pingHealth() (HealthStatus, int) {
-	client := &http.Client{Timeout: 10 * time.Second}
-	
-	start := time.Now()
-	resp, err := client.Get(s.healthURL)
-	latency := int(time.Since(start).Milliseconds())
-
-	if err != nil {
-		log.Printf("❌ [MONITOR] Health check failed: %v", err)
-		return StatusDown, latency
+// sendAlert sends a WhatsApp alert to every recipient
+func (s *MonitorService) sendAlert(ctx context.Context, state TargetStatus, status HealthStatus) {
+	if len(s.cfg.Recipients) == 0 {
+		log.Printf("⚠️ [MONITOR] Cannot send %s alert for %s: no recipients configured", status, state.Name)
+		return
+	}
+	client, ok := s.waManager.GetClient(s.cfg.Session)
+	if !ok || !client.IsReady() {
+		log.Printf("⚠️ [MONITOR] Cannot send %s alert for %s: session %s is not ready", status, state.Name, s.cfg.Session)
+		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode >= 500 {
-		return StatusDown, latency
+	message := templates.GenerateHealthAlert(string(status), state.LatencyMs, time.Now()) +
+		fmt.Sprintf("\n\n🎯 Target: *%s* (%s)", state.Name, state.endpoint())
+	if status == StatusDown && state.Error != "" {
+		message += "\n❗ " + state.Error
 	}
 
-	if latency > 5000 {
-		return StatusSlow, latency
+	for _, recipient := range s.cfg.Recipients {
+		jid, err := recipientJID(recipient)
+		if err != nil {
+			log.Printf("❌ [MONITOR] Invalid alert recipient %q: %v", recipient, err)
+			continue
+		}
+		_, err = client.WAClient.SendMessage(ctx, jid, &waProto.Message{
+			Conversation: proto.String(message),
+		})
+		if err != nil {
+			log.Printf("❌ [MONITOR] Failed to send alert to %s: %v", recipient, err)
+		} else {
+			log.Printf("📤 [MONITOR] %s alert for %s sent to %s", status, state.Name, recipient)
+		}
 	}
+}
 
-	return StatusUp, latency
+// recipientJID accepts a phone number or a full JID such as a group (…@g.us)
+func recipientJID(recipient string) (types.JID, error) {
+	if strings.Contains(recipient, "@") {
+		return types.ParseJID(recipient)
+	}
+	return types.NewJID(recipient, types.DefaultUserServer), nil
 }
 
-// sendAlert sends a WhatsApp alert
-func (s *MonitorService) sendAlert(ctx context.Context, status HealthStatus, latency int) {
-	if s.waClient == nil || s.alertPhone == "" {
-		log.Printf("⚠️ [MONITOR] Cannot send alert: client or phone not configured")
+func (s *MonitorService) pruneHistory(ctx context.Context) {
+	deleted, err := s.repo.PruneChecks(ctx, time.Now().AddDate(0, 0, -s.cfg.HistoryDays))
+	if err != nil {
+		log.Printf("⚠️ [MONITOR] Failed to prune check history: %v", err)
 		return
 	}
+	if deleted > 0 {
+		log.Printf("🗑️ [MONITOR] Pruned %d old checks", deleted)
+	}
+}
 
-	var message string
-	timestamp := time.Now()
+// GetStatus returns the live state of every target, in configuration order
+func (s *MonitorService) GetStatus() []TargetStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	switch status {
-	case StatusRecovery:
-		message = fmt.Sprintf("✅ *SISTEM PULIH*\n\n🕐 %s\n\nSistem Valpro Intertech kembali online setelah mengalami gangguan.",
-			timestamp.Format("02 Jan 2006 15:04"))
-	case StatusSlow:
-		message = fmt.Sprintf("⚠️ *SISTEM LAMBAT*\n\n🕐 %s\n⏱️ Latency: %dms\n\nRespon sistem lebih lambat dari normal (> 5 detik).",
-			timestamp.Format("02 Jan 2006 15:04"), latency)
-	case StatusDown:
-		message = fmt.Sprintf("🚨 *SISTEM DOWN*\n\n🕐 %s\n⏱️ Down sejak: %s\n\nSistem tidak dapat diakses. Tim teknis sedang menangani.",
-			timestamp.Format("02 Jan 2006 15:04"), s.downSince.Format("15:04"))
-	default:
-		return
+	statuses := make([]TargetStatus, 0, len(s.cfg.Targets))
+	for _, target := range s.cfg.Targets {
+		statuses = append(statuses, *s.states[target.Name])
 	}
+	return statuses
+}
 
-	jid := types.NewJID(s.alertPhone, types.DefaultUserServer)
-	_, err := s.waClient.SendMessage(ctx, jid, &waProto.Message{
-		Conversation: proto.String(message),
-	})
-	if err != nil {
-		log.Printf("❌ [MONITOR] Failed to send alert: %v", err)
-	} else {
-		log.Printf("📤 [MONITOR] Alert sent for status: %s", status)
-	}
+// HasTarget reports whether a target is configured
+func (s *MonitorService) HasTarget(name string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.states[name]
+	return ok
 }
 
-// GetStatus returns current health status (for API)
-func (s *MonitorService) GetStatus() map[string]interface{} {
-	return map[string]interface{}{
-		"lastStatus": s.lastStatus,
-		"downSince":  s.downSince,
-		"alertSent":  s.alertSent,
-		"slowCount":  s.slowCount,
-	}
+// History returns the most recent checks of a target first
+func (s *MonitorService) History(ctx context.Context, target string, limit int) ([]Check, error) {
+	return s.repo.ListChecks(ctx, target, limit)
 }
@@ -0,0 +1,165 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Target types
+const (
+	TargetHTTP = "http"
+	TargetTCP  = "tcp"
+)
+
+// Target is an endpoint checked by the monitor
+type Target struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`              // http (default) or tcp
+	URL     string `json:"url,omitempty"`     // http targets
+	Address string `json:"address,omitempty"` // tcp targets, host:port
+
+	SlowMs           int    `json:"slowMs,omitempty"`           // latency above this is slow (default 5000)
+	SlowChecks       int    `json:"slowChecks,omitempty"`       // consecutive slow checks before alerting (default 3)
+	DownGraceSeconds int    `json:"downGraceSeconds,omitempty"` // downtime before alerting (default 300)
+	ExpectStatus     int    `json:"expectStatus,omitempty"`     // required HTTP status; default is any status below 500
+	ExpectBody       string `json:"expectBody,omitempty"`       // substring the HTTP body must contain
+	TimeoutSeconds   int    `json:"timeoutSeconds,omitempty"`   // default 10
+}
+
+// ParseTargets reads a JSON array of targets and applies defaults
+func ParseTargets(value string) ([]Target, error) {
+	var targets []Target
+	if err := json.Unmarshal([]byte(value), &targets); err != nil {
+		return nil, fmt.Errorf("invalid monitor targets: %w", err)
+	}
+	seen := make(map[string]bool)
+	for i := range targets {
+		if err := targets[i].normalize(); err != nil {
+			return nil, err
+		}
+		if seen[targets[i].Name] {
+			return nil, fmt.Errorf("duplicate monitor target %q", targets[i].Name)
+		}
+		seen[targets[i].Name] = true
+	}
+	return targets, nil
+}
+
+// HealthTarget returns the default target: the web app's health endpoint
+func HealthTarget(webURL string) Target {
+	t := Target{Name: "web", URL: fmt.Sprintf("%s/api/health", webURL)}
+	_ = t.normalize()
+	return t
+}
+
+func (t *Target) normalize() error {
+	if t.Type == "" {
+		t.Type = TargetHTTP
+	}
+	switch t.Type {
+	case TargetHTTP:
+		if t.URL == "" {
+			return fmt.Errorf("monitor target %q needs a url", t.Name)
+		}
+	case TargetTCP:
+		if t.Address == "" {
+			return fmt.Errorf("monitor target %q needs an address", t.Name)
+		}
+	default:
+		return fmt.Errorf("monitor target %q has unknown type %q", t.Name, t.Type)
+	}
+	if t.Name == "" || strings.ContainsAny(t.Name, "/?#") {
+		return fmt.Errorf("monitor target name %q is empty or contains / ? #", t.Name)
+	}
+	if t.SlowMs <= 0 {
+		t.SlowMs = 5000
+	}
+	if t.SlowChecks <= 0 {
+		t.SlowChecks = 3
+	}
+	if t.DownGraceSeconds <= 0 {
+		t.DownGraceSeconds = 300
+	}
+	if t.TimeoutSeconds <= 0 {
+		t.TimeoutSeconds = 10
+	}
+	return nil
+}
+
+// endpoint returns the URL or address checked
+func (t *Target) endpoint() string {
+	if t.Type == TargetTCP {
+		return t.Address
+	}
+	return t.URL
+}
+
+// check performs one check of the target
+func (t *Target) check(ctx context.Context) Check {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(t.TimeoutSeconds)*time.Second)
+	defer cancel()
+
+	result := Check{Target: t.Name, CheckedAt: time.Now()}
+	start := time.Now()
+	var err error
+	if t.Type == TargetTCP {
+		err = t.dial(ctx)
+	} else {
+		result.StatusCode, err = t.get(ctx)
+	}
+	result.LatencyMs = int(time.Since(start).Milliseconds())
+
+	switch {
+	case err != nil:
+		result.Status = StatusDown
+		result.Error = err.Error()
+	case result.LatencyMs > t.SlowMs:
+		result.Status = StatusSlow
+	default:
+		result.Status = StatusUp
+	}
+	return result
+}
+
+func (t *Target) dial(ctx context.Context) error {
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", t.Address)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func (t *Target) get(ctx context.Context) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.URL, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if t.ExpectStatus != 0 && resp.StatusCode != t.ExpectStatus {
+		return resp.StatusCode, fmt.Errorf("expected HTTP %d, got %d", t.ExpectStatus, resp.StatusCode)
+	}
+	if t.ExpectStatus == 0 && resp.StatusCode >= 500 {
+		return resp.StatusCode, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	if t.ExpectBody != "" {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		if err != nil {
+			return resp.StatusCode, fmt.Errorf("failed to read body: %w", err)
+		}
+		if !strings.Contains(string(body), t.ExpectBody) {
+			return resp.StatusCode, fmt.Errorf("body does not contain %q", t.ExpectBody)
+		}
+	}
+	return resp.StatusCode, nil
+}
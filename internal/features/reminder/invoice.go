@@ -0,0 +1,84 @@
+package reminder
+
+import (
+	"context"
+	"time"
+)
+
+// InvoiceStatus is the payment state of an invoice
+type InvoiceStatus string
+
+const (
+	StatusUnpaid    InvoiceStatus = "unpaid"
+	StatusPartial   InvoiceStatus = "partial"
+	StatusPaid      InvoiceStatus = "paid"
+	StatusCancelled InvoiceStatus = "cancelled"
+)
+
+// Valid reports whether s is a known status
+func (s InvoiceStatus) Valid() bool {
+	switch s {
+	case StatusUnpaid, StatusPartial, StatusPaid, StatusCancelled:
+		return true
+	}
+	return false
+}
+
+// Open reports whether reminders are still sent for the status
+func (s InvoiceStatus) Open() bool {
+	return s == StatusUnpaid || s == StatusPartial
+}
+
+// Invoice is an invoice the server sends reminders for
+type Invoice struct {
+	ID         string        `json:"id"`
+	Number     string        `json:"number"`
+	ClientName string        `json:"clientName"`
+	Phone      string        `json:"phone"`
+	DueDate    time.Time     `json:"dueDate"`
+	Amount     int64         `json:"amount"` // remaining amount in Rupiah
+	Status     InvoiceStatus `json:"status"`
+	PdfURL     string        `json:"pdfUrl,omitempty"`
+	Session    string        `json:"session"`
+	CreatedAt  time.Time     `json:"createdAt"`
+	UpdatedAt  time.Time     `json:"updatedAt"`
+	PaidAt     *time.Time    `json:"paidAt,omitempty"`
+}
+
+// DunningStep records one reminder sent (or attempted) for an invoice. A step that failed to queue
+// is retried in the same record until it succeeds.
+type DunningStep struct {
+	ID        int64     `json:"id"`
+	InvoiceID string    `json:"invoiceId"`
+	Step      string    `json:"step"`     // reminder, overdue-<days>, paid
+	Template  string    `json:"template"` // templates.GenerateInvoiceMessage status key
+	DueDate   time.Time `json:"dueDate"`  // due date of the invoice the step was sent for; zero for steps recorded before it was kept
+	JobID     string    `json:"jobId,omitempty"`
+	Error     string    `json:"error,omitempty"` // error of the last attempt
+	Attempts  int       `json:"attempts"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"` // last attempt
+}
+
+// Repository persists invoices and their dunning history
+type Repository interface {
+	// Save inserts or updates an invoice; invoice numbers are unique
+	Save(ctx context.Context, invoice *Invoice) error
+	// Get returns an invoice by ID, or nil if it does not exist
+	Get(ctx context.Context, id string) (*Invoice, error)
+	// GetByNumber returns an invoice by number, or nil if it does not exist
+	GetByNumber(ctx context.Context, number string) (*Invoice, error)
+	// List returns invoices by due date, optionally filtered by status and by session (empty means all)
+	List(ctx context.Context, status InvoiceStatus, sessions []string, limit int) ([]Invoice, error)
+	// ListOpen returns unpaid and partially paid invoices due on or before the given time
+	ListOpen(ctx context.Context, dueBefore time.Time) ([]Invoice, error)
+
+	AddStep(ctx context.Context, step *DunningStep) error
+	// UpdateStep stores the outcome of another attempt of a recorded step
+	UpdateStep(ctx context.Context, step *DunningStep) error
+	// ListSteps returns the dunning history of an invoice, oldest first
+	ListSteps(ctx context.Context, invoiceID string) ([]DunningStep, error)
+}
+
+// SendFunc queues an invoice message and returns the outbox job ID
+type SendFunc func(ctx context.Context, invoice *Invoice, message string) (string, error)
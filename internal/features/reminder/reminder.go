@@ -0,0 +1,343 @@
+package reminder
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"wa-server-go/internal/templates"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Config controls when reminders are sent
+type Config struct {
+	Timezone    string
+	SendHour    int   // local hour reminders go out
+	OverdueDays []int // days after the due date an overdue notice is sent, ascending
+}
+
+// DefaultConfig returns default reminder configuration
+func DefaultConfig() Config {
+	return Config{
+		Timezone:    "Asia/Jakarta",
+		SendHour:    9,
+		OverdueDays: []int{1, 3, 7, 14, 30},
+	}
+}
+
+// step is a dunning step and the window in which it may be sent
+type step struct {
+	name     string
+	template string
+	from     time.Time
+	until    time.Time // zero means no end
+}
+
+// InvoiceReminderService sends invoice reminders and overdue notices based on due dates
+type InvoiceReminderService struct {
+	repo     Repository
+	cfg      Config
+	loc      *time.Location
+	send     SendFunc
+	cron     *cron.Cron
+	onUpdate func(Invoice, DunningStep)
+}
+
+// NewInvoiceReminderService creates a new reminder service
+func NewInvoiceReminderService(repo Repository, cfg Config) (*InvoiceReminderService, error) {
+	loc, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", cfg.Timezone, err)
+	}
+	if cfg.SendHour < 0 || cfg.SendHour > 23 {
+		cfg.SendHour = DefaultConfig().SendHour
+	}
+	return &InvoiceReminderService{
+		repo: repo,
+		cfg:  cfg,
+		loc:  loc,
+		cron: cron.New(cron.WithLocation(loc)),
+	}, nil
+}
+
+// SetSender sets the function that queues invoice messages
+func (s *InvoiceReminderService) SetSender(fn SendFunc) {
+	s.send = fn
+}
+
+// OnUpdate sets a callback invoked for every dunning step recorded
+func (s *InvoiceReminderService) OnUpdate(fn func(Invoice, DunningStep)) {
+	s.onUpdate = fn
+}
+
+// Location returns the timezone due dates are interpreted in
+func (s *InvoiceReminderService) Location() *time.Location {
+	return s.loc
+}
+
+// Start checks invoices every 15 minutes (and once right away)
+func (s *InvoiceReminderService) Start(ctx context.Context) error {
+	_, err := s.cron.AddFunc("*/15 * * * *", func() {
+		s.RunDue(ctx)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to schedule invoice reminders: %w", err)
+	}
+
+	s.cron.Start()
+	go s.RunDue(ctx)
+	log.Printf("✅ [REMINDER] Scheduler started (sends at %02d:00 %s, overdue after %v days)", s.cfg.SendHour, s.cfg.Timezone, s.cfg.OverdueDays)
+	return nil
+}
+
+// Stop stops the reminder cron job
+func (s *InvoiceReminderService) Stop() {
+	s.cron.Stop()
+}
+
+// Save creates or updates an invoice by number; reminders restart from the current due date
+func (s *InvoiceReminderService) Save(ctx context.Context, invoice *Invoice) error {
+	if invoice.Number == "" || invoice.Phone == "" {
+		return fmt.Errorf("number and phone are required")
+	}
+	if invoice.DueDate.IsZero() {
+		return fmt.Errorf("dueDate is required")
+	}
+	if invoice.Status != "" && !invoice.Status.Valid() {
+		return fmt.Errorf("invalid status %q", invoice.Status)
+	}
+
+	now := time.Now()
+	existing, err := s.repo.GetByNumber(ctx, invoice.Number)
+	if err != nil {
+		return err
+	}
+	if invoice.Status == "" {
+		// An update without a status keeps the current one, so a paid invoice is not reopened
+		invoice.Status = StatusUnpaid
+		if existing != nil {
+			invoice.Status = existing.Status
+		}
+	}
+	if existing != nil {
+		invoice.ID = existing.ID
+		invoice.CreatedAt = existing.CreatedAt
+		invoice.PaidAt = existing.PaidAt
+	} else {
+		if invoice.ID, err = newInvoiceID(); err != nil {
+			return err
+		}
+		invoice.CreatedAt = now
+	}
+	if invoice.Status == StatusPaid && invoice.PaidAt == nil {
+		invoice.PaidAt = &now
+	}
+	invoice.UpdatedAt = now
+
+	return s.repo.Save(ctx, invoice)
+}
+
+// Get returns an invoice by ID, or nil if it does not exist
+func (s *InvoiceReminderService) Get(ctx context.Context, id string) (*Invoice, error) {
+	return s.repo.Get(ctx, id)
+}
+
+// GetByNumber returns an invoice by number, or nil if it does not exist
+func (s *InvoiceReminderService) GetByNumber(ctx context.Context, number string) (*Invoice, error) {
+	return s.repo.GetByNumber(ctx, number)
+}
+
+// List returns invoices by due date, optionally filtered by status and by session (empty means all)
+func (s *InvoiceReminderService) List(ctx context.Context, status InvoiceStatus, sessions []string, limit int) ([]Invoice, error) {
+	return s.repo.List(ctx, status, sessions, limit)
+}
+
+// Steps returns the dunning history of an invoice
+func (s *InvoiceReminderService) Steps(ctx context.Context, id string) ([]DunningStep, error) {
+	return s.repo.ListSteps(ctx, id)
+}
+
+// MarkPaid stops reminders for an invoice and optionally sends the "paid" confirmation
+func (s *InvoiceReminderService) MarkPaid(ctx context.Context, id string, notify bool) (*Invoice, error) {
+	invoice, err := s.repo.Get(ctx, id)
+	if err != nil || invoice == nil {
+		return invoice, err
+	}
+	if invoice.Status == StatusPaid {
+		return invoice, nil
+	}
+
+	now := time.Now()
+	invoice.Status = StatusPaid
+	invoice.Amount = 0
+	invoice.PaidAt = &now
+	invoice.UpdatedAt = now
+	if err := s.repo.Save(ctx, invoice); err != nil {
+		return nil, err
+	}
+	log.Printf("✅ [REMINDER] Invoice %s marked paid", invoice.Number)
+
+	if notify {
+		s.sendStep(ctx, invoice, step{name: "paid", template: "paid"}, nil)
+	}
+	return invoice, nil
+}
+
+// RunDue sends the current dunning step of every open invoice that has not received it for its current due date
+func (s *InvoiceReminderService) RunDue(ctx context.Context) {
+	now := time.Now().In(s.loc)
+	// Reminders start the day before the due date
+	invoices, err := s.repo.ListOpen(ctx, now.AddDate(0, 0, 1))
+	if err != nil {
+		log.Printf("⚠️ [REMINDER] Failed to list open invoices: %v", err)
+		return
+	}
+
+	for i := range invoices {
+		invoice := &invoices[i]
+		current, ok := s.currentStep(invoice, now)
+		if !ok {
+			continue
+		}
+
+		history, err := s.repo.ListSteps(ctx, invoice.ID)
+		if err != nil {
+			log.Printf("⚠️ [REMINDER] Failed to load history of %s: %v", invoice.Number, err)
+			continue
+		}
+		last := lastStep(history, current.name, invoice.DueDate)
+		if last != nil && last.Error == "" {
+			continue
+		}
+
+		s.sendStep(ctx, invoice, current, last)
+	}
+}
+
+// currentStep returns the dunning step whose window contains now.
+// Only the latest step is sent, so an invoice imported late does not receive a burst of notices.
+func (s *InvoiceReminderService) currentStep(invoice *Invoice, now time.Time) (step, bool) {
+	for _, st := range s.steps(invoice) {
+		if !now.Before(st.from) && (st.until.IsZero() || now.Before(st.until)) {
+			return st, true
+		}
+	}
+	return step{}, false
+}
+
+// steps lists the dunning steps of an invoice in order
+func (s *InvoiceReminderService) steps(invoice *Invoice) []step {
+	due := invoice.DueDate.In(s.loc)
+	dueDay := time.Date(due.Year(), due.Month(), due.Day(), 0, 0, 0, 0, s.loc)
+	at := func(days int) time.Time {
+		return dueDay.AddDate(0, 0, days).Add(time.Duration(s.cfg.SendHour) * time.Hour)
+	}
+
+	// "reminder" says the invoice is due tomorrow, so it is only valid until the due date starts.
+	// A partially paid invoice gets the "partial" message, which also asks to settle the rest before the due date.
+	reminderTemplate := "reminder"
+	if invoice.Status == StatusPartial {
+		reminderTemplate = "partial"
+	}
+	steps := []step{{name: "reminder", template: reminderTemplate, from: at(-1), until: dueDay}}
+	for i, days := range s.cfg.OverdueDays {
+		st := step{name: fmt.Sprintf("overdue-%d", days), template: "overdue", from: at(days)}
+		if i+1 < len(s.cfg.OverdueDays) {
+			st.until = at(s.cfg.OverdueDays[i+1])
+		}
+		steps = append(steps, st)
+	}
+	return steps
+}
+
+// sendStep queues the message of a dunning step and records it. A failed earlier attempt of the
+// step is passed as retry and updated instead of adding a record per attempt.
+func (s *InvoiceReminderService) sendStep(ctx context.Context, invoice *Invoice, st step, retry *DunningStep) {
+	now := time.Now()
+	record := retry
+	if record == nil {
+		record = &DunningStep{InvoiceID: invoice.ID, Step: st.name, CreatedAt: now}
+	}
+	record.Template = st.template
+	record.DueDate = invoice.DueDate
+	record.JobID, record.Error = "", ""
+	record.Attempts++
+	record.UpdatedAt = now
+
+	var err error
+	if s.send == nil {
+		err = errors.New("no sender configured")
+	} else {
+		record.JobID, err = s.send(ctx, invoice, s.Message(invoice, st.template))
+	}
+	if err != nil {
+		record.Error = err.Error()
+		log.Printf("❌ [REMINDER] Failed to queue %s for invoice %s: %v", st.name, invoice.Number, err)
+	} else {
+		log.Printf("📤 [REMINDER] Queued %s for invoice %s (job %s)", st.name, invoice.Number, record.JobID)
+	}
+
+	if record.ID == 0 {
+		err = s.repo.AddStep(ctx, record)
+	} else {
+		err = s.repo.UpdateStep(ctx, record)
+	}
+	if err != nil {
+		log.Printf("⚠️ [REMINDER] Failed to record %s for invoice %s: %v", st.name, invoice.Number, err)
+	}
+	if s.onUpdate != nil {
+		s.onUpdate(*invoice, *record)
+	}
+}
+
+// Message renders the invoice template for the given status key
+func (s *InvoiceReminderService) Message(invoice *Invoice, statusKey string) string {
+	return templates.GenerateInvoiceMessage(templates.InvoiceTemplateData{
+		ClientName:      invoice.ClientName,
+		InvoiceNumber:   invoice.Number,
+		DueDate:         invoice.DueDate.In(s.loc).Format("02 January 2006"),
+		Status:          statusLabel(invoice.Status),
+		StatusKey:       statusKey,
+		RemainingAmount: templates.FormatRupiah(invoice.Amount),
+	})
+}
+
+// lastStep returns the latest record of a step sent for the given due date, or nil. A changed due date
+// starts the steps over; records from before due dates were kept count for any due date.
+func lastStep(history []DunningStep, name string, dueDate time.Time) *DunningStep {
+	for i := len(history) - 1; i >= 0; i-- {
+		h := &history[i]
+		if h.Step == name && (h.DueDate.IsZero() || h.DueDate.Equal(dueDate)) {
+			return h
+		}
+	}
+	return nil
+}
+
+// statusLabel returns the Indonesian display label of a status
+func statusLabel(status InvoiceStatus) string {
+	switch status {
+	case StatusPaid:
+		return "Lunas"
+	case StatusPartial:
+		return "Dibayar Sebagian"
+	case StatusCancelled:
+		return "Dibatalkan"
+	default:
+		return "Belum Lunas"
+	}
+}
+
+// newInvoiceID generates a random invoice ID
+func newInvoiceID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,240 @@
+package reminder
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memRepository is an in-memory Repository
+type memRepository struct {
+	mu       sync.Mutex
+	invoices map[string]Invoice
+	steps    []DunningStep
+}
+
+func newMemRepository() *memRepository {
+	return &memRepository{invoices: make(map[string]Invoice)}
+}
+
+func (r *memRepository) Save(ctx context.Context, invoice *Invoice) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.invoices[invoice.ID] = *invoice
+	return nil
+}
+
+func (r *memRepository) Get(ctx context.Context, id string) (*Invoice, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	invoice, ok := r.invoices[id]
+	if !ok {
+		return nil, nil
+	}
+	return &invoice, nil
+}
+
+func (r *memRepository) GetByNumber(ctx context.Context, number string) (*Invoice, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, invoice := range r.invoices {
+		if invoice.Number == number {
+			return &invoice, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memRepository) List(ctx context.Context, status InvoiceStatus, sessions []string, limit int) ([]Invoice, error) {
+	return nil, nil
+}
+
+func (r *memRepository) ListOpen(ctx context.Context, dueBefore time.Time) ([]Invoice, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var open []Invoice
+	for _, invoice := range r.invoices {
+		if invoice.Status.Open() && !invoice.DueDate.After(dueBefore) {
+			open = append(open, invoice)
+		}
+	}
+	return open, nil
+}
+
+func (r *memRepository) AddStep(ctx context.Context, step *DunningStep) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	step.ID = int64(len(r.steps) + 1)
+	r.steps = append(r.steps, *step)
+	return nil
+}
+
+func (r *memRepository) UpdateStep(ctx context.Context, step *DunningStep) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.steps[step.ID-1] = *step
+	return nil
+}
+
+func (r *memRepository) ListSteps(ctx context.Context, invoiceID string) ([]DunningStep, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var steps []DunningStep
+	for _, step := range r.steps {
+		if step.InvoiceID == invoiceID {
+			steps = append(steps, step)
+		}
+	}
+	return steps, nil
+}
+
+func newTestService(t *testing.T, repo Repository) *InvoiceReminderService {
+	t.Helper()
+	s, err := NewInvoiceReminderService(repo, Config{Timezone: "Asia/Jakarta", SendHour: 9, OverdueDays: []int{1, 3, 7}})
+	if err != nil {
+		t.Fatalf("NewInvoiceReminderService: %v", err)
+	}
+	return s
+}
+
+func TestCurrentStep(t *testing.T) {
+	s := newTestService(t, newMemRepository())
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, time.March, day, hour, minute, 0, 0, s.Location())
+	}
+	due := at(10, 15, 0) // the time of day of the due date does not matter
+
+	tests := []struct {
+		name         string
+		now          time.Time
+		status       InvoiceStatus
+		wantStep     string // empty means no step is due
+		wantTemplate string
+	}{
+		{name: "two days before", now: at(8, 12, 0)},
+		{name: "day before, before the send hour", now: at(9, 8, 59)},
+		{name: "day before, at the send hour", now: at(9, 9, 0), wantStep: "reminder", wantTemplate: "reminder"},
+		{name: "day before, late evening", now: at(9, 23, 59), wantStep: "reminder", wantTemplate: "reminder"},
+		{name: "partially paid", now: at(9, 10, 0), status: StatusPartial, wantStep: "reminder", wantTemplate: "partial"},
+		{name: "due date", now: at(10, 12, 0)},
+		{name: "first overdue day", now: at(11, 9, 0), wantStep: "overdue-1", wantTemplate: "overdue"},
+		{name: "until the next step", now: at(13, 8, 59), wantStep: "overdue-1", wantTemplate: "overdue"},
+		{name: "next step", now: at(13, 9, 0), wantStep: "overdue-3", wantTemplate: "overdue"},
+		{name: "last step has no end", now: at(30, 9, 0), wantStep: "overdue-7", wantTemplate: "overdue"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := tt.status
+			if status == "" {
+				status = StatusUnpaid
+			}
+			st, ok := s.currentStep(&Invoice{DueDate: due, Status: status}, tt.now)
+			if !ok {
+				if tt.wantStep != "" {
+					t.Errorf("no step, want %s", tt.wantStep)
+				}
+				return
+			}
+			if st.name != tt.wantStep || st.template != tt.wantTemplate {
+				t.Errorf("step = %s (%s), want %s (%s)", st.name, st.template, tt.wantStep, tt.wantTemplate)
+			}
+		})
+	}
+}
+
+func TestLastStep(t *testing.T) {
+	due := time.Date(2026, time.March, 10, 0, 0, 0, 0, time.UTC)
+	moved := due.AddDate(0, 0, 7)
+
+	tests := []struct {
+		name    string
+		history []DunningStep
+		step    string
+		dueDate time.Time
+		wantID  int64 // 0 means none
+	}{
+		{name: "no history", step: "reminder", dueDate: due},
+		{name: "other step", history: []DunningStep{{ID: 1, Step: "reminder", DueDate: due}}, step: "overdue-1", dueDate: due},
+		{name: "same due date", history: []DunningStep{{ID: 1, Step: "reminder", DueDate: due}}, step: "reminder", dueDate: due, wantID: 1},
+		{name: "due date changed", history: []DunningStep{{ID: 1, Step: "reminder", DueDate: due}}, step: "reminder", dueDate: moved},
+		{name: "record without a due date counts for any", history: []DunningStep{{ID: 1, Step: "reminder"}}, step: "reminder", dueDate: moved, wantID: 1},
+		{
+			name: "latest record",
+			history: []DunningStep{
+				{ID: 1, Step: "reminder", DueDate: due},
+				{ID: 2, Step: "reminder", DueDate: moved},
+				{ID: 3, Step: "reminder", DueDate: due},
+			},
+			step: "reminder", dueDate: due, wantID: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := lastStep(tt.history, tt.step, tt.dueDate)
+			var id int64
+			if got != nil {
+				id = got.ID
+			}
+			if id != tt.wantID {
+				t.Errorf("lastStep = %d, want %d", id, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestRunDueRetriesFailedStepInPlace(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemRepository()
+	s := newTestService(t, repo)
+
+	sends := 0
+	fail := true
+	s.SetSender(func(ctx context.Context, invoice *Invoice, message string) (string, error) {
+		sends++
+		if fail {
+			return "", errors.New("no session")
+		}
+		return "job1", nil
+	})
+
+	// Due two days ago at noon: overdue-1 is due whatever the time of day
+	now := time.Now().In(s.Location())
+	dueDate := time.Date(now.Year(), now.Month(), now.Day()-2, 12, 0, 0, 0, s.Location())
+	invoice := &Invoice{ID: "inv1", Number: "INV-1", Phone: "62811", DueDate: dueDate, Status: StatusUnpaid}
+	if err := repo.Save(ctx, invoice); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	s.RunDue(ctx)
+	if len(repo.steps) != 1 || repo.steps[0].Error == "" || repo.steps[0].Attempts != 1 {
+		t.Fatalf("after a failed send: %+v", repo.steps)
+	}
+
+	fail = false
+	s.RunDue(ctx)
+	if len(repo.steps) != 1 {
+		t.Fatalf("a retry added a record: %+v", repo.steps)
+	}
+	if st := repo.steps[0]; st.Step != "overdue-1" || st.Error != "" || st.Attempts != 2 || st.JobID != "job1" {
+		t.Errorf("after the retry: %+v", st)
+	}
+
+	s.RunDue(ctx)
+	if sends != 2 {
+		t.Errorf("sends = %d after the step was queued, want 2", sends)
+	}
+
+	// A new due date starts the steps over, even on the same day
+	invoice.DueDate = dueDate.Add(time.Hour)
+	if err := repo.Save(ctx, invoice); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	s.RunDue(ctx)
+	if sends != 3 || len(repo.steps) != 2 {
+		t.Errorf("after the due date changed: %d sends, %d records, want 3 and 2", sends, len(repo.steps))
+	}
+}
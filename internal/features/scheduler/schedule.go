@@ -0,0 +1,76 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+)
+
+// Status is the lifecycle state of a scheduled message
+type Status string
+
+const (
+	StatusActive    Status = "active"
+	StatusPaused    Status = "paused"
+	StatusCompleted Status = "completed" // one-shot message that has been sent
+	StatusCancelled Status = "cancelled"
+)
+
+// Kinds of scheduled content
+const (
+	KindText  = "text"
+	KindMedia = "media"
+)
+
+// DefaultTimezone is used when a schedule does not name one (server timezone in the Dockerfile)
+const DefaultTimezone = "Asia/Jakarta"
+
+// ScheduledMessage is a message sent once at SendAt, or repeatedly on a cron expression
+type ScheduledMessage struct {
+	ID        string     `json:"id"`
+	Session   string     `json:"session"`
+	Recipient string     `json:"recipient"`
+	Kind      string     `json:"kind"`
+	Message   string     `json:"message,omitempty"`
+	MediaURL  string     `json:"mediaUrl,omitempty"`
+	MediaType string     `json:"mediaType,omitempty"`
+	SendAt    *time.Time `json:"sendAt,omitempty"`
+	Cron      string     `json:"cron,omitempty"`
+	Timezone  string     `json:"timezone"`
+	Status    Status     `json:"status"`
+	NextRunAt *time.Time `json:"nextRunAt,omitempty"`
+	LastRunAt *time.Time `json:"lastRunAt,omitempty"`
+	RunCount  int        `json:"runCount"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
+
+// Execution records one firing of a schedule
+type Execution struct {
+	ID         int64     `json:"id"`
+	ScheduleID string    `json:"scheduleId"`
+	JobID      string    `json:"jobId,omitempty"` // outbox job carrying the send
+	Error      string    `json:"error,omitempty"`
+	RunAt      time.Time `json:"runAt"`
+}
+
+// Repository persists schedules and their execution history
+type Repository interface {
+	Create(ctx context.Context, msg *ScheduledMessage) error
+	// Get returns a schedule by ID, or nil if it does not exist
+	Get(ctx context.Context, id string) (*ScheduledMessage, error)
+	// List returns schedules, newest first, optionally filtered by status and by session (empty means all)
+	List(ctx context.Context, status Status, sessions []string, limit int) ([]ScheduledMessage, error)
+	// ListDue returns active schedules whose next run is at or before now
+	ListDue(ctx context.Context, now time.Time, limit int) ([]ScheduledMessage, error)
+	Update(ctx context.Context, msg *ScheduledMessage) error
+	// UpdateRun saves a run of a schedule only if it is still active and due at dueAt; it reports
+	// false if the schedule was paused, cancelled or run since it was read
+	UpdateRun(ctx context.Context, msg *ScheduledMessage, dueAt time.Time) (bool, error)
+
+	AddExecution(ctx context.Context, exec *Execution) error
+	// ListExecutions returns the most recent executions of a schedule first
+	ListExecutions(ctx context.Context, scheduleID string, limit int) ([]Execution, error)
+}
+
+// SendFunc hands a due message to the outbox and returns the job ID
+type SendFunc func(ctx context.Context, msg *ScheduledMessage) (string, error)
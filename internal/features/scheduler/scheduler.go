@@ -0,0 +1,271 @@
+package scheduler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// pollInterval is how often due schedules are looked up
+const pollInterval = 15 * time.Second
+
+// ErrInvalidTransition is returned when pausing, resuming or cancelling a schedule in the wrong state
+var ErrInvalidTransition = errors.New("schedule cannot change to that state")
+
+// cronParser accepts standard 5-field expressions and descriptors such as @daily
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// SchedulerService fires scheduled messages into the outbox
+type SchedulerService struct {
+	repo            Repository
+	send            SendFunc
+	defaultTimezone string
+	onUpdate        func(ScheduledMessage)
+}
+
+// NewSchedulerService creates a new scheduler; an empty timezone means DefaultTimezone
+func NewSchedulerService(repo Repository, timezone string) *SchedulerService {
+	if timezone == "" {
+		timezone = DefaultTimezone
+	}
+	return &SchedulerService{
+		repo:            repo,
+		defaultTimezone: timezone,
+	}
+}
+
+// SetSender sets the function that enqueues due messages
+func (s *SchedulerService) SetSender(fn SendFunc) {
+	s.send = fn
+}
+
+// OnUpdate sets a callback invoked whenever a schedule fires or changes state
+func (s *SchedulerService) OnUpdate(fn func(ScheduledMessage)) {
+	s.onUpdate = fn
+}
+
+// Create validates and stores a new schedule and computes its first run
+func (s *SchedulerService) Create(ctx context.Context, msg *ScheduledMessage) error {
+	if msg.Timezone == "" {
+		msg.Timezone = s.defaultTimezone
+	}
+	loc, err := time.LoadLocation(msg.Timezone)
+	if err != nil {
+		return fmt.Errorf("invalid timezone %q: %w", msg.Timezone, err)
+	}
+
+	now := time.Now()
+	switch {
+	case msg.Cron != "" && msg.SendAt != nil:
+		return fmt.Errorf("provide either sendAt or cron, not both")
+	case msg.Cron != "":
+		schedule, err := cronParser.Parse(msg.Cron)
+		if err != nil {
+			return fmt.Errorf("invalid cron expression: %w", err)
+		}
+		next := schedule.Next(now.In(loc))
+		msg.NextRunAt = &next
+	case msg.SendAt != nil:
+		if !msg.SendAt.After(now) {
+			return fmt.Errorf("sendAt must be in the future")
+		}
+		next := *msg.SendAt
+		msg.NextRunAt = &next
+	default:
+		return fmt.Errorf("sendAt or cron is required")
+	}
+
+	id, err := newScheduleID()
+	if err != nil {
+		return err
+	}
+	msg.ID = id
+	msg.Status = StatusActive
+	msg.RunCount = 0
+	msg.LastRunAt = nil
+	msg.CreatedAt = now
+	msg.UpdatedAt = now
+
+	if err := s.repo.Create(ctx, msg); err != nil {
+		return fmt.Errorf("failed to store schedule: %w", err)
+	}
+	log.Printf("🗓️ [SCHEDULER] Scheduled %s to %s, next run %s", msg.ID, msg.Recipient, msg.NextRunAt.In(loc).Format(time.RFC3339))
+	s.notify(msg)
+	return nil
+}
+
+// Get returns a schedule by ID, or nil if it does not exist
+func (s *SchedulerService) Get(ctx context.Context, id string) (*ScheduledMessage, error) {
+	return s.repo.Get(ctx, id)
+}
+
+// List returns schedules, newest first, optionally filtered by status and by session (empty means all)
+func (s *SchedulerService) List(ctx context.Context, status Status, sessions []string, limit int) ([]ScheduledMessage, error) {
+	return s.repo.List(ctx, status, sessions, limit)
+}
+
+// Executions returns the execution history of a schedule, newest first
+func (s *SchedulerService) Executions(ctx context.Context, id string, limit int) ([]Execution, error) {
+	return s.repo.ListExecutions(ctx, id, limit)
+}
+
+// Pause stops an active schedule from firing
+func (s *SchedulerService) Pause(ctx context.Context, id string) (*ScheduledMessage, error) {
+	return s.transition(ctx, id, StatusPaused, StatusActive)
+}
+
+// Resume re-activates a paused schedule. Recurring schedules continue from the next occurrence;
+// one-shot messages whose time passed while paused are sent right away.
+func (s *SchedulerService) Resume(ctx context.Context, id string) (*ScheduledMessage, error) {
+	return s.transition(ctx, id, StatusActive, StatusPaused)
+}
+
+// Cancel permanently stops an active or paused schedule
+func (s *SchedulerService) Cancel(ctx context.Context, id string) (*ScheduledMessage, error) {
+	return s.transition(ctx, id, StatusCancelled, StatusActive, StatusPaused)
+}
+
+func (s *SchedulerService) transition(ctx context.Context, id string, to Status, from ...Status) (*ScheduledMessage, error) {
+	msg, err := s.repo.Get(ctx, id)
+	if err != nil || msg == nil {
+		return msg, err
+	}
+
+	allowed := false
+	for _, status := range from {
+		if msg.Status == status {
+			allowed = true
+		}
+	}
+	if !allowed {
+		return msg, ErrInvalidTransition
+	}
+
+	msg.Status = to
+	if to == StatusActive && msg.Cron != "" {
+		if next, err := nextRun(msg, time.Now()); err == nil {
+			msg.NextRunAt = &next
+		}
+	}
+	msg.UpdatedAt = time.Now()
+	if err := s.repo.Update(ctx, msg); err != nil {
+		return nil, err
+	}
+	log.Printf("🗓️ [SCHEDULER] Schedule %s is now %s", msg.ID, msg.Status)
+	s.notify(msg)
+	return msg, nil
+}
+
+// Start launches the polling loop; schedules that came due while the server was down fire once on start
+func (s *SchedulerService) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+
+		for {
+			s.runDue(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	log.Printf("✅ [SCHEDULER] Started (default timezone %s)", s.defaultTimezone)
+}
+
+// runDue fires every schedule that is due
+func (s *SchedulerService) runDue(ctx context.Context) {
+	now := time.Now()
+	due, err := s.repo.ListDue(ctx, now, 100)
+	if err != nil {
+		log.Printf("⚠️ [SCHEDULER] Failed to list due schedules: %v", err)
+		return
+	}
+
+	for i := range due {
+		s.fire(ctx, &due[i], now)
+	}
+}
+
+// fire advances a schedule and enqueues its run. The schedule is advanced first, on the condition that it
+// is still active and due, so a pause or cancel that raced with runDue is neither overwritten nor followed by a send.
+func (s *SchedulerService) fire(ctx context.Context, msg *ScheduledMessage, now time.Time) {
+	dueAt := *msg.NextRunAt
+	msg.RunCount++
+	msg.LastRunAt = &now
+	if msg.Cron == "" {
+		msg.Status = StatusCompleted
+		msg.NextRunAt = nil
+	} else if next, err := nextRun(msg, now); err != nil {
+		// The expression was validated on create, so this only happens after manual edits
+		log.Printf("⚠️ [SCHEDULER] Schedule %s has an invalid cron expression, pausing: %v", msg.ID, err)
+		msg.Status = StatusPaused
+	} else {
+		msg.NextRunAt = &next
+	}
+	msg.UpdatedAt = time.Now()
+
+	updated, err := s.repo.UpdateRun(ctx, msg, dueAt)
+	if err != nil {
+		log.Printf("⚠️ [SCHEDULER] Failed to persist schedule %s: %v", msg.ID, err)
+		return
+	}
+	if !updated {
+		log.Printf("🗓️ [SCHEDULER] Schedule %s changed before it fired, skipping", msg.ID)
+		return
+	}
+
+	exec := &Execution{ScheduleID: msg.ID, RunAt: now}
+	var jobID string
+	err = errors.New("no sender configured")
+	if s.send != nil {
+		jobID, err = s.send(ctx, msg)
+	}
+	if err != nil {
+		exec.Error = err.Error()
+		log.Printf("❌ [SCHEDULER] Schedule %s failed to enqueue: %v", msg.ID, err)
+	} else {
+		exec.JobID = jobID
+		log.Printf("📤 [SCHEDULER] Schedule %s queued as job %s", msg.ID, jobID)
+	}
+	if err := s.repo.AddExecution(ctx, exec); err != nil {
+		log.Printf("⚠️ [SCHEDULER] Failed to record execution of %s: %v", msg.ID, err)
+	}
+	s.notify(msg)
+}
+
+func (s *SchedulerService) notify(msg *ScheduledMessage) {
+	if s.onUpdate != nil {
+		s.onUpdate(*msg)
+	}
+}
+
+// nextRun returns the next cron occurrence after now in the schedule's timezone
+func nextRun(msg *ScheduledMessage, now time.Time) (time.Time, error) {
+	loc, err := time.LoadLocation(msg.Timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	schedule, err := cronParser.Parse(msg.Cron)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return schedule.Next(now.In(loc)), nil
+}
+
+// newScheduleID generates a random schedule ID
+func newScheduleID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
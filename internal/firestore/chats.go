@@ -2,47 +2,14 @@ package firestore
 
 import (
 	"context"
-	"strings"
 	"time"
 
+	"wa-server-go/internal/storage"
+
 	"cloud.google.com/go/firestore"
 	"google.golang.org/api/iterator"
 )
 
-// WAChat represents a WhatsApp chat in Firestore
-type WAChat struct {
-	ID              string    `firestore:"-"`
-	JID             string    `firestore:"jid"`
-	Name            string    `firestore:"name"`
-	Number          string    `firestore:"number"`
-	IsGroup         bool      `firestore:"isGroup"`
-	UnreadCount     int       `firestore:"unreadCount"`
-	LastMessageBody string    `firestore:"lastMessageBody,omitempty"`
-	LastMessageAt   time.Time `firestore:"lastMessageAt,omitempty"`
-	ProfilePicURL   string    `firestore:"profilePicUrl,omitempty"`
-	HasInvoice      bool      `firestore:"hasInvoice,omitempty"`
-	IsOTP           bool      `firestore:"isOTP,omitempty"`
-	UpdatedAt       time.Time `firestore:"updatedAt"`
-}
-
-// WAMessage represents a WhatsApp message in Firestore
-type WAMessage struct {
-	ID        string    `firestore:"-"`
-	MessageID string    `firestore:"messageId"`
-	ChatID    string    `firestore:"chatId"`
-	From      string    `firestore:"from"`
-	To        string    `firestore:"to"`
-	Body      string    `firestore:"body"`
-	Timestamp time.Time `firestore:"timestamp"`
-	FromMe    bool      `firestore:"fromMe"`
-	HasMedia  bool      `firestore:"hasMedia"`
-	MediaType string    `firestore:"mediaType,omitempty"`
-	MediaURL  string    `firestore:"mediaUrl,omitempty"`
-	Type      string    `firestore:"type"` // text, image, document, audio, video
-	Ack       int       `firestore:"ack"`
-	CreatedAt time.Time `firestore:"createdAt"`
-}
-
 // ChatsRepository provides access to the wa_chats and wa_messages collections
 type ChatsRepository struct {
 	client             *Client
@@ -60,7 +27,7 @@ func NewChatsRepository(client *Client) *ChatsRepository {
 }
 
 // GetRecentChats retrieves recent chats ordered by last message time
-func (r *ChatsRepository) GetRecentChats(ctx context.Context, limit int) ([]WAChat, error) {
+func (r *ChatsRepository) GetRecentChats(ctx context.Context, limit int) ([]storage.WAChat, error) {
 	query := r.client.Collection(r.chatsCollection).
 		Where("isOTP", "==", false).
 		OrderBy("lastMessageAt", firestore.Desc)
@@ -70,7 +37,7 @@ func (r *ChatsRepository) GetRecentChats(ctx context.Context, limit int) ([]WACh
 
 	iter := query.Documents(ctx)
 
-	var chats []WAChat
+	var chats []storage.WAChat
 	for {
 		doc, err := iter.Next()
 		if err == iterator.Done {
@@ -80,7 +47,7 @@ func (r *ChatsRepository) GetRecentChats(ctx context.Context, limit int) ([]WACh
 			return nil, err
 		}
 
-		var chat WAChat
+		var chat storage.WAChat
 		if err := doc.DataTo(&chat); err != nil {
 			continue
 		}
@@ -91,8 +58,29 @@ func (r *ChatsRepository) GetRecentChats(ctx context.Context, limit int) ([]WACh
 	return chats, nil
 }
 
+// GetChat returns a session's chat by JID, or nil if it does not exist
+func (r *ChatsRepository) GetChat(ctx context.Context, session, jid string) (*storage.WAChat, error) {
+	iter := r.chatQuery(session, jid).Documents(ctx)
+	defer iter.Stop()
+
+	doc, err := iter.Next()
+	if err == iterator.Done {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var chat storage.WAChat
+	if err := doc.DataTo(&chat); err != nil {
+		return nil, err
+	}
+	chat.ID = doc.Ref.ID
+	return &chat, nil
+}
+
 // GetChatMessages retrieves messages for a specific chat
-func (r *ChatsRepository) GetChatMessages(ctx context.Context, chatID string, limit int) ([]WAMessage, error) {
+func (r *ChatsRepository) GetChatMessages(ctx context.Context, chatID string, limit int) ([]storage.WAMessage, error) {
 	query := r.client.Collection(r.messagesCollection).
 		Where("chatId", "==", chatID).
 		OrderBy("timestamp", firestore.Desc)
@@ -102,7 +90,7 @@ func (r *ChatsRepository) GetChatMessages(ctx context.Context, chatID string, li
 
 	iter := query.Documents(ctx)
 
-	var messages []WAMessage
+	var messages []storage.WAMessage
 	for {
 		doc, err := iter.Next()
 		if err == iterator.Done {
@@ -112,7 +100,7 @@ func (r *ChatsRepository) GetChatMessages(ctx context.Context, chatID string, li
 			return nil, err
 		}
 
-		var msg WAMessage
+		var msg storage.WAMessage
 		if err := doc.DataTo(&msg); err != nil {
 			continue
 		}
@@ -123,13 +111,150 @@ func (r *ChatsRepository) GetChatMessages(ctx context.Context, chatID string, li
 	return messages, nil
 }
 
-// SaveMessage saves a new message and updates the chat
-func (r *ChatsRepository) SaveMessage(ctx context.Context, msg *WAMessage) error {
-	now := time.Now()
-	msg.CreatedAt = now
+// ListChats returns a page of chats, newest last message first.
+// Pages continue with StartAfter on (lastMessageAt, document ID). The unread filter is applied while
+// iterating, since Firestore cannot combine it with the lastMessageAt ordering without an extra index.
+func (r *ChatsRepository) ListChats(ctx context.Context, filter storage.ChatFilter) ([]storage.WAChat, string, error) {
+	cursor, err := storage.ParseCursor(filter.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	query := r.client.Collection(r.chatsCollection).Where("isOTP", "==", filter.OTP)
+	if filter.Session != "" {
+		query = query.Where("session", "==", filter.Session)
+	}
+	if filter.HasInvoice {
+		query = query.Where("hasInvoice", "==", true)
+	}
+	if filter.Groups != nil {
+		query = query.Where("isGroup", "==", *filter.Groups)
+	}
+	if !filter.Before.IsZero() {
+		query = query.Where("lastMessageAt", "<", filter.Before)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("lastMessageAt", ">=", filter.Since)
+	}
+	query = query.OrderBy("lastMessageAt", firestore.Desc).OrderBy(firestore.DocumentID, firestore.Desc)
+	if cursor != nil {
+		query = query.StartAfter(cursor.Time, cursor.ID)
+	}
+	if filter.Limit > 0 && !filter.UnreadOnly {
+		query = query.Limit(filter.Limit + 1)
+	}
+
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+
+	chats := []storage.WAChat{}
+	for filter.Limit <= 0 || len(chats) <= filter.Limit {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, "", err
+		}
+
+		var chat storage.WAChat
+		if err := doc.DataTo(&chat); err != nil {
+			continue
+		}
+		if filter.UnreadOnly && chat.UnreadCount == 0 {
+			continue
+		}
+		chat.ID = doc.Ref.ID
+		chats = append(chats, chat)
+	}
 
-	// Save message (Idempotent: Use MessageID as Document ID)
-	_, err := r.client.Collection(r.messagesCollection).Doc(msg.MessageID).Set(ctx, msg)
+	next := ""
+	if filter.Limit > 0 && len(chats) > filter.Limit {
+		chats = chats[:filter.Limit]
+		last := chats[len(chats)-1]
+		next = storage.NextCursor(last.LastMessageAt, last.ID)
+	}
+	return chats, next, nil
+}
+
+// ListChatMessages returns a page of the messages of a session's chat, newest first.
+// Pages continue with StartAfter on (timestamp, document ID).
+func (r *ChatsRepository) ListChatMessages(ctx context.Context, session, chatID string, filter storage.MessageFilter) ([]storage.WAMessage, string, error) {
+	cursor, err := storage.ParseCursor(filter.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	query := r.client.Collection(r.messagesCollection).Where("session", "==", session).Where("chatId", "==", chatID)
+	if !filter.Before.IsZero() {
+		query = query.Where("timestamp", "<", filter.Before)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("timestamp", ">=", filter.Since)
+	}
+	query = query.OrderBy("timestamp", firestore.Desc).OrderBy(firestore.DocumentID, firestore.Desc)
+	if cursor != nil {
+		query = query.StartAfter(cursor.Time, cursor.ID)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit + 1)
+	}
+
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+
+	messages := []storage.WAMessage{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, "", err
+		}
+
+		var msg storage.WAMessage
+		if err := doc.DataTo(&msg); err != nil {
+			continue
+		}
+		msg.ID = doc.Ref.ID
+		messages = append(messages, msg)
+	}
+
+	next := ""
+	if filter.Limit > 0 && len(messages) > filter.Limit {
+		messages = messages[:filter.Limit]
+		last := messages[len(messages)-1]
+		next = storage.NextCursor(last.Timestamp, last.ID)
+	}
+	return messages, next, nil
+}
+
+// SaveMessage saves a message and updates the chat.
+// A re-save merges into the session's stored message like the SQLite upsert: the ack level is never lowered,
+// and createdAt, media keys and sender are kept when the new save does not carry them.
+func (r *ChatsRepository) SaveMessage(ctx context.Context, msg *storage.WAMessage) error {
+	msg.CreatedAt = time.Now()
+	msg.SearchTokens = storage.SearchTokens(msg.Body)
+
+	// Idempotent: the session and MessageID make the document ID
+	ref := r.client.Collection(r.messagesCollection).Doc(messageDocID(msg.Session, msg.MessageID))
+	err := r.client.FS.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		// GetAll reports missing documents instead of returning a NotFound error
+		docs, err := tx.GetAll([]*firestore.DocumentRef{ref})
+		if err != nil {
+			return err
+		}
+		merged := *msg
+		if len(docs) > 0 && docs[0].Exists() {
+			var stored storage.WAMessage
+			if err := docs[0].DataTo(&stored); err != nil {
+				return err
+			}
+			mergeMessage(&merged, &stored)
+		}
+		return tx.Set(ref, &merged)
+	})
 	if err != nil {
 		return err
 	}
@@ -138,46 +263,252 @@ func (r *ChatsRepository) SaveMessage(ctx context.Context, msg *WAMessage) error
 	return r.updateChatFromMessage(ctx, msg)
 }
 
+// mergeMessage fills msg with what a re-save must not lose from the stored message
+func mergeMessage(msg, stored *storage.WAMessage) {
+	msg.Ack = max(msg.Ack, stored.Ack)
+	if !stored.CreatedAt.IsZero() {
+		msg.CreatedAt = stored.CreatedAt
+	}
+	if msg.MediaType == "" {
+		msg.MediaType = stored.MediaType
+	}
+	if msg.MediaURL == "" {
+		msg.MediaURL = stored.MediaURL
+	}
+	if msg.MediaDirectPath == "" {
+		msg.MediaDirectPath = stored.MediaDirectPath
+	}
+	if msg.MediaKey == nil {
+		msg.MediaKey = stored.MediaKey
+	}
+	if msg.MediaFileSHA256 == nil {
+		msg.MediaFileSHA256 = stored.MediaFileSHA256
+	}
+	if msg.MediaFileEncSHA256 == nil {
+		msg.MediaFileEncSHA256 = stored.MediaFileEncSHA256
+	}
+	msg.MediaFileLength = max(msg.MediaFileLength, stored.MediaFileLength)
+	if msg.SenderJID == "" {
+		msg.SenderJID = stored.SenderJID
+	}
+	if msg.SenderName == "" {
+		msg.SenderName = stored.SenderName
+	}
+}
+
+// SearchMessages returns a page of messages matching a full-text query, newest first.
+// Firestore has no text index: the longest query word is matched against the searchTokens
+// written by SaveMessage and the other words are checked while iterating, so words match
+// whole words only and messages saved before searchTokens existed are not found.
+// A session filter longer than an "in" query allows is also checked while iterating.
+func (r *ChatsRepository) SearchMessages(ctx context.Context, filter storage.SearchFilter) ([]storage.MessageHit, string, error) {
+	cursor, err := storage.ParseCursor(filter.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	tokens := storage.SearchTokens(filter.Query)
+	if len(tokens) == 0 {
+		return []storage.MessageHit{}, "", nil
+	}
+	longest := tokens[0]
+	for _, token := range tokens {
+		if len(token) > len(longest) {
+			longest = token
+		}
+	}
+
+	if filter.Sessions != nil && len(filter.Sessions) == 0 {
+		return []storage.MessageHit{}, "", nil
+	}
+
+	query := r.client.Collection(r.messagesCollection).Where("searchTokens", "array-contains", longest)
+	var sessions map[string]bool
+	if len(filter.Sessions) > maxInValues {
+		sessions = make(map[string]bool, len(filter.Sessions))
+		for _, session := range filter.Sessions {
+			sessions[session] = true
+		}
+	} else if filter.Sessions != nil {
+		query = query.Where("session", "in", filter.Sessions)
+	}
+	if filter.ChatID != "" {
+		query = query.Where("chatId", "==", filter.ChatID)
+	}
+	if filter.FromMe != nil {
+		query = query.Where("fromMe", "==", *filter.FromMe)
+	}
+	if filter.Type != "" {
+		query = query.Where("type", "==", filter.Type)
+	}
+	if filter.HasMedia != nil {
+		query = query.Where("hasMedia", "==", *filter.HasMedia)
+	}
+	if !filter.Before.IsZero() {
+		query = query.Where("timestamp", "<", filter.Before)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("timestamp", ">=", filter.Since)
+	}
+	query = query.OrderBy("timestamp", firestore.Desc).OrderBy(firestore.DocumentID, firestore.Desc)
+	if cursor != nil {
+		query = query.StartAfter(cursor.Time, cursor.ID)
+	}
+	if filter.Limit > 0 && len(tokens) == 1 && sessions == nil {
+		query = query.Limit(filter.Limit + 1)
+	}
+
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+
+	hits := []storage.MessageHit{}
+	for filter.Limit <= 0 || len(hits) <= filter.Limit {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, "", err
+		}
+
+		var msg storage.WAMessage
+		if err := doc.DataTo(&msg); err != nil {
+			continue
+		}
+		if !containsAll(msg.SearchTokens, tokens) || (sessions != nil && !sessions[msg.Session]) {
+			continue
+		}
+		msg.ID = doc.Ref.ID
+		hits = append(hits, storage.MessageHit{Message: msg, Snippet: storage.Snippet(msg.Body, tokens)})
+	}
+
+	next := ""
+	if filter.Limit > 0 && len(hits) > filter.Limit {
+		hits = hits[:filter.Limit]
+		last := hits[len(hits)-1].Message
+		next = storage.NextCursor(last.Timestamp, last.ID)
+	}
+	return hits, next, nil
+}
+
+// maxInValues is the most values a Firestore "in" filter accepts
+const maxInValues = 30
+
+// containsAll reports whether every one of want is in have
+func containsAll(have, want []string) bool {
+	set := make(map[string]bool, len(have))
+	for _, s := range have {
+		set[s] = true
+	}
+	for _, s := range want {
+		if !set[s] {
+			return false
+		}
+	}
+	return true
+}
+
+// GetMessage returns a session's message by ID, or nil if it does not exist
+func (r *ChatsRepository) GetMessage(ctx context.Context, session, messageID string) (*storage.WAMessage, error) {
+	// GetAll reports missing documents instead of returning a NotFound error
+	docs, err := r.client.FS.GetAll(ctx, []*firestore.DocumentRef{
+		r.client.Collection(r.messagesCollection).Doc(messageDocID(session, messageID)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 || !docs[0].Exists() {
+		return nil, nil
+	}
+
+	var msg storage.WAMessage
+	if err := docs[0].DataTo(&msg); err != nil {
+		return nil, err
+	}
+	msg.ID = docs[0].Ref.ID
+	return &msg, nil
+}
+
+// UpdateMessageMedia stores the local media URL, mime type and media keys of msg, in msg's session
+func (r *ChatsRepository) UpdateMessageMedia(ctx context.Context, msg *storage.WAMessage) error {
+	_, err := r.client.Collection(r.messagesCollection).Doc(messageDocID(msg.Session, msg.MessageID)).Update(ctx, []firestore.Update{
+		{Path: "mediaUrl", Value: msg.MediaURL},
+		{Path: "mediaType", Value: msg.MediaType},
+		{Path: "mediaDirectPath", Value: msg.MediaDirectPath},
+		{Path: "mediaKey", Value: msg.MediaKey},
+		{Path: "mediaFileSha256", Value: msg.MediaFileSHA256},
+		{Path: "mediaFileEncSha256", Value: msg.MediaFileEncSHA256},
+		{Path: "mediaFileLength", Value: msg.MediaFileLength},
+	})
+	return err
+}
+
+// UpdateMessageAck raises the ack level of the given messages of a session; it never lowers it
+func (r *ChatsRepository) UpdateMessageAck(ctx context.Context, session string, messageIDs []string, ack int) error {
+	if len(messageIDs) == 0 {
+		return nil
+	}
+
+	refs := make([]*firestore.DocumentRef, len(messageIDs))
+	for i, id := range messageIDs {
+		refs[i] = r.client.Collection(r.messagesCollection).Doc(messageDocID(session, id))
+	}
+
+	docs, err := r.client.FS.GetAll(ctx, refs)
+	if err != nil {
+		return err
+	}
+
+	batch := r.client.Batch()
+	updates := 0
+	for _, doc := range docs {
+		// Receipts may arrive for messages we never stored
+		if !doc.Exists() {
+			continue
+		}
+		current, _ := doc.DataAt("ack")
+		if n, ok := current.(int64); ok && int(n) >= ack {
+			continue
+		}
+		batch.Update(doc.Ref, []firestore.Update{{Path: "ack", Value: ack}})
+		updates++
+	}
+	if updates == 0 {
+		return nil
+	}
+
+	_, err = batch.Commit(ctx)
+	return err
+}
+
 // updateChatFromMessage updates chat info from a message
-func (r *ChatsRepository) updateChatFromMessage(ctx context.Context, msg *WAMessage) error {
+func (r *ChatsRepository) updateChatFromMessage(ctx context.Context, msg *storage.WAMessage) error {
 	// Find existing chat
-	iter := r.client.Collection(r.chatsCollection).
-		Where("jid", "==", msg.ChatID).
-		Limit(1).
-		Documents(ctx)
+	iter := r.chatQuery(msg.Session, msg.ChatID).Documents(ctx)
 
 	doc, err := iter.Next()
 	now := time.Now()
 
 	if err == iterator.Done {
 		// Create new chat
-		newChat := WAChat{
+		newChat := storage.WAChat{
+			Session:         msg.Session,
 			JID:             msg.ChatID,
 			Number:          msg.From,
-			IsGroup:         false, // Will be updated if needed
+			IsGroup:         storage.IsGroupJID(msg.ChatID),
 			UnreadCount:     0,
-			LastMessageBody: truncateBody(msg.Body),
+			LastMessageBody: storage.TruncateBody(msg.Body),
 			LastMessageAt:   msg.Timestamp,
 			UpdatedAt:       now,
-			HasInvoice:      strings.Contains(strings.ToUpper(msg.Body), "INV-") || strings.Contains(strings.ToLower(msg.Body), "invoice") || strings.Contains(strings.ToLower(msg.Body), "tagihan"),
-			IsOTP:           strings.Contains(strings.ToLower(msg.Body), "otp") || strings.Contains(strings.ToLower(msg.Body), "kode verifikasi") || strings.Contains(strings.ToLower(msg.Body), "verification code"),
+			HasInvoice:      storage.IsInvoiceBody(msg.Body),
+			IsOTP:           storage.IsOTPBody(msg.Body) || storage.IsOTPSender(msg.From),
 		}
 		if msg.FromMe {
 			newChat.Number = msg.To
 		} else {
 			newChat.UnreadCount = 1
 		}
-		
-		// Initial Filter Check for New Chat
-		// Check Body
-		bodyLower := strings.ToLower(msg.Body)
-		if strings.Contains(bodyLower, "otp") || strings.Contains(bodyLower, "kode verifikasi") || strings.Contains(bodyLower, "verification code") {
-			newChat.IsOTP = true
-		}
-		// Check Sender
-		sender := strings.ToLower(msg.From)
-		if strings.Contains(sender, "stockbit") || strings.Contains(sender, "628999800123") || strings.Contains(sender, "tri") {
-			newChat.IsOTP = true
+		if newChat.IsGroup {
+			newChat.Number = msg.ChatID
 		}
 
 		_, _, err = r.client.Collection(r.chatsCollection).Add(ctx, newChat)
@@ -189,34 +520,27 @@ func (r *ChatsRepository) updateChatFromMessage(ctx context.Context, msg *WAMess
 
 	// Update existing chat
 	updates := []firestore.Update{
-		{Path: "lastMessageBody", Value: truncateBody(msg.Body)},
+		{Path: "lastMessageBody", Value: storage.TruncateBody(msg.Body)},
 		{Path: "lastMessageAt", Value: msg.Timestamp},
 		{Path: "updatedAt", Value: now},
 	}
 	if !msg.FromMe {
 		updates = append(updates, firestore.Update{Path: "unreadCount", Value: firestore.Increment(1)})
 	}
+	if storage.IsGroupJID(msg.ChatID) {
+		// Older group chats stored the first sender as the number
+		updates = append(updates,
+			firestore.Update{Path: "number", Value: msg.ChatID},
+			firestore.Update{Path: "isGroup", Value: true})
+	}
 
 	// Check for invoice keywords to auto-mark as relevant
-	if strings.Contains(strings.ToUpper(msg.Body), "INV-") || strings.Contains(strings.ToLower(msg.Body), "invoice") || strings.Contains(strings.ToLower(msg.Body), "tagihan") {
+	if storage.IsInvoiceBody(msg.Body) {
 		updates = append(updates, firestore.Update{Path: "hasInvoice", Value: true})
 	}
 
-	// Check for OTP keywords to auto-mark as OTP
-	bodyLower := strings.ToLower(msg.Body)
-	isOTP := strings.Contains(bodyLower, "otp") || strings.Contains(bodyLower, "kode verifikasi") || strings.Contains(bodyLower, "verification code")
-	
-	// Filter specific senders (Stockbit, TRI)
-	// We check the JID/Number or Name if available in the doc (but doc is 'doc', here we just have msg and updates).
-	// We can't easily check Name from 'updates' unless we read 'doc' data again or used 'doc.DataTo' earlier.
-	// However, usually these come from specific numbers or sender IDs.
-	// For now, let's filter by Number in the 'msg.From' (JID).
-	sender := strings.ToLower(msg.From) // 62899... or stockbit
-	if strings.Contains(sender, "stockbit") || strings.Contains(sender, "628999800123") || strings.Contains(sender, "tri") {
-		isOTP = true
-	}
-
-	if isOTP {
+	// Check for OTP keywords and known OTP senders (Stockbit, TRI) to auto-mark as OTP
+	if storage.IsOTPBody(msg.Body) || storage.IsOTPSender(msg.From) {
 		updates = append(updates, firestore.Update{Path: "isOTP", Value: true})
 	}
 
@@ -224,12 +548,9 @@ func (r *ChatsRepository) updateChatFromMessage(ctx context.Context, msg *WAMess
 	return err
 }
 
-// MarkChatAsRead marks a chat as read
-func (r *ChatsRepository) MarkChatAsRead(ctx context.Context, chatJID string) error {
-	iter := r.client.Collection(r.chatsCollection).
-		Where("jid", "==", chatJID).
-		Limit(1).
-		Documents(ctx)
+// MarkChatAsRead marks a session's chat as read
+func (r *ChatsRepository) MarkChatAsRead(ctx context.Context, session, chatJID string) error {
+	iter := r.chatQuery(session, chatJID).Documents(ctx)
 
 	doc, err := iter.Next()
 	if err != nil {
@@ -243,9 +564,10 @@ func (r *ChatsRepository) MarkChatAsRead(ctx context.Context, chatJID string) er
 	return err
 }
 
-// GetInvoiceChats retrieves chats that contain invoice messages
-func (r *ChatsRepository) GetInvoiceChats(ctx context.Context, limit int) ([]WAChat, error) {
+// GetInvoiceChats retrieves a session's chats that contain invoice messages
+func (r *ChatsRepository) GetInvoiceChats(ctx context.Context, session string, limit int) ([]storage.WAChat, error) {
 	query := r.client.Collection(r.chatsCollection).
+		Where("session", "==", session).
 		Where("hasInvoice", "==", true).
 		OrderBy("lastMessageAt", firestore.Desc)
 	if limit > 0 {
@@ -254,7 +576,7 @@ func (r *ChatsRepository) GetInvoiceChats(ctx context.Context, limit int) ([]WAC
 
 	iter := query.Documents(ctx)
 
-	var chats []WAChat
+	var chats []storage.WAChat
 	for {
 		doc, err := iter.Next()
 		if err == iterator.Done {
@@ -264,7 +586,7 @@ func (r *ChatsRepository) GetInvoiceChats(ctx context.Context, limit int) ([]WAC
 			return nil, err
 		}
 
-		var chat WAChat
+		var chat storage.WAChat
 		if err := doc.DataTo(&chat); err != nil {
 			continue
 		}
@@ -276,11 +598,8 @@ func (r *ChatsRepository) GetInvoiceChats(ctx context.Context, limit int) ([]WAC
 }
 
 // SetChatHasInvoice marks a chat as relevant to invoices
-func (r *ChatsRepository) SetChatHasInvoice(ctx context.Context, jid string, hasInvoice bool) error {
-	iter := r.client.Collection(r.chatsCollection).
-		Where("jid", "==", jid).
-		Limit(1).
-		Documents(ctx)
+func (r *ChatsRepository) SetChatHasInvoice(ctx context.Context, session, jid string, hasInvoice bool) error {
+	iter := r.chatQuery(session, jid).Documents(ctx)
 
 	doc, err := iter.Next()
 	// If chat doesn't exist, we should create it or ignore. 
@@ -301,11 +620,8 @@ func (r *ChatsRepository) SetChatHasInvoice(ctx context.Context, jid string, has
 }
 
 // UpdateChatName updates the name of a chat
-func (r *ChatsRepository) UpdateChatName(ctx context.Context, jid string, name string) error {
-	iter := r.client.Collection(r.chatsCollection).
-		Where("jid", "==", jid).
-		Limit(1).
-		Documents(ctx)
+func (r *ChatsRepository) UpdateChatName(ctx context.Context, session, jid string, name string) error {
+	iter := r.chatQuery(session, jid).Documents(ctx)
 
 	doc, err := iter.Next()
 	if err == iterator.Done {
@@ -324,12 +640,37 @@ func (r *ChatsRepository) UpdateChatName(ctx context.Context, jid string, name s
 	return err
 }
 
+// UpsertChat creates a chat that has no messages yet, or renames it if it exists
+func (r *ChatsRepository) UpsertChat(ctx context.Context, session, jid string, name string) error {
+	iter := r.chatQuery(session, jid).Documents(ctx)
+
+	now := time.Now()
+	doc, err := iter.Next()
+	if err == iterator.Done {
+		_, _, err = r.client.Collection(r.chatsCollection).Add(ctx, storage.WAChat{
+			Session:   session,
+			JID:       jid,
+			Name:      name,
+			Number:    jid,
+			IsGroup:   storage.IsGroupJID(jid),
+			UpdatedAt: now,
+		})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = doc.Ref.Update(ctx, []firestore.Update{
+		{Path: "name", Value: name},
+		{Path: "updatedAt", Value: now},
+	})
+	return err
+}
+
 // UpdateChatProfilePic updates the profile picture of a chat
-func (r *ChatsRepository) UpdateChatProfilePic(ctx context.Context, jid string, url string) error {
-	iter := r.client.Collection(r.chatsCollection).
-		Where("jid", "==", jid).
-		Limit(1).
-		Documents(ctx)
+func (r *ChatsRepository) UpdateChatProfilePic(ctx context.Context, session, jid string, url string) error {
+	iter := r.chatQuery(session, jid).Documents(ctx)
 
 	doc, err := iter.Next()
 	if err != nil { // Handle Done and Error
@@ -342,14 +683,6 @@ func (r *ChatsRepository) UpdateChatProfilePic(ctx context.Context, jid string,
 	return err
 }
 
-func truncateBody(body string) string {
-	const maxLen = 100
-	if len(body) <= maxLen {
-		return body
-	}
-	return body[:maxLen] + "..."
-}
-
 // ScanChatMetadata scans all chats and updates metadata flags (HasInvoice, IsOTP) based on keywords
 func (r *ChatsRepository) ScanChatMetadata(ctx context.Context) (int, error) {
 	iter := r.client.Collection(r.chatsCollection).Documents(ctx)
@@ -367,26 +700,16 @@ func (r *ChatsRepository) ScanChatMetadata(ctx context.Context) (int, error) {
 			return count, err
 		}
 
-		var chat WAChat
+		var chat storage.WAChat
 		if err := doc.DataTo(&chat); err != nil {
 			continue
 		}
 
-		body := strings.ToLower(chat.LastMessageBody)
-		
 		// 1. Invoice Check
-		hasInvoice := strings.Contains(body, "inv-") || strings.Contains(body, "invoice") || strings.Contains(body, "tagihan")
-		
-		// 2. OTP Check
-		isOTP := strings.Contains(body, "otp") || strings.Contains(body, "kode verifikasi") || strings.Contains(body, "verification code")
+		hasInvoice := storage.IsInvoiceBody(chat.LastMessageBody)
 
-		// 3. Sender Filter (Stockbit, TRI)
-		// Check Name and Number
-		nameLower := strings.ToLower(chat.Name)
-		number := chat.Number // usually just digits, but sometimes full JID
-		if strings.Contains(nameLower, "stockbit") || strings.Contains(nameLower, "tri indonesia") || strings.Contains(number, "628999800123") {
-			isOTP = true
-		}
+		// 2. OTP Check (keywords, plus sender name/number for Stockbit, TRI)
+		isOTP := storage.IsOTPBody(chat.LastMessageBody) || storage.IsOTPChat(chat.Name, chat.Number)
 
 		updates := []firestore.Update{}
 		needsUpdate := false
@@ -410,6 +733,9 @@ func (r *ChatsRepository) ScanChatMetadata(ctx context.Context) (int, error) {
 		updates = append(updates, firestore.Update{Path: "isOTP", Value: isOTP})
 		needsUpdate = true // Force update to ensure backfill
 
+		// isGroup was never set on older chats, and the groups filter needs it
+		updates = append(updates, firestore.Update{Path: "isGroup", Value: storage.IsGroupJID(chat.JID)})
+
 		if needsUpdate {
 			batch.Update(doc.Ref, updates)
 			count++
@@ -435,3 +761,111 @@ func (r *ChatsRepository) ScanChatMetadata(ctx context.Context) (int, error) {
 
 	return count, nil
 }
+
+// AssignSession assigns the chats and messages stored before sessions were recorded to session,
+// and moves messages stored under their message ID alone to their session's document ID.
+// Documents written before sessions have no session field, which no query can match, so both collections
+// are scanned once; a marker document in settings keeps later starts from scanning again.
+func (r *ChatsRepository) AssignSession(ctx context.Context, session string) (int, error) {
+	marker := r.client.FS.Collection("settings").Doc("wa_message_sessions")
+	docs, err := r.client.FS.GetAll(ctx, []*firestore.DocumentRef{marker})
+	if err != nil {
+		return 0, err
+	}
+	if len(docs) > 0 && docs[0].Exists() {
+		return 0, nil
+	}
+
+	chats, err := r.rewriteCollection(ctx, r.chatsCollection, func(batch *firestore.WriteBatch, doc *firestore.DocumentSnapshot) int {
+		if current, err := doc.DataAt("session"); err == nil && current != "" {
+			return 0
+		}
+		batch.Update(doc.Ref, []firestore.Update{{Path: "session", Value: session}})
+		return 1
+	})
+	if err != nil {
+		return chats, err
+	}
+
+	messages, err := r.rewriteCollection(ctx, r.messagesCollection, func(batch *firestore.WriteBatch, doc *firestore.DocumentSnapshot) int {
+		data := doc.Data()
+		msgSession, _ := data["session"].(string)
+		if msgSession == "" {
+			msgSession = session
+		}
+		messageID, _ := data["messageId"].(string)
+		id := messageDocID(msgSession, messageID)
+		if doc.Ref.ID == id && data["session"] == msgSession {
+			return 0
+		}
+
+		data["session"] = msgSession
+		batch.Set(r.client.Collection(r.messagesCollection).Doc(id), data)
+		if doc.Ref.ID == id {
+			return 1
+		}
+		batch.Delete(doc.Ref)
+		return 2
+	})
+	if err != nil {
+		return chats + messages, err
+	}
+
+	_, err = marker.Set(ctx, map[string]interface{}{"session": session, "assignedAt": time.Now()})
+	return chats + messages, err
+}
+
+// rewriteCollection scans a collection, letting rewrite add the writes for each document to a batch and
+// return how many it added, and commits the batch as it fills. It returns the number of documents rewritten.
+func (r *ChatsRepository) rewriteCollection(ctx context.Context, collection string, rewrite func(batch *firestore.WriteBatch, doc *firestore.DocumentSnapshot) int) (int, error) {
+	iter := r.client.Collection(collection).Documents(ctx)
+	defer iter.Stop()
+
+	count := 0
+	batch := r.client.Batch()
+	pending := 0
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return count, err
+		}
+
+		writes := rewrite(batch, doc)
+		if writes == 0 {
+			continue
+		}
+		count++
+		pending += writes
+		// A batch holds at most 500 writes
+		if pending >= 498 {
+			if _, err := batch.Commit(ctx); err != nil {
+				return count, err
+			}
+			batch = r.client.Batch()
+			pending = 0
+		}
+	}
+
+	if pending > 0 {
+		if _, err := batch.Commit(ctx); err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+// messageDocID is the document ID of a session's message; sessions that see the same message store a copy each
+func messageDocID(session, messageID string) string {
+	return session + "_" + messageID
+}
+
+// chatQuery matches a session's chat by JID
+func (r *ChatsRepository) chatQuery(session, jid string) firestore.Query {
+	return r.client.Collection(r.chatsCollection).
+		Where("session", "==", session).
+		Where("jid", "==", jid).
+		Limit(1)
+}
@@ -6,22 +6,9 @@ import (
 
 	"cloud.google.com/go/firestore"
 	"google.golang.org/api/iterator"
-)
 
-// Lead represents a contact/lead in Firestore (replacement for WA Labels)
-type Lead struct {
-	ID            string    `firestore:"-"`
-	Phone         string    `firestore:"phone"`
-	Name          string    `firestore:"name"`
-	PushName      string    `firestore:"pushname,omitempty"`
-	Tags          []string  `firestore:"tags"`
-	Source        string    `firestore:"source"` // whatsapp_sync, manual, import, incoming_message
-	LastMessageAt time.Time `firestore:"lastMessageAt,omitempty"`
-	MessageCount  int       `firestore:"messageCount,omitempty"`
-	SyncedAt      time.Time `firestore:"syncedAt"`
-	CreatedAt     time.Time `firestore:"createdAt"`
-	UpdatedAt     time.Time `firestore:"updatedAt"`
-}
+	"wa-server-go/internal/storage"
+)
 
 // LeadsRepository provides access to the leads collection
 type LeadsRepository struct {
@@ -38,12 +25,12 @@ func NewLeadsRepository(client *Client) *LeadsRepository {
 }
 
 // GetByTag retrieves leads with a specific tag
-func (r *LeadsRepository) GetByTag(ctx context.Context, tag string) ([]Lead, error) {
+func (r *LeadsRepository) GetByTag(ctx context.Context, tag string) ([]storage.Lead, error) {
 	iter := r.client.Collection(r.collection).
 		Where("tags", "array-contains", tag).
 		Documents(ctx)
 
-	var leads []Lead
+	var leads []storage.Lead
 	for {
 		doc, err := iter.Next()
 		if err == iterator.Done {
@@ -53,7 +40,7 @@ func (r *LeadsRepository) GetByTag(ctx context.Context, tag string) ([]Lead, err
 			return nil, err
 		}
 
-		var lead Lead
+		var lead storage.Lead
 		if err := doc.DataTo(&lead); err != nil {
 			continue
 		}
@@ -65,7 +52,7 @@ func (r *LeadsRepository) GetByTag(ctx context.Context, tag string) ([]Lead, err
 }
 
 // GetAll retrieves all leads
-func (r *LeadsRepository) GetAll(ctx context.Context, limit int) ([]Lead, error) {
+func (r *LeadsRepository) GetAll(ctx context.Context, limit int) ([]storage.Lead, error) {
 	query := r.client.Collection(r.collection).OrderBy("createdAt", firestore.Desc)
 	if limit > 0 {
 		query = query.Limit(limit)
@@ -73,7 +60,7 @@ func (r *LeadsRepository) GetAll(ctx context.Context, limit int) ([]Lead, error)
 
 	iter := query.Documents(ctx)
 
-	var leads []Lead
+	var leads []storage.Lead
 	for {
 		doc, err := iter.Next()
 		if err == iterator.Done {
@@ -83,7 +70,7 @@ func (r *LeadsRepository) GetAll(ctx context.Context, limit int) ([]Lead, error)
 			return nil, err
 		}
 
-		var lead Lead
+		var lead storage.Lead
 		if err := doc.DataTo(&lead); err != nil {
 			continue
 		}
@@ -95,7 +82,7 @@ func (r *LeadsRepository) GetAll(ctx context.Context, limit int) ([]Lead, error)
 }
 
 // GetByPhone retrieves a lead by phone number
-func (r *LeadsRepository) GetByPhone(ctx context.Context, phone string) (*Lead, error) {
+func (r *LeadsRepository) GetByPhone(ctx context.Context, phone string) (*storage.Lead, error) {
 	iter := r.client.Collection(r.collection).
 		Where("phone", "==", phone).
 		Limit(1).
@@ -109,7 +96,7 @@ func (r *LeadsRepository) GetByPhone(ctx context.Context, phone string) (*Lead,
 		return nil, err
 	}
 
-	var lead Lead
+	var lead storage.Lead
 	if err := doc.DataTo(&lead); err != nil {
 		return nil, err
 	}
@@ -118,7 +105,7 @@ func (r *LeadsRepository) GetByPhone(ctx context.Context, phone string) (*Lead,
 }
 
 // Create creates a new lead
-func (r *LeadsRepository) Create(ctx context.Context, lead *Lead) (string, error) {
+func (r *LeadsRepository) Create(ctx context.Context, lead *storage.Lead) (string, error) {
 	now := time.Now()
 	lead.CreatedAt = now
 	lead.UpdatedAt = now
@@ -166,7 +153,7 @@ func (r *LeadsRepository) UpsertFromMessage(ctx context.Context, phone, pushName
 	}
 
 	// Create new lead
-	newLead := &Lead{
+	newLead := &storage.Lead{
 		Phone:         phone,
 		Name:          pushName,
 		PushName:      pushName,
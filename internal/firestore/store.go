@@ -0,0 +1,22 @@
+package firestore
+
+import (
+	"wa-server-go/internal/storage"
+)
+
+var (
+	_ storage.ChatsRepository    = (*ChatsRepository)(nil)
+	_ storage.LeadsRepository    = (*LeadsRepository)(nil)
+	_ storage.WAStatusRepository = (*WAStatusRepository)(nil)
+)
+
+// NewStore creates a storage.Store backed by Firestore
+func NewStore(client *Client) *storage.Store {
+	return storage.NewStore(
+		storage.BackendFirestore,
+		NewChatsRepository(client),
+		NewLeadsRepository(client),
+		NewWAStatusRepository(client),
+		client.Close,
+	)
+}
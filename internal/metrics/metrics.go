@@ -0,0 +1,150 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"wa-server-go/internal/storage"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Collectors are registered with the default Prometheus registry and served on /metrics
+var (
+	// MessagesSent counts delivered outbox jobs; endpoint is the send kind (text, invoice, media)
+	MessagesSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "wa_messages_sent_total",
+		Help: "Outbound messages delivered, by send endpoint and session.",
+	}, []string{"endpoint", "session"})
+
+	// MessagesFailed counts outbox jobs that failed permanently
+	MessagesFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "wa_messages_failed_total",
+		Help: "Outbound messages that failed after all retries, by send endpoint and session.",
+	}, []string{"endpoint", "session"})
+
+	// IncomingMessages counts live messages received, by message type
+	IncomingMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "wa_incoming_messages_total",
+		Help: "Incoming WhatsApp messages, by message type and session.",
+	}, []string{"type", "session"})
+
+	// SaveMessageDuration observes ChatsRepository.SaveMessage latency
+	SaveMessageDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "wa_storage_save_message_duration_seconds",
+		Help:    "Latency of saving a message to the storage backend.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"backend"})
+
+	// SaveMessageErrors counts failed ChatsRepository.SaveMessage calls
+	SaveMessageErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "wa_storage_save_message_errors_total",
+		Help: "Failed message writes to the storage backend.",
+	}, []string{"backend"})
+
+	// BroadcastsDropped counts message events dropped because the event channel was full
+	BroadcastsDropped = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "wa_broadcasts_dropped_total",
+		Help: "Message events dropped because the broadcast channel was full.",
+	})
+
+	// LIDCacheHits counts LID to phone number lookups answered without asking WhatsApp
+	LIDCacheHits = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "wa_lid_cache_hits_total",
+		Help: "LID to phone number lookups resolved from the LID store or learned mappings.",
+	})
+
+	// LIDCacheMisses counts LID lookups not known locally
+	LIDCacheMisses = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "wa_lid_cache_misses_total",
+		Help: "LID to phone number lookups not known locally.",
+	})
+
+	// SessionConnected is 1 while a session is logged in and connected, 0 otherwise
+	SessionConnected = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "wa_session_connected",
+		Help: "Whether a WhatsApp session is connected and ready (1) or not (0).",
+	}, []string{"session"})
+)
+
+// RegisterWebSocketClients exposes the number of connected WebSocket clients
+func RegisterWebSocketClients(count func() int) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "wa_websocket_clients",
+		Help: "Connected WebSocket clients.",
+	}, func() float64 {
+		return float64(count())
+	})
+}
+
+// RegisterOutbox exposes the number of unfinished outbox jobs by status (queued, retrying, sending)
+func RegisterOutbox(depth func() map[string]int) {
+	prometheus.MustRegister(&countsCollector{
+		desc:   prometheus.NewDesc("wa_outbox_jobs", "Outbox jobs not yet sent or failed, by status.", []string{"status"}, nil),
+		counts: depth,
+	})
+}
+
+// RegisterWebhookQueue exposes the number of webhook deliveries queued and waiting for a retry
+func RegisterWebhookQueue(pending func() map[string]int) {
+	prometheus.MustRegister(&countsCollector{
+		desc:   prometheus.NewDesc("wa_webhook_deliveries_pending", "Webhook deliveries not yet delivered or dead-lettered, by state.", []string{"state"}, nil),
+		counts: pending,
+	})
+}
+
+// countsCollector reports a labelled gauge whose values are read on every scrape
+type countsCollector struct {
+	desc   *prometheus.Desc
+	counts func() map[string]int
+}
+
+func (c *countsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *countsCollector) Collect(ch chan<- prometheus.Metric) {
+	for label, n := range c.counts() {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(n), label)
+	}
+}
+
+// SetSessionConnected records the connection state of a session
+func SetSessionConnected(session string, connected bool) {
+	value := 0.0
+	if connected {
+		value = 1
+	}
+	SessionConnected.WithLabelValues(session).Set(value)
+}
+
+// RemoveSession drops the connection state of a session that no longer exists
+func RemoveSession(session string) {
+	SessionConnected.DeleteLabelValues(session)
+}
+
+// chatsRepository times SaveMessage calls of the wrapped repository
+type chatsRepository struct {
+	storage.ChatsRepository
+	backend string
+}
+
+// InstrumentChats wraps a chats repository to record SaveMessage latency and errors
+func InstrumentChats(repo storage.ChatsRepository, backend string) storage.ChatsRepository {
+	if repo == nil {
+		return nil
+	}
+	return &chatsRepository{ChatsRepository: repo, backend: backend}
+}
+
+// SaveMessage saves the message and records how long it took
+func (r *chatsRepository) SaveMessage(ctx context.Context, msg *storage.WAMessage) error {
+	start := time.Now()
+	err := r.ChatsRepository.SaveMessage(ctx, msg)
+	SaveMessageDuration.WithLabelValues(r.backend).Observe(time.Since(start).Seconds())
+	if err != nil {
+		SaveMessageErrors.WithLabelValues(r.backend).Inc()
+	}
+	return err
+}
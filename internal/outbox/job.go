@@ -0,0 +1,59 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// JobStatus is the delivery state of a queued send
+type JobStatus string
+
+const (
+	StatusQueued   JobStatus = "queued"
+	StatusSending  JobStatus = "sending"
+	StatusRetrying JobStatus = "retrying"
+	StatusSent     JobStatus = "sent"
+	StatusFailed   JobStatus = "failed"
+)
+
+// ErrClientNotReady is returned by a job handler when the WhatsApp client is not connected yet.
+// The job is postponed without consuming an attempt.
+var ErrClientNotReady = errors.New("whatsapp client is not ready")
+
+// Job is a send request waiting in (or finished with) the outbox
+type Job struct {
+	ID            string                 `json:"id"`
+	Kind          string                 `json:"kind"` // text, invoice, media
+	ClientID      string                 `json:"client"`
+	Recipient     string                 `json:"recipient"`
+	Payload       json.RawMessage        `json:"-"`
+	Status        JobStatus              `json:"status"`
+	Attempts      int                    `json:"attempts"`
+	MaxAttempts   int                    `json:"maxAttempts"`
+	LastError     string                 `json:"lastError,omitempty"`
+	Result        map[string]interface{} `json:"result,omitempty"`
+	NextAttemptAt time.Time              `json:"nextAttemptAt"`
+	CreatedAt     time.Time              `json:"createdAt"`
+	UpdatedAt     time.Time              `json:"updatedAt"`
+}
+
+// Repository persists outbox jobs
+type Repository interface {
+	Enqueue(ctx context.Context, job *Job) error
+	Get(ctx context.Context, id string) (*Job, error)
+	// ListDue returns queued or retrying jobs whose next attempt is due, oldest first
+	ListDue(ctx context.Context, now time.Time, limit int) ([]Job, error)
+	// Claim moves a due job to sending; it reports false if another worker claimed it first
+	Claim(ctx context.Context, id string, now time.Time) (bool, error)
+	Update(ctx context.Context, job *Job) error
+	// RequeueInflight resets jobs left in sending by a previous process
+	RequeueInflight(ctx context.Context) (int, error)
+	// CountUnfinished returns the number of queued, retrying and sending jobs by status
+	CountUnfinished(ctx context.Context) (map[JobStatus]int, error)
+}
+
+// HandlerFunc delivers a job and returns its result (message IDs etc.). A result returned with an error
+// is kept on the job, so the next attempt can see what was already delivered.
+type HandlerFunc func(ctx context.Context, job *Job) (map[string]interface{}, error)
@@ -0,0 +1,349 @@
+package outbox
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"wa-server-go/internal/metrics"
+	"wa-server-go/internal/utils"
+)
+
+// Config configures delivery concurrency, rate limits and retries
+type Config struct {
+	Workers         int
+	RatePerMinute   int           // global sends per minute across all chats
+	PerChatInterval time.Duration // minimum gap between two sends to the same recipient
+	MaxAttempts     int
+	RetryBackoff    time.Duration // first retry delay, doubled per attempt
+	PollInterval    time.Duration
+	MaxClientWait   time.Duration // how long after enqueueing a job may wait for its client to connect
+}
+
+// DefaultConfig returns default queue configuration
+func DefaultConfig() Config {
+	return Config{
+		Workers:         2,
+		RatePerMinute:   20,
+		PerChatInterval: 5 * time.Second,
+		MaxAttempts:     5,
+		RetryBackoff:    30 * time.Second,
+		PollInterval:    2 * time.Second,
+		MaxClientWait:   time.Hour,
+	}
+}
+
+// Queue delivers outbox jobs in the background
+type Queue struct {
+	repo     Repository
+	cfg      Config
+	handlers map[string]HandlerFunc
+	onUpdate func(Job)
+	wake     chan struct{}
+
+	mu           sync.Mutex
+	inflight     map[string]bool      // recipients with a job being sent
+	lastChatSend map[string]time.Time // recipient -> last send
+	nextGlobal   time.Time            // earliest time the next send may start
+}
+
+// NewQueue creates a new outbox queue
+func NewQueue(repo Repository, cfg Config) *Queue {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 1
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 2 * time.Second
+	}
+	if cfg.MaxClientWait <= 0 {
+		cfg.MaxClientWait = time.Hour
+	}
+	return &Queue{
+		repo:         repo,
+		cfg:          cfg,
+		handlers:     make(map[string]HandlerFunc),
+		wake:         make(chan struct{}, 1),
+		inflight:     make(map[string]bool),
+		lastChatSend: make(map[string]time.Time),
+	}
+}
+
+// Register sets the handler that delivers jobs of the given kind
+func (q *Queue) Register(kind string, handler HandlerFunc) {
+	q.handlers[kind] = handler
+}
+
+// OnUpdate sets a callback invoked on every job state transition
+func (q *Queue) OnUpdate(fn func(Job)) {
+	q.onUpdate = fn
+}
+
+// Enqueue persists a new job and wakes the workers
+func (q *Queue) Enqueue(ctx context.Context, kind, clientID, recipient string, payload interface{}) (*Job, error) {
+	if _, ok := q.handlers[kind]; !ok {
+		return nil, fmt.Errorf("no handler registered for job kind %q", kind)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode job payload: %w", err)
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	job := &Job{
+		ID:            id,
+		Kind:          kind,
+		ClientID:      clientID,
+		Recipient:     recipient,
+		Payload:       data,
+		Status:        StatusQueued,
+		MaxAttempts:   q.cfg.MaxAttempts,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := q.repo.Enqueue(ctx, job); err != nil {
+		return nil, fmt.Errorf("failed to enqueue job: %w", err)
+	}
+
+	q.notify(job)
+	q.signal()
+	return job, nil
+}
+
+// Get returns a job by ID
+func (q *Queue) Get(ctx context.Context, id string) (*Job, error) {
+	return q.repo.Get(ctx, id)
+}
+
+// Depth returns the number of queued, retrying and sending jobs, with every status present
+func (q *Queue) Depth(ctx context.Context) (map[JobStatus]int, error) {
+	counts, err := q.repo.CountUnfinished(ctx)
+	if err != nil {
+		return nil, err
+	}
+	depth := map[JobStatus]int{StatusQueued: 0, StatusRetrying: 0, StatusSending: 0}
+	for status, n := range counts {
+		depth[status] = n
+	}
+	return depth, nil
+}
+
+// Start requeues jobs interrupted by a restart and launches the workers
+func (q *Queue) Start(ctx context.Context) {
+	if n, err := q.repo.RequeueInflight(ctx); err != nil {
+		log.Printf("⚠️ [OUTBOX] Failed to requeue interrupted jobs: %v", err)
+	} else if n > 0 {
+		log.Printf("🔁 [OUTBOX] Requeued %d interrupted jobs", n)
+	}
+
+	for i := 0; i < q.cfg.Workers; i++ {
+		go q.worker(ctx)
+	}
+	log.Printf("✅ [OUTBOX] Started %d workers (%d/min, %s per chat)", q.cfg.Workers, q.cfg.RatePerMinute, q.cfg.PerChatInterval)
+}
+
+func (q *Queue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *Queue) worker(ctx context.Context) {
+	ticker := time.NewTicker(q.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Drain everything that is due before sleeping again
+		for q.runNext(ctx) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// runNext claims and processes one job, reporting whether a job was found
+func (q *Queue) runNext(ctx context.Context) bool {
+	job := q.claimNext(ctx)
+	if job == nil {
+		return false
+	}
+	defer q.release(job.Recipient)
+
+	q.waitForGlobalSlot(ctx)
+	q.process(ctx, job)
+	return true
+}
+
+// claimNext picks the oldest due job whose recipient is not rate limited or busy
+func (q *Queue) claimNext(ctx context.Context) *Job {
+	now := time.Now()
+	jobs, err := q.repo.ListDue(ctx, now, 50)
+	if err != nil {
+		log.Printf("⚠️ [OUTBOX] Failed to list due jobs: %v", err)
+		return nil
+	}
+
+	for i := range jobs {
+		job := &jobs[i]
+
+		q.mu.Lock()
+		busy := q.inflight[job.Recipient]
+		limited := now.Sub(q.lastChatSend[job.Recipient]) < q.cfg.PerChatInterval
+		if busy || limited {
+			q.mu.Unlock()
+			continue
+		}
+		q.inflight[job.Recipient] = true
+		q.mu.Unlock()
+
+		ok, err := q.repo.Claim(ctx, job.ID, now)
+		if err != nil || !ok {
+			q.release(job.Recipient)
+			continue
+		}
+		job.Status = StatusSending
+		job.UpdatedAt = now
+		q.notify(job)
+		return job
+	}
+	return nil
+}
+
+func (q *Queue) release(recipient string) {
+	q.mu.Lock()
+	delete(q.inflight, recipient)
+	q.mu.Unlock()
+}
+
+// waitForGlobalSlot blocks until the global rate limit allows another send
+func (q *Queue) waitForGlobalSlot(ctx context.Context) {
+	if q.cfg.RatePerMinute <= 0 {
+		return
+	}
+	interval := time.Minute / time.Duration(q.cfg.RatePerMinute)
+
+	q.mu.Lock()
+	now := time.Now()
+	start := q.nextGlobal
+	if start.Before(now) {
+		start = now
+	}
+	q.nextGlobal = start.Add(interval)
+	q.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Until(start)):
+	}
+}
+
+// process runs the job handler and records the outcome
+func (q *Queue) process(ctx context.Context, job *Job) {
+	handler := q.handlers[job.Kind]
+	if handler == nil {
+		q.finish(ctx, job, StatusFailed, nil, fmt.Errorf("no handler registered for job kind %q", job.Kind))
+		return
+	}
+
+	job.Attempts++
+	result, err := handler(ctx, job)
+	if result != nil {
+		// Kept on a failed attempt too, so the next attempt can skip what was already delivered
+		job.Result = result
+	}
+
+	q.mu.Lock()
+	q.lastChatSend[job.Recipient] = time.Now()
+	q.mu.Unlock()
+
+	if err == nil {
+		q.finish(ctx, job, StatusSent, result, nil)
+		return
+	}
+
+	if errors.Is(err, ErrClientNotReady) {
+		// A deleted or logged out session never connects again
+		if time.Since(job.CreatedAt) >= q.cfg.MaxClientWait {
+			q.finish(ctx, job, StatusFailed, job.Result, fmt.Errorf("%w after waiting %s", err, q.cfg.MaxClientWait))
+			return
+		}
+		// Not an attempt: wait for the client to connect
+		job.Attempts--
+		job.Status = StatusRetrying
+		job.LastError = err.Error()
+		job.NextAttemptAt = time.Now().Add(15 * time.Second)
+		q.save(ctx, job)
+		return
+	}
+
+	if utils.IsRetryableError(err) && job.Attempts < job.MaxAttempts {
+		backoff := q.cfg.RetryBackoff << (job.Attempts - 1)
+		log.Printf("🔁 [OUTBOX] Job %s attempt %d failed, retrying in %s: %v", job.ID, job.Attempts, backoff, err)
+		job.Status = StatusRetrying
+		job.LastError = err.Error()
+		job.NextAttemptAt = time.Now().Add(backoff)
+		q.save(ctx, job)
+		return
+	}
+
+	q.finish(ctx, job, StatusFailed, job.Result, err)
+}
+
+func (q *Queue) finish(ctx context.Context, job *Job, status JobStatus, result map[string]interface{}, err error) {
+	job.Status = status
+	job.Result = result
+	if err != nil {
+		job.LastError = err.Error()
+		metrics.MessagesFailed.WithLabelValues(job.Kind, job.ClientID).Inc()
+		log.Printf("❌ [OUTBOX] Job %s (%s to %s) failed: %v", job.ID, job.Kind, job.Recipient, err)
+	} else {
+		job.LastError = ""
+		metrics.MessagesSent.WithLabelValues(job.Kind, job.ClientID).Inc()
+		log.Printf("✅ [OUTBOX] Job %s (%s to %s) sent", job.ID, job.Kind, job.Recipient)
+	}
+	q.save(ctx, job)
+}
+
+func (q *Queue) save(ctx context.Context, job *Job) {
+	job.UpdatedAt = time.Now()
+	if err := q.repo.Update(ctx, job); err != nil {
+		log.Printf("⚠️ [OUTBOX] Failed to persist job %s: %v", job.ID, err)
+	}
+	q.notify(job)
+}
+
+func (q *Queue) notify(job *Job) {
+	if q.onUpdate != nil {
+		q.onUpdate(*job)
+	}
+}
+
+// newJobID generates a random job ID
+func newJobID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,254 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// jobKindTest is the kind of the jobs the tests enqueue
+const jobKindTest = "test"
+
+// memRepository is an in-memory Repository
+type memRepository struct {
+	mu   sync.Mutex
+	jobs map[string]Job
+}
+
+func newMemRepository() *memRepository {
+	return &memRepository{jobs: make(map[string]Job)}
+}
+
+func (r *memRepository) Enqueue(ctx context.Context, job *Job) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jobs[job.ID] = *job
+	return nil
+}
+
+func (r *memRepository) Get(ctx context.Context, id string) (*Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[id]
+	if !ok {
+		return nil, nil
+	}
+	return &job, nil
+}
+
+func (r *memRepository) ListDue(ctx context.Context, now time.Time, limit int) ([]Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var due []Job
+	for _, job := range r.jobs {
+		if (job.Status == StatusQueued || job.Status == StatusRetrying) && !job.NextAttemptAt.After(now) {
+			due = append(due, job)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].CreatedAt.Before(due[j].CreatedAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+func (r *memRepository) Claim(ctx context.Context, id string, now time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[id]
+	if !ok || (job.Status != StatusQueued && job.Status != StatusRetrying) {
+		return false, nil
+	}
+	job.Status = StatusSending
+	r.jobs[id] = job
+	return true, nil
+}
+
+func (r *memRepository) Update(ctx context.Context, job *Job) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jobs[job.ID] = *job
+	return nil
+}
+
+func (r *memRepository) RequeueInflight(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (r *memRepository) CountUnfinished(ctx context.Context) (map[JobStatus]int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	counts := make(map[JobStatus]int)
+	for _, job := range r.jobs {
+		if job.Status != StatusSent && job.Status != StatusFailed {
+			counts[job.Status]++
+		}
+	}
+	return counts, nil
+}
+
+func testConfig() Config {
+	return Config{
+		Workers:         1,
+		PerChatInterval: time.Minute,
+		MaxAttempts:     3,
+		RetryBackoff:    10 * time.Second,
+		PollInterval:    time.Second,
+		MaxClientWait:   time.Hour,
+	}
+}
+
+func TestProcessTransitions(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		attempts     int           // attempts before this one
+		age          time.Duration // time since the job was enqueued
+		wantStatus   JobStatus
+		wantAttempts int
+		wantDelay    time.Duration // until the next attempt, for retrying jobs
+	}{
+		{name: "sent", wantStatus: StatusSent, wantAttempts: 1},
+		{name: "first retryable failure", err: errors.New("connection reset by peer"), wantStatus: StatusRetrying, wantAttempts: 1, wantDelay: 10 * time.Second},
+		{name: "second retryable failure doubles the backoff", err: errors.New("i/o timeout"), attempts: 1, wantStatus: StatusRetrying, wantAttempts: 2, wantDelay: 20 * time.Second},
+		{name: "retryable failure on the last attempt", err: errors.New("i/o timeout"), attempts: 2, wantStatus: StatusFailed, wantAttempts: 3},
+		{name: "permanent failure", err: errors.New("invalid number"), wantStatus: StatusFailed, wantAttempts: 1},
+		{name: "client not ready is not an attempt", err: ErrClientNotReady, attempts: 1, wantStatus: StatusRetrying, wantAttempts: 1, wantDelay: 15 * time.Second},
+		{name: "client not ready past the wait", err: ErrClientNotReady, attempts: 1, age: 2 * time.Hour, wantStatus: StatusFailed, wantAttempts: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMemRepository()
+			q := NewQueue(repo, testConfig())
+			q.Register(jobKindTest, func(ctx context.Context, job *Job) (map[string]interface{}, error) {
+				return nil, tt.err
+			})
+
+			job := &Job{
+				ID:          "job1",
+				Kind:        jobKindTest,
+				Recipient:   "62811@s.whatsapp.net",
+				Status:      StatusSending,
+				Attempts:    tt.attempts,
+				MaxAttempts: 3,
+				CreatedAt:   time.Now().Add(-tt.age),
+			}
+			before := time.Now()
+			q.process(context.Background(), job)
+			after := time.Now()
+
+			stored, _ := repo.Get(context.Background(), "job1")
+			if stored == nil {
+				t.Fatal("job was not saved")
+			}
+			if stored.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", stored.Status, tt.wantStatus)
+			}
+			if stored.Attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", stored.Attempts, tt.wantAttempts)
+			}
+			if tt.err != nil && stored.LastError == "" {
+				t.Error("lastError is empty")
+			}
+			if tt.err == nil && stored.LastError != "" {
+				t.Errorf("lastError = %q, want empty", stored.LastError)
+			}
+			if tt.wantStatus == StatusRetrying {
+				next := stored.NextAttemptAt
+				if next.Before(before.Add(tt.wantDelay)) || next.After(after.Add(tt.wantDelay)) {
+					t.Errorf("next attempt in %s, want %s", next.Sub(before), tt.wantDelay)
+				}
+			}
+		})
+	}
+}
+
+func TestProcessKeepsResultOfFailedAttempt(t *testing.T) {
+	repo := newMemRepository()
+	q := NewQueue(repo, testConfig())
+	q.Register(jobKindTest, func(ctx context.Context, job *Job) (map[string]interface{}, error) {
+		if job.Result != nil {
+			return job.Result, nil
+		}
+		return map[string]interface{}{"messageId": "m1"}, errors.New("connection reset")
+	})
+
+	job := &Job{ID: "job1", Kind: jobKindTest, Recipient: "a", MaxAttempts: 3, CreatedAt: time.Now()}
+	q.process(context.Background(), job)
+	if job.Status != StatusRetrying || job.Result["messageId"] != "m1" {
+		t.Fatalf("after the failed attempt: status %s, result %v", job.Status, job.Result)
+	}
+	q.process(context.Background(), job)
+	if job.Status != StatusSent || job.Result["messageId"] != "m1" {
+		t.Errorf("after the retry: status %s, result %v", job.Status, job.Result)
+	}
+}
+
+func TestClaimNextPerChatRateLimit(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemRepository()
+	q := NewQueue(repo, testConfig())
+	q.Register(jobKindTest, func(ctx context.Context, job *Job) (map[string]interface{}, error) {
+		return nil, nil
+	})
+
+	for _, recipient := range []string{"a", "a", "b"} {
+		if _, err := q.Enqueue(ctx, jobKindTest, "bot", recipient, nil); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+		time.Sleep(time.Millisecond) // distinct CreatedAt, so the order is fixed
+	}
+
+	first := q.claimNext(ctx)
+	if first == nil || first.Recipient != "a" {
+		t.Fatalf("first claim = %+v, want a job for a", first)
+	}
+
+	// a is busy: the next claim skips its second job
+	second := q.claimNext(ctx)
+	if second == nil || second.Recipient != "b" {
+		t.Fatalf("claim while a is busy = %+v, want the job for b", second)
+	}
+
+	q.process(ctx, first)
+	q.release(first.Recipient)
+
+	// a was just sent to: its second job waits for PerChatInterval
+	if job := q.claimNext(ctx); job != nil {
+		t.Errorf("claim within the per-chat interval = %+v, want none", job)
+	}
+
+	q.mu.Lock()
+	q.lastChatSend["a"] = time.Now().Add(-time.Minute)
+	q.mu.Unlock()
+	if job := q.claimNext(ctx); job == nil || job.Recipient != "a" {
+		t.Errorf("claim after the per-chat interval = %+v, want the second job for a", job)
+	}
+}
+
+func TestWaitForGlobalSlotSpacesSends(t *testing.T) {
+	cfg := testConfig()
+	cfg.RatePerMinute = 60
+	q := NewQueue(newMemRepository(), cfg)
+
+	q.waitForGlobalSlot(context.Background())
+	q.mu.Lock()
+	next := time.Until(q.nextGlobal)
+	q.mu.Unlock()
+	if next <= 0 || next > time.Second {
+		t.Errorf("next send allowed in %s, want within 1s", next)
+	}
+
+	// A cancelled context does not wait for the slot
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	q.waitForGlobalSlot(ctx)
+	if waited := time.Since(start); waited > 100*time.Millisecond {
+		t.Errorf("waited %s with a cancelled context", waited)
+	}
+}
@@ -0,0 +1,197 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"wa-server-go/internal/apikey"
+)
+
+var _ apikey.Repository = (*APIKeyRepository)(nil)
+
+// APIKeyRepository stores API keys in the api_keys table and the audit log in api_key_audit
+type APIKeyRepository struct {
+	client *Client
+}
+
+// NewAPIKeyRepository creates a new API key repository
+func NewAPIKeyRepository(client *Client) *APIKeyRepository {
+	return &APIKeyRepository{client: client}
+}
+
+const apiKeyColumns = `id, name, prefix, hash, scopes, sessions, expires_at, created_at, last_used_at, revoked_at`
+
+// Create inserts a new key
+func (r *APIKeyRepository) Create(ctx context.Context, key *apikey.Key) error {
+	scopes, err := json.Marshal(key.Scopes)
+	if err != nil {
+		return err
+	}
+	sessions := key.Sessions
+	if sessions == nil {
+		sessions = []string{}
+	}
+	sessionsJSON, err := json.Marshal(sessions)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.client.DB.ExecContext(ctx, `INSERT INTO api_keys (`+apiKeyColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		key.ID, key.Name, key.Prefix, key.Hash, string(scopes), string(sessionsJSON),
+		optionalMillis(key.ExpiresAt), toMillis(key.CreatedAt),
+		optionalMillis(key.LastUsedAt), optionalMillis(key.RevokedAt))
+	return err
+}
+
+// GetByHash returns the key with the given secret hash, or nil if none exists
+func (r *APIKeyRepository) GetByHash(ctx context.Context, hash string) (*apikey.Key, error) {
+	row := r.client.DB.QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE hash = ?`, hash)
+	key, err := scanAPIKey(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return key, err
+}
+
+// Get returns the key with the given ID, or nil if none exists
+func (r *APIKeyRepository) Get(ctx context.Context, id string) (*apikey.Key, error) {
+	row := r.client.DB.QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE id = ?`, id)
+	key, err := scanAPIKey(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return key, err
+}
+
+// List returns all keys, newest first
+func (r *APIKeyRepository) List(ctx context.Context) ([]apikey.Key, error) {
+	rows, err := r.client.DB.QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []apikey.Key{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+	return keys, rows.Err()
+}
+
+// Revoke marks a key revoked
+func (r *APIKeyRepository) Revoke(ctx context.Context, id string, at time.Time) (bool, error) {
+	res, err := r.client.DB.ExecContext(ctx,
+		`UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at = 0`, toMillis(at), id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// Touch records when a key was last used
+func (r *APIKeyRepository) Touch(ctx context.Context, id string, at time.Time) error {
+	_, err := r.client.DB.ExecContext(ctx, `UPDATE api_keys SET last_used_at = ? WHERE id = ?`, toMillis(at), id)
+	return err
+}
+
+// AddAudit appends an audit entry
+func (r *APIKeyRepository) AddAudit(ctx context.Context, entry *apikey.AuditEntry) error {
+	res, err := r.client.DB.ExecContext(ctx, `INSERT INTO api_key_audit
+		(key_id, key_name, method, path, status, session, recipient, action, details, ip, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.KeyID, entry.KeyName, entry.Method, entry.Path, entry.Status,
+		entry.Session, entry.Recipient, entry.Action, entry.Details, entry.IP, toMillis(entry.CreatedAt))
+	if err != nil {
+		return err
+	}
+	entry.ID, err = res.LastInsertId()
+	return err
+}
+
+// PruneAudit deletes entries older than before
+func (r *APIKeyRepository) PruneAudit(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.client.DB.ExecContext(ctx, `DELETE FROM api_key_audit WHERE created_at < ?`, toMillis(before))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// ListAudit returns the most recent entries first, optionally for a single key
+func (r *APIKeyRepository) ListAudit(ctx context.Context, keyID string, limit int) ([]apikey.AuditEntry, error) {
+	query := `SELECT id, key_id, key_name, method, path, status, session, recipient, action, details, ip, created_at FROM api_key_audit`
+	args := []interface{}{}
+	if keyID != "" {
+		query += ` WHERE key_id = ?`
+		args = append(args, keyID)
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, sqlLimit(limit))
+
+	rows, err := r.client.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []apikey.AuditEntry{}
+	for rows.Next() {
+		var entry apikey.AuditEntry
+		var createdAt int64
+		if err := rows.Scan(&entry.ID, &entry.KeyID, &entry.KeyName, &entry.Method, &entry.Path,
+			&entry.Status, &entry.Session, &entry.Recipient, &entry.Action, &entry.Details, &entry.IP, &createdAt); err != nil {
+			return nil, err
+		}
+		entry.CreatedAt = fromMillis(createdAt)
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+func scanAPIKey(row rowScanner) (*apikey.Key, error) {
+	var key apikey.Key
+	var scopes, sessions string
+	var expiresAt, createdAt, lastUsedAt, revokedAt int64
+	err := row.Scan(&key.ID, &key.Name, &key.Prefix, &key.Hash, &scopes, &sessions,
+		&expiresAt, &createdAt, &lastUsedAt, &revokedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(scopes), &key.Scopes); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(sessions), &key.Sessions); err != nil {
+		return nil, err
+	}
+	key.CreatedAt = fromMillis(createdAt)
+	key.ExpiresAt = optionalTime(expiresAt)
+	key.LastUsedAt = optionalTime(lastUsedAt)
+	key.RevokedAt = optionalTime(revokedAt)
+	return &key, nil
+}
+
+// optionalMillis converts an optional time to stored unix milliseconds (0 when unset)
+func optionalMillis(t *time.Time) int64 {
+	if t == nil {
+		return 0
+	}
+	return toMillis(*t)
+}
+
+// optionalTime converts stored unix milliseconds back to an optional time
+func optionalTime(ms int64) *time.Time {
+	if ms == 0 {
+		return nil
+	}
+	t := fromMillis(ms)
+	return &t
+}
@@ -0,0 +1,90 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"wa-server-go/internal/features/backup"
+)
+
+var _ backup.Repository = (*BackupRunRepository)(nil)
+
+// BackupRunRepository stores backup runs in backup_runs
+type BackupRunRepository struct {
+	client *Client
+}
+
+// NewBackupRunRepository creates a new backup run repository
+func NewBackupRunRepository(client *Client) *BackupRunRepository {
+	return &BackupRunRepository{client: client}
+}
+
+const backupRunColumns = `id, trigger, session, status, stage, files, error, started_at, finished_at`
+
+// Save inserts or updates a run
+func (r *BackupRunRepository) Save(ctx context.Context, run *backup.Run) error {
+	files, err := json.Marshal(run.Files)
+	if err != nil {
+		return err
+	}
+	_, err = r.client.DB.ExecContext(ctx, `INSERT INTO backup_runs (`+backupRunColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			status = excluded.status,
+			stage = excluded.stage,
+			files = excluded.files,
+			error = excluded.error,
+			finished_at = excluded.finished_at`,
+		run.ID, run.Trigger, run.Session, string(run.Status), run.Stage, string(files), run.Error,
+		toMillis(run.StartedAt), optionalMillis(run.FinishedAt))
+	return err
+}
+
+// Get returns a run by ID, or nil if it does not exist
+func (r *BackupRunRepository) Get(ctx context.Context, id string) (*backup.Run, error) {
+	row := r.client.DB.QueryRowContext(ctx, `SELECT `+backupRunColumns+` FROM backup_runs WHERE id = ?`, id)
+	run, err := scanBackupRun(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return run, err
+}
+
+// List returns the most recent runs first
+func (r *BackupRunRepository) List(ctx context.Context, limit int) ([]backup.Run, error) {
+	rows, err := r.client.DB.QueryContext(ctx, `SELECT `+backupRunColumns+` FROM backup_runs
+		ORDER BY started_at DESC LIMIT ?`, sqlLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := []backup.Run{}
+	for rows.Next() {
+		run, err := scanBackupRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, *run)
+	}
+	return runs, rows.Err()
+}
+
+func scanBackupRun(row rowScanner) (*backup.Run, error) {
+	var run backup.Run
+	var status, files string
+	var startedAt, finishedAt int64
+	err := row.Scan(&run.ID, &run.Trigger, &run.Session, &status, &run.Stage, &files, &run.Error, &startedAt, &finishedAt)
+	if err != nil {
+		return nil, err
+	}
+	run.Status = backup.RunStatus(status)
+	if err := json.Unmarshal([]byte(files), &run.Files); err != nil || run.Files == nil {
+		run.Files = []backup.File{}
+	}
+	run.StartedAt = fromMillis(startedAt)
+	run.FinishedAt = optionalTime(finishedAt)
+	return &run, nil
+}
@@ -0,0 +1,105 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"wa-server-go/internal/features/blog"
+)
+
+var _ blog.Repository = (*BlogTopicRepository)(nil)
+
+// BlogTopicRepository stores the blog topic queue in blog_topics
+type BlogTopicRepository struct {
+	client *Client
+}
+
+// NewBlogTopicRepository creates a new blog topic repository
+func NewBlogTopicRepository(client *Client) *BlogTopicRepository {
+	return &BlogTopicRepository{client: client}
+}
+
+const blogTopicColumns = `id, topic, status, draft, title, slug, entry_id, url, error, created_at, updated_at`
+
+// Create inserts a new topic
+func (r *BlogTopicRepository) Create(ctx context.Context, topic *blog.Topic) error {
+	_, err := r.client.DB.ExecContext(ctx, `INSERT INTO blog_topics (`+blogTopicColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		topic.ID, topic.Topic, string(topic.Status), boolToInt(topic.Draft), topic.Title, topic.Slug, topic.EntryID,
+		topic.URL, topic.Error, toMillis(topic.CreatedAt), toMillis(topic.UpdatedAt))
+	return err
+}
+
+// Update saves the state and outcome of a topic
+func (r *BlogTopicRepository) Update(ctx context.Context, topic *blog.Topic) error {
+	_, err := r.client.DB.ExecContext(ctx, `UPDATE blog_topics
+		SET status = ?, title = ?, slug = ?, entry_id = ?, url = ?, error = ?, updated_at = ?
+		WHERE id = ?`,
+		string(topic.Status), topic.Title, topic.Slug, topic.EntryID, topic.URL, topic.Error, toMillis(topic.UpdatedAt), topic.ID)
+	return err
+}
+
+// Get returns a topic by ID, or nil if it does not exist
+func (r *BlogTopicRepository) Get(ctx context.Context, id string) (*blog.Topic, error) {
+	return r.get(ctx, `SELECT `+blogTopicColumns+` FROM blog_topics WHERE id = ?`, id)
+}
+
+// NextQueued returns the oldest queued topic, or nil if the queue is empty
+func (r *BlogTopicRepository) NextQueued(ctx context.Context) (*blog.Topic, error) {
+	return r.get(ctx, `SELECT `+blogTopicColumns+` FROM blog_topics
+		WHERE status = ? ORDER BY created_at LIMIT 1`, string(blog.TopicQueued))
+}
+
+// List returns topics, newest first, optionally filtered by status
+func (r *BlogTopicRepository) List(ctx context.Context, status blog.TopicStatus, limit int) ([]blog.Topic, error) {
+	query := `SELECT ` + blogTopicColumns + ` FROM blog_topics`
+	args := []interface{}{}
+	if status != "" {
+		query += ` WHERE status = ?`
+		args = append(args, string(status))
+	}
+	query += ` ORDER BY created_at DESC LIMIT ?`
+	args = append(args, sqlLimit(limit))
+
+	rows, err := r.client.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	topics := []blog.Topic{}
+	for rows.Next() {
+		topic, err := scanBlogTopic(rows)
+		if err != nil {
+			return nil, err
+		}
+		topics = append(topics, *topic)
+	}
+	return topics, rows.Err()
+}
+
+func (r *BlogTopicRepository) get(ctx context.Context, query string, args ...interface{}) (*blog.Topic, error) {
+	topic, err := scanBlogTopic(r.client.DB.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return topic, err
+}
+
+func scanBlogTopic(row rowScanner) (*blog.Topic, error) {
+	var topic blog.Topic
+	var status string
+	var draft int
+	var createdAt, updatedAt int64
+	err := row.Scan(&topic.ID, &topic.Topic, &status, &draft, &topic.Title, &topic.Slug, &topic.EntryID,
+		&topic.URL, &topic.Error, &createdAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	topic.Status = blog.TopicStatus(status)
+	topic.Draft = draft != 0
+	topic.CreatedAt = fromMillis(createdAt)
+	topic.UpdatedAt = fromMillis(updatedAt)
+	return &topic, nil
+}
@@ -0,0 +1,595 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"wa-server-go/internal/storage"
+)
+
+const chatColumns = `session, jid, name, number, is_group, unread_count, last_message_body, last_message_at, profile_pic_url, has_invoice, is_otp, updated_at`
+
+const messageColumns = `message_id, chat_id, from_jid, to_jid, body, timestamp, from_me, has_media, media_type, media_url, type, ack, created_at,
+	media_direct_path, media_key, media_file_sha256, media_file_enc_sha256, media_file_length, sender_jid, sender_name, session`
+
+// ChatsRepository provides access to the wa_chats and wa_messages tables
+type ChatsRepository struct {
+	client *Client
+}
+
+// NewChatsRepository creates a new chats repository
+func NewChatsRepository(client *Client) *ChatsRepository {
+	return &ChatsRepository{client: client}
+}
+
+// GetRecentChats retrieves recent chats ordered by last message time
+func (r *ChatsRepository) GetRecentChats(ctx context.Context, limit int) ([]storage.WAChat, error) {
+	return r.queryChats(ctx, `SELECT `+chatColumns+` FROM wa_chats WHERE is_otp = 0 ORDER BY last_message_at DESC LIMIT ?`, sqlLimit(limit))
+}
+
+// ListChats returns a page of chats, newest last message first, keyed on (last_message_at, jid)
+func (r *ChatsRepository) ListChats(ctx context.Context, filter storage.ChatFilter) ([]storage.WAChat, string, error) {
+	cursor, err := storage.ParseCursor(filter.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	where := []string{"is_otp = ?"}
+	args := []interface{}{boolToInt(filter.OTP)}
+	if filter.Session != "" {
+		where = append(where, "session = ?")
+		args = append(args, filter.Session)
+	}
+	if filter.UnreadOnly {
+		where = append(where, "unread_count > 0")
+	}
+	if filter.HasInvoice {
+		where = append(where, "has_invoice = 1")
+	}
+	if filter.Groups != nil {
+		// Older rows never had is_group set, so the JID decides
+		if *filter.Groups {
+			where = append(where, "jid LIKE '%@g.us'")
+		} else {
+			where = append(where, "jid NOT LIKE '%@g.us'")
+		}
+	}
+	if !filter.Before.IsZero() {
+		where = append(where, "last_message_at < ?")
+		args = append(args, toMillis(filter.Before))
+	}
+	if !filter.Since.IsZero() {
+		where = append(where, "last_message_at >= ?")
+		args = append(args, toMillis(filter.Since))
+	}
+	if cursor != nil {
+		where = append(where, "(last_message_at < ? OR (last_message_at = ? AND jid < ?))")
+		args = append(args, toMillis(cursor.Time), toMillis(cursor.Time), cursor.ID)
+	}
+	args = append(args, pageLimit(filter.Limit))
+
+	chats, err := r.queryChats(ctx, `SELECT `+chatColumns+` FROM wa_chats WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY last_message_at DESC, jid DESC LIMIT ?`, args...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	next := ""
+	if filter.Limit > 0 && len(chats) > filter.Limit {
+		chats = chats[:filter.Limit]
+		last := chats[len(chats)-1]
+		next = storage.NextCursor(last.LastMessageAt, last.JID)
+	}
+	return chats, next, nil
+}
+
+// GetChat returns a session's chat by JID, or nil if it does not exist
+func (r *ChatsRepository) GetChat(ctx context.Context, session, jid string) (*storage.WAChat, error) {
+	row := r.client.DB.QueryRowContext(ctx, `SELECT `+chatColumns+` FROM wa_chats WHERE session = ? AND jid = ?`, session, jid)
+	chat, err := scanChat(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return chat, err
+}
+
+// GetInvoiceChats retrieves a session's chats that contain invoice messages
+func (r *ChatsRepository) GetInvoiceChats(ctx context.Context, session string, limit int) ([]storage.WAChat, error) {
+	return r.queryChats(ctx, `SELECT `+chatColumns+` FROM wa_chats WHERE session = ? AND has_invoice = 1 ORDER BY last_message_at DESC LIMIT ?`,
+		session, sqlLimit(limit))
+}
+
+// GetChatMessages retrieves messages for a specific chat
+func (r *ChatsRepository) GetChatMessages(ctx context.Context, chatID string, limit int) ([]storage.WAMessage, error) {
+	rows, err := r.client.DB.QueryContext(ctx,
+		`SELECT `+messageColumns+` FROM wa_messages WHERE chat_id = ? ORDER BY timestamp DESC LIMIT ?`,
+		chatID, sqlLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []storage.WAMessage
+	for rows.Next() {
+		msg, err := scanMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, *msg)
+	}
+	return messages, rows.Err()
+}
+
+// ListChatMessages returns a page of the messages of a session's chat, newest first, keyed on (timestamp, message_id)
+func (r *ChatsRepository) ListChatMessages(ctx context.Context, session, chatID string, filter storage.MessageFilter) ([]storage.WAMessage, string, error) {
+	cursor, err := storage.ParseCursor(filter.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	query := `SELECT ` + messageColumns + ` FROM wa_messages WHERE session = ? AND chat_id = ?`
+	args := []interface{}{session, chatID}
+	if !filter.Before.IsZero() {
+		query += ` AND timestamp < ?`
+		args = append(args, toMillis(filter.Before))
+	}
+	if !filter.Since.IsZero() {
+		query += ` AND timestamp >= ?`
+		args = append(args, toMillis(filter.Since))
+	}
+	if cursor != nil {
+		query += ` AND (timestamp < ? OR (timestamp = ? AND message_id < ?))`
+		args = append(args, toMillis(cursor.Time), toMillis(cursor.Time), cursor.ID)
+	}
+	query += ` ORDER BY timestamp DESC, message_id DESC LIMIT ?`
+	args = append(args, pageLimit(filter.Limit))
+
+	rows, err := r.client.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	messages := []storage.WAMessage{}
+	for rows.Next() {
+		msg, err := scanMessage(rows)
+		if err != nil {
+			return nil, "", err
+		}
+		messages = append(messages, *msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	next := ""
+	if filter.Limit > 0 && len(messages) > filter.Limit {
+		messages = messages[:filter.Limit]
+		last := messages[len(messages)-1]
+		next = storage.NextCursor(last.Timestamp, last.MessageID)
+	}
+	return messages, next, nil
+}
+
+// SaveMessage saves a new message and updates the chat
+func (r *ChatsRepository) SaveMessage(ctx context.Context, msg *storage.WAMessage) error {
+	now := time.Now()
+	msg.CreatedAt = now
+
+	tx, err := r.client.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Idempotent: the session and MessageID are the primary key, a re-save merges into the session's stored message
+	_, err = tx.ExecContext(ctx, `INSERT INTO wa_messages (`+messageColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (session, message_id) DO UPDATE SET
+			chat_id = excluded.chat_id,
+			from_jid = excluded.from_jid,
+			to_jid = excluded.to_jid,
+			body = excluded.body,
+			timestamp = excluded.timestamp,
+			from_me = excluded.from_me,
+			has_media = excluded.has_media,
+			media_type = COALESCE(NULLIF(excluded.media_type, ''), wa_messages.media_type),
+			media_url = COALESCE(NULLIF(excluded.media_url, ''), wa_messages.media_url),
+			type = excluded.type,
+			ack = MAX(wa_messages.ack, excluded.ack),
+			created_at = wa_messages.created_at,
+			media_direct_path = COALESCE(NULLIF(excluded.media_direct_path, ''), wa_messages.media_direct_path),
+			media_key = COALESCE(excluded.media_key, wa_messages.media_key),
+			media_file_sha256 = COALESCE(excluded.media_file_sha256, wa_messages.media_file_sha256),
+			media_file_enc_sha256 = COALESCE(excluded.media_file_enc_sha256, wa_messages.media_file_enc_sha256),
+			media_file_length = MAX(wa_messages.media_file_length, excluded.media_file_length),
+			sender_jid = COALESCE(NULLIF(excluded.sender_jid, ''), wa_messages.sender_jid),
+			sender_name = COALESCE(NULLIF(excluded.sender_name, ''), wa_messages.sender_name)`,
+		msg.MessageID, msg.ChatID, msg.From, msg.To, msg.Body, toMillis(msg.Timestamp),
+		boolToInt(msg.FromMe), boolToInt(msg.HasMedia), msg.MediaType, msg.MediaURL,
+		msg.Type, msg.Ack, toMillis(msg.CreatedAt),
+		msg.MediaDirectPath, msg.MediaKey, msg.MediaFileSHA256, msg.MediaFileEncSHA256, int64(msg.MediaFileLength),
+		msg.SenderJID, msg.SenderName, msg.Session)
+	if err != nil {
+		return err
+	}
+
+	if err := r.updateChatFromMessage(ctx, tx, msg, now); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SearchMessages returns a page of messages matching a full-text query, newest first.
+// Every word of the query must match the start of a word in the body.
+func (r *ChatsRepository) SearchMessages(ctx context.Context, filter storage.SearchFilter) ([]storage.MessageHit, string, error) {
+	cursor, err := storage.ParseCursor(filter.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	tokens := storage.SearchTokens(filter.Query)
+	if len(tokens) == 0 {
+		return []storage.MessageHit{}, "", nil
+	}
+	terms := make([]string, len(tokens))
+	for i, token := range tokens {
+		terms[i] = `"` + token + `"*`
+	}
+
+	// snippet() needs the FTS row, so the filters apply to the matches of the subquery
+	query := `SELECT ` + messageColumns + `, snippet FROM (
+		SELECT wa_messages.*, snippet(wa_messages_fts, 0, ?, ?, '…', 16) AS snippet
+		FROM wa_messages_fts JOIN wa_messages ON wa_messages.rowid = wa_messages_fts.rowid
+		WHERE wa_messages_fts MATCH ?
+	) WHERE 1 = 1`
+	// Bodies are not HTML; the snippet is escaped before the raw markers become <mark> tags
+	args := []interface{}{storage.RawHighlightStart, storage.RawHighlightEnd, strings.Join(terms, " ")}
+	if filter.Sessions != nil {
+		query += ` AND session IN (` + placeholders(len(filter.Sessions)) + `)`
+		for _, session := range filter.Sessions {
+			args = append(args, session)
+		}
+	}
+	if filter.ChatID != "" {
+		query += ` AND chat_id = ?`
+		args = append(args, filter.ChatID)
+	}
+	if filter.FromMe != nil {
+		query += ` AND from_me = ?`
+		args = append(args, boolToInt(*filter.FromMe))
+	}
+	if filter.Type != "" {
+		query += ` AND type = ?`
+		args = append(args, filter.Type)
+	}
+	if filter.HasMedia != nil {
+		query += ` AND has_media = ?`
+		args = append(args, boolToInt(*filter.HasMedia))
+	}
+	if !filter.Before.IsZero() {
+		query += ` AND timestamp < ?`
+		args = append(args, toMillis(filter.Before))
+	}
+	if !filter.Since.IsZero() {
+		query += ` AND timestamp >= ?`
+		args = append(args, toMillis(filter.Since))
+	}
+	if cursor != nil {
+		query += ` AND (timestamp < ? OR (timestamp = ? AND message_id < ?))`
+		args = append(args, toMillis(cursor.Time), toMillis(cursor.Time), cursor.ID)
+	}
+	query += ` ORDER BY timestamp DESC, message_id DESC LIMIT ?`
+	args = append(args, pageLimit(filter.Limit))
+
+	rows, err := r.client.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	hits := []storage.MessageHit{}
+	for rows.Next() {
+		var snippet string
+		msg, err := scanMessage(extraColumns{rows, []interface{}{&snippet}})
+		if err != nil {
+			return nil, "", err
+		}
+		hits = append(hits, storage.MessageHit{Message: *msg, Snippet: storage.HighlightSnippet(snippet)})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	next := ""
+	if filter.Limit > 0 && len(hits) > filter.Limit {
+		hits = hits[:filter.Limit]
+		last := hits[len(hits)-1].Message
+		next = storage.NextCursor(last.Timestamp, last.MessageID)
+	}
+	return hits, next, nil
+}
+
+// GetMessage returns a session's message by ID, or nil if it does not exist
+func (r *ChatsRepository) GetMessage(ctx context.Context, session, messageID string) (*storage.WAMessage, error) {
+	row := r.client.DB.QueryRowContext(ctx,
+		`SELECT `+messageColumns+` FROM wa_messages WHERE session = ? AND message_id = ?`, session, messageID)
+	msg, err := scanMessage(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return msg, err
+}
+
+// UpdateMessageMedia stores the local media URL, mime type and media keys of msg, in msg's session
+func (r *ChatsRepository) UpdateMessageMedia(ctx context.Context, msg *storage.WAMessage) error {
+	_, err := r.client.DB.ExecContext(ctx, `UPDATE wa_messages SET
+			media_url = ?, media_type = ?, media_direct_path = ?, media_key = ?,
+			media_file_sha256 = ?, media_file_enc_sha256 = ?, media_file_length = ?
+		WHERE session = ? AND message_id = ?`,
+		msg.MediaURL, msg.MediaType, msg.MediaDirectPath, msg.MediaKey,
+		msg.MediaFileSHA256, msg.MediaFileEncSHA256, int64(msg.MediaFileLength), msg.Session, msg.MessageID)
+	return err
+}
+
+// UpdateMessageAck raises the ack level of the given messages of a session; it never lowers it
+func (r *ChatsRepository) UpdateMessageAck(ctx context.Context, session string, messageIDs []string, ack int) error {
+	if len(messageIDs) == 0 {
+		return nil
+	}
+
+	args := make([]interface{}, 0, len(messageIDs)+3)
+	args = append(args, ack, session)
+	for _, id := range messageIDs {
+		args = append(args, id)
+	}
+	args = append(args, ack)
+
+	_, err := r.client.DB.ExecContext(ctx,
+		`UPDATE wa_messages SET ack = ? WHERE session = ? AND message_id IN (`+placeholders(len(messageIDs))+`) AND ack < ?`,
+		args...)
+	return err
+}
+
+// updateChatFromMessage creates the chat or updates its preview, counters and flags
+func (r *ChatsRepository) updateChatFromMessage(ctx context.Context, tx *sql.Tx, msg *storage.WAMessage, now time.Time) error {
+	number := msg.From
+	unread := 1
+	if msg.FromMe {
+		number = msg.To
+		unread = 0
+	}
+	if storage.IsGroupJID(msg.ChatID) {
+		number = msg.ChatID
+	}
+	hasInvoice := storage.IsInvoiceBody(msg.Body)
+	isOTP := storage.IsOTPBody(msg.Body) || storage.IsOTPSender(msg.From)
+
+	// Flags only ever switch on from a message, matching the Firestore implementation
+	_, err := tx.ExecContext(ctx, `INSERT INTO wa_chats
+		(session, jid, number, is_group, unread_count, last_message_body, last_message_at, has_invoice, is_otp, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (session, jid) DO UPDATE SET
+			last_message_body = excluded.last_message_body,
+			last_message_at = excluded.last_message_at,
+			updated_at = excluded.updated_at,
+			number = CASE WHEN excluded.is_group = 1 THEN excluded.number ELSE wa_chats.number END,
+			is_group = MAX(wa_chats.is_group, excluded.is_group),
+			unread_count = wa_chats.unread_count + excluded.unread_count,
+			has_invoice = MAX(wa_chats.has_invoice, excluded.has_invoice),
+			is_otp = MAX(wa_chats.is_otp, excluded.is_otp)`,
+		msg.Session, msg.ChatID, number, boolToInt(storage.IsGroupJID(msg.ChatID)), unread, storage.TruncateBody(msg.Body), toMillis(msg.Timestamp),
+		boolToInt(hasInvoice), boolToInt(isOTP), toMillis(now))
+	return err
+}
+
+// MarkChatAsRead marks a session's chat as read
+func (r *ChatsRepository) MarkChatAsRead(ctx context.Context, session, chatJID string) error {
+	res, err := r.client.DB.ExecContext(ctx,
+		`UPDATE wa_chats SET unread_count = 0, updated_at = ? WHERE session = ? AND jid = ?`,
+		toMillis(time.Now()), session, chatJID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// SetChatHasInvoice marks a chat as relevant to invoices
+func (r *ChatsRepository) SetChatHasInvoice(ctx context.Context, session, jid string, hasInvoice bool) error {
+	_, err := r.client.DB.ExecContext(ctx,
+		`UPDATE wa_chats SET has_invoice = ? WHERE session = ? AND jid = ?`, boolToInt(hasInvoice), session, jid)
+	return err
+}
+
+// UpdateChatName updates the name of a chat
+func (r *ChatsRepository) UpdateChatName(ctx context.Context, session, jid string, name string) error {
+	_, err := r.client.DB.ExecContext(ctx,
+		`UPDATE wa_chats SET name = ? WHERE session = ? AND jid = ?`, name, session, jid)
+	return err
+}
+
+// UpsertChat creates a chat that has no messages yet, or renames it if it exists
+func (r *ChatsRepository) UpsertChat(ctx context.Context, session, jid string, name string) error {
+	now := toMillis(time.Now())
+	_, err := r.client.DB.ExecContext(ctx, `INSERT INTO wa_chats (session, jid, name, number, is_group, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (session, jid) DO UPDATE SET name = excluded.name, updated_at = excluded.updated_at`,
+		session, jid, name, jid, boolToInt(storage.IsGroupJID(jid)), now)
+	return err
+}
+
+// UpdateChatProfilePic updates the profile picture of a chat
+func (r *ChatsRepository) UpdateChatProfilePic(ctx context.Context, session, jid string, url string) error {
+	_, err := r.client.DB.ExecContext(ctx,
+		`UPDATE wa_chats SET profile_pic_url = ? WHERE session = ? AND jid = ?`, url, session, jid)
+	return err
+}
+
+// ScanChatMetadata scans all chats and updates metadata flags (HasInvoice, IsOTP) based on keywords
+func (r *ChatsRepository) ScanChatMetadata(ctx context.Context) (int, error) {
+	chats, err := r.queryChats(ctx, `SELECT `+chatColumns+` FROM wa_chats`)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := r.client.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	count := 0
+	for _, chat := range chats {
+		hasInvoice := chat.HasInvoice || storage.IsInvoiceBody(chat.LastMessageBody)
+		isOTP := storage.IsOTPBody(chat.LastMessageBody) || storage.IsOTPChat(chat.Name, chat.Number)
+
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE wa_chats SET has_invoice = ?, is_otp = ?, is_group = ? WHERE session = ? AND jid = ?`,
+			boolToInt(hasInvoice), boolToInt(isOTP), boolToInt(storage.IsGroupJID(chat.JID)), chat.Session, chat.JID); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, tx.Commit()
+}
+
+// AssignSession assigns the chats and messages stored before sessions were recorded to session.
+// A chat the session already has under the same JID absorbs the unassigned one.
+func (r *ChatsRepository) AssignSession(ctx context.Context, session string) (int, error) {
+	tx, err := r.client.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	chats, err := tx.ExecContext(ctx, `INSERT INTO wa_chats (`+chatColumns+`)
+		SELECT ?, jid, name, number, is_group, unread_count, last_message_body, last_message_at, profile_pic_url, has_invoice, is_otp, updated_at
+		FROM wa_chats WHERE session = ''
+		ON CONFLICT (session, jid) DO UPDATE SET
+			name = COALESCE(NULLIF(wa_chats.name, ''), excluded.name),
+			unread_count = wa_chats.unread_count + excluded.unread_count,
+			last_message_body = CASE WHEN excluded.last_message_at > wa_chats.last_message_at THEN excluded.last_message_body ELSE wa_chats.last_message_body END,
+			last_message_at = MAX(wa_chats.last_message_at, excluded.last_message_at),
+			profile_pic_url = COALESCE(NULLIF(wa_chats.profile_pic_url, ''), excluded.profile_pic_url),
+			has_invoice = MAX(wa_chats.has_invoice, excluded.has_invoice),
+			is_otp = MAX(wa_chats.is_otp, excluded.is_otp)`, session)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM wa_chats WHERE session = ''`); err != nil {
+		return 0, err
+	}
+	// A message the session already has under the same ID keeps the session's copy
+	messages, err := tx.ExecContext(ctx, `UPDATE OR IGNORE wa_messages SET session = ? WHERE session = ''`, session)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM wa_messages WHERE session = ''`); err != nil {
+		return 0, err
+	}
+
+	nChats, _ := chats.RowsAffected()
+	nMessages, _ := messages.RowsAffected()
+	return int(nChats + nMessages), tx.Commit()
+}
+
+// queryChats runs a chat query and scans every row
+func (r *ChatsRepository) queryChats(ctx context.Context, query string, args ...interface{}) ([]storage.WAChat, error) {
+	rows, err := r.client.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chats []storage.WAChat
+	for rows.Next() {
+		chat, err := scanChat(rows)
+		if err != nil {
+			return nil, err
+		}
+		chats = append(chats, *chat)
+	}
+	return chats, rows.Err()
+}
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanChat(row rowScanner) (*storage.WAChat, error) {
+	var chat storage.WAChat
+	var isGroup, hasInvoice, isOTP int
+	var lastMessageAt, updatedAt int64
+	err := row.Scan(&chat.Session, &chat.JID, &chat.Name, &chat.Number, &isGroup, &chat.UnreadCount,
+		&chat.LastMessageBody, &lastMessageAt, &chat.ProfilePicURL, &hasInvoice, &isOTP, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	chat.ID = chat.JID
+	chat.IsGroup = isGroup == 1
+	chat.HasInvoice = hasInvoice == 1
+	chat.IsOTP = isOTP == 1
+	chat.LastMessageAt = fromMillis(lastMessageAt)
+	chat.UpdatedAt = fromMillis(updatedAt)
+	return &chat, nil
+}
+
+func scanMessage(row rowScanner) (*storage.WAMessage, error) {
+	var msg storage.WAMessage
+	var fromMe, hasMedia int
+	var timestamp, createdAt, fileLength int64
+	err := row.Scan(&msg.MessageID, &msg.ChatID, &msg.From, &msg.To, &msg.Body, &timestamp,
+		&fromMe, &hasMedia, &msg.MediaType, &msg.MediaURL, &msg.Type, &msg.Ack, &createdAt,
+		&msg.MediaDirectPath, &msg.MediaKey, &msg.MediaFileSHA256, &msg.MediaFileEncSHA256, &fileLength,
+		&msg.SenderJID, &msg.SenderName, &msg.Session)
+	if err != nil {
+		return nil, err
+	}
+	msg.MediaFileLength = uint64(fileLength)
+	msg.ID = msg.MessageID
+	msg.FromMe = fromMe == 1
+	msg.HasMedia = hasMedia == 1
+	msg.Timestamp = fromMillis(timestamp)
+	msg.CreatedAt = fromMillis(createdAt)
+	return &msg, nil
+}
+
+// extraColumns scans the columns following those a scan function knows about
+type extraColumns struct {
+	rowScanner
+	dest []interface{}
+}
+
+func (e extraColumns) Scan(dest ...interface{}) error {
+	return e.rowScanner.Scan(append(dest, e.dest...)...)
+}
+
+// sqlLimit maps the repository convention (0 = no limit) to SQLite's LIMIT -1
+func sqlLimit(limit int) int {
+	if limit <= 0 {
+		return -1
+	}
+	return limit
+}
+
+// pageLimit fetches one row more than a page holds, to tell whether another page follows
+func pageLimit(limit int) int {
+	if limit <= 0 {
+		return -1
+	}
+	return limit + 1
+}
+
+// placeholders returns n comma-separated SQL parameter markers
+func placeholders(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strings.Repeat("?, ", n-1) + "?"
+}
@@ -0,0 +1,522 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"wa-server-go/internal/storage"
+)
+
+// newTestClient opens a fresh database in a temporary directory
+func newTestClient(t *testing.T) *Client {
+	t.Helper()
+	client, err := NewClient(context.Background(), filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+var testEpoch = time.Date(2026, 1, 2, 9, 0, 0, 0, time.UTC)
+
+// testSession is the session saveMessage stores messages under
+const testSession = "bot"
+
+func saveMessage(t *testing.T, repo *ChatsRepository, id, chatID, body string, at time.Time) {
+	t.Helper()
+	saveSessionMessage(t, repo, testSession, id, chatID, body, at)
+}
+
+func saveSessionMessage(t *testing.T, repo *ChatsRepository, session, id, chatID, body string, at time.Time) {
+	t.Helper()
+	err := repo.SaveMessage(context.Background(), &storage.WAMessage{
+		Session:   session,
+		MessageID: id,
+		ChatID:    chatID,
+		From:      chatID,
+		To:        "628000@s.whatsapp.net",
+		Body:      body,
+		Timestamp: at,
+		Type:      "text",
+		Ack:       storage.AckServer,
+	})
+	if err != nil {
+		t.Fatalf("SaveMessage %s: %v", id, err)
+	}
+}
+
+func TestListChatMessagesCursor(t *testing.T) {
+	ctx := context.Background()
+	repo := NewChatsRepository(newTestClient(t))
+	chat := "62811@s.whatsapp.net"
+
+	// m2 and m3 share a timestamp, so the message ID breaks the tie
+	saveMessage(t, repo, "m1", chat, "one", testEpoch)
+	saveMessage(t, repo, "m2", chat, "two", testEpoch.Add(time.Minute))
+	saveMessage(t, repo, "m3", chat, "three", testEpoch.Add(time.Minute))
+	saveMessage(t, repo, "m4", chat, "four", testEpoch.Add(2*time.Minute))
+	saveMessage(t, repo, "m5", chat, "five", testEpoch.Add(3*time.Minute))
+	saveMessage(t, repo, "other", "62822@s.whatsapp.net", "elsewhere", testEpoch.Add(time.Hour))
+
+	var got []string
+	var pages []int
+	cursor := ""
+	for {
+		messages, next, err := repo.ListChatMessages(ctx, testSession, chat, storage.MessageFilter{Limit: 2, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("ListChatMessages: %v", err)
+		}
+		pages = append(pages, len(messages))
+		for _, msg := range messages {
+			got = append(got, msg.MessageID)
+		}
+		if next == "" {
+			break
+		}
+		if len(pages) > 5 {
+			t.Fatal("pagination does not end")
+		}
+		cursor = next
+	}
+
+	if want := []string{"m5", "m4", "m3", "m2", "m1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("messages = %v, want %v", got, want)
+	}
+	if want := []int{2, 2, 1}; !reflect.DeepEqual(pages, want) {
+		t.Errorf("page sizes = %v, want %v", pages, want)
+	}
+}
+
+func TestListChatMessagesLastFullPage(t *testing.T) {
+	repo := NewChatsRepository(newTestClient(t))
+	chat := "62811@s.whatsapp.net"
+	saveMessage(t, repo, "m1", chat, "one", testEpoch)
+	saveMessage(t, repo, "m2", chat, "two", testEpoch.Add(time.Minute))
+
+	messages, next, err := repo.ListChatMessages(context.Background(), testSession, chat, storage.MessageFilter{Limit: 2})
+	if err != nil {
+		t.Fatalf("ListChatMessages: %v", err)
+	}
+	if len(messages) != 2 || next != "" {
+		t.Errorf("got %d messages and cursor %q, want 2 and no cursor", len(messages), next)
+	}
+}
+
+func TestListChatMessagesInvalidCursor(t *testing.T) {
+	repo := NewChatsRepository(newTestClient(t))
+	_, _, err := repo.ListChatMessages(context.Background(), testSession, "62811@s.whatsapp.net", storage.MessageFilter{Limit: 2, Cursor: "not a cursor"})
+	if !errors.Is(err, storage.ErrInvalidCursor) {
+		t.Errorf("error = %v, want ErrInvalidCursor", err)
+	}
+}
+
+func TestListChatsCursor(t *testing.T) {
+	ctx := context.Background()
+	repo := NewChatsRepository(newTestClient(t))
+
+	// b and c have their last message at the same time, so the JID breaks the tie
+	saveMessage(t, repo, "m1", "62801@s.whatsapp.net", "hi", testEpoch)
+	saveMessage(t, repo, "m2", "62802@s.whatsapp.net", "hi", testEpoch.Add(time.Minute))
+	saveMessage(t, repo, "m3", "62803@s.whatsapp.net", "hi", testEpoch.Add(time.Minute))
+	saveMessage(t, repo, "m4", "62804@s.whatsapp.net", "hi", testEpoch.Add(2*time.Minute))
+	// A later message moves the first chat to the top
+	saveMessage(t, repo, "m5", "62801@s.whatsapp.net", "again", testEpoch.Add(3*time.Minute))
+
+	var got []string
+	cursor := ""
+	for page := 0; ; page++ {
+		chats, next, err := repo.ListChats(ctx, storage.ChatFilter{Limit: 3, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("ListChats: %v", err)
+		}
+		for _, chat := range chats {
+			got = append(got, chat.JID)
+		}
+		if next == "" {
+			break
+		}
+		if page > 5 {
+			t.Fatal("pagination does not end")
+		}
+		cursor = next
+	}
+
+	want := []string{"62801@s.whatsapp.net", "62804@s.whatsapp.net", "62803@s.whatsapp.net", "62802@s.whatsapp.net"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("chats = %v, want %v", got, want)
+	}
+}
+
+func TestChatsPerSession(t *testing.T) {
+	ctx := context.Background()
+	repo := NewChatsRepository(newTestClient(t))
+	chat := "62811@s.whatsapp.net"
+
+	saveSessionMessage(t, repo, "bot", "m1", chat, "from bot", testEpoch)
+	saveSessionMessage(t, repo, "cs", "m2", chat, "from cs", testEpoch.Add(time.Minute))
+	saveSessionMessage(t, repo, "cs", "m3", chat, "again", testEpoch.Add(2*time.Minute))
+
+	for session, want := range map[string][]string{"bot": {"m1"}, "cs": {"m3", "m2"}, "other": {}} {
+		messages, _, err := repo.ListChatMessages(ctx, session, chat, storage.MessageFilter{Limit: 10})
+		if err != nil {
+			t.Fatalf("ListChatMessages %s: %v", session, err)
+		}
+		got := []string{}
+		for _, msg := range messages {
+			got = append(got, msg.MessageID)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s messages = %v, want %v", session, got, want)
+		}
+	}
+
+	botChat, err := repo.GetChat(ctx, "bot", chat)
+	if err != nil || botChat == nil {
+		t.Fatalf("GetChat bot: %v, %v", botChat, err)
+	}
+	csChat, err := repo.GetChat(ctx, "cs", chat)
+	if err != nil || csChat == nil {
+		t.Fatalf("GetChat cs: %v, %v", csChat, err)
+	}
+	if csChat.LastMessageBody != "again" || csChat.UnreadCount != 2 || csChat.Session != "cs" {
+		t.Errorf("cs chat = %+v", csChat)
+	}
+
+	if err := repo.MarkChatAsRead(ctx, "cs", chat); err != nil {
+		t.Fatalf("MarkChatAsRead: %v", err)
+	}
+	if botChat, _ = repo.GetChat(ctx, "bot", chat); botChat.UnreadCount == 0 {
+		t.Error("marking the cs chat read reset the bot chat")
+	}
+
+	chats, _, err := repo.ListChats(ctx, storage.ChatFilter{Session: "cs", Limit: 10})
+	if err != nil {
+		t.Fatalf("ListChats: %v", err)
+	}
+	if len(chats) != 1 || chats[0].Session != "cs" {
+		t.Errorf("cs chats = %+v, want the cs chat only", chats)
+	}
+}
+
+func TestSameMessageInTwoSessions(t *testing.T) {
+	ctx := context.Background()
+	repo := NewChatsRepository(newTestClient(t))
+	group := "120363000000000001@g.us"
+
+	// A group message both sessions receive
+	saveSessionMessage(t, repo, "bot", "m1", group, "halo semua", testEpoch)
+	saveSessionMessage(t, repo, "cs", "m1", group, "halo semua", testEpoch)
+
+	for _, session := range []string{"bot", "cs"} {
+		messages, _, err := repo.ListChatMessages(ctx, session, group, storage.MessageFilter{Limit: 10})
+		if err != nil {
+			t.Fatalf("ListChatMessages %s: %v", session, err)
+		}
+		if len(messages) != 1 || messages[0].MessageID != "m1" || messages[0].Session != session {
+			t.Errorf("%s messages = %+v, want its copy of m1", session, messages)
+		}
+		chat, err := repo.GetChat(ctx, session, group)
+		if err != nil || chat == nil || chat.UnreadCount != 1 {
+			t.Errorf("%s chat = %+v, %v, want 1 unread", session, chat, err)
+		}
+	}
+
+	// Receipts and media of one session leave the other's copy alone
+	if err := repo.UpdateMessageAck(ctx, "cs", []string{"m1"}, storage.AckRead); err != nil {
+		t.Fatalf("UpdateMessageAck: %v", err)
+	}
+	if err := repo.UpdateMessageMedia(ctx, &storage.WAMessage{Session: "cs", MessageID: "m1", MediaURL: "/m1.jpg"}); err != nil {
+		t.Fatalf("UpdateMessageMedia: %v", err)
+	}
+	bot, err := repo.GetMessage(ctx, "bot", "m1")
+	if err != nil || bot == nil {
+		t.Fatalf("GetMessage bot: %v, %v", bot, err)
+	}
+	cs, err := repo.GetMessage(ctx, "cs", "m1")
+	if err != nil || cs == nil {
+		t.Fatalf("GetMessage cs: %v, %v", cs, err)
+	}
+	if bot.Ack != storage.AckServer || bot.MediaURL != "" {
+		t.Errorf("bot copy = ack %d, media %q, want unchanged", bot.Ack, bot.MediaURL)
+	}
+	if cs.Ack != storage.AckRead || cs.MediaURL != "/m1.jpg" {
+		t.Errorf("cs copy = ack %d, media %q", cs.Ack, cs.MediaURL)
+	}
+
+	if got := searchIDs(t, repo, "halo"); !reflect.DeepEqual(got, []string{"m1", "m1"}) {
+		t.Errorf("search = %v, want both copies", got)
+	}
+}
+
+func TestAssignSession(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	// A database from before chats and messages had a session
+	for _, stmt := range []string{
+		`CREATE TABLE wa_chats (
+			jid TEXT PRIMARY KEY, name TEXT NOT NULL DEFAULT '', number TEXT NOT NULL DEFAULT '',
+			is_group INTEGER NOT NULL DEFAULT 0, unread_count INTEGER NOT NULL DEFAULT 0,
+			last_message_body TEXT NOT NULL DEFAULT '', last_message_at INTEGER NOT NULL DEFAULT 0,
+			profile_pic_url TEXT NOT NULL DEFAULT '', has_invoice INTEGER NOT NULL DEFAULT 0,
+			is_otp INTEGER NOT NULL DEFAULT 0, updated_at INTEGER NOT NULL DEFAULT 0
+		)`,
+		`CREATE INDEX idx_wa_chats_last_message_at ON wa_chats (last_message_at DESC)`,
+		`CREATE TABLE wa_messages (
+			message_id TEXT PRIMARY KEY, chat_id TEXT NOT NULL, from_jid TEXT NOT NULL DEFAULT '',
+			to_jid TEXT NOT NULL DEFAULT '', body TEXT NOT NULL DEFAULT '', timestamp INTEGER NOT NULL DEFAULT 0,
+			from_me INTEGER NOT NULL DEFAULT 0, has_media INTEGER NOT NULL DEFAULT 0,
+			media_type TEXT NOT NULL DEFAULT '', media_url TEXT NOT NULL DEFAULT '',
+			type TEXT NOT NULL DEFAULT 'text', ack INTEGER NOT NULL DEFAULT 0, created_at INTEGER NOT NULL DEFAULT 0
+		)`,
+		`INSERT INTO wa_chats (jid, name, unread_count, last_message_body, last_message_at) VALUES ('62811@s.whatsapp.net', 'Budi', 1, 'old', 1000)`,
+		`INSERT INTO wa_messages (message_id, chat_id, body, timestamp) VALUES ('m1', '62811@s.whatsapp.net', 'old', 1000)`,
+	} {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	db.Close()
+
+	client, err := NewClient(ctx, path)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer client.Close()
+	repo := NewChatsRepository(client)
+
+	chat, err := repo.GetChat(ctx, "", "62811@s.whatsapp.net")
+	if err != nil || chat == nil || chat.Name != "Budi" {
+		t.Fatalf("migrated chat = %+v, %v", chat, err)
+	}
+
+	// A message the bot session received before the assignment, for the same chat
+	saveMessage(t, repo, "m2", "62811@s.whatsapp.net", "new", fromMillis(2000))
+
+	n, err := repo.AssignSession(ctx, testSession)
+	if err != nil {
+		t.Fatalf("AssignSession: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("assigned %d, want the chat and a message", n)
+	}
+	if n, _ := repo.AssignSession(ctx, testSession); n != 0 {
+		t.Errorf("second AssignSession assigned %d, want 0", n)
+	}
+
+	if chat, _ := repo.GetChat(ctx, "", "62811@s.whatsapp.net"); chat != nil {
+		t.Errorf("unassigned chat left: %+v", chat)
+	}
+	chat, err = repo.GetChat(ctx, testSession, "62811@s.whatsapp.net")
+	if err != nil || chat == nil {
+		t.Fatalf("GetChat: %v, %v", chat, err)
+	}
+	if chat.Name != "Budi" || chat.UnreadCount != 2 || chat.LastMessageBody != "new" {
+		t.Errorf("merged chat = %+v", chat)
+	}
+	messages, _, err := repo.ListChatMessages(ctx, testSession, "62811@s.whatsapp.net", storage.MessageFilter{})
+	if err != nil || len(messages) != 2 {
+		t.Errorf("messages = %d, %v, want 2", len(messages), err)
+	}
+	// The search index is rebuilt with the table
+	if got := searchIDs(t, repo, "old"); !reflect.DeepEqual(got, []string{"m1"}) {
+		t.Errorf("search after the migration = %v, want [m1]", got)
+	}
+}
+
+func TestUpdateMessageAckMonotonic(t *testing.T) {
+	ctx := context.Background()
+	repo := NewChatsRepository(newTestClient(t))
+	chat := "62811@s.whatsapp.net"
+	saveMessage(t, repo, "m1", chat, "one", testEpoch)
+	saveMessage(t, repo, "m2", chat, "two", testEpoch)
+
+	ack := func(id string) int {
+		t.Helper()
+		msg, err := repo.GetMessage(ctx, testSession, id)
+		if err != nil || msg == nil {
+			t.Fatalf("GetMessage %s: %v, %v", id, msg, err)
+		}
+		return msg.Ack
+	}
+
+	if err := repo.UpdateMessageAck(ctx, testSession, []string{"m1", "m2", "unknown"}, storage.AckRead); err != nil {
+		t.Fatalf("UpdateMessageAck: %v", err)
+	}
+	if ack("m1") != storage.AckRead || ack("m2") != storage.AckRead {
+		t.Fatalf("acks = %d, %d, want read", ack("m1"), ack("m2"))
+	}
+
+	// A late delivery receipt must not lower the ack
+	if err := repo.UpdateMessageAck(ctx, testSession, []string{"m1"}, storage.AckDelivered); err != nil {
+		t.Fatalf("UpdateMessageAck: %v", err)
+	}
+	if got := ack("m1"); got != storage.AckRead {
+		t.Errorf("ack after a lower receipt = %d, want %d", got, storage.AckRead)
+	}
+
+	// Neither does saving the message again, as the send echo does
+	saveMessage(t, repo, "m2", chat, "two", testEpoch)
+	if got := ack("m2"); got != storage.AckRead {
+		t.Errorf("ack after a re-save = %d, want %d", got, storage.AckRead)
+	}
+
+	if err := repo.UpdateMessageAck(ctx, testSession, []string{"m2"}, storage.AckPlayed); err != nil {
+		t.Fatalf("UpdateMessageAck: %v", err)
+	}
+	if got := ack("m2"); got != storage.AckPlayed {
+		t.Errorf("ack = %d, want %d", got, storage.AckPlayed)
+	}
+}
+
+// searchIDs returns the IDs of the messages matching query, newest first
+func searchIDs(t *testing.T, repo *ChatsRepository, query string) []string {
+	t.Helper()
+	hits, _, err := repo.SearchMessages(context.Background(), storage.SearchFilter{Query: query})
+	if err != nil {
+		t.Fatalf("SearchMessages %q: %v", query, err)
+	}
+	ids := []string{}
+	for _, hit := range hits {
+		ids = append(ids, hit.Message.MessageID)
+	}
+	return ids
+}
+
+func TestSearchIndexTriggers(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t)
+	repo := NewChatsRepository(client)
+	chat := "62811@s.whatsapp.net"
+
+	saveMessage(t, repo, "m1", chat, "Transfer sudah dikirim", testEpoch)
+	saveMessage(t, repo, "m2", chat, "Terima kasih", testEpoch.Add(time.Minute))
+	if got := searchIDs(t, repo, "transf"); !reflect.DeepEqual(got, []string{"m1"}) {
+		t.Errorf("after insert: %v, want [m1]", got)
+	}
+
+	// Saving the message again with another body reindexes it
+	saveMessage(t, repo, "m1", chat, "Pembayaran diterima", testEpoch)
+	if got := searchIDs(t, repo, "transfer"); len(got) != 0 {
+		t.Errorf("old body still matches: %v", got)
+	}
+	if got := searchIDs(t, repo, "pembayaran"); !reflect.DeepEqual(got, []string{"m1"}) {
+		t.Errorf("after update: %v, want [m1]", got)
+	}
+
+	if _, err := client.DB.ExecContext(ctx, `DELETE FROM wa_messages WHERE message_id = ?`, "m1"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if got := searchIDs(t, repo, "pembayaran"); len(got) != 0 {
+		t.Errorf("deleted message still matches: %v", got)
+	}
+	if got := searchIDs(t, repo, "terima"); !reflect.DeepEqual(got, []string{"m2"}) {
+		t.Errorf("other message: %v, want [m2]", got)
+	}
+}
+
+func TestSearchIndexBuiltForExistingMessages(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "test.db")
+	client, err := NewClient(ctx, path)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	saveMessage(t, NewChatsRepository(client), "m1", "62811@s.whatsapp.net", "Invoice terlampir", testEpoch)
+
+	// A database from before the index existed
+	for _, stmt := range []string{
+		`DROP TRIGGER wa_messages_fts_insert`,
+		`DROP TRIGGER wa_messages_fts_delete`,
+		`DROP TRIGGER wa_messages_fts_update`,
+		`DROP TABLE wa_messages_fts`,
+	} {
+		if _, err := client.DB.ExecContext(ctx, stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	client.Close()
+
+	client, err = NewClient(ctx, path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer client.Close()
+	if got := searchIDs(t, NewChatsRepository(client), "invoice"); !reflect.DeepEqual(got, []string{"m1"}) {
+		t.Errorf("existing message after reopen: %v, want [m1]", got)
+	}
+}
+
+func TestSearchMessagesCursor(t *testing.T) {
+	ctx := context.Background()
+	repo := NewChatsRepository(newTestClient(t))
+	saveMessage(t, repo, "m1", "62811@s.whatsapp.net", "transfer satu", testEpoch)
+	saveMessage(t, repo, "m2", "62822@s.whatsapp.net", "transfer dua", testEpoch.Add(time.Minute))
+	saveMessage(t, repo, "m3", "62811@s.whatsapp.net", "transfer tiga", testEpoch.Add(time.Minute))
+	saveMessage(t, repo, "m4", "62811@s.whatsapp.net", "lain", testEpoch.Add(2*time.Minute))
+
+	var got []string
+	cursor := ""
+	for page := 0; ; page++ {
+		hits, next, err := repo.SearchMessages(ctx, storage.SearchFilter{Query: "transfer", Limit: 2, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("SearchMessages: %v", err)
+		}
+		for _, hit := range hits {
+			got = append(got, hit.Message.MessageID)
+		}
+		if next == "" {
+			break
+		}
+		if page > 5 {
+			t.Fatal("pagination does not end")
+		}
+		cursor = next
+	}
+	if want := []string{"m3", "m2", "m1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("hits = %v, want %v", got, want)
+	}
+}
+
+func TestSearchMessagesSessions(t *testing.T) {
+	ctx := context.Background()
+	repo := NewChatsRepository(newTestClient(t))
+	saveSessionMessage(t, repo, "bot", "m1", "62811@s.whatsapp.net", "transfer satu", testEpoch)
+	saveSessionMessage(t, repo, "cs", "m2", "62811@s.whatsapp.net", "transfer dua", testEpoch.Add(time.Minute))
+	saveSessionMessage(t, repo, "sales", "m3", "62822@s.whatsapp.net", "transfer tiga", testEpoch.Add(2*time.Minute))
+
+	tests := []struct {
+		name     string
+		sessions []string
+		want     []string
+	}{
+		{name: "all sessions", want: []string{"m3", "m2", "m1"}},
+		{name: "one session", sessions: []string{"cs"}, want: []string{"m2"}},
+		{name: "two sessions", sessions: []string{"bot", "sales"}, want: []string{"m3", "m1"}},
+		{name: "no sessions", sessions: []string{}, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits, _, err := repo.SearchMessages(ctx, storage.SearchFilter{Query: "transfer", Sessions: tt.sessions})
+			if err != nil {
+				t.Fatalf("SearchMessages: %v", err)
+			}
+			got := []string{}
+			for _, hit := range hits {
+				got = append(got, hit.Message.MessageID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("hits = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
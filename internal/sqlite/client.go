@@ -0,0 +1,465 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// chatsTable creates wa_chats; a chat is keyed on its session and JID
+const chatsTable = `CREATE TABLE IF NOT EXISTS wa_chats (
+		session           TEXT NOT NULL DEFAULT '',
+		jid               TEXT NOT NULL,
+		name              TEXT NOT NULL DEFAULT '',
+		number            TEXT NOT NULL DEFAULT '',
+		is_group          INTEGER NOT NULL DEFAULT 0,
+		unread_count      INTEGER NOT NULL DEFAULT 0,
+		last_message_body TEXT NOT NULL DEFAULT '',
+		last_message_at   INTEGER NOT NULL DEFAULT 0,
+		profile_pic_url   TEXT NOT NULL DEFAULT '',
+		has_invoice       INTEGER NOT NULL DEFAULT 0,
+		is_otp            INTEGER NOT NULL DEFAULT 0,
+		updated_at        INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (session, jid)
+	)`
+
+// messagesTable creates wa_messages; a message is keyed on its session and ID,
+// so sessions that see the same message (a group both are in) store a copy each
+const messagesTable = `CREATE TABLE IF NOT EXISTS wa_messages (
+		session               TEXT NOT NULL DEFAULT '',
+		message_id            TEXT NOT NULL,
+		chat_id               TEXT NOT NULL,
+		from_jid              TEXT NOT NULL DEFAULT '',
+		to_jid                TEXT NOT NULL DEFAULT '',
+		body                  TEXT NOT NULL DEFAULT '',
+		timestamp             INTEGER NOT NULL DEFAULT 0,
+		from_me               INTEGER NOT NULL DEFAULT 0,
+		has_media             INTEGER NOT NULL DEFAULT 0,
+		media_type            TEXT NOT NULL DEFAULT '',
+		media_url             TEXT NOT NULL DEFAULT '',
+		type                  TEXT NOT NULL DEFAULT 'text',
+		ack                   INTEGER NOT NULL DEFAULT 0,
+		created_at            INTEGER NOT NULL DEFAULT 0,
+		media_direct_path     TEXT NOT NULL DEFAULT '',
+		media_key             BLOB,
+		media_file_sha256     BLOB,
+		media_file_enc_sha256 BLOB,
+		media_file_length     INTEGER NOT NULL DEFAULT 0,
+		sender_jid            TEXT NOT NULL DEFAULT '',
+		sender_name           TEXT NOT NULL DEFAULT '',
+		PRIMARY KEY (session, message_id)
+	)`
+
+const messagesIndex = `CREATE INDEX IF NOT EXISTS idx_wa_messages_chat_timestamp ON wa_messages (chat_id, timestamp DESC)`
+
+// schema is applied on every start; statements must be idempotent
+var schema = []string{
+	chatsTable,
+	`CREATE INDEX IF NOT EXISTS idx_wa_chats_last_message_at ON wa_chats (last_message_at DESC)`,
+	messagesTable,
+	messagesIndex,
+	`CREATE TABLE IF NOT EXISTS leads (
+		id              TEXT PRIMARY KEY,
+		phone           TEXT NOT NULL,
+		name            TEXT NOT NULL DEFAULT '',
+		pushname        TEXT NOT NULL DEFAULT '',
+		tags            TEXT NOT NULL DEFAULT '[]',
+		source          TEXT NOT NULL DEFAULT '',
+		last_message_at INTEGER NOT NULL DEFAULT 0,
+		message_count   INTEGER NOT NULL DEFAULT 0,
+		synced_at       INTEGER NOT NULL DEFAULT 0,
+		created_at      INTEGER NOT NULL DEFAULT 0,
+		updated_at      INTEGER NOT NULL DEFAULT 0
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_leads_phone ON leads (phone)`,
+	`CREATE TABLE IF NOT EXISTS settings (
+		key        TEXT PRIMARY KEY,
+		value      TEXT NOT NULL,
+		updated_at INTEGER NOT NULL DEFAULT 0
+	)`,
+	`CREATE TABLE IF NOT EXISTS outbox_jobs (
+		id              TEXT PRIMARY KEY,
+		kind            TEXT NOT NULL,
+		client_id       TEXT NOT NULL,
+		recipient       TEXT NOT NULL,
+		payload         TEXT NOT NULL,
+		status          TEXT NOT NULL,
+		attempts        INTEGER NOT NULL DEFAULT 0,
+		max_attempts    INTEGER NOT NULL DEFAULT 1,
+		last_error      TEXT NOT NULL DEFAULT '',
+		result          TEXT NOT NULL DEFAULT '',
+		next_attempt_at INTEGER NOT NULL DEFAULT 0,
+		created_at      INTEGER NOT NULL DEFAULT 0,
+		updated_at      INTEGER NOT NULL DEFAULT 0
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_outbox_jobs_due ON outbox_jobs (status, next_attempt_at)`,
+	`CREATE TABLE IF NOT EXISTS webhook_dead_letters (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		delivery_id TEXT NOT NULL,
+		endpoint    TEXT NOT NULL,
+		event       TEXT NOT NULL,
+		payload     TEXT NOT NULL,
+		attempts    INTEGER NOT NULL DEFAULT 0,
+		last_error  TEXT NOT NULL DEFAULT '',
+		created_at  INTEGER NOT NULL DEFAULT 0
+	)`,
+	`CREATE TABLE IF NOT EXISTS wa_sessions (
+		id           TEXT PRIMARY KEY,
+		role         TEXT NOT NULL,
+		auto_connect INTEGER NOT NULL DEFAULT 0,
+		created_at   INTEGER NOT NULL DEFAULT 0
+	)`,
+	`CREATE TABLE IF NOT EXISTS api_keys (
+		id           TEXT PRIMARY KEY,
+		name         TEXT NOT NULL,
+		prefix       TEXT NOT NULL,
+		hash         TEXT NOT NULL UNIQUE,
+		scopes       TEXT NOT NULL DEFAULT '[]',
+		sessions     TEXT NOT NULL DEFAULT '[]',
+		expires_at   INTEGER NOT NULL DEFAULT 0,
+		created_at   INTEGER NOT NULL DEFAULT 0,
+		last_used_at INTEGER NOT NULL DEFAULT 0,
+		revoked_at   INTEGER NOT NULL DEFAULT 0
+	)`,
+	`CREATE TABLE IF NOT EXISTS api_key_audit (
+		id         INTEGER PRIMARY KEY AUTOINCREMENT,
+		key_id     TEXT NOT NULL,
+		key_name   TEXT NOT NULL DEFAULT '',
+		method     TEXT NOT NULL,
+		path       TEXT NOT NULL,
+		status     INTEGER NOT NULL DEFAULT 0,
+		session    TEXT NOT NULL DEFAULT '',
+		recipient  TEXT NOT NULL DEFAULT '',
+		ip         TEXT NOT NULL DEFAULT '',
+		created_at INTEGER NOT NULL DEFAULT 0
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_api_key_audit_key ON api_key_audit (key_id, id DESC)`,
+	`CREATE TABLE IF NOT EXISTS scheduled_messages (
+		id          TEXT PRIMARY KEY,
+		session     TEXT NOT NULL,
+		recipient   TEXT NOT NULL,
+		kind        TEXT NOT NULL,
+		message     TEXT NOT NULL DEFAULT '',
+		media_url   TEXT NOT NULL DEFAULT '',
+		media_type  TEXT NOT NULL DEFAULT '',
+		send_at     INTEGER NOT NULL DEFAULT 0,
+		cron        TEXT NOT NULL DEFAULT '',
+		timezone    TEXT NOT NULL,
+		status      TEXT NOT NULL,
+		next_run_at INTEGER NOT NULL DEFAULT 0,
+		last_run_at INTEGER NOT NULL DEFAULT 0,
+		run_count   INTEGER NOT NULL DEFAULT 0,
+		created_at  INTEGER NOT NULL DEFAULT 0,
+		updated_at  INTEGER NOT NULL DEFAULT 0
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_scheduled_messages_due ON scheduled_messages (status, next_run_at)`,
+	`CREATE TABLE IF NOT EXISTS scheduled_message_executions (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		schedule_id TEXT NOT NULL,
+		job_id      TEXT NOT NULL DEFAULT '',
+		error       TEXT NOT NULL DEFAULT '',
+		run_at      INTEGER NOT NULL DEFAULT 0
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_scheduled_message_executions_schedule ON scheduled_message_executions (schedule_id, id DESC)`,
+	`CREATE TABLE IF NOT EXISTS invoices (
+		id          TEXT PRIMARY KEY,
+		number      TEXT NOT NULL UNIQUE,
+		client_name TEXT NOT NULL DEFAULT '',
+		phone       TEXT NOT NULL,
+		due_date    INTEGER NOT NULL,
+		amount      INTEGER NOT NULL DEFAULT 0,
+		status      TEXT NOT NULL,
+		pdf_url     TEXT NOT NULL DEFAULT '',
+		session     TEXT NOT NULL,
+		created_at  INTEGER NOT NULL DEFAULT 0,
+		updated_at  INTEGER NOT NULL DEFAULT 0,
+		paid_at     INTEGER NOT NULL DEFAULT 0
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_invoices_due ON invoices (status, due_date)`,
+	`CREATE TABLE IF NOT EXISTS invoice_dunning_steps (
+		id         INTEGER PRIMARY KEY AUTOINCREMENT,
+		invoice_id TEXT NOT NULL,
+		step       TEXT NOT NULL,
+		template   TEXT NOT NULL,
+		job_id     TEXT NOT NULL DEFAULT '',
+		error      TEXT NOT NULL DEFAULT '',
+		created_at INTEGER NOT NULL DEFAULT 0
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_invoice_dunning_steps_invoice ON invoice_dunning_steps (invoice_id, id)`,
+	`CREATE TABLE IF NOT EXISTS backup_runs (
+		id          TEXT PRIMARY KEY,
+		trigger     TEXT NOT NULL,
+		session     TEXT NOT NULL DEFAULT '',
+		status      TEXT NOT NULL,
+		stage       TEXT NOT NULL DEFAULT '',
+		files       TEXT NOT NULL DEFAULT '[]',
+		error       TEXT NOT NULL DEFAULT '',
+		started_at  INTEGER NOT NULL DEFAULT 0,
+		finished_at INTEGER NOT NULL DEFAULT 0
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_backup_runs_started ON backup_runs (started_at DESC)`,
+	`CREATE TABLE IF NOT EXISTS monitor_checks (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		target      TEXT NOT NULL,
+		status      TEXT NOT NULL,
+		latency_ms  INTEGER NOT NULL DEFAULT 0,
+		status_code INTEGER NOT NULL DEFAULT 0,
+		error       TEXT NOT NULL DEFAULT '',
+		checked_at  INTEGER NOT NULL DEFAULT 0
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_monitor_checks_target ON monitor_checks (target, id DESC)`,
+	`CREATE INDEX IF NOT EXISTS idx_monitor_checks_checked ON monitor_checks (checked_at)`,
+	`CREATE TABLE IF NOT EXISTS blog_topics (
+		id         TEXT PRIMARY KEY,
+		topic      TEXT NOT NULL,
+		status     TEXT NOT NULL,
+		draft      INTEGER NOT NULL DEFAULT 0,
+		title      TEXT NOT NULL DEFAULT '',
+		entry_id   TEXT NOT NULL DEFAULT '',
+		url        TEXT NOT NULL DEFAULT '',
+		error      TEXT NOT NULL DEFAULT '',
+		created_at INTEGER NOT NULL DEFAULT 0,
+		updated_at INTEGER NOT NULL DEFAULT 0
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_blog_topics_status ON blog_topics (status, created_at)`,
+	`CREATE TABLE IF NOT EXISTS contacts (
+		jid             TEXT PRIMARY KEY,
+		custom_name     TEXT NOT NULL DEFAULT '',
+		notes           TEXT NOT NULL DEFAULT '',
+		profile_pic_url TEXT NOT NULL DEFAULT '',
+		updated_at      INTEGER NOT NULL DEFAULT 0
+	)`,
+	`CREATE TABLE IF NOT EXISTS lid_mappings (
+		lid        TEXT PRIMARY KEY,
+		phone      TEXT NOT NULL,
+		updated_at INTEGER NOT NULL DEFAULT 0
+	)`,
+}
+
+// columnMigrations adds columns introduced after a table was first created
+var columnMigrations = []struct {
+	table, column, definition string
+}{
+	{"wa_messages", "media_direct_path", "TEXT NOT NULL DEFAULT ''"},
+	{"wa_messages", "media_key", "BLOB"},
+	{"wa_messages", "media_file_sha256", "BLOB"},
+	{"wa_messages", "media_file_enc_sha256", "BLOB"},
+	{"wa_messages", "media_file_length", "INTEGER NOT NULL DEFAULT 0"},
+	{"wa_messages", "sender_jid", "TEXT NOT NULL DEFAULT ''"},
+	{"wa_messages", "sender_name", "TEXT NOT NULL DEFAULT ''"},
+	{"wa_messages", "session", "TEXT NOT NULL DEFAULT ''"},
+	{"blog_topics", "slug", "TEXT NOT NULL DEFAULT ''"},
+	{"invoice_dunning_steps", "due_date", "INTEGER NOT NULL DEFAULT 0"},
+	{"invoice_dunning_steps", "attempts", "INTEGER NOT NULL DEFAULT 1"},
+	{"invoice_dunning_steps", "updated_at", "INTEGER NOT NULL DEFAULT 0"},
+	{"api_key_audit", "action", "TEXT NOT NULL DEFAULT ''"},
+	{"api_key_audit", "details", "TEXT NOT NULL DEFAULT ''"},
+}
+
+// searchIndex is the FTS5 index over message bodies; the triggers keep it in sync with wa_messages
+var searchIndex = []string{
+	`CREATE VIRTUAL TABLE IF NOT EXISTS wa_messages_fts USING fts5(
+		body, content = 'wa_messages', content_rowid = 'rowid', tokenize = 'unicode61 remove_diacritics 2'
+	)`,
+	`CREATE TRIGGER IF NOT EXISTS wa_messages_fts_insert AFTER INSERT ON wa_messages BEGIN
+		INSERT INTO wa_messages_fts (rowid, body) VALUES (new.rowid, new.body);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS wa_messages_fts_delete AFTER DELETE ON wa_messages BEGIN
+		INSERT INTO wa_messages_fts (wa_messages_fts, rowid, body) VALUES ('delete', old.rowid, old.body);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS wa_messages_fts_update AFTER UPDATE OF body ON wa_messages BEGIN
+		INSERT INTO wa_messages_fts (wa_messages_fts, rowid, body) VALUES ('delete', old.rowid, old.body);
+		INSERT INTO wa_messages_fts (rowid, body) VALUES (new.rowid, new.body);
+	END`,
+}
+
+// Client wraps the SQLite database used for local business data
+type Client struct {
+	DB   *sql.DB
+	Path string
+}
+
+// NewClient opens (or creates) the SQLite database and applies the schema
+func NewClient(ctx context.Context, dbPath string) (*Client, error) {
+	// Same pragmas as the whatsmeow session stores for concurrent access
+	dsn := dbPath + "?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=synchronous(NORMAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite database: %w", err)
+	}
+
+	if err := ensureChatSessions(ctx, db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to add sessions to wa_chats: %w", err)
+	}
+	for _, stmt := range schema {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to apply SQLite schema: %w", err)
+		}
+	}
+	for _, m := range columnMigrations {
+		if err := ensureColumn(ctx, db, m.table, m.column, m.definition); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to migrate %s.%s: %w", m.table, m.column, err)
+		}
+	}
+
+	if err := ensureMessageSessions(ctx, db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to key wa_messages on sessions: %w", err)
+	}
+	if err := ensureSearchIndex(ctx, db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create message search index: %w", err)
+	}
+
+	log.Printf("✅ SQLite storage initialized at: %s", dbPath)
+
+	return &Client{
+		DB:   db,
+		Path: dbPath,
+	}, nil
+}
+
+// Close closes the database
+func (c *Client) Close() error {
+	if c.DB != nil {
+		return c.DB.Close()
+	}
+	return nil
+}
+
+// ensureColumn adds a column to an existing table if it is missing
+func ensureColumn(ctx context.Context, db *sql.DB, table, column, definition string) error {
+	var count int
+	err := db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&count)
+	if err != nil || count > 0 {
+		return err
+	}
+	_, err = db.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, definition))
+	return err
+}
+
+// ensureChatSessions rebuilds a wa_chats table created before chats had a session,
+// since the session is part of the primary key. The chats keep an empty session until AssignSession.
+func ensureChatSessions(ctx context.Context, db *sql.DB) error {
+	var columns, sessions int
+	err := db.QueryRowContext(ctx,
+		`SELECT COUNT(*), COUNT(CASE WHEN name = 'session' THEN 1 END) FROM pragma_table_info('wa_chats')`).Scan(&columns, &sessions)
+	if err != nil || columns == 0 || sessions > 0 {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	const copied = `jid, name, number, is_group, unread_count, last_message_body, last_message_at, profile_pic_url, has_invoice, is_otp, updated_at`
+	for _, stmt := range []string{
+		`ALTER TABLE wa_chats RENAME TO wa_chats_unsessioned`,
+		`DROP INDEX IF EXISTS idx_wa_chats_last_message_at`,
+		chatsTable,
+		`INSERT INTO wa_chats (` + copied + `) SELECT ` + copied + ` FROM wa_chats_unsessioned`,
+		`DROP TABLE wa_chats_unsessioned`,
+	} {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ensureMessageSessions rebuilds a wa_messages table keyed on the message ID alone, so that it is keyed
+// on the session and message ID. The search index refers to rows by rowid, so it is dropped and rebuilt.
+func ensureMessageSessions(ctx context.Context, db *sql.DB) error {
+	var keys int
+	err := db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM pragma_table_info('wa_messages') WHERE pk > 0`).Scan(&keys)
+	if err != nil || keys != 1 {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range []string{
+		`DROP TRIGGER IF EXISTS wa_messages_fts_insert`,
+		`DROP TRIGGER IF EXISTS wa_messages_fts_delete`,
+		`DROP TRIGGER IF EXISTS wa_messages_fts_update`,
+		`DROP TABLE IF EXISTS wa_messages_fts`,
+		`ALTER TABLE wa_messages RENAME TO wa_messages_unsessioned`,
+		`DROP INDEX IF EXISTS idx_wa_messages_chat_timestamp`,
+		messagesTable,
+		messagesIndex,
+		`INSERT INTO wa_messages (` + messageColumns + `) SELECT ` + messageColumns + ` FROM wa_messages_unsessioned`,
+		`DROP TABLE wa_messages_unsessioned`,
+	} {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ensureSearchIndex creates the message search index, indexing the existing messages the first time
+func ensureSearchIndex(ctx context.Context, db *sql.DB) error {
+	var count int
+	err := db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'wa_messages_fts'`).Scan(&count)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range searchIndex {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	if count == 0 {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO wa_messages_fts (wa_messages_fts) VALUES ('rebuild')`); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// toMillis converts a time to the unix milliseconds stored in INTEGER columns
+func toMillis(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+// fromMillis converts stored unix milliseconds back to a time
+func fromMillis(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
+
+// boolToInt converts a bool to the 0/1 stored in INTEGER columns
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,77 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"wa-server-go/internal/contacts"
+)
+
+var _ contacts.Repository = (*ContactRepository)(nil)
+
+// ContactRepository stores custom contact names, notes and profile pictures in the contacts table
+type ContactRepository struct {
+	client *Client
+}
+
+// NewContactRepository creates a new contact repository
+func NewContactRepository(client *Client) *ContactRepository {
+	return &ContactRepository{client: client}
+}
+
+const contactColumns = `jid, custom_name, notes, profile_pic_url, updated_at`
+
+// Get returns the record of a contact, or nil if there is none
+func (r *ContactRepository) Get(ctx context.Context, jid string) (*contacts.Record, error) {
+	record, err := scanContact(r.client.DB.QueryRowContext(ctx,
+		`SELECT `+contactColumns+` FROM contacts WHERE jid = ?`, jid))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return record, err
+}
+
+// List returns every contact record
+func (r *ContactRepository) List(ctx context.Context) ([]contacts.Record, error) {
+	rows, err := r.client.DB.QueryContext(ctx, `SELECT `+contactColumns+` FROM contacts ORDER BY jid`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []contacts.Record{}
+	for rows.Next() {
+		record, err := scanContact(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, *record)
+	}
+	return records, rows.Err()
+}
+
+// Save inserts or replaces a contact record
+func (r *ContactRepository) Save(ctx context.Context, record *contacts.Record) error {
+	_, err := r.client.DB.ExecContext(ctx, `INSERT INTO contacts (`+contactColumns+`) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(jid) DO UPDATE SET custom_name = excluded.custom_name, notes = excluded.notes,
+			profile_pic_url = excluded.profile_pic_url, updated_at = excluded.updated_at`,
+		record.JID, record.CustomName, record.Notes, record.ProfilePicURL, toMillis(record.UpdatedAt))
+	return err
+}
+
+// Delete removes a contact record
+func (r *ContactRepository) Delete(ctx context.Context, jid string) error {
+	_, err := r.client.DB.ExecContext(ctx, `DELETE FROM contacts WHERE jid = ?`, jid)
+	return err
+}
+
+func scanContact(row rowScanner) (*contacts.Record, error) {
+	var record contacts.Record
+	var updatedAt int64
+	if err := row.Scan(&record.JID, &record.CustomName, &record.Notes, &record.ProfilePicURL, &updatedAt); err != nil {
+		return nil, err
+	}
+	record.UpdatedAt = fromMillis(updatedAt)
+	return &record, nil
+}
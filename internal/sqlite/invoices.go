@@ -0,0 +1,176 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"wa-server-go/internal/features/reminder"
+)
+
+var _ reminder.Repository = (*InvoiceRepository)(nil)
+
+// InvoiceRepository stores invoices in invoices and their reminders in invoice_dunning_steps
+type InvoiceRepository struct {
+	client *Client
+}
+
+// NewInvoiceRepository creates a new invoice repository
+func NewInvoiceRepository(client *Client) *InvoiceRepository {
+	return &InvoiceRepository{client: client}
+}
+
+const invoiceColumns = `id, number, client_name, phone, due_date, amount, status, pdf_url, session,
+	created_at, updated_at, paid_at`
+
+// Save inserts or updates an invoice
+func (r *InvoiceRepository) Save(ctx context.Context, invoice *reminder.Invoice) error {
+	_, err := r.client.DB.ExecContext(ctx, `INSERT INTO invoices (`+invoiceColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			number = excluded.number,
+			client_name = excluded.client_name,
+			phone = excluded.phone,
+			due_date = excluded.due_date,
+			amount = excluded.amount,
+			status = excluded.status,
+			pdf_url = excluded.pdf_url,
+			session = excluded.session,
+			updated_at = excluded.updated_at,
+			paid_at = excluded.paid_at`,
+		invoice.ID, invoice.Number, invoice.ClientName, invoice.Phone, toMillis(invoice.DueDate),
+		invoice.Amount, string(invoice.Status), invoice.PdfURL, invoice.Session,
+		toMillis(invoice.CreatedAt), toMillis(invoice.UpdatedAt), optionalMillis(invoice.PaidAt))
+	return err
+}
+
+// Get returns an invoice by ID, or nil if it does not exist
+func (r *InvoiceRepository) Get(ctx context.Context, id string) (*reminder.Invoice, error) {
+	return r.get(ctx, `SELECT `+invoiceColumns+` FROM invoices WHERE id = ?`, id)
+}
+
+// GetByNumber returns an invoice by number, or nil if it does not exist
+func (r *InvoiceRepository) GetByNumber(ctx context.Context, number string) (*reminder.Invoice, error) {
+	return r.get(ctx, `SELECT `+invoiceColumns+` FROM invoices WHERE number = ?`, number)
+}
+
+// List returns invoices by due date, optionally filtered by status and by session (empty means all)
+func (r *InvoiceRepository) List(ctx context.Context, status reminder.InvoiceStatus, sessions []string, limit int) ([]reminder.Invoice, error) {
+	query := `SELECT ` + invoiceColumns + ` FROM invoices WHERE 1 = 1`
+	args := []interface{}{}
+	if status != "" {
+		query += ` AND status = ?`
+		args = append(args, string(status))
+	}
+	if len(sessions) > 0 {
+		query += ` AND session IN (` + placeholders(len(sessions)) + `)`
+		for _, session := range sessions {
+			args = append(args, session)
+		}
+	}
+	query += ` ORDER BY due_date LIMIT ?`
+	args = append(args, sqlLimit(limit))
+	return r.query(ctx, query, args...)
+}
+
+// ListOpen returns unpaid and partially paid invoices due on or before the given time
+func (r *InvoiceRepository) ListOpen(ctx context.Context, dueBefore time.Time) ([]reminder.Invoice, error) {
+	return r.query(ctx, `SELECT `+invoiceColumns+` FROM invoices
+		WHERE status IN (?, ?) AND due_date <= ? ORDER BY due_date`,
+		string(reminder.StatusUnpaid), string(reminder.StatusPartial), toMillis(dueBefore))
+}
+
+// AddStep records one dunning step of an invoice
+func (r *InvoiceRepository) AddStep(ctx context.Context, step *reminder.DunningStep) error {
+	res, err := r.client.DB.ExecContext(ctx, `INSERT INTO invoice_dunning_steps
+		(invoice_id, step, template, due_date, job_id, error, attempts, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		step.InvoiceID, step.Step, step.Template, toMillis(step.DueDate), step.JobID, step.Error,
+		step.Attempts, toMillis(step.CreatedAt), toMillis(step.UpdatedAt))
+	if err != nil {
+		return err
+	}
+	step.ID, err = res.LastInsertId()
+	return err
+}
+
+// UpdateStep stores the outcome of another attempt of a recorded step
+func (r *InvoiceRepository) UpdateStep(ctx context.Context, step *reminder.DunningStep) error {
+	_, err := r.client.DB.ExecContext(ctx, `UPDATE invoice_dunning_steps
+		SET template = ?, due_date = ?, job_id = ?, error = ?, attempts = ?, updated_at = ? WHERE id = ?`,
+		step.Template, toMillis(step.DueDate), step.JobID, step.Error, step.Attempts, toMillis(step.UpdatedAt), step.ID)
+	return err
+}
+
+// ListSteps returns the dunning history of an invoice, oldest first
+func (r *InvoiceRepository) ListSteps(ctx context.Context, invoiceID string) ([]reminder.DunningStep, error) {
+	rows, err := r.client.DB.QueryContext(ctx, `SELECT id, invoice_id, step, template, due_date, job_id, error,
+		attempts, created_at, updated_at
+		FROM invoice_dunning_steps WHERE invoice_id = ? ORDER BY id`, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	steps := []reminder.DunningStep{}
+	for rows.Next() {
+		var step reminder.DunningStep
+		var dueDate, createdAt, updatedAt int64
+		if err := rows.Scan(&step.ID, &step.InvoiceID, &step.Step, &step.Template, &dueDate, &step.JobID, &step.Error,
+			&step.Attempts, &createdAt, &updatedAt); err != nil {
+			return nil, err
+		}
+		step.DueDate = fromMillis(dueDate)
+		step.CreatedAt = fromMillis(createdAt)
+		step.UpdatedAt = fromMillis(updatedAt)
+		if step.UpdatedAt.IsZero() {
+			step.UpdatedAt = step.CreatedAt
+		}
+		steps = append(steps, step)
+	}
+	return steps, rows.Err()
+}
+
+func (r *InvoiceRepository) get(ctx context.Context, query string, args ...interface{}) (*reminder.Invoice, error) {
+	invoice, err := scanInvoice(r.client.DB.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return invoice, err
+}
+
+func (r *InvoiceRepository) query(ctx context.Context, query string, args ...interface{}) ([]reminder.Invoice, error) {
+	rows, err := r.client.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invoices := []reminder.Invoice{}
+	for rows.Next() {
+		invoice, err := scanInvoice(rows)
+		if err != nil {
+			return nil, err
+		}
+		invoices = append(invoices, *invoice)
+	}
+	return invoices, rows.Err()
+}
+
+func scanInvoice(row rowScanner) (*reminder.Invoice, error) {
+	var invoice reminder.Invoice
+	var status string
+	var dueDate, createdAt, updatedAt, paidAt int64
+	err := row.Scan(&invoice.ID, &invoice.Number, &invoice.ClientName, &invoice.Phone, &dueDate,
+		&invoice.Amount, &status, &invoice.PdfURL, &invoice.Session, &createdAt, &updatedAt, &paidAt)
+	if err != nil {
+		return nil, err
+	}
+	invoice.Status = reminder.InvoiceStatus(status)
+	invoice.DueDate = fromMillis(dueDate)
+	invoice.CreatedAt = fromMillis(createdAt)
+	invoice.UpdatedAt = fromMillis(updatedAt)
+	invoice.PaidAt = optionalTime(paidAt)
+	return &invoice, nil
+}
@@ -0,0 +1,208 @@
+package sqlite
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"wa-server-go/internal/storage"
+)
+
+const leadColumns = `id, phone, name, pushname, tags, source, last_message_at, message_count, synced_at, created_at, updated_at`
+
+// leadFieldColumns maps the Firestore field names accepted by Update to table columns
+var leadFieldColumns = map[string]string{
+	"phone":         "phone",
+	"name":          "name",
+	"pushname":      "pushname",
+	"tags":          "tags",
+	"source":        "source",
+	"lastMessageAt": "last_message_at",
+	"messageCount":  "message_count",
+	"syncedAt":      "synced_at",
+	"createdAt":     "created_at",
+	"updatedAt":     "updated_at",
+}
+
+// LeadsRepository provides access to the leads table
+type LeadsRepository struct {
+	client *Client
+}
+
+// NewLeadsRepository creates a new leads repository
+func NewLeadsRepository(client *Client) *LeadsRepository {
+	return &LeadsRepository{client: client}
+}
+
+// GetByTag retrieves leads with a specific tag
+func (r *LeadsRepository) GetByTag(ctx context.Context, tag string) ([]storage.Lead, error) {
+	return r.queryLeads(ctx,
+		`SELECT `+leadColumns+` FROM leads WHERE EXISTS (SELECT 1 FROM json_each(leads.tags) WHERE json_each.value = ?)`,
+		tag)
+}
+
+// GetAll retrieves all leads
+func (r *LeadsRepository) GetAll(ctx context.Context, limit int) ([]storage.Lead, error) {
+	return r.queryLeads(ctx, `SELECT `+leadColumns+` FROM leads ORDER BY created_at DESC LIMIT ?`, sqlLimit(limit))
+}
+
+// GetByPhone retrieves a lead by phone number
+func (r *LeadsRepository) GetByPhone(ctx context.Context, phone string) (*storage.Lead, error) {
+	row := r.client.DB.QueryRowContext(ctx, `SELECT `+leadColumns+` FROM leads WHERE phone = ? LIMIT 1`, phone)
+	lead, err := scanLead(row)
+	if err == sql.ErrNoRows {
+		return nil, nil // Not found
+	}
+	return lead, err
+}
+
+// Create creates a new lead
+func (r *LeadsRepository) Create(ctx context.Context, lead *storage.Lead) (string, error) {
+	now := time.Now()
+	lead.CreatedAt = now
+	lead.UpdatedAt = now
+	lead.SyncedAt = now
+
+	id, err := newID()
+	if err != nil {
+		return "", err
+	}
+	tags, err := json.Marshal(nonNilTags(lead.Tags))
+	if err != nil {
+		return "", err
+	}
+
+	_, err = r.client.DB.ExecContext(ctx, `INSERT INTO leads (`+leadColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		id, lead.Phone, lead.Name, lead.PushName, string(tags), lead.Source,
+		toMillis(lead.LastMessageAt), lead.MessageCount, toMillis(lead.SyncedAt),
+		toMillis(lead.CreatedAt), toMillis(lead.UpdatedAt))
+	if err != nil {
+		return "", err
+	}
+	lead.ID = id
+	return id, nil
+}
+
+// Update updates an existing lead
+func (r *LeadsRepository) Update(ctx context.Context, id string, updates map[string]interface{}) error {
+	updates["updatedAt"] = time.Now()
+
+	sets := make([]string, 0, len(updates))
+	args := make([]interface{}, 0, len(updates)+1)
+	for key, value := range updates {
+		column, ok := leadFieldColumns[key]
+		if !ok {
+			return fmt.Errorf("unknown lead field: %s", key)
+		}
+		switch v := value.(type) {
+		case time.Time:
+			value = toMillis(v)
+		case []string:
+			data, err := json.Marshal(nonNilTags(v))
+			if err != nil {
+				return err
+			}
+			value = string(data)
+		}
+		sets = append(sets, column+" = ?")
+		args = append(args, value)
+	}
+	args = append(args, id)
+
+	_, err := r.client.DB.ExecContext(ctx, `UPDATE leads SET `+strings.Join(sets, ", ")+` WHERE id = ?`, args...)
+	return err
+}
+
+// UpsertFromMessage creates or updates a lead from an incoming message
+func (r *LeadsRepository) UpsertFromMessage(ctx context.Context, phone, pushName string) error {
+	existing, err := r.GetByPhone(ctx, phone)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	if existing != nil {
+		// Update existing lead
+		updates := map[string]interface{}{
+			"lastMessageAt": now,
+			"messageCount":  existing.MessageCount + 1,
+		}
+		if pushName != "" && existing.PushName != pushName {
+			updates["pushname"] = pushName
+		}
+		return r.Update(ctx, existing.ID, updates)
+	}
+
+	// Create new lead
+	newLead := &storage.Lead{
+		Phone:         phone,
+		Name:          pushName,
+		PushName:      pushName,
+		Tags:          []string{},
+		Source:        "incoming_message",
+		LastMessageAt: now,
+		MessageCount:  1,
+	}
+	_, err = r.Create(ctx, newLead)
+	return err
+}
+
+// queryLeads runs a lead query and scans every row
+func (r *LeadsRepository) queryLeads(ctx context.Context, query string, args ...interface{}) ([]storage.Lead, error) {
+	rows, err := r.client.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var leads []storage.Lead
+	for rows.Next() {
+		lead, err := scanLead(rows)
+		if err != nil {
+			return nil, err
+		}
+		leads = append(leads, *lead)
+	}
+	return leads, rows.Err()
+}
+
+func scanLead(row rowScanner) (*storage.Lead, error) {
+	var lead storage.Lead
+	var tags string
+	var lastMessageAt, syncedAt, createdAt, updatedAt int64
+	err := row.Scan(&lead.ID, &lead.Phone, &lead.Name, &lead.PushName, &tags, &lead.Source,
+		&lastMessageAt, &lead.MessageCount, &syncedAt, &createdAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(tags), &lead.Tags); err != nil {
+		lead.Tags = []string{}
+	}
+	lead.LastMessageAt = fromMillis(lastMessageAt)
+	lead.SyncedAt = fromMillis(syncedAt)
+	lead.CreatedAt = fromMillis(createdAt)
+	lead.UpdatedAt = fromMillis(updatedAt)
+	return &lead, nil
+}
+
+func nonNilTags(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return tags
+}
+
+// newID generates a random document ID like Firestore's auto IDs
+func newID() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,90 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"wa-server-go/internal/whatsapp"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+var _ whatsapp.LIDRepository = (*LIDRepository)(nil)
+
+// lidLookupBatch bounds the LIDs looked up per query
+const lidLookupBatch = 500
+
+// LIDRepository stores learned LID to phone number mappings in the lid_mappings table
+type LIDRepository struct {
+	client *Client
+}
+
+// NewLIDRepository creates a new LID mapping repository
+func NewLIDRepository(client *Client) *LIDRepository {
+	return &LIDRepository{client: client}
+}
+
+// GetPhones returns the phone number JIDs of the given LIDs that have one
+func (r *LIDRepository) GetPhones(ctx context.Context, lids []types.JID) (map[types.JID]types.JID, error) {
+	phones := make(map[types.JID]types.JID, len(lids))
+	for start := 0; start < len(lids); start += lidLookupBatch {
+		batch := lids[start:min(start+lidLookupBatch, len(lids))]
+		args := make([]interface{}, len(batch))
+		for i, lid := range batch {
+			args[i] = lid.String()
+		}
+
+		rows, err := r.client.DB.QueryContext(ctx,
+			`SELECT lid, phone FROM lid_mappings WHERE lid IN (`+placeholders(len(batch))+`)`, args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var lid, phone string
+			if err := rows.Scan(&lid, &phone); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			lidJID, err := types.ParseJID(lid)
+			if err != nil {
+				continue
+			}
+			phoneJID, err := types.ParseJID(phone)
+			if err != nil {
+				continue
+			}
+			phones[lidJID] = phoneJID
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return phones, nil
+}
+
+// SaveMappings stores mappings in one transaction, replacing the phone number of LIDs already known
+func (r *LIDRepository) SaveMappings(ctx context.Context, mappings []whatsapp.LIDMapping) error {
+	tx, err := r.client.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO lid_mappings (lid, phone, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(lid) DO UPDATE SET phone = excluded.phone, updated_at = excluded.updated_at
+		WHERE phone != excluded.phone`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	now := toMillis(time.Now())
+	for _, m := range mappings {
+		if _, err := stmt.ExecContext(ctx, m.LID.String(), m.Phone.String(), now); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
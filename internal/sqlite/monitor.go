@@ -0,0 +1,65 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"wa-server-go/internal/features/monitor"
+)
+
+var _ monitor.Repository = (*MonitorCheckRepository)(nil)
+
+// MonitorCheckRepository stores health check history in monitor_checks
+type MonitorCheckRepository struct {
+	client *Client
+}
+
+// NewMonitorCheckRepository creates a new monitor check repository
+func NewMonitorCheckRepository(client *Client) *MonitorCheckRepository {
+	return &MonitorCheckRepository{client: client}
+}
+
+// AddCheck records one check
+func (r *MonitorCheckRepository) AddCheck(ctx context.Context, check *monitor.Check) error {
+	res, err := r.client.DB.ExecContext(ctx, `INSERT INTO monitor_checks
+		(target, status, latency_ms, status_code, error, checked_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		check.Target, string(check.Status), check.LatencyMs, check.StatusCode, check.Error, toMillis(check.CheckedAt))
+	if err != nil {
+		return err
+	}
+	check.ID, err = res.LastInsertId()
+	return err
+}
+
+// ListChecks returns the most recent checks of a target first
+func (r *MonitorCheckRepository) ListChecks(ctx context.Context, target string, limit int) ([]monitor.Check, error) {
+	rows, err := r.client.DB.QueryContext(ctx, `SELECT id, target, status, latency_ms, status_code, error, checked_at
+		FROM monitor_checks WHERE target = ? ORDER BY id DESC LIMIT ?`, target, sqlLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	checks := []monitor.Check{}
+	for rows.Next() {
+		var check monitor.Check
+		var status string
+		var checkedAt int64
+		if err := rows.Scan(&check.ID, &check.Target, &status, &check.LatencyMs, &check.StatusCode, &check.Error, &checkedAt); err != nil {
+			return nil, err
+		}
+		check.Status = monitor.HealthStatus(status)
+		check.CheckedAt = fromMillis(checkedAt)
+		checks = append(checks, check)
+	}
+	return checks, rows.Err()
+}
+
+// PruneChecks deletes checks older than before
+func (r *MonitorCheckRepository) PruneChecks(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.client.DB.ExecContext(ctx, `DELETE FROM monitor_checks WHERE checked_at < ?`, toMillis(before))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
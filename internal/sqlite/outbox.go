@@ -0,0 +1,161 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"wa-server-go/internal/outbox"
+)
+
+const jobColumns = `id, kind, client_id, recipient, payload, status, attempts, max_attempts, last_error, result, next_attempt_at, created_at, updated_at`
+
+var _ outbox.Repository = (*OutboxRepository)(nil)
+
+// OutboxRepository persists outbound message jobs in the outbox_jobs table
+type OutboxRepository struct {
+	client *Client
+}
+
+// NewOutboxRepository creates a new outbox repository
+func NewOutboxRepository(client *Client) *OutboxRepository {
+	return &OutboxRepository{client: client}
+}
+
+// Enqueue inserts a new job
+func (r *OutboxRepository) Enqueue(ctx context.Context, job *outbox.Job) error {
+	result, err := encodeResult(job.Result)
+	if err != nil {
+		return err
+	}
+	_, err = r.client.DB.ExecContext(ctx, `INSERT INTO outbox_jobs (`+jobColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		job.ID, job.Kind, job.ClientID, job.Recipient, string(job.Payload), string(job.Status),
+		job.Attempts, job.MaxAttempts, job.LastError, result,
+		toMillis(job.NextAttemptAt), toMillis(job.CreatedAt), toMillis(job.UpdatedAt))
+	return err
+}
+
+// Get retrieves a job by ID, returning nil if it does not exist
+func (r *OutboxRepository) Get(ctx context.Context, id string) (*outbox.Job, error) {
+	row := r.client.DB.QueryRowContext(ctx, `SELECT `+jobColumns+` FROM outbox_jobs WHERE id = ?`, id)
+	job, err := scanJob(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return job, err
+}
+
+// ListDue returns queued or retrying jobs whose next attempt is due, oldest first
+func (r *OutboxRepository) ListDue(ctx context.Context, now time.Time, limit int) ([]outbox.Job, error) {
+	rows, err := r.client.DB.QueryContext(ctx, `SELECT `+jobColumns+` FROM outbox_jobs
+		WHERE status IN (?, ?) AND next_attempt_at <= ?
+		ORDER BY next_attempt_at, created_at LIMIT ?`,
+		string(outbox.StatusQueued), string(outbox.StatusRetrying), toMillis(now), sqlLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []outbox.Job
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, rows.Err()
+}
+
+// Claim moves a due job to sending; it reports false if the job was claimed elsewhere
+func (r *OutboxRepository) Claim(ctx context.Context, id string, now time.Time) (bool, error) {
+	res, err := r.client.DB.ExecContext(ctx, `UPDATE outbox_jobs SET status = ?, updated_at = ?
+		WHERE id = ? AND status IN (?, ?)`,
+		string(outbox.StatusSending), toMillis(now), id,
+		string(outbox.StatusQueued), string(outbox.StatusRetrying))
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// Update stores the job's state, attempts, error and result
+func (r *OutboxRepository) Update(ctx context.Context, job *outbox.Job) error {
+	result, err := encodeResult(job.Result)
+	if err != nil {
+		return err
+	}
+	_, err = r.client.DB.ExecContext(ctx, `UPDATE outbox_jobs SET
+			status = ?, attempts = ?, last_error = ?, result = ?, next_attempt_at = ?, updated_at = ?
+		WHERE id = ?`,
+		string(job.Status), job.Attempts, job.LastError, result,
+		toMillis(job.NextAttemptAt), toMillis(job.UpdatedAt), job.ID)
+	return err
+}
+
+// RequeueInflight resets jobs left in sending by a previous process
+func (r *OutboxRepository) RequeueInflight(ctx context.Context) (int, error) {
+	res, err := r.client.DB.ExecContext(ctx, `UPDATE outbox_jobs SET status = ?, updated_at = ? WHERE status = ?`,
+		string(outbox.StatusQueued), toMillis(time.Now()), string(outbox.StatusSending))
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// CountUnfinished returns the number of queued, retrying and sending jobs by status
+func (r *OutboxRepository) CountUnfinished(ctx context.Context) (map[outbox.JobStatus]int, error) {
+	rows, err := r.client.DB.QueryContext(ctx, `SELECT status, COUNT(*) FROM outbox_jobs
+		WHERE status IN (?, ?, ?) GROUP BY status`,
+		string(outbox.StatusQueued), string(outbox.StatusRetrying), string(outbox.StatusSending))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[outbox.JobStatus]int)
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		counts[outbox.JobStatus(status)] = n
+	}
+	return counts, rows.Err()
+}
+
+func scanJob(row rowScanner) (*outbox.Job, error) {
+	var job outbox.Job
+	var payload, status, result string
+	var nextAttemptAt, createdAt, updatedAt int64
+	err := row.Scan(&job.ID, &job.Kind, &job.ClientID, &job.Recipient, &payload, &status,
+		&job.Attempts, &job.MaxAttempts, &job.LastError, &result,
+		&nextAttemptAt, &createdAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	job.Payload = json.RawMessage(payload)
+	job.Status = outbox.JobStatus(status)
+	if result != "" {
+		_ = json.Unmarshal([]byte(result), &job.Result)
+	}
+	job.NextAttemptAt = fromMillis(nextAttemptAt)
+	job.CreatedAt = fromMillis(createdAt)
+	job.UpdatedAt = fromMillis(updatedAt)
+	return &job, nil
+}
+
+func encodeResult(result map[string]interface{}) (string, error) {
+	if result == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
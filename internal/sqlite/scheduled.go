@@ -0,0 +1,168 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"wa-server-go/internal/features/scheduler"
+)
+
+var _ scheduler.Repository = (*ScheduledMessageRepository)(nil)
+
+// ScheduledMessageRepository stores schedules in scheduled_messages and their runs in scheduled_message_executions
+type ScheduledMessageRepository struct {
+	client *Client
+}
+
+// NewScheduledMessageRepository creates a new scheduled message repository
+func NewScheduledMessageRepository(client *Client) *ScheduledMessageRepository {
+	return &ScheduledMessageRepository{client: client}
+}
+
+const scheduledMessageColumns = `id, session, recipient, kind, message, media_url, media_type, send_at, cron, timezone,
+	status, next_run_at, last_run_at, run_count, created_at, updated_at`
+
+// Create inserts a new schedule
+func (r *ScheduledMessageRepository) Create(ctx context.Context, msg *scheduler.ScheduledMessage) error {
+	_, err := r.client.DB.ExecContext(ctx, `INSERT INTO scheduled_messages (`+scheduledMessageColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		msg.ID, msg.Session, msg.Recipient, msg.Kind, msg.Message, msg.MediaURL, msg.MediaType,
+		optionalMillis(msg.SendAt), msg.Cron, msg.Timezone, string(msg.Status),
+		optionalMillis(msg.NextRunAt), optionalMillis(msg.LastRunAt), msg.RunCount,
+		toMillis(msg.CreatedAt), toMillis(msg.UpdatedAt))
+	return err
+}
+
+// Get returns a schedule by ID, or nil if it does not exist
+func (r *ScheduledMessageRepository) Get(ctx context.Context, id string) (*scheduler.ScheduledMessage, error) {
+	row := r.client.DB.QueryRowContext(ctx, `SELECT `+scheduledMessageColumns+` FROM scheduled_messages WHERE id = ?`, id)
+	msg, err := scanScheduledMessage(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return msg, err
+}
+
+// List returns schedules, newest first, optionally filtered by status
+func (r *ScheduledMessageRepository) List(ctx context.Context, status scheduler.Status, sessions []string, limit int) ([]scheduler.ScheduledMessage, error) {
+	query := `SELECT ` + scheduledMessageColumns + ` FROM scheduled_messages WHERE 1 = 1`
+	args := []interface{}{}
+	if status != "" {
+		query += ` AND status = ?`
+		args = append(args, string(status))
+	}
+	if len(sessions) > 0 {
+		query += ` AND session IN (` + placeholders(len(sessions)) + `)`
+		for _, session := range sessions {
+			args = append(args, session)
+		}
+	}
+	query += ` ORDER BY created_at DESC LIMIT ?`
+	args = append(args, sqlLimit(limit))
+	return r.query(ctx, query, args...)
+}
+
+// ListDue returns active schedules whose next run is at or before now, earliest first
+func (r *ScheduledMessageRepository) ListDue(ctx context.Context, now time.Time, limit int) ([]scheduler.ScheduledMessage, error) {
+	return r.query(ctx, `SELECT `+scheduledMessageColumns+` FROM scheduled_messages
+		WHERE status = ? AND next_run_at > 0 AND next_run_at <= ?
+		ORDER BY next_run_at LIMIT ?`,
+		string(scheduler.StatusActive), toMillis(now), sqlLimit(limit))
+}
+
+// Update saves the mutable state of a schedule
+func (r *ScheduledMessageRepository) Update(ctx context.Context, msg *scheduler.ScheduledMessage) error {
+	_, err := r.client.DB.ExecContext(ctx, `UPDATE scheduled_messages
+		SET status = ?, next_run_at = ?, last_run_at = ?, run_count = ?, updated_at = ?
+		WHERE id = ?`,
+		string(msg.Status), optionalMillis(msg.NextRunAt), optionalMillis(msg.LastRunAt),
+		msg.RunCount, toMillis(msg.UpdatedAt), msg.ID)
+	return err
+}
+
+// UpdateRun saves a run of a schedule only if it is still active and due at dueAt
+func (r *ScheduledMessageRepository) UpdateRun(ctx context.Context, msg *scheduler.ScheduledMessage, dueAt time.Time) (bool, error) {
+	res, err := r.client.DB.ExecContext(ctx, `UPDATE scheduled_messages
+		SET status = ?, next_run_at = ?, last_run_at = ?, run_count = ?, updated_at = ?
+		WHERE id = ? AND status = ? AND next_run_at = ?`,
+		string(msg.Status), optionalMillis(msg.NextRunAt), optionalMillis(msg.LastRunAt),
+		msg.RunCount, toMillis(msg.UpdatedAt), msg.ID, string(scheduler.StatusActive), toMillis(dueAt))
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// AddExecution records one run of a schedule
+func (r *ScheduledMessageRepository) AddExecution(ctx context.Context, exec *scheduler.Execution) error {
+	res, err := r.client.DB.ExecContext(ctx, `INSERT INTO scheduled_message_executions
+		(schedule_id, job_id, error, run_at) VALUES (?, ?, ?, ?)`,
+		exec.ScheduleID, exec.JobID, exec.Error, toMillis(exec.RunAt))
+	if err != nil {
+		return err
+	}
+	exec.ID, err = res.LastInsertId()
+	return err
+}
+
+// ListExecutions returns the most recent executions of a schedule first
+func (r *ScheduledMessageRepository) ListExecutions(ctx context.Context, scheduleID string, limit int) ([]scheduler.Execution, error) {
+	rows, err := r.client.DB.QueryContext(ctx, `SELECT id, schedule_id, job_id, error, run_at
+		FROM scheduled_message_executions WHERE schedule_id = ? ORDER BY id DESC LIMIT ?`,
+		scheduleID, sqlLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	executions := []scheduler.Execution{}
+	for rows.Next() {
+		var exec scheduler.Execution
+		var runAt int64
+		if err := rows.Scan(&exec.ID, &exec.ScheduleID, &exec.JobID, &exec.Error, &runAt); err != nil {
+			return nil, err
+		}
+		exec.RunAt = fromMillis(runAt)
+		executions = append(executions, exec)
+	}
+	return executions, rows.Err()
+}
+
+func (r *ScheduledMessageRepository) query(ctx context.Context, query string, args ...interface{}) ([]scheduler.ScheduledMessage, error) {
+	rows, err := r.client.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []scheduler.ScheduledMessage{}
+	for rows.Next() {
+		msg, err := scanScheduledMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, *msg)
+	}
+	return messages, rows.Err()
+}
+
+func scanScheduledMessage(row rowScanner) (*scheduler.ScheduledMessage, error) {
+	var msg scheduler.ScheduledMessage
+	var status string
+	var sendAt, nextRunAt, lastRunAt, createdAt, updatedAt int64
+	err := row.Scan(&msg.ID, &msg.Session, &msg.Recipient, &msg.Kind, &msg.Message, &msg.MediaURL, &msg.MediaType,
+		&sendAt, &msg.Cron, &msg.Timezone, &status, &nextRunAt, &lastRunAt, &msg.RunCount, &createdAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	msg.Status = scheduler.Status(status)
+	msg.SendAt = optionalTime(sendAt)
+	msg.NextRunAt = optionalTime(nextRunAt)
+	msg.LastRunAt = optionalTime(lastRunAt)
+	msg.CreatedAt = fromMillis(createdAt)
+	msg.UpdatedAt = fromMillis(updatedAt)
+	return &msg, nil
+}
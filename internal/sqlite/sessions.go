@@ -0,0 +1,60 @@
+package sqlite
+
+import (
+	"context"
+
+	"wa-server-go/internal/whatsapp"
+)
+
+var _ whatsapp.SessionRegistry = (*SessionRepository)(nil)
+
+// SessionRepository persists registered WhatsApp sessions in the wa_sessions table
+type SessionRepository struct {
+	client *Client
+}
+
+// NewSessionRepository creates a new session repository
+func NewSessionRepository(client *Client) *SessionRepository {
+	return &SessionRepository{client: client}
+}
+
+// ListSessions returns all registered sessions, oldest first
+func (r *SessionRepository) ListSessions(ctx context.Context) ([]whatsapp.SessionRecord, error) {
+	rows, err := r.client.DB.QueryContext(ctx,
+		`SELECT id, role, auto_connect, created_at FROM wa_sessions ORDER BY created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []whatsapp.SessionRecord{}
+	for rows.Next() {
+		var session whatsapp.SessionRecord
+		var role string
+		var autoConnect int
+		var createdAt int64
+		if err := rows.Scan(&session.ID, &role, &autoConnect, &createdAt); err != nil {
+			return nil, err
+		}
+		session.Role = whatsapp.Role(role)
+		session.AutoConnect = autoConnect != 0
+		session.CreatedAt = fromMillis(createdAt)
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// SaveSession inserts or updates a session; the original creation time is kept
+func (r *SessionRepository) SaveSession(ctx context.Context, session *whatsapp.SessionRecord) error {
+	_, err := r.client.DB.ExecContext(ctx, `INSERT INTO wa_sessions (id, role, auto_connect, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET role = excluded.role, auto_connect = excluded.auto_connect`,
+		session.ID, string(session.Role), boolToInt(session.AutoConnect), toMillis(session.CreatedAt))
+	return err
+}
+
+// DeleteSession removes a session
+func (r *SessionRepository) DeleteSession(ctx context.Context, id string) error {
+	_, err := r.client.DB.ExecContext(ctx, `DELETE FROM wa_sessions WHERE id = ?`, id)
+	return err
+}
@@ -0,0 +1,23 @@
+package sqlite
+
+import (
+	"wa-server-go/internal/storage"
+)
+
+var (
+	_ storage.ChatsRepository    = (*ChatsRepository)(nil)
+	_ storage.LeadsRepository    = (*LeadsRepository)(nil)
+	_ storage.WAStatusRepository = (*WAStatusRepository)(nil)
+)
+
+// NewStore creates a storage.Store backed by the local SQLite database.
+// The client is shared with the outbox, so the caller is responsible for closing it.
+func NewStore(client *Client) *storage.Store {
+	return storage.NewStore(
+		storage.BackendSQLite,
+		NewChatsRepository(client),
+		NewLeadsRepository(client),
+		NewWAStatusRepository(client),
+		nil,
+	)
+}
@@ -0,0 +1,123 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"wa-server-go/internal/storage"
+)
+
+// Settings keys, named after the Firestore settings documents they replace
+const (
+	settingsWAStatusIDs = "wa_status_ids"
+	settingsWhatsApp    = "whatsapp_settings"
+	settingsBanners     = "banner_settings"
+)
+
+// WAStatusRepository manages WhatsApp Status entries in the settings table
+type WAStatusRepository struct {
+	client *Client
+}
+
+// NewWAStatusRepository creates a new WA status repository
+func NewWAStatusRepository(client *Client) *WAStatusRepository {
+	return &WAStatusRepository{client: client}
+}
+
+// SaveStatusIDs saves the posted WA status IDs
+func (r *WAStatusRepository) SaveStatusIDs(ctx context.Context, entries []storage.WAStatusEntry, activeNumber string) error {
+	record := storage.WAStatusRecord{
+		StatusIDs:    entries,
+		LastPostedAt: time.Now(),
+		ActiveNumber: activeNumber,
+	}
+	if err := r.client.putSetting(ctx, settingsWAStatusIDs, record); err != nil {
+		return fmt.Errorf("failed to save WA status IDs: %w", err)
+	}
+
+	fmt.Printf("💾 Saved %d WA status IDs to SQLite\n", len(entries))
+	return nil
+}
+
+// GetStatusIDs retrieves the posted WA status IDs
+func (r *WAStatusRepository) GetStatusIDs(ctx context.Context) (*storage.WAStatusRecord, error) {
+	var record storage.WAStatusRecord
+	found, err := r.client.getSetting(ctx, settingsWAStatusIDs, &record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode WA status record: %w", err)
+	}
+	if !found {
+		// Setting doesn't exist yet
+		return &storage.WAStatusRecord{StatusIDs: []storage.WAStatusEntry{}}, nil
+	}
+	return &record, nil
+}
+
+// ClearStatusIDs removes all WA status IDs
+func (r *WAStatusRepository) ClearStatusIDs(ctx context.Context) error {
+	if err := r.client.putSetting(ctx, settingsWAStatusIDs, storage.WAStatusRecord{
+		StatusIDs:    []storage.WAStatusEntry{},
+		LastPostedAt: time.Now(),
+	}); err != nil {
+		return fmt.Errorf("failed to clear WA status IDs: %w", err)
+	}
+
+	fmt.Println("🗑️ Cleared all WA status IDs from SQLite")
+	return nil
+}
+
+// GetWhatsAppActiveNumber reads the whatsapp_settings setting and applies the frontend rotation logic
+func (r *WAStatusRepository) GetWhatsAppActiveNumber(ctx context.Context) (string, error) {
+	var settings storage.WASettingsRecord
+	found, err := r.client.getSetting(ctx, settingsWhatsApp, &settings)
+	if err != nil || !found {
+		return storage.DefaultActiveNumber, nil
+	}
+	return storage.ResolveActiveNumber(&settings, time.Now()), nil
+}
+
+// GetBannerSettings reads the banner_settings setting ({"images": [{"url", "title"}]})
+func (r *WAStatusRepository) GetBannerSettings(ctx context.Context) ([]map[string]string, error) {
+	var settings struct {
+		Images []map[string]string `json:"images"`
+	}
+	found, err := r.client.getSetting(ctx, settingsBanners, &settings)
+	if err != nil {
+		return nil, fmt.Errorf("banner_settings is invalid: %w", err)
+	}
+	if !found {
+		return nil, fmt.Errorf("banner_settings not found")
+	}
+	if settings.Images == nil {
+		return []map[string]string{}, nil
+	}
+	return settings.Images, nil
+}
+
+// getSetting decodes a JSON setting into dest, reporting whether it exists
+func (c *Client) getSetting(ctx context.Context, key string, dest interface{}) (bool, error) {
+	var value string
+	err := c.DB.QueryRowContext(ctx, `SELECT value FROM settings WHERE key = ?`, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal([]byte(value), dest)
+}
+
+// putSetting stores value as a JSON setting
+func (c *Client) putSetting(ctx context.Context, key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, err = c.DB.ExecContext(ctx, `INSERT INTO settings (key, value, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT (key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`,
+		key, string(data), toMillis(time.Now()))
+	return err
+}
@@ -0,0 +1,59 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+
+	"wa-server-go/internal/webhook"
+)
+
+var _ webhook.DeadLetterRepository = (*WebhookDeadLetterRepository)(nil)
+
+// WebhookDeadLetterRepository stores failed webhook deliveries in the webhook_dead_letters table
+type WebhookDeadLetterRepository struct {
+	client *Client
+}
+
+// NewWebhookDeadLetterRepository creates a new dead-letter repository
+func NewWebhookDeadLetterRepository(client *Client) *WebhookDeadLetterRepository {
+	return &WebhookDeadLetterRepository{client: client}
+}
+
+// Add records a failed delivery
+func (r *WebhookDeadLetterRepository) Add(ctx context.Context, letter *webhook.DeadLetter) error {
+	res, err := r.client.DB.ExecContext(ctx, `INSERT INTO webhook_dead_letters
+		(delivery_id, endpoint, event, payload, attempts, last_error, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		letter.DeliveryID, letter.Endpoint, letter.Event, string(letter.Payload),
+		letter.Attempts, letter.LastError, toMillis(letter.CreatedAt))
+	if err != nil {
+		return err
+	}
+	letter.ID, err = res.LastInsertId()
+	return err
+}
+
+// List returns the most recent dead letters first
+func (r *WebhookDeadLetterRepository) List(ctx context.Context, limit int) ([]webhook.DeadLetter, error) {
+	rows, err := r.client.DB.QueryContext(ctx, `SELECT id, delivery_id, endpoint, event, payload, attempts, last_error, created_at
+		FROM webhook_dead_letters ORDER BY id DESC LIMIT ?`, sqlLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	letters := []webhook.DeadLetter{}
+	for rows.Next() {
+		var letter webhook.DeadLetter
+		var payload string
+		var createdAt int64
+		if err := rows.Scan(&letter.ID, &letter.DeliveryID, &letter.Endpoint, &letter.Event,
+			&payload, &letter.Attempts, &letter.LastError, &createdAt); err != nil {
+			return nil, err
+		}
+		letter.Payload = json.RawMessage(payload)
+		letter.CreatedAt = fromMillis(createdAt)
+		letters = append(letters, letter)
+	}
+	return letters, rows.Err()
+}
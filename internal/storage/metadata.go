@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"strings"
+)
+
+// IsInvoiceBody checks a message body for invoice keywords
+func IsInvoiceBody(body string) bool {
+	return strings.Contains(strings.ToUpper(body), "INV-") || strings.Contains(strings.ToLower(body), "invoice") || strings.Contains(strings.ToLower(body), "tagihan")
+}
+
+// IsOTPBody checks a message body for OTP keywords
+func IsOTPBody(body string) bool {
+	bodyLower := strings.ToLower(body)
+	return strings.Contains(bodyLower, "otp") || strings.Contains(bodyLower, "kode verifikasi") || strings.Contains(bodyLower, "verification code")
+}
+
+// IsOTPSender checks whether a sender JID belongs to a known OTP sender (Stockbit, TRI)
+func IsOTPSender(sender string) bool {
+	sender = strings.ToLower(sender)
+	return strings.Contains(sender, "stockbit") || strings.Contains(sender, "628999800123") || strings.Contains(sender, "tri")
+}
+
+// IsOTPChat checks a stored chat's name and number against known OTP senders
+func IsOTPChat(name, number string) bool {
+	nameLower := strings.ToLower(name)
+	return strings.Contains(nameLower, "stockbit") || strings.Contains(nameLower, "tri indonesia") || strings.Contains(number, "628999800123")
+}
+
+// TruncateBody shortens a message body for the chat preview
+func TruncateBody(body string) string {
+	const maxLen = 100
+	if len(body) <= maxLen {
+		return body
+	}
+	return body[:maxLen] + "..."
+}
+
+// IsGroupJID reports whether a chat JID is a group (…@g.us)
+func IsGroupJID(jid string) bool {
+	return strings.HasSuffix(jid, "@g.us")
+}
@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"time"
+)
+
+// WAChat represents a WhatsApp chat
+type WAChat struct {
+	ID              string    `firestore:"-"`
+	JID             string    `firestore:"jid"`
+	Name            string    `firestore:"name"`
+	Number          string    `firestore:"number"`
+	IsGroup         bool      `firestore:"isGroup"`
+	UnreadCount     int       `firestore:"unreadCount"`
+	LastMessageBody string    `firestore:"lastMessageBody,omitempty"`
+	LastMessageAt   time.Time `firestore:"lastMessageAt,omitempty"`
+	ProfilePicURL   string    `firestore:"profilePicUrl,omitempty"`
+	HasInvoice      bool      `firestore:"hasInvoice,omitempty"`
+	IsOTP           bool      `firestore:"isOTP,omitempty"`
+	UpdatedAt       time.Time `firestore:"updatedAt"`
+
+	// Session the chat belongs to; two sessions talking to the same JID have a chat each
+	Session string `firestore:"session"`
+}
+
+// WAMessage represents a WhatsApp message
+type WAMessage struct {
+	ID        string    `firestore:"-"`
+	MessageID string    `firestore:"messageId"`
+	ChatID    string    `firestore:"chatId"`
+	From      string    `firestore:"from"`
+	To        string    `firestore:"to"`
+	Body      string    `firestore:"body"`
+	Timestamp time.Time `firestore:"timestamp"`
+	FromMe    bool      `firestore:"fromMe"`
+	HasMedia  bool      `firestore:"hasMedia"`
+	MediaType string    `firestore:"mediaType,omitempty"`
+	MediaURL  string    `firestore:"mediaUrl,omitempty"`
+	Type      string    `firestore:"type"` // text, image, document, audio, video, sticker
+	Ack       int       `firestore:"ack"`
+	CreatedAt time.Time `firestore:"createdAt"`
+
+	// Session that sent or received the message, and that can download its media again.
+	// A message is keyed on its session and ID: two sessions that see it (a group both are in) store a copy each.
+	Session string `firestore:"session"`
+
+	// Sender of the message, the participant in a group chat
+	SenderJID  string `firestore:"senderJid,omitempty"`
+	SenderName string `firestore:"senderName,omitempty"`
+
+	// Media keys, kept so the file can be re-downloaded from WhatsApp later
+	MediaDirectPath    string `firestore:"mediaDirectPath,omitempty"`
+	MediaKey           []byte `firestore:"mediaKey,omitempty"`
+	MediaFileSHA256    []byte `firestore:"mediaFileSha256,omitempty"`
+	MediaFileEncSHA256 []byte `firestore:"mediaFileEncSha256,omitempty"`
+	MediaFileLength    uint64 `firestore:"mediaFileLength,omitempty"`
+
+	// Words of Body for full-text search where the backend has no search index (Firestore)
+	SearchTokens []string `firestore:"searchTokens,omitempty"`
+}
+
+// Message ack levels, matching the values used by the web app
+const (
+	AckPending   = 0
+	AckServer    = 1 // accepted by the WhatsApp server
+	AckDelivered = 2
+	AckRead      = 3
+	AckPlayed    = 4 // voice note / view-once opened
+)
+
+// AckName returns the display name of an ack level
+func AckName(ack int) string {
+	switch ack {
+	case AckServer:
+		return "server"
+	case AckDelivered:
+		return "delivered"
+	case AckRead:
+		return "read"
+	case AckPlayed:
+		return "played"
+	default:
+		return "pending"
+	}
+}
+
+// Lead represents a contact/lead (replacement for WA Labels)
+type Lead struct {
+	ID            string    `firestore:"-"`
+	Phone         string    `firestore:"phone"`
+	Name          string    `firestore:"name"`
+	PushName      string    `firestore:"pushname,omitempty"`
+	Tags          []string  `firestore:"tags"`
+	Source        string    `firestore:"source"` // whatsapp_sync, manual, import, incoming_message
+	LastMessageAt time.Time `firestore:"lastMessageAt,omitempty"`
+	MessageCount  int       `firestore:"messageCount,omitempty"`
+	SyncedAt      time.Time `firestore:"syncedAt"`
+	CreatedAt     time.Time `firestore:"createdAt"`
+	UpdatedAt     time.Time `firestore:"updatedAt"`
+}
+
+// WAStatusEntry represents a single WA status that was posted
+type WAStatusEntry struct {
+	MessageID string    `firestore:"messageId" json:"messageId"`
+	BannerURL string    `firestore:"bannerUrl" json:"bannerUrl"`
+	Caption   string    `firestore:"caption" json:"caption"`
+	PostedAt  time.Time `firestore:"postedAt" json:"postedAt"`
+}
+
+// WAStatusRecord represents the set of currently posted statuses
+type WAStatusRecord struct {
+	StatusIDs    []WAStatusEntry `firestore:"statusIds" json:"statusIds"`
+	LastPostedAt time.Time       `firestore:"lastPostedAt" json:"lastPostedAt"`
+	ActiveNumber string          `firestore:"activeNumber" json:"activeNumber"`
+}
+
+// IsExpired checks whether the last post is older than 24 hours
+func (r *WAStatusRecord) IsExpired() bool {
+	if r.LastPostedAt.IsZero() {
+		return true
+	}
+	return time.Since(r.LastPostedAt) >= 24*time.Hour
+}
+
+// WASettingsRecord represents the whatsapp_settings document shared with the web app
+type WASettingsRecord struct {
+	MainNumber      string   `firestore:"mainNumber" json:"mainNumber"`
+	ScheduleEnabled bool     `firestore:"scheduleEnabled" json:"scheduleEnabled"`
+	ScheduleType    string   `firestore:"scheduleType" json:"scheduleType"`
+	ExcludedNumbers []string `firestore:"excludedNumbers" json:"excludedNumbers"`
+}
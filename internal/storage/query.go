@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor is returned for a cursor that was not produced by NextCursor
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ChatFilter selects a page of chats, newest last message first
+type ChatFilter struct {
+	Session    string // only chats of this session; empty means all sessions
+	Limit      int
+	Cursor     string    // NextCursor of the previous page
+	Before     time.Time // only chats whose last message is older; zero means no bound
+	Since      time.Time // only chats whose last message is at or after; zero means no bound
+	UnreadOnly bool
+	HasInvoice bool  // only chats with invoices
+	OTP        bool  // only OTP chats; otherwise OTP chats are left out
+	Groups     *bool // only groups (true) or only direct chats (false); nil means both
+}
+
+// MessageFilter selects a page of a chat's messages, newest first
+type MessageFilter struct {
+	Limit  int
+	Cursor string    // NextCursor of the previous page
+	Before time.Time // only older messages; zero means no bound
+	Since  time.Time // only messages at or after; zero means no bound
+}
+
+// Cursor is a decoded page position: the sort time and ID of the last item returned
+type Cursor struct {
+	Time time.Time
+	ID   string
+}
+
+// NextCursor encodes the position after an item, as an opaque string
+func NextCursor(t time.Time, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(t.UnixMicro(), 10) + ":" + id))
+}
+
+// ParseCursor decodes a cursor; an empty string yields nil
+func ParseCursor(cursor string) (*Cursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	micros, id, ok := strings.Cut(string(raw), ":")
+	if !ok || id == "" {
+		return nil, ErrInvalidCursor
+	}
+	us, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &Cursor{Time: time.UnixMicro(us), ID: id}, nil
+}
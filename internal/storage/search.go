@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"html"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Markers around the matched terms of a search snippet; the rest of a snippet is HTML-escaped
+const (
+	HighlightStart = "<mark>"
+	HighlightEnd   = "</mark>"
+)
+
+// Control characters a full-text index puts around matches in place of the highlight markers,
+// so the snippet can be escaped before the markers are inserted
+const (
+	RawHighlightStart = "\x02"
+	RawHighlightEnd   = "\x03"
+)
+
+// highlighter escapes a raw snippet and turns its raw markers into highlight markers
+var highlighter = strings.NewReplacer(RawHighlightStart, HighlightStart, RawHighlightEnd, HighlightEnd)
+
+// maxSearchTokens bounds the tokens indexed per message, keeping Firestore index entries small
+const maxSearchTokens = 100
+
+// snippetRadius is how many characters of context a snippet keeps around the first match
+const snippetRadius = 60
+
+// SearchFilter selects a page of messages matching a full-text query, newest first
+type SearchFilter struct {
+	Query    string
+	Sessions []string  // only messages of these sessions; nil means all sessions
+	ChatID   string    // only messages of this chat; empty means all chats
+	Before   time.Time // only older messages; zero means no bound
+	Since    time.Time // only messages at or after; zero means no bound
+	FromMe   *bool     // only sent (true) or only received (false); nil means both
+	Type     string    // message type, e.g. text or image; empty means any
+	HasMedia *bool
+	Limit    int
+	Cursor   string // NextCursor of the previous page
+}
+
+// MessageHit is a message matching a search, with the matched terms highlighted in Snippet
+type MessageHit struct {
+	Message WAMessage
+	Snippet string
+}
+
+// SearchTokens splits text into the lower-cased words a search matches on, without duplicates
+func SearchTokens(text string) []string {
+	seen := make(map[string]bool)
+	var tokens []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), isSeparator) {
+		if seen[word] {
+			continue
+		}
+		seen[word] = true
+		tokens = append(tokens, word)
+		if len(tokens) == maxSearchTokens {
+			break
+		}
+	}
+	return tokens
+}
+
+// Snippet returns the HTML-escaped part of body around the first word matching one of terms, with the
+// matching words highlighted. Terms match word prefixes, like the SQLite index.
+func Snippet(body string, terms []string) string {
+	runes := []rune(body)
+	lower := []rune(strings.ToLower(body))
+	if len(lower) != len(runes) {
+		// Lower-casing changed the length; match on the original text
+		lower = runes
+	}
+
+	// Find where each word starts and whether it matches a term
+	type match struct{ start, end int }
+	var matches []match
+	for i := 0; i < len(lower); {
+		if isSeparator(lower[i]) {
+			i++
+			continue
+		}
+		end := i
+		for end < len(lower) && !isSeparator(lower[end]) {
+			end++
+		}
+		word := string(lower[i:end])
+		for _, term := range terms {
+			if strings.HasPrefix(word, term) {
+				matches = append(matches, match{i, end})
+				break
+			}
+		}
+		i = end
+	}
+
+	from, to := 0, len(runes)
+	if len(matches) > 0 {
+		from = max(0, matches[0].start-snippetRadius)
+		to = min(len(runes), matches[0].end+snippetRadius)
+	} else {
+		to = min(len(runes), 2*snippetRadius)
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	pos := from
+	for _, m := range matches {
+		if m.start < from || m.end > to {
+			continue
+		}
+		b.WriteString(html.EscapeString(string(runes[pos:m.start])))
+		b.WriteString(HighlightStart)
+		b.WriteString(html.EscapeString(string(runes[m.start:m.end])))
+		b.WriteString(HighlightEnd)
+		pos = m.end
+	}
+	b.WriteString(html.EscapeString(string(runes[pos:to])))
+	if to < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// HighlightSnippet HTML-escapes a snippet whose matches are wrapped in the raw markers and
+// replaces those with the highlight markers
+func HighlightSnippet(raw string) string {
+	return highlighter.Replace(html.EscapeString(raw))
+}
+
+// isSeparator reports whether r separates words; letters and digits form words
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
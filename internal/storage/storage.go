@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+)
+
+// Backend names accepted by STORAGE_BACKEND
+const (
+	BackendFirestore = "firestore"
+	BackendSQLite    = "sqlite"
+)
+
+// ChatsRepository stores chats and their messages
+type ChatsRepository interface {
+	GetRecentChats(ctx context.Context, limit int) ([]WAChat, error)
+	// GetChat returns a session's chat by JID, or nil if it does not exist
+	GetChat(ctx context.Context, session, jid string) (*WAChat, error)
+	GetChatMessages(ctx context.Context, chatID string, limit int) ([]WAMessage, error)
+	// ListChats returns a page of chats and the cursor of the next page, empty on the last page
+	ListChats(ctx context.Context, filter ChatFilter) ([]WAChat, string, error)
+	// ListChatMessages returns a page of the messages of a session's chat and the cursor of the next page, empty on the last page
+	ListChatMessages(ctx context.Context, session, chatID string, filter MessageFilter) ([]WAMessage, string, error)
+	SaveMessage(ctx context.Context, msg *WAMessage) error
+	// SearchMessages returns a page of messages matching a full-text query, newest first, and the cursor of the next page
+	SearchMessages(ctx context.Context, filter SearchFilter) ([]MessageHit, string, error)
+	// GetMessage returns a session's message by ID, or nil if it does not exist
+	GetMessage(ctx context.Context, session, messageID string) (*WAMessage, error)
+	// UpdateMessageMedia stores the local media URL, mime type and media keys of msg, in msg's session
+	UpdateMessageMedia(ctx context.Context, msg *WAMessage) error
+	// UpdateMessageAck raises the ack level of the given messages of a session; it never lowers it
+	UpdateMessageAck(ctx context.Context, session string, messageIDs []string, ack int) error
+	MarkChatAsRead(ctx context.Context, session, chatJID string) error
+	GetInvoiceChats(ctx context.Context, session string, limit int) ([]WAChat, error)
+	SetChatHasInvoice(ctx context.Context, session, jid string, hasInvoice bool) error
+	UpdateChatName(ctx context.Context, session, jid string, name string) error
+	// UpsertChat creates a chat that has no messages yet, or renames it if it exists
+	UpsertChat(ctx context.Context, session, jid string, name string) error
+	UpdateChatProfilePic(ctx context.Context, session, jid string, url string) error
+	ScanChatMetadata(ctx context.Context) (int, error)
+	// AssignSession assigns the chats and messages stored before sessions were recorded to session,
+	// returning how many it assigned
+	AssignSession(ctx context.Context, session string) (int, error)
+}
+
+// LeadsRepository stores contacts collected as leads
+type LeadsRepository interface {
+	GetByTag(ctx context.Context, tag string) ([]Lead, error)
+	GetAll(ctx context.Context, limit int) ([]Lead, error)
+	GetByPhone(ctx context.Context, phone string) (*Lead, error)
+	Create(ctx context.Context, lead *Lead) (string, error)
+	Update(ctx context.Context, id string, updates map[string]interface{}) error
+	UpsertFromMessage(ctx context.Context, phone, pushName string) error
+}
+
+// WAStatusRepository stores posted WhatsApp statuses and the settings they depend on
+type WAStatusRepository interface {
+	SaveStatusIDs(ctx context.Context, entries []WAStatusEntry, activeNumber string) error
+	GetStatusIDs(ctx context.Context) (*WAStatusRecord, error)
+	ClearStatusIDs(ctx context.Context) error
+	GetWhatsAppActiveNumber(ctx context.Context) (string, error)
+	GetBannerSettings(ctx context.Context) ([]map[string]string, error)
+}
+
+// Store bundles the repositories of one storage backend
+type Store struct {
+	Backend  string
+	Chats    ChatsRepository
+	Leads    LeadsRepository
+	WAStatus WAStatusRepository
+	closer   func() error
+}
+
+// NewStore creates a store; closer releases the backend connection and may be nil
+func NewStore(backend string, chats ChatsRepository, leads LeadsRepository, waStatus WAStatusRepository, closer func() error) *Store {
+	return &Store{
+		Backend:  backend,
+		Chats:    chats,
+		Leads:    leads,
+		WAStatus: waStatus,
+		closer:   closer,
+	}
+}
+
+// Close releases the backend connection
+func (s *Store) Close() error {
+	if s == nil || s.closer == nil {
+		return nil
+	}
+	return s.closer()
+}
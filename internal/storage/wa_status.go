@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"time"
+)
+
+// DefaultActiveNumber is used when no WhatsApp settings are available
+const DefaultActiveNumber = "6281399710085"
+
+// rotationAgents are the numbers rotated through when scheduling is enabled
+var rotationAgents = []string{
+	"6281399710085",
+	"6283190138549",
+	"6289518530306",
+	"62895635367495",
+	"62881023845975",
+	"6282258115474",
+}
+
+// ResolveActiveNumber applies the same rotation logic as the frontend
+func ResolveActiveNumber(settings *WASettingsRecord, now time.Time) string {
+	if settings == nil {
+		return DefaultActiveNumber
+	}
+
+	// If schedule is not enabled, return static main number
+	if !settings.ScheduleEnabled {
+		if settings.MainNumber != "" {
+			return settings.MainNumber
+		}
+		return DefaultActiveNumber
+	}
+
+	available := []string{}
+	for _, num := range rotationAgents {
+		isExcluded := false
+		for _, ex := range settings.ExcludedNumbers {
+			if ex == num {
+				isExcluded = true
+				break
+			}
+		}
+		if !isExcluded {
+			available = append(available, num)
+		}
+	}
+
+	if len(available) == 0 {
+		return DefaultActiveNumber
+	}
+
+	var index int
+
+	schedType := settings.ScheduleType
+	if schedType == "" {
+		schedType = "daily"
+	}
+
+	switch schedType {
+	case "daily":
+		days := int(now.Unix() / 86400)
+		index = days % len(available)
+	case "weekly":
+		weeks := int(now.Unix() / (86400 * 7))
+		index = weeks % len(available)
+	case "monthly":
+		months := (now.Year() * 12) + int(now.Month())
+		index = months % len(available)
+	default:
+		index = 0
+	}
+
+	return available[index]
+}
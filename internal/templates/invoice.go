@@ -2,6 +2,8 @@ package templates
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -66,6 +68,25 @@ func GenerateInvoiceMessage(data InvoiceTemplateData) string {
 	return header + body + footer
 }
 
+// FormatRupiah formats an amount as Indonesian Rupiah, e.g. "Rp 1.000.000"
+func FormatRupiah(amount int64) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	digits := strconv.FormatInt(amount, 10)
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(d)
+	}
+	return sign + "Rp " + b.String()
+}
+
 // GenerateOTPMessage generates an OTP message for authentication
 func GenerateOTPMessage(otp string) string {
 	return fmt.Sprintf("🔐 *Kode Login Valpro*\n\nKode OTP Anda: *%s*\n\nJangan berikan kode ini kepada siapapun.\nBerlaku 5 menit.", otp)
@@ -111,3 +132,19 @@ func GenerateHealthAlert(status string, latency int, timestamp time.Time) string
 			timestamp.Format("02 Jan 2006 15:04"), status, latency)
 	}
 }
+
+// GenerateBlogNotification generates the admin notification of a blog run.
+// status is "published", "draft" or "failed"; detail is the post link, or the error when failed.
+func GenerateBlogNotification(status, title, detail string, timestamp time.Time) string {
+	switch status {
+	case "published":
+		return fmt.Sprintf("📰 *ARTIKEL TERBIT*\n\n📝 %s\n🔗 %s\n🕐 %s",
+			title, detail, timestamp.Format("02 Jan 2006 15:04"))
+	case "draft":
+		return fmt.Sprintf("📝 *DRAFT ARTIKEL SIAP*\n\n📝 %s\n🆔 %s\n🕐 %s\n\nSilakan review dan publish di Contentful.",
+			title, detail, timestamp.Format("02 Jan 2006 15:04"))
+	default:
+		return fmt.Sprintf("❌ *ARTIKEL GAGAL DIBUAT*\n\n📝 %s\n❗ %s\n🕐 %s",
+			title, detail, timestamp.Format("02 Jan 2006 15:04"))
+	}
+}
@@ -0,0 +1,26 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// DeadLetter is a webhook delivery that exhausted its retries
+type DeadLetter struct {
+	ID         int64           `json:"id"`
+	DeliveryID string          `json:"deliveryId"`
+	Endpoint   string          `json:"endpoint"`
+	Event      string          `json:"event"`
+	Payload    json.RawMessage `json:"payload"`
+	Attempts   int             `json:"attempts"`
+	LastError  string          `json:"lastError"`
+	CreatedAt  time.Time       `json:"createdAt"`
+}
+
+// DeadLetterRepository persists failed deliveries for inspection
+type DeadLetterRepository interface {
+	Add(ctx context.Context, letter *DeadLetter) error
+	// List returns the most recent dead letters first
+	List(ctx context.Context, limit int) ([]DeadLetter, error)
+}
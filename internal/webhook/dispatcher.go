@@ -0,0 +1,311 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Signature headers sent with every delivery
+const (
+	HeaderSignature = "X-Webhook-Signature" // sha256=<hex HMAC of the body>
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+)
+
+// Endpoint is a webhook receiver and the events it subscribes to
+type Endpoint struct {
+	URL    string
+	Events []string // empty means all events
+}
+
+// Accepts reports whether the endpoint subscribes to the event
+func (e Endpoint) Accepts(event string) bool {
+	if len(e.Events) == 0 {
+		return true
+	}
+	for _, ev := range e.Events {
+		if ev == event || ev == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// Config configures webhook delivery
+type Config struct {
+	Endpoints    []Endpoint
+	Secret       string
+	MaxAttempts  int
+	RetryBackoff time.Duration // first retry delay, doubled per attempt
+	Timeout      time.Duration
+	Workers      int
+}
+
+// DefaultConfig returns default webhook configuration
+func DefaultConfig() Config {
+	return Config{
+		MaxAttempts:  6,
+		RetryBackoff: 5 * time.Second,
+		Timeout:      10 * time.Second,
+		Workers:      2,
+	}
+}
+
+// Envelope is the JSON body posted to endpoints
+type Envelope struct {
+	ID        string      `json:"id"`
+	Event     string      `json:"event"`
+	Timestamp int64       `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+type delivery struct {
+	id       string
+	endpoint string
+	event    string
+	body     []byte
+	attempts int
+}
+
+// errShutdown is recorded on deliveries still queued or waiting for a retry when the server stops
+var errShutdown = errors.New("server shut down before delivery")
+
+// Dispatcher posts signed events to the configured endpoints
+type Dispatcher struct {
+	cfg         Config
+	httpClient  *http.Client
+	deadLetters DeadLetterRepository
+	queue       chan *delivery
+
+	mu      sync.Mutex
+	retries map[*delivery]*time.Timer // deliveries waiting for their next attempt
+	stopped bool
+}
+
+// NewDispatcher creates a new webhook dispatcher; deadLetters may be nil
+func NewDispatcher(cfg Config, deadLetters DeadLetterRepository) *Dispatcher {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 1
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	return &Dispatcher{
+		cfg:         cfg,
+		httpClient:  &http.Client{Timeout: cfg.Timeout},
+		deadLetters: deadLetters,
+		queue:       make(chan *delivery, 500),
+		retries:     make(map[*delivery]*time.Timer),
+	}
+}
+
+// Enabled reports whether any endpoint is configured
+func (d *Dispatcher) Enabled() bool {
+	return d != nil && len(d.cfg.Endpoints) > 0
+}
+
+// DeadLetters returns the dead-letter repository
+func (d *Dispatcher) DeadLetters() DeadLetterRepository {
+	return d.deadLetters
+}
+
+// Start launches the delivery workers
+func (d *Dispatcher) Start(ctx context.Context) {
+	if !d.Enabled() {
+		return
+	}
+	for i := 0; i < d.cfg.Workers; i++ {
+		go d.worker(ctx)
+	}
+	log.Printf("✅ [WEBHOOK] Delivering events to %d endpoints", len(d.cfg.Endpoints))
+}
+
+// Pending returns the number of deliveries waiting in the queue and waiting for a retry
+func (d *Dispatcher) Pending() (queued, retrying int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.queue), len(d.retries)
+}
+
+// Stop records deliveries still queued or waiting for a retry in the dead-letter log, so they are not lost on shutdown
+func (d *Dispatcher) Stop() {
+	if !d.Enabled() {
+		return
+	}
+
+	var pending []*delivery
+	d.mu.Lock()
+	d.stopped = true
+	for dl, timer := range d.retries {
+		if timer.Stop() {
+			pending = append(pending, dl)
+		}
+	}
+	d.retries = nil
+	d.mu.Unlock()
+
+	for drained := false; !drained; {
+		select {
+		case dl := <-d.queue:
+			pending = append(pending, dl)
+		default:
+			drained = true
+		}
+	}
+
+	for _, dl := range pending {
+		d.deadLetter(dl, errShutdown)
+	}
+}
+
+// Dispatch queues an event for every endpoint subscribed to it. It never blocks.
+func (d *Dispatcher) Dispatch(event string, data interface{}) {
+	if !d.Enabled() {
+		return
+	}
+
+	id := newDeliveryID()
+	body, err := json.Marshal(Envelope{
+		ID:        id,
+		Event:     event,
+		Timestamp: time.Now().Unix(),
+		Data:      data,
+	})
+	if err != nil {
+		log.Printf("⚠️ [WEBHOOK] Failed to encode %s event: %v", event, err)
+		return
+	}
+
+	for _, endpoint := range d.cfg.Endpoints {
+		if !endpoint.Accepts(event) {
+			continue
+		}
+		d.enqueue(&delivery{id: id, endpoint: endpoint.URL, event: event, body: body})
+	}
+}
+
+func (d *Dispatcher) enqueue(dl *delivery) {
+	select {
+	case d.queue <- dl:
+	default:
+		d.deadLetter(dl, fmt.Errorf("delivery queue full"))
+	}
+}
+
+func (d *Dispatcher) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case dl := <-d.queue:
+			d.deliver(ctx, dl)
+		}
+	}
+}
+
+// deliver posts one delivery and schedules a retry on failure
+func (d *Dispatcher) deliver(ctx context.Context, dl *delivery) {
+	dl.attempts++
+	retryable, err := d.post(ctx, dl)
+	if err == nil {
+		return
+	}
+
+	if !retryable || dl.attempts >= d.cfg.MaxAttempts {
+		d.deadLetter(dl, err)
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stopped {
+		d.deadLetter(dl, fmt.Errorf("%w: %v", errShutdown, err))
+		return
+	}
+
+	backoff := d.cfg.RetryBackoff << (dl.attempts - 1)
+	log.Printf("🔁 [WEBHOOK] %s to %s attempt %d failed, retrying in %s: %v", dl.event, dl.endpoint, dl.attempts, backoff, err)
+	d.retries[dl] = time.AfterFunc(backoff, func() {
+		d.mu.Lock()
+		delete(d.retries, dl)
+		d.mu.Unlock()
+		d.enqueue(dl)
+	})
+}
+
+// post sends the signed request, reporting whether a failure is worth retrying
+func (d *Dispatcher) post(ctx context.Context, dl *delivery) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dl.endpoint, bytes.NewReader(dl.body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, dl.event)
+	req.Header.Set(HeaderDelivery, dl.id)
+	if d.cfg.Secret != "" {
+		req.Header.Set(HeaderSignature, "sha256="+Sign(d.cfg.Secret, dl.body))
+	}
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	// Client errors won't succeed on retry, except timeouts and rate limits
+	retryable := resp.StatusCode >= 500 ||
+		resp.StatusCode == http.StatusRequestTimeout ||
+		resp.StatusCode == http.StatusTooManyRequests
+	return retryable, fmt.Errorf("endpoint returned %s", resp.Status)
+}
+
+func (d *Dispatcher) deadLetter(dl *delivery, err error) {
+	log.Printf("❌ [WEBHOOK] Giving up on %s to %s after %d attempts: %v", dl.event, dl.endpoint, dl.attempts, err)
+	if d.deadLetters == nil {
+		return
+	}
+
+	letter := &DeadLetter{
+		DeliveryID: dl.id,
+		Endpoint:   dl.endpoint,
+		Event:      dl.event,
+		Payload:    dl.body,
+		Attempts:   dl.attempts,
+		LastError:  err.Error(),
+		CreatedAt:  time.Now(),
+	}
+	if err := d.deadLetters.Add(context.Background(), letter); err != nil {
+		log.Printf("⚠️ [WEBHOOK] Failed to record dead letter: %v", err)
+	}
+}
+
+// Sign returns the hex HMAC-SHA256 of body using secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// newDeliveryID generates a random delivery ID
+func newDeliveryID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
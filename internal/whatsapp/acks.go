@@ -0,0 +1,58 @@
+package whatsapp
+
+import (
+	"context"
+	"time"
+
+	"wa-server-go/internal/storage"
+)
+
+// recentAckTTL is how long a receipt is remembered for a message that may not be saved yet
+const recentAckTTL = 2 * time.Minute
+
+type ackKey struct {
+	session   string
+	messageID string
+}
+
+type recentAck struct {
+	ack int
+	at  time.Time
+}
+
+// SaveSentMessage saves a message this server just sent. A receipt that arrived before the
+// message was saved (the recipient's phone can answer before SendMessage returns) is applied to it.
+func (m *Manager) SaveSentMessage(ctx context.Context, msg *storage.WAMessage) error {
+	if m.Repo == nil {
+		return nil
+	}
+
+	m.ackMu.Lock()
+	defer m.ackMu.Unlock()
+	if recent, ok := m.recentAcks[ackKey{msg.Session, msg.MessageID}]; ok && recent.ack > msg.Ack {
+		msg.Ack = recent.ack
+	}
+	return m.Repo.SaveMessage(ctx, msg)
+}
+
+// applyAck stores a receipt and remembers it for SaveSentMessage.
+// Both hold ackMu, so a receipt is either applied to the saved message or seen when it is saved.
+func (m *Manager) applyAck(ctx context.Context, session string, messageIDs []string, ack int) error {
+	m.ackMu.Lock()
+	defer m.ackMu.Unlock()
+
+	now := time.Now()
+	for key, recent := range m.recentAcks {
+		if now.Sub(recent.at) > recentAckTTL {
+			delete(m.recentAcks, key)
+		}
+	}
+	for _, id := range messageIDs {
+		key := ackKey{session, id}
+		if recent, ok := m.recentAcks[key]; !ok || ack > recent.ack {
+			m.recentAcks[key] = recentAck{ack: ack, at: now}
+		}
+	}
+
+	return m.Repo.UpdateMessageAck(ctx, session, messageIDs, ack)
+}
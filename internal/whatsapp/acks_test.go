@@ -0,0 +1,68 @@
+package whatsapp
+
+import (
+	"context"
+	"testing"
+
+	"wa-server-go/internal/storage"
+)
+
+// ackRepository keeps saved messages and applies acks to them like the storage backends
+type ackRepository struct {
+	storage.ChatsRepository
+	messages map[string]*storage.WAMessage
+}
+
+func (r *ackRepository) SaveMessage(ctx context.Context, msg *storage.WAMessage) error {
+	saved := *msg
+	r.messages[msg.Session+"/"+msg.MessageID] = &saved
+	return nil
+}
+
+func (r *ackRepository) UpdateMessageAck(ctx context.Context, session string, messageIDs []string, ack int) error {
+	for _, id := range messageIDs {
+		if msg, ok := r.messages[session+"/"+id]; ok && msg.Ack < ack {
+			msg.Ack = ack
+		}
+	}
+	return nil
+}
+
+func TestReceiptBeforeSentMessageIsSaved(t *testing.T) {
+	ctx := context.Background()
+	repo := &ackRepository{messages: make(map[string]*storage.WAMessage)}
+	m := NewManager(repo)
+
+	// Delivered and read arrive before the send path saves the message
+	if err := m.applyAck(ctx, "bot", []string{"m1"}, storage.AckDelivered); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.applyAck(ctx, "bot", []string{"m1"}, storage.AckRead); err != nil {
+		t.Fatal(err)
+	}
+	// A receipt of another session is not applied
+	if err := m.applyAck(ctx, "cs", []string{"m2"}, storage.AckRead); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{"m1", "m2"} {
+		msg := &storage.WAMessage{Session: "bot", MessageID: id, Ack: storage.AckServer}
+		if err := m.SaveSentMessage(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := repo.messages["bot/m1"].Ack; got != storage.AckRead {
+		t.Errorf("m1 ack = %d, want read (%d)", got, storage.AckRead)
+	}
+	if got := repo.messages["bot/m2"].Ack; got != storage.AckServer {
+		t.Errorf("m2 ack = %d, want server (%d)", got, storage.AckServer)
+	}
+
+	// Receipts after the save update the stored message
+	if err := m.applyAck(ctx, "bot", []string{"m2"}, storage.AckDelivered); err != nil {
+		t.Fatal(err)
+	}
+	if got := repo.messages["bot/m2"].Ack; got != storage.AckDelivered {
+		t.Errorf("m2 ack after receipt = %d, want delivered (%d)", got, storage.AckDelivered)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"os"
 	"sync"
 
+	"wa-server-go/internal/metrics"
+
 	"github.com/skip2/go-qrcode"
 	"go.mau.fi/whatsmeow"
 	waProto "go.mau.fi/whatsmeow/proto/waE2E"
@@ -23,9 +25,12 @@ type Client struct {
 	Container *sqlstore.Container
 	Device    *store.Device
 	ID        string
+	Role      Role
 	Ready     bool
 	QRCode    string
 	mu        sync.RWMutex
+
+	groups map[types.JID]*Group // group metadata cache, see GroupInfo
 }
 
 // NewClient creates a new WhatsApp client with SQLite session storage
@@ -118,6 +123,7 @@ func (c *Client) SetReady(ready bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	c.Ready = ready
+	metrics.SetSessionConnected(c.ID, ready)
 }
 
 // GetQRCode returns the current QR code
@@ -3,12 +3,12 @@ package whatsapp
 import (
 	"context"
 	"fmt"
-	"mime"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
-	"wa-server-go/internal/firestore"
+	"wa-server-go/internal/metrics"
+	"wa-server-go/internal/storage"
 
 	waProto "go.mau.fi/whatsmeow/proto/waE2E"
 	"go.mau.fi/whatsmeow/types"
@@ -30,15 +30,15 @@ func (m *Manager) handleEvent(clientID string, client *Client, evt interface{})
 		client.SetReady(true)
 		m.statusChan <- StatusUpdate{Client: clientID, Ready: true}
 		
-		// For leads client, trigger app state sync to get labels
-		if clientID == "leads" {
+		// For the contact sync client, trigger app state sync to get labels
+		if client.Role.IgnoresContent() {
 			fmt.Printf("🏷️ [%s] Triggering app state sync for labels on connect...\n", clientID)
 		}
 
 	case *events.AppStateSyncComplete:
 		// App state sync completed - labels should now be available
 		fmt.Printf("📱 [%s] AppStateSyncComplete for: %s\n", clientID, v.Name)
-		if clientID == "leads" {
+		if client.Role.IgnoresContent() {
 			fmt.Printf("🏷️ [%s] Current labels in store: %v\n", clientID, m.LabelStore.GetAllLabels())
 			fmt.Printf("🏷️ [%s] Current associations in store: %v\n", clientID, m.LabelStore.GetAllAssociations())
 		}
@@ -62,9 +62,12 @@ func (m *Manager) handleEvent(clientID string, client *Client, evt interface{})
 		fmt.Printf("📝 [%s] Push name set: %s\n", clientID, v.NewPushName)
 
 	case *events.Message:
-		// PRIVACY UPDATE: Ignore messages from "leads" client (Number B)
+		// Mappings are learned on every session, the contact sync session included
+		m.LIDs.learnFromMessage(&v.Info)
+
+		// PRIVACY UPDATE: Ignore messages on privacy sessions (e.g. the "leads" client, Number B)
 		// We only want to sync contacts, not view private chats.
-		if clientID == "leads" {
+		if client.Role.IgnoresContent() {
 			return
 		}
 		
@@ -72,30 +75,16 @@ func (m *Manager) handleEvent(clientID string, client *Client, evt interface{})
 
 		// Determine message type and download media
 		msg := v.Message
-		msgType := "text"
-		hasMedia := false
-		var mediaURL, mediaTypeStr string
+		media, msgType, mediaTypeStr, _ := mediaMessage(msg)
+		hasMedia := media != nil
+		metrics.IncomingMessages.WithLabelValues(msgType, clientID).Inc()
+		var mediaURL string
 		var err error
 
-		if msg.ImageMessage != nil {
-			msgType = "image"
-			hasMedia = true
-			mediaURL, mediaTypeStr, err = saveMedia(client, msg, v.Info.ID, v.Info.IsFromMe)
-		} else if msg.DocumentMessage != nil {
-			msgType = "document"
-			hasMedia = true
-			mediaURL, mediaTypeStr, err = saveMedia(client, msg, v.Info.ID, v.Info.IsFromMe)
-		} else if msg.AudioMessage != nil {
-			msgType = "audio"
-			hasMedia = true
-		} else if msg.VideoMessage != nil {
-			msgType = "video"
-			hasMedia = true
-		} else if msg.StickerMessage != nil {
-			msgType = "sticker"
-			hasMedia = true
+		if hasMedia {
+			mediaURL, _, err = saveMedia(client, msg, v.Info.ID, v.Info.IsFromMe)
 		}
-		
+
 		if err != nil {
 			fmt.Printf("⚠️ Failed to download media: %v\n", err)
 		}
@@ -118,35 +107,51 @@ func (m *Manager) handleEvent(clientID string, client *Client, evt interface{})
 			senderName = v.Info.PushName
 		}
 
+		// A group is named by its subject, not by whoever spoke last
+		chatName := senderName
+		if v.Info.IsGroup {
+			chatName = ""
+			if group := client.CachedGroup(v.Info.Chat); group != nil {
+				chatName = group.Name
+			}
+		}
+
 		// Send to websocket
 		m.msgChan <- NewMessageEvent{
-			Client:    clientID,
-			ID:        v.Info.ID,
-			From:      v.Info.Sender.String(),
-			To:        v.Info.Chat.String(),
-			Body:      body,
-			Timestamp: v.Info.Timestamp.Unix(),
-			FromMe:    v.Info.IsFromMe,
-			ChatID:    v.Info.Chat.String(),
-			ChatName:  senderName,
-			HasMedia:  hasMedia,
-			Type:      msgType,
+			Client:     clientID,
+			ID:         v.Info.ID,
+			From:       v.Info.Sender.String(),
+			To:         v.Info.Chat.String(),
+			Body:       body,
+			Timestamp:  v.Info.Timestamp.Unix(),
+			FromMe:     v.Info.IsFromMe,
+			ChatID:     v.Info.Chat.String(),
+			ChatName:   chatName,
+			SenderName: senderName,
+			HasMedia:   hasMedia,
+			Type:       msgType,
 		}
 
-		// Save to Firestore if Repo is configured
+		// Save to storage if Repo is configured
 		if m.Repo != nil {
 			go func() {
-				waMsg := &firestore.WAMessage{
-					MessageID: v.Info.ID,
-					ChatID:    v.Info.Chat.String(),
-					Body:      body,
-					Timestamp: v.Info.Timestamp,
-					FromMe:    v.Info.IsFromMe,
-					HasMedia:  hasMedia,
-					MediaType: mediaTypeStr,
-					MediaURL:  mediaURL,
-					Type:      msgType,
-					Ack:       1,
+				waMsg := &storage.WAMessage{
+					Session:    clientID,
+					MessageID:  v.Info.ID,
+					ChatID:     v.Info.Chat.String(),
+					Body:       body,
+					Timestamp:  v.Info.Timestamp,
+					FromMe:     v.Info.IsFromMe,
+					HasMedia:   hasMedia,
+					MediaType:  mediaTypeStr,
+					MediaURL:   mediaURL,
+					Type:       msgType,
+					Ack:        storage.AckServer,
+					SenderJID:  v.Info.Sender.ToNonAD().String(),
+					SenderName: senderName,
+				}
+				if hasMedia {
+					setMediaKeys(waMsg, media)
 				}
 
 				if v.Info.IsFromMe {
@@ -165,12 +170,19 @@ func (m *Manager) handleEvent(clientID string, client *Client, evt interface{})
 				}
 
 				if err := m.Repo.SaveMessage(context.Background(), waMsg); err != nil {
-					fmt.Printf("❌ Failed to save message to Firestore: %v\n", err)
+					fmt.Printf("❌ Failed to save message to storage: %v\n", err)
 				} else {
-					fmt.Printf("💾 Message saved to Firestore: %s\n", waMsg.MessageID)
-					// Update Chat Name if provided
-					if senderName != "" {
-						_ = m.Repo.UpdateChatName(context.Background(), waMsg.ChatID, senderName)
+					fmt.Printf("💾 Message saved to storage: %s\n", waMsg.MessageID)
+					// Update Chat Name: the group subject, or the contact's name
+					if v.Info.IsGroup {
+						group, err := client.GroupInfo(context.Background(), v.Info.Chat, false)
+						if err != nil {
+							fmt.Printf("⚠️ Failed to fetch group info for %s: %v\n", waMsg.ChatID, err)
+						} else if group.Name != "" {
+							_ = m.Repo.UpdateChatName(context.Background(), clientID, waMsg.ChatID, group.Name)
+						}
+					} else if senderName != "" && !v.Info.IsFromMe {
+						_ = m.Repo.UpdateChatName(context.Background(), clientID, waMsg.ChatID, senderName)
 					}
 				}
 			}()
@@ -178,10 +190,49 @@ func (m *Manager) handleEvent(clientID string, client *Client, evt interface{})
 
 	case *events.Receipt:
 		// Message delivery/read receipts
+		if client.Role.IgnoresContent() {
+			return
+		}
+
+		ack, ok := receiptAck(v.Type)
+		if !ok {
+			return
+		}
+
+		ids := make([]string, len(v.MessageIDs))
+		for i, id := range v.MessageIDs {
+			ids[i] = string(id)
+		}
+
+		if m.Repo != nil {
+			go func() {
+				if err := m.applyAck(context.Background(), clientID, ids, ack); err != nil {
+					fmt.Printf("⚠️ Failed to update message ack: %v\n", err)
+				}
+			}()
+		}
+
+		select {
+		case m.receiptChan <- ReceiptEvent{
+			Client:     clientID,
+			MessageIDs: ids,
+			ChatID:     v.Chat.String(),
+			Sender:     v.Sender.String(),
+			Type:       storage.AckName(ack),
+			Ack:        ack,
+			Timestamp:  v.Timestamp.Unix(),
+		}:
+		default:
+			fmt.Println("⚠️ Receipt channel full, dropping receipt")
+		}
+
+	case *events.MediaRetry:
+		// Phone re-uploaded media we asked for in DownloadMedia
+		m.deliverMediaRetry(clientID, v)
 
 	case *events.HistorySync:
-		// PRIVACY UPDATE: Ignore history sync from "leads" client
-		if clientID == "leads" {
+		// PRIVACY UPDATE: Ignore history sync on privacy sessions
+		if client.Role.IgnoresContent() {
 			return
 		}
 
@@ -199,18 +250,11 @@ func (m *Manager) handleEvent(clientID string, client *Client, evt interface{})
 						msg := webMsg.Message
 
 						// Type & Media
-						msgType := "text"
-						hasMedia := false
-						var mediaURL, mediaTypeStr string
-
-						// We don't download history media automatically to save bandwidth
-						if msg.ImageMessage != nil {
-							msgType = "image"
-							hasMedia = true
-						} else if msg.DocumentMessage != nil {
-							msgType = "document"
-							hasMedia = true
-						}
+						// We don't download history media automatically to save bandwidth;
+						// the keys are stored so GET /get-media can fetch it on demand
+						media, msgType, mediaTypeStr, _ := mediaMessage(msg)
+						hasMedia := media != nil
+						var mediaURL string
 
 						// Body
 						body := ""
@@ -225,7 +269,8 @@ func (m *Manager) handleEvent(clientID string, client *Client, evt interface{})
 						}
 
 						ts := int64(webMsg.GetMessageTimestamp())
-						waMsg := &firestore.WAMessage{
+						waMsg := &storage.WAMessage{
+							Session:   clientID,
 							MessageID: webMsg.Key.GetID(),
 							ChatID:    conv.GetID(),
 							Body:      body,
@@ -235,16 +280,29 @@ func (m *Manager) handleEvent(clientID string, client *Client, evt interface{})
 							MediaType: mediaTypeStr,
 							MediaURL:  mediaURL,
 							Type:      msgType,
-							Ack:       3, // Read/Played
+							Ack:       storage.AckRead,
+						}
+						if hasMedia {
+							setMediaKeys(waMsg, media)
 						}
 
 						if waMsg.FromMe {
-							waMsg.From = m.clients[clientID].WAClient.Store.ID.ToNonAD().String()
+							waMsg.From = client.WAClient.Store.ID.ToNonAD().String()
 							waMsg.To = conv.GetID()
 						} else {
 							waMsg.From = conv.GetID()
-							waMsg.To = m.clients[clientID].WAClient.Store.ID.ToNonAD().String()
+							waMsg.To = client.WAClient.Store.ID.ToNonAD().String()
+							waMsg.SenderName = webMsg.GetPushName()
+						}
+						// In groups the sender is the participant, not the group
+						participant := webMsg.GetParticipant()
+						if participant == "" {
+							participant = webMsg.Key.GetParticipant()
 						}
+						if participant != "" && !waMsg.FromMe {
+							waMsg.From = participant
+						}
+						waMsg.SenderJID = waMsg.From
 
 						// Save without waiting
 						_ = m.Repo.SaveMessage(context.Background(), waMsg)
@@ -255,6 +313,39 @@ func (m *Manager) handleEvent(clientID string, client *Client, evt interface{})
 			}()
 		}
 
+	case *events.JoinedGroup:
+		// The session was added to a group or created one
+		if client.Role.IgnoresContent() {
+			return
+		}
+		group := client.cacheGroup(&v.GroupInfo)
+		fmt.Printf("👥 [%s] Joined group %s (%s)\n", clientID, group.Name, group.JID)
+		if m.Repo != nil && group.Name != "" {
+			go func() {
+				_ = m.Repo.UpdateChatName(context.Background(), clientID, group.JID, group.Name)
+			}()
+		}
+		evt := GroupEvent{Client: clientID, ChatID: group.JID, Action: "joined", Name: group.Name, Topic: group.Topic, Timestamp: time.Now().Unix()}
+		if v.Sender != nil {
+			evt.Sender = v.Sender.String()
+		}
+		m.sendGroupEvent(evt)
+
+	case *events.GroupInfo:
+		// Members, subject, description or settings of a group changed
+		if client.Role.IgnoresContent() {
+			return
+		}
+		client.forgetGroup(v.JID)
+		if m.Repo != nil && v.Name != nil && v.Name.Name != "" {
+			go func() {
+				_ = m.Repo.UpdateChatName(context.Background(), clientID, v.JID.String(), v.Name.Name)
+			}()
+		}
+		for _, evt := range groupEvents(clientID, v) {
+			m.sendGroupEvent(evt)
+		}
+
 	case *events.AppState:
 		// App state sync - trigger label sync for leads client
 		fmt.Printf("📱 [%s] AppState sync received, labels may be updated\n", clientID)
@@ -309,8 +400,8 @@ func (m *Manager) handleEvent(clientID string, client *Client, evt interface{})
 		}
 
 	default:
-		// Log unknown events for leads client to debug what we're receiving
-		if clientID == "leads" {
+		// Log unknown events for the contact sync client to debug what we're receiving
+		if client.Role.IgnoresContent() {
 			// Only log certain types to avoid spam
 			switch evt.(type) {
 			case *events.OfflineSyncPreview, *events.OfflineSyncCompleted:
@@ -338,40 +429,23 @@ func resolveContactName(client *Client, jid types.JID) string {
 	return ""
 }
 
-// saveMedia downloads media from message and saves to disk
-func saveMedia(client *Client, msg *waProto.Message, id string, isFromMe bool) (string, string, error) {
-	var ext string
-	var mimeType string
+// eagerMediaLimit is the largest media downloaded while handling the message event.
+// Larger files (most video and long audio) are downloaded on demand by GetMedia, so the event loop is not held up.
+const eagerMediaLimit = 1 << 20
 
+// saveMedia downloads media from message and saves to disk.
+// Media above eagerMediaLimit, or of unknown size, is left for GetMedia and an empty file name is returned.
+func saveMedia(client *Client, msg *waProto.Message, id string, isFromMe bool) (string, string, error) {
 	// 1. Determine extension and mime type
-	if msg.ImageMessage != nil {
-		ext = ".jpg"
-		mimeType = msg.ImageMessage.GetMimetype()
-	} else if msg.DocumentMessage != nil {
-		// Extract extension from filename or mimetype
-		fileName := msg.DocumentMessage.GetFileName()
-		ext = filepath.Ext(fileName)
-		if ext == "" {
-			exts, _ := mime.ExtensionsByType(msg.DocumentMessage.GetMimetype())
-			if len(exts) > 0 {
-				ext = exts[0]
-			} else {
-				ext = ".bin"
-			}
-		}
-		mimeType = msg.DocumentMessage.GetMimetype()
-	} else {
+	media, msgType, mimeType, fileName := mediaMessage(msg)
+	if media == nil {
 		return "", "", fmt.Errorf("unsupported media type")
 	}
+	ext := MediaExtension(msgType, mimeType, fileName)
 
 	// 2. Prepare paths
-	uploadsDir := "./uploads/media"
-	if err := os.MkdirAll(uploadsDir, 0755); err != nil {
-		return "", "", err
-	}
-
 	filename := fmt.Sprintf("%s%s", id, ext)
-	localPath := filepath.Join(uploadsDir, filename)
+	localPath := filepath.Join(MediaDir, filename)
 
 	// 3. Check if file already exists (e.g. from outgoing send)
 	// Retry logic for outgoing messages to handle race condition
@@ -383,7 +457,7 @@ func saveMedia(client *Client, msg *waProto.Message, id string, isFromMe bool) (
 	for i := 0; i < attempts; i++ {
 		if _, err := os.Stat(localPath); err == nil {
 			fmt.Printf("📂 Media already exists locally for %s, skipping download (attempt %d).\n", id, i+1)
-			return fmt.Sprintf("/uploads/media/%s", filename), mimeType, nil
+			return filename, mimeType, nil
 		}
 		if isFromMe && i < attempts-1 {
 			fmt.Printf("⏳ Outgoing media not found yet, waiting... (attempt %d)\n", i+1)
@@ -391,17 +465,13 @@ func saveMedia(client *Client, msg *waProto.Message, id string, isFromMe bool) (
 		}
 	}
 
-	// 4. Download if not exists
-	fmt.Printf("🎬 Start downloading media for msg %s\n", id)
-	var payload []byte
-	var err error
-
-	if msg.ImageMessage != nil {
-		payload, err = client.WAClient.Download(context.Background(), msg.ImageMessage)
-	} else if msg.DocumentMessage != nil {
-		payload, err = client.WAClient.Download(context.Background(), msg.DocumentMessage)
+	// 4. Download if not exists and small enough
+	if sized, ok := media.(interface{ GetFileLength() uint64 }); !ok || sized.GetFileLength() == 0 || sized.GetFileLength() > eagerMediaLimit {
+		fmt.Printf("📥 Media for msg %s will be downloaded on demand\n", id)
+		return "", mimeType, nil
 	}
-
+	fmt.Printf("🎬 Start downloading media for msg %s\n", id)
+	payload, err := client.WAClient.Download(context.Background(), media)
 	if err != nil {
 		fmt.Printf("❌ Error downloading media %s: %v\n", id, err)
 		return "", "", err
@@ -409,12 +479,11 @@ func saveMedia(client *Client, msg *waProto.Message, id string, isFromMe bool) (
 
 	fmt.Printf("✅ Media downloaded successfully for %s, saving to disk...\n", id)
 
-	if err := os.WriteFile(localPath, payload, 0644); err != nil {
+	mediaURL, err := WriteMediaFile(id, ext, payload)
+	if err != nil {
 		return "", "", err
 	}
-
-	// Return URL relative to server root
-	return fmt.Sprintf("/uploads/media/%s", filename), mimeType, nil
+	return mediaURL, mimeType, nil
 }
 
 // truncate shortens a string for logging
@@ -424,3 +493,26 @@ func truncate(s string, maxLen int) string {
 	}
 	return s[:maxLen] + "..."
 }
+
+// sendGroupEvent forwards a group change without blocking the event handler
+func (m *Manager) sendGroupEvent(evt GroupEvent) {
+	select {
+	case m.groupChan <- evt:
+	default:
+		fmt.Println("⚠️ Group channel full, dropping group event")
+	}
+}
+
+// receiptAck maps the receipt types we track to message ack levels
+func receiptAck(t types.ReceiptType) (int, bool) {
+	switch t {
+	case types.ReceiptTypeDelivered:
+		return storage.AckDelivered, true
+	case types.ReceiptTypeRead:
+		return storage.AckRead, true
+	case types.ReceiptTypePlayed:
+		return storage.AckPlayed, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,184 @@
+package whatsapp
+
+import (
+	"context"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// groupCacheTTL is how long fetched group metadata is reused before asking WhatsApp again
+const groupCacheTTL = time.Hour
+
+// Group is the metadata of a group chat, as cached per session
+type Group struct {
+	JID              string             `json:"jid"`
+	Name             string             `json:"name"`
+	Topic            string             `json:"topic"`
+	Owner            string             `json:"owner,omitempty"`
+	Announce         bool               `json:"announce"` // only admins can send messages
+	Locked           bool               `json:"locked"`   // only admins can edit the group info
+	CreatedAt        time.Time          `json:"createdAt"`
+	ParticipantCount int                `json:"participantCount"`
+	Participants     []GroupParticipant `json:"participants,omitempty"`
+	FetchedAt        time.Time          `json:"fetchedAt"`
+}
+
+// GroupParticipant is a member of a group
+type GroupParticipant struct {
+	JID          string `json:"jid"`
+	Phone        string `json:"phone,omitempty"` // phone number JID when JID is a LID
+	Name         string `json:"name,omitempty"`  // name from the session's contacts
+	IsAdmin      bool   `json:"isAdmin"`
+	IsSuperAdmin bool   `json:"isSuperAdmin"`
+}
+
+// GroupEvent represents a change to a group: members joining, leaving, promoted or demoted,
+// a new subject or description, or the session itself joining
+type GroupEvent struct {
+	Client       string   `json:"client"`
+	ChatID       string   `json:"chatId"`
+	Action       string   `json:"action"` // joined, join, leave, promote, demote, subject, topic, settings
+	Sender       string   `json:"sender,omitempty"`
+	Participants []string `json:"participants,omitempty"`
+	Name         string   `json:"name,omitempty"`
+	Topic        string   `json:"topic,omitempty"`
+	Timestamp    int64    `json:"timestamp"`
+}
+
+// GroupInfo returns the metadata of a group, from the cache while it is fresh
+func (c *Client) GroupInfo(ctx context.Context, jid types.JID, refresh bool) (*Group, error) {
+	if !refresh {
+		if group := c.CachedGroup(jid); group != nil && time.Since(group.FetchedAt) < groupCacheTTL {
+			return group, nil
+		}
+	}
+	info, err := c.WAClient.GetGroupInfo(ctx, jid)
+	if err != nil {
+		return nil, err
+	}
+	return c.cacheGroup(info), nil
+}
+
+// JoinedGroups fetches every group the session is a member of and refreshes the cache
+func (c *Client) JoinedGroups(ctx context.Context) ([]*Group, error) {
+	infos, err := c.WAClient.GetJoinedGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]*Group, 0, len(infos))
+	for _, info := range infos {
+		groups = append(groups, c.cacheGroup(info))
+	}
+	return groups, nil
+}
+
+// CreateGroup creates a group with the session as its admin and caches it
+func (c *Client) CreateGroup(ctx context.Context, name string, participants []types.JID) (*Group, error) {
+	info, err := c.WAClient.CreateGroup(ctx, whatsmeow.ReqCreateGroup{Name: name, Participants: participants})
+	if err != nil {
+		return nil, err
+	}
+	return c.cacheGroup(info), nil
+}
+
+// CachedGroup returns the cached metadata of a group without contacting WhatsApp, or nil
+func (c *Client) CachedGroup(jid types.JID) *Group {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.groups[jid.ToNonAD()]
+}
+
+// forgetGroup drops a group from the cache so the next lookup fetches it again
+func (c *Client) forgetGroup(jid types.JID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.groups, jid.ToNonAD())
+}
+
+func (c *Client) cacheGroup(info *types.GroupInfo) *Group {
+	group := &Group{
+		JID:              info.JID.String(),
+		Name:             info.Name,
+		Topic:            info.Topic,
+		Announce:         info.IsAnnounce,
+		Locked:           info.IsLocked,
+		CreatedAt:        info.GroupCreated,
+		ParticipantCount: max(info.ParticipantCount, len(info.Participants)),
+		Participants:     make([]GroupParticipant, 0, len(info.Participants)),
+		FetchedAt:        time.Now(),
+	}
+	if !info.OwnerJID.IsEmpty() {
+		group.Owner = info.OwnerJID.String()
+	}
+	for _, p := range info.Participants {
+		participant := GroupParticipant{
+			JID:          p.JID.String(),
+			IsAdmin:      p.IsAdmin,
+			IsSuperAdmin: p.IsSuperAdmin,
+			Name:         p.DisplayName,
+		}
+		contact := p.JID
+		if !p.PhoneNumber.IsEmpty() && p.PhoneNumber != p.JID {
+			participant.Phone = p.PhoneNumber.String()
+			contact = p.PhoneNumber
+		}
+		if name := resolveContactName(c, contact); name != "" {
+			participant.Name = name
+		}
+		group.Participants = append(group.Participants, participant)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.groups == nil {
+		c.groups = make(map[types.JID]*Group)
+	}
+	c.groups[info.JID.ToNonAD()] = group
+	return group
+}
+
+// groupEvents turns a group change notification into one event per kind of change
+func groupEvents(clientID string, v *events.GroupInfo) []GroupEvent {
+	base := GroupEvent{Client: clientID, ChatID: v.JID.String(), Timestamp: v.Timestamp.Unix()}
+	if v.Sender != nil {
+		base.Sender = v.Sender.String()
+	}
+
+	var evts []GroupEvent
+	add := func(action string, fill func(e *GroupEvent)) {
+		evt := base
+		evt.Action = action
+		if fill != nil {
+			fill(&evt)
+		}
+		evts = append(evts, evt)
+	}
+	members := func(action string, jids []types.JID) {
+		if len(jids) == 0 {
+			return
+		}
+		add(action, func(e *GroupEvent) {
+			for _, jid := range jids {
+				e.Participants = append(e.Participants, jid.String())
+			}
+		})
+	}
+
+	members("join", v.Join)
+	members("leave", v.Leave)
+	members("promote", v.Promote)
+	members("demote", v.Demote)
+	if v.Name != nil {
+		add("subject", func(e *GroupEvent) { e.Name = v.Name.Name })
+	}
+	if v.Topic != nil {
+		add("topic", func(e *GroupEvent) { e.Topic = v.Topic.Topic })
+	}
+	if v.Locked != nil || v.Announce != nil || v.Ephemeral != nil || v.MembershipApprovalMode != nil {
+		add("settings", nil)
+	}
+	return evts
+}
@@ -0,0 +1,270 @@
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"wa-server-go/internal/metrics"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+// Where a LID resolution came from
+const (
+	LIDSourceStore   = "store"   // whatsmeow's LID mapping store of the session
+	LIDSourceLearned = "learned" // mappings learned from messages, contact sync or earlier lookups
+	LIDSourceNetwork = "network" // asked WhatsApp
+)
+
+// lidNetworkDelay is the pause between network lookups, which WhatsApp rate limits
+const lidNetworkDelay = 2 * time.Second
+
+// LIDMapping pairs a LID with the phone number JID it stands for
+type LIDMapping struct {
+	LID   types.JID
+	Phone types.JID
+}
+
+// LIDRepository persists learned LID mappings
+type LIDRepository interface {
+	// GetPhones returns the phone number JIDs of the given LIDs that have one
+	GetPhones(ctx context.Context, lids []types.JID) (map[types.JID]types.JID, error)
+	// SaveMappings stores mappings in one transaction, replacing the phone number of LIDs already known
+	SaveMappings(ctx context.Context, mappings []LIDMapping) error
+}
+
+// LIDResolution is the outcome of resolving one LID
+type LIDResolution struct {
+	LID    string `json:"lid"`
+	Phone  string `json:"phone,omitempty"`  // phone number JID; empty when unresolved
+	Source string `json:"source,omitempty"` // store, learned or network
+}
+
+// LIDResolver maps LIDs to phone numbers: first whatsmeow's LID store of the session, then the
+// mappings we learned ourselves and, if asked to, WhatsApp itself
+type LIDResolver struct {
+	repo LIDRepository // optional; without it nothing is learned
+
+	mu    sync.RWMutex
+	known map[types.JID]types.JID // LID -> phone number JID, mirrors what repo holds
+}
+
+// NewLIDResolver creates a LID resolver; repo may be nil
+func NewLIDResolver(repo LIDRepository) *LIDResolver {
+	return &LIDResolver{repo: repo, known: make(map[types.JID]types.JID)}
+}
+
+// Phone returns the phone number JID of a LID without contacting WhatsApp. Phone number JIDs are returned as they are.
+func (r *LIDResolver) Phone(ctx context.Context, client *Client, jid types.JID) (types.JID, bool) {
+	jid = jid.ToNonAD()
+	if jid.Server != types.HiddenUserServer {
+		return jid, true
+	}
+	resolved, err := r.Resolve(ctx, client, []types.JID{jid}, false)
+	if err != nil || resolved[0].Phone == "" {
+		return types.EmptyJID, false
+	}
+	phone, err := types.ParseJID(resolved[0].Phone)
+	return phone, err == nil
+}
+
+// LID returns the LID of a phone number JID from whatsmeow's LID store of the session, or an empty JID
+func (r *LIDResolver) LID(ctx context.Context, client *Client, phone types.JID) types.JID {
+	if client == nil {
+		return types.EmptyJID
+	}
+	lid, err := client.WAClient.Store.LIDs.GetLIDForPN(ctx, phone.ToNonAD())
+	if err != nil {
+		return types.EmptyJID
+	}
+	return lid.ToNonAD()
+}
+
+// Resolve resolves LIDs in order. With network set, the LIDs unknown locally are looked up on WhatsApp
+// one at a time; a rate limit (429) stops the network lookups for the rest of the call.
+func (r *LIDResolver) Resolve(ctx context.Context, client *Client, lids []types.JID, network bool) ([]LIDResolution, error) {
+	results := make([]LIDResolution, len(lids))
+	var pending []int
+	for i, lid := range lids {
+		lid = lid.ToNonAD()
+		results[i].LID = lid.String()
+		if client != nil {
+			if phone, err := client.WAClient.Store.LIDs.GetPNForLID(ctx, lid); err == nil && !phone.IsEmpty() {
+				results[i].Phone, results[i].Source = phone.ToNonAD().String(), LIDSourceStore
+				continue
+			}
+		}
+		pending = append(pending, i)
+	}
+
+	learned, err := r.learned(ctx, lids, pending)
+	if err != nil {
+		return nil, err
+	}
+	var unresolved []int
+	for _, i := range pending {
+		if phone, ok := learned[lids[i].ToNonAD()]; ok {
+			results[i].Phone, results[i].Source = phone.String(), LIDSourceLearned
+		} else {
+			unresolved = append(unresolved, i)
+		}
+	}
+	metrics.LIDCacheHits.Add(float64(len(lids) - len(unresolved)))
+	metrics.LIDCacheMisses.Add(float64(len(unresolved)))
+
+	if network && client != nil && len(unresolved) > 0 {
+		r.resolveNetwork(ctx, client, lids, unresolved, results)
+	}
+	return results, nil
+}
+
+// learned returns the learned phone numbers of lids[i] for every i in pending, from memory or the repository
+func (r *LIDResolver) learned(ctx context.Context, lids []types.JID, pending []int) (map[types.JID]types.JID, error) {
+	found := make(map[types.JID]types.JID)
+	var missing []types.JID
+	r.mu.RLock()
+	for _, i := range pending {
+		lid := lids[i].ToNonAD()
+		if phone, ok := r.known[lid]; ok {
+			found[lid] = phone
+		} else {
+			missing = append(missing, lid)
+		}
+	}
+	r.mu.RUnlock()
+	if len(missing) == 0 || r.repo == nil {
+		return found, nil
+	}
+
+	phones, err := r.repo.GetPhones(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for lid, phone := range phones {
+		found[lid] = phone
+		r.known[lid] = phone
+	}
+	return found, nil
+}
+
+// resolveNetwork asks WhatsApp for the phone numbers of lids[i] for every i in unresolved and learns the answers
+func (r *LIDResolver) resolveNetwork(ctx context.Context, client *Client, lids []types.JID, unresolved []int, results []LIDResolution) {
+	for n, i := range unresolved {
+		if n > 0 {
+			// Respectful delay between requests
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(lidNetworkDelay):
+			}
+		}
+
+		lid := lids[i].ToNonAD()
+		resp, err := client.WAClient.GetUserInfo(ctx, []types.JID{lid})
+		if err != nil {
+			if strings.Contains(err.Error(), "429") || strings.Contains(err.Error(), "rate-overlimit") {
+				log.Printf("⚠️ Rate Limit Hit (429). Circuit Breaker Activated. Switching to local-only for remaining %d LIDs.", len(unresolved)-n)
+				return
+			}
+			log.Printf("⚠️ Failed to look up LID %s: %v", lid, err)
+			continue
+		}
+		for _, info := range resp {
+			for _, device := range info.Devices {
+				if device.Server == types.DefaultUserServer {
+					phone := types.NewJID(device.User, types.DefaultUserServer)
+					results[i].Phone, results[i].Source = phone.String(), LIDSourceNetwork
+					r.Learn(ctx, LIDMapping{LID: lid, Phone: phone})
+					break
+				}
+			}
+		}
+	}
+}
+
+// Learn stores mappings that are new or changed; failures are logged
+func (r *LIDResolver) Learn(ctx context.Context, mappings ...LIDMapping) {
+	var changed []LIDMapping
+	r.mu.Lock()
+	for _, m := range mappings {
+		lid, phone := m.LID.ToNonAD(), m.Phone.ToNonAD()
+		if lid.Server != types.HiddenUserServer || phone.Server != types.DefaultUserServer || r.known[lid] == phone {
+			continue
+		}
+		r.known[lid] = phone
+		changed = append(changed, LIDMapping{LID: lid, Phone: phone})
+	}
+	r.mu.Unlock()
+
+	if len(changed) == 0 || r.repo == nil {
+		return
+	}
+	if err := r.repo.SaveMappings(ctx, changed); err != nil {
+		log.Printf("⚠️ Failed to store %d LID mappings: %v", len(changed), err)
+		// Forget them so they are stored when seen again
+		r.mu.Lock()
+		for _, m := range changed {
+			delete(r.known, m.LID)
+		}
+		r.mu.Unlock()
+	}
+}
+
+// learnFromMessage learns the LID mappings a message carries in the alternative addresses of its sender and,
+// in a direct chat, its recipient
+func (r *LIDResolver) learnFromMessage(info *types.MessageInfo) {
+	var mappings []LIDMapping
+	add := func(jid, alt types.JID) {
+		if jid.Server == types.DefaultUserServer {
+			jid, alt = alt, jid
+		}
+		if !alt.IsEmpty() {
+			mappings = append(mappings, LIDMapping{LID: jid, Phone: alt})
+		}
+	}
+	add(info.Sender, info.SenderAlt)
+	if !info.IsGroup {
+		add(info.Chat, info.RecipientAlt)
+	}
+	r.Learn(context.Background(), mappings...)
+}
+
+// ImportFile learns the mappings of the JSON file that used to cache LIDs (LID user -> phone number user)
+// and renames it so it is imported only once. A missing file is not an error.
+func (r *LIDResolver) ImportFile(ctx context.Context, path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	var users map[string]string
+	if err := json.Unmarshal(data, &users); err != nil {
+		return 0, fmt.Errorf("invalid LID mapping file %s: %w", path, err)
+	}
+	if r.repo == nil {
+		return 0, errors.New("LID storage is not configured")
+	}
+
+	mappings := make([]LIDMapping, 0, len(users))
+	for lidUser, phoneUser := range users {
+		mappings = append(mappings, LIDMapping{
+			LID:   types.NewJID(lidUser, types.HiddenUserServer),
+			Phone: types.NewJID(phoneUser, types.DefaultUserServer),
+		})
+	}
+	if err := r.repo.SaveMappings(ctx, mappings); err != nil {
+		return 0, err
+	}
+	return len(mappings), os.Rename(path, path+".imported")
+}
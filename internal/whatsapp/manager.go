@@ -4,34 +4,55 @@ import (
 	"context"
 	"fmt"
 	"sync"
-	"wa-server-go/internal/firestore"
+	"wa-server-go/internal/metrics"
+	"wa-server-go/internal/storage"
+
+	"go.mau.fi/whatsmeow/types/events"
+	"golang.org/x/sync/singleflight"
 )
 
 // Manager manages multiple WhatsApp clients
 type Manager struct {
-	clients    map[string]*Client
-	Repo       *firestore.ChatsRepository
-	LabelStore *LabelStore
-	mu         sync.RWMutex
-	qrChan     chan QRImageEvent
-	statusChan chan StatusUpdate
-	msgChan    chan NewMessageEvent
+	clients     map[string]*Client
+	Repo        storage.ChatsRepository
+	Registry    SessionRegistry // optional; persists sessions started through StartSession
+	LabelStore  *LabelStore
+	LIDs        *LIDResolver // learns LID mappings from incoming messages
+	mu          sync.RWMutex
+	qrChan      chan QRImageEvent
+	statusChan  chan StatusUpdate
+	msgChan     chan NewMessageEvent
+	receiptChan chan ReceiptEvent
+	groupChan   chan GroupEvent
+
+	retryMu      sync.Mutex
+	mediaRetries map[string]chan *events.MediaRetry // session/message ID -> pending media retry
+	retries      singleflight.Group                 // one media retry per session/message ID, shared by concurrent downloads
+
+	ackMu      sync.Mutex
+	recentAcks map[ackKey]recentAck // receipts of the last few minutes, for messages saved after their receipt
 }
 
 // NewManager creates a new client manager
-func NewManager(repo *firestore.ChatsRepository) *Manager {
+func NewManager(repo storage.ChatsRepository) *Manager {
 	return &Manager{
-		clients:    make(map[string]*Client),
-		Repo:       repo,
-		LabelStore: NewLabelStore(),
-		qrChan:     make(chan QRImageEvent, 10),
-		statusChan: make(chan StatusUpdate, 10),
-		msgChan:    make(chan NewMessageEvent, 100),
+		clients:     make(map[string]*Client),
+		Repo:        repo,
+		LabelStore:  NewLabelStore(),
+		LIDs:        NewLIDResolver(nil),
+		qrChan:      make(chan QRImageEvent, 10),
+		statusChan:  make(chan StatusUpdate, 10),
+		msgChan:     make(chan NewMessageEvent, 100),
+		receiptChan: make(chan ReceiptEvent, 100),
+		groupChan:   make(chan GroupEvent, 100),
+
+		mediaRetries: make(map[string]chan *events.MediaRetry),
+		recentAcks:   make(map[ackKey]recentAck),
 	}
 }
 
 // CreateClient creates and registers a new WhatsApp client
-func (m *Manager) CreateClient(ctx context.Context, clientID string, dbPath string) error {
+func (m *Manager) CreateClient(ctx context.Context, clientID string, dbPath string, role Role) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -43,6 +64,8 @@ func (m *Manager) CreateClient(ctx context.Context, clientID string, dbPath stri
 	if err != nil {
 		return err
 	}
+	client.Role = role
+	metrics.SetSessionConnected(clientID, false)
 
 	m.clients[clientID] = client
 	return nil
@@ -130,6 +153,7 @@ func (m *Manager) DestroyClient(clientID string) error {
 
 	client.Close()
 	delete(m.clients, clientID)
+	metrics.RemoveSession(clientID)
 	return nil
 }
 
@@ -148,11 +172,22 @@ func (m *Manager) MessageChannel() <-chan NewMessageEvent {
 	return m.msgChan
 }
 
+// ReceiptChannel returns the channel for receipt events
+func (m *Manager) ReceiptChannel() <-chan ReceiptEvent {
+	return m.receiptChan
+}
+
+// GroupChannel returns the channel for group change events
+func (m *Manager) GroupChannel() <-chan GroupEvent {
+	return m.groupChan
+}
+
 // BroadcastMessage allows external packages to broadcast messages via WebSocket
 func (m *Manager) BroadcastMessage(evt NewMessageEvent) {
 	select {
 	case m.msgChan <- evt:
 	default:
+		metrics.BroadcastsDropped.Inc()
 		fmt.Println("⚠️ Message channel full, dropping broadcast")
 	}
 }
@@ -167,6 +202,7 @@ func (m *Manager) GetAllStatus() map[string]interface{} {
 		status[id] = map[string]interface{}{
 			"ready": client.IsReady(),
 			"qr":    client.GetQRCode(),
+			"role":  client.Role,
 		}
 	}
 	return status
@@ -185,4 +221,6 @@ func (m *Manager) Close() {
 	close(m.qrChan)
 	close(m.statusChan)
 	close(m.msgChan)
+	close(m.receiptChan)
+	close(m.groupChan)
 }
@@ -0,0 +1,270 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"wa-server-go/internal/storage"
+
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/proto/waMmsRetry"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/protobuf/proto"
+)
+
+// MediaDir is where downloaded and sent media files are cached. It is not served statically:
+// files are only served by /get-media, which checks the API key's sessions.
+const MediaDir = "./media"
+
+// legacyMediaDir is where media was cached while it was served statically under /uploads
+const legacyMediaDir = "./uploads/media"
+
+// mediaRetryTimeout bounds how long we wait for the sender's phone to re-upload expired media
+const mediaRetryTimeout = 30 * time.Second
+
+// ErrNoMediaKeys is returned when a stored message lacks the keys needed to download its media
+var ErrNoMediaKeys = errors.New("message has no stored media keys")
+
+// mediaMessage returns the downloadable part of a message along with its type, mime type and file name
+func mediaMessage(msg *waProto.Message) (whatsmeow.DownloadableMessage, string, string, string) {
+	switch {
+	case msg.GetImageMessage() != nil:
+		return msg.GetImageMessage(), "image", msg.GetImageMessage().GetMimetype(), ""
+	case msg.GetDocumentMessage() != nil:
+		doc := msg.GetDocumentMessage()
+		return doc, "document", doc.GetMimetype(), doc.GetFileName()
+	case msg.GetAudioMessage() != nil:
+		return msg.GetAudioMessage(), "audio", msg.GetAudioMessage().GetMimetype(), ""
+	case msg.GetVideoMessage() != nil:
+		return msg.GetVideoMessage(), "video", msg.GetVideoMessage().GetMimetype(), ""
+	case msg.GetStickerMessage() != nil:
+		return msg.GetStickerMessage(), "sticker", msg.GetStickerMessage().GetMimetype(), ""
+	default:
+		return nil, "text", "", ""
+	}
+}
+
+// setMediaKeys copies the keys needed to re-download media onto the stored message
+func setMediaKeys(waMsg *storage.WAMessage, media whatsmeow.DownloadableMessage) {
+	waMsg.MediaDirectPath = media.GetDirectPath()
+	waMsg.MediaKey = media.GetMediaKey()
+	waMsg.MediaFileSHA256 = media.GetFileSHA256()
+	waMsg.MediaFileEncSHA256 = media.GetFileEncSHA256()
+	if sized, ok := media.(interface{ GetFileLength() uint64 }); ok {
+		waMsg.MediaFileLength = sized.GetFileLength()
+	}
+}
+
+// MediaExtension returns the file extension used when caching media of the given type
+func MediaExtension(msgType, mimeType, fileName string) string {
+	if ext := filepath.Ext(fileName); ext != "" {
+		return ext
+	}
+
+	switch msgType {
+	case "image":
+		return ".jpg"
+	case "sticker":
+		return ".webp"
+	case "video":
+		return ".mp4"
+	case "audio":
+		if strings.HasPrefix(mimeType, "audio/ogg") {
+			return ".ogg"
+		}
+	}
+
+	// Strip parameters such as "; codecs=opus"
+	baseType := strings.TrimSpace(strings.Split(mimeType, ";")[0])
+	if exts, _ := mime.ExtensionsByType(baseType); len(exts) > 0 {
+		return exts[0]
+	}
+	return ".bin"
+}
+
+// WriteMediaFile caches media under MediaDir and returns the file name, which is stored as the message's MediaURL
+func WriteMediaFile(messageID, ext string, data []byte) (string, error) {
+	if err := os.MkdirAll(MediaDir, 0700); err != nil {
+		return "", err
+	}
+	fileName := messageID + ext
+	if err := os.WriteFile(filepath.Join(MediaDir, fileName), data, 0600); err != nil {
+		return "", err
+	}
+	return fileName, nil
+}
+
+// MediaPath returns the cache file of a stored MediaURL, which older messages kept as /uploads/media/<file>
+func MediaPath(mediaURL string) string {
+	return filepath.Join(MediaDir, filepath.Base(mediaURL))
+}
+
+// MoveLegacyMediaDir moves the media cache out of the old public uploads directory, unless MediaDir exists
+func MoveLegacyMediaDir() error {
+	if _, err := os.Stat(MediaDir); err == nil {
+		return nil
+	}
+	if _, err := os.Stat(legacyMediaDir); os.IsNotExist(err) {
+		return nil
+	}
+	return os.Rename(legacyMediaDir, MediaDir)
+}
+
+// storedMedia rebuilds a downloadable message from the media keys kept on a stored message
+func storedMedia(msg *storage.WAMessage) (whatsmeow.DownloadableMessage, error) {
+	if len(msg.MediaKey) == 0 || msg.MediaDirectPath == "" {
+		return nil, ErrNoMediaKeys
+	}
+
+	directPath := proto.String(msg.MediaDirectPath)
+	length := proto.Uint64(msg.MediaFileLength)
+	mimetype := proto.String(msg.MediaType)
+
+	switch msg.Type {
+	case "image":
+		return &waProto.ImageMessage{DirectPath: directPath, MediaKey: msg.MediaKey, FileSHA256: msg.MediaFileSHA256,
+			FileEncSHA256: msg.MediaFileEncSHA256, FileLength: length, Mimetype: mimetype}, nil
+	case "document":
+		return &waProto.DocumentMessage{DirectPath: directPath, MediaKey: msg.MediaKey, FileSHA256: msg.MediaFileSHA256,
+			FileEncSHA256: msg.MediaFileEncSHA256, FileLength: length, Mimetype: mimetype}, nil
+	case "audio":
+		return &waProto.AudioMessage{DirectPath: directPath, MediaKey: msg.MediaKey, FileSHA256: msg.MediaFileSHA256,
+			FileEncSHA256: msg.MediaFileEncSHA256, FileLength: length, Mimetype: mimetype}, nil
+	case "video":
+		return &waProto.VideoMessage{DirectPath: directPath, MediaKey: msg.MediaKey, FileSHA256: msg.MediaFileSHA256,
+			FileEncSHA256: msg.MediaFileEncSHA256, FileLength: length, Mimetype: mimetype}, nil
+	case "sticker":
+		return &waProto.StickerMessage{DirectPath: directPath, MediaKey: msg.MediaKey, FileSHA256: msg.MediaFileSHA256,
+			FileEncSHA256: msg.MediaFileEncSHA256, FileLength: length, Mimetype: mimetype}, nil
+	default:
+		return nil, fmt.Errorf("unsupported media type %q", msg.Type)
+	}
+}
+
+// isExpiredMedia reports whether a download failed because the CDN copy is gone
+func isExpiredMedia(err error) bool {
+	return errors.Is(err, whatsmeow.ErrMediaDownloadFailedWith403) ||
+		errors.Is(err, whatsmeow.ErrMediaDownloadFailedWith404) ||
+		errors.Is(err, whatsmeow.ErrMediaDownloadFailedWith410)
+}
+
+// DownloadMedia downloads the media of a stored message.
+// If the CDN copy has expired, the sender's phone is asked to re-upload it and msg.MediaDirectPath is updated.
+func (m *Manager) DownloadMedia(ctx context.Context, clientID string, msg *storage.WAMessage) ([]byte, error) {
+	client, ok := m.GetClient(clientID)
+	if !ok || !client.IsReady() {
+		return nil, fmt.Errorf("client %s is not ready", clientID)
+	}
+
+	media, err := storedMedia(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := client.WAClient.Download(ctx, media)
+	if err == nil || !isExpiredMedia(err) {
+		return data, err
+	}
+
+	// Concurrent downloads of the message share one retry; it is not bound to the request that started it
+	retry := m.retries.DoChan(retryKey(clientID, msg.MessageID), func() (interface{}, error) {
+		fmt.Printf("🔁 [%s] Media for %s expired, requesting re-upload from phone\n", clientID, msg.MessageID)
+		return m.requestMediaRetry(context.Background(), client, clientID, msg)
+	})
+	var result singleflight.Result
+	select {
+	case result = <-retry:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	msg.MediaDirectPath = result.Val.(string)
+	media, err = storedMedia(msg)
+	if err != nil {
+		return nil, err
+	}
+	return client.WAClient.Download(ctx, media)
+}
+
+// retryKey identifies a pending media retry; a message seen by two sessions is retried by each
+func retryKey(clientID, messageID string) string {
+	return clientID + "/" + messageID
+}
+
+// requestMediaRetry sends a media retry receipt and waits for the new direct path
+func (m *Manager) requestMediaRetry(ctx context.Context, client *Client, clientID string, msg *storage.WAMessage) (string, error) {
+	chat, err := types.ParseJID(msg.ChatID)
+	if err != nil {
+		return "", fmt.Errorf("invalid chat JID: %w", err)
+	}
+	info := &types.MessageInfo{
+		MessageSource: types.MessageSource{
+			Chat:     chat,
+			IsFromMe: msg.FromMe,
+			IsGroup:  chat.Server == types.GroupServer,
+		},
+		ID: msg.MessageID,
+	}
+	if sender, err := types.ParseJID(msg.From); err == nil {
+		info.Sender = sender
+	}
+
+	key := retryKey(clientID, msg.MessageID)
+	ch := make(chan *events.MediaRetry, 1)
+	m.retryMu.Lock()
+	m.mediaRetries[key] = ch
+	m.retryMu.Unlock()
+	defer func() {
+		m.retryMu.Lock()
+		delete(m.mediaRetries, key)
+		m.retryMu.Unlock()
+	}()
+
+	if err := client.WAClient.SendMediaRetryReceipt(ctx, info, msg.MediaKey); err != nil {
+		return "", fmt.Errorf("failed to request media retry: %w", err)
+	}
+
+	var evt *events.MediaRetry
+	select {
+	case evt = <-ch:
+	case <-time.After(mediaRetryTimeout):
+		return "", fmt.Errorf("timed out waiting for media re-upload")
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	notif, err := whatsmeow.DecryptMediaRetryNotification(evt, msg.MediaKey)
+	if err != nil {
+		return "", err
+	}
+	if notif.GetResult() != waMmsRetry.MediaRetryNotification_SUCCESS {
+		return "", fmt.Errorf("media re-upload failed: %s", notif.GetResult())
+	}
+	return notif.GetDirectPath(), nil
+}
+
+// deliverMediaRetry hands a media retry response to the retry waiting for it on the session
+func (m *Manager) deliverMediaRetry(clientID string, evt *events.MediaRetry) {
+	m.retryMu.Lock()
+	ch, ok := m.mediaRetries[retryKey(clientID, string(evt.MessageID))]
+	m.retryMu.Unlock()
+	if !ok {
+		return
+	}
+	select {
+	case ch <- evt:
+	default:
+	}
+}
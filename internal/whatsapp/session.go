@@ -0,0 +1,265 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+)
+
+// Role controls what a session is allowed to do
+type Role string
+
+const (
+	// RoleSender sends messages and stores its chats (the invoice/broadcast bot)
+	RoleSender Role = "sender"
+	// RoleSync is read-only: chats are stored but sending is refused
+	RoleSync Role = "sync"
+	// RolePrivacy is read-only and ignores message content entirely (contact/label sync only)
+	RolePrivacy Role = "privacy"
+)
+
+// Valid reports whether r is a known role
+func (r Role) Valid() bool {
+	return r == RoleSender || r == RoleSync || r == RolePrivacy
+}
+
+// CanSend reports whether sessions with this role may send messages
+func (r Role) CanSend() bool {
+	return r == RoleSender
+}
+
+// IgnoresContent reports whether messages, history and receipts are dropped
+func (r Role) IgnoresContent() bool {
+	return r == RolePrivacy
+}
+
+// SessionRecord is a registered WhatsApp session
+type SessionRecord struct {
+	ID          string    `json:"id"`
+	Role        Role      `json:"role"`
+	AutoConnect bool      `json:"autoConnect"` // connect on server start
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// SessionInfo is a registered session together with its live state
+type SessionInfo struct {
+	SessionRecord
+	Running bool   `json:"running"`
+	Ready   bool   `json:"ready"`
+	QR      string `json:"qr,omitempty"`
+	Phone   string `json:"phone,omitempty"`
+}
+
+// SessionRegistry persists registered sessions across restarts
+type SessionRegistry interface {
+	ListSessions(ctx context.Context) ([]SessionRecord, error)
+	SaveSession(ctx context.Context, session *SessionRecord) error
+	DeleteSession(ctx context.Context, id string) error
+}
+
+var sessionIDPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,32}$`)
+
+// ValidSessionID reports whether id can be used as a session ID (and session DB file name)
+func ValidSessionID(id string) bool {
+	return sessionIDPattern.MatchString(id)
+}
+
+// SessionDBPath returns the whatsmeow session database of a session
+func SessionDBPath(id string) string {
+	return fmt.Sprintf("session-%s.db", id)
+}
+
+// RestoreSessions registers the default sessions if missing and connects every auto-connect session
+func (m *Manager) RestoreSessions(ctx context.Context, defaults ...SessionRecord) error {
+	var records []SessionRecord
+	if m.Registry != nil {
+		var err error
+		records, err = m.Registry.ListSessions(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to load session registry: %w", err)
+		}
+	}
+
+	for _, def := range defaults {
+		found := false
+		for _, rec := range records {
+			if rec.ID == def.ID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			def.CreatedAt = time.Now()
+			if m.Registry != nil {
+				if err := m.Registry.SaveSession(ctx, &def); err != nil {
+					return fmt.Errorf("failed to register session %s: %w", def.ID, err)
+				}
+			}
+			records = append(records, def)
+		}
+	}
+
+	for _, rec := range records {
+		if !rec.AutoConnect {
+			continue
+		}
+		if err := m.StartSession(ctx, rec); err != nil {
+			return fmt.Errorf("failed to start session %s: %w", rec.ID, err)
+		}
+	}
+	return nil
+}
+
+// StartSession registers a session, creates its client and connects it in the background.
+// Starting a session that is already running only updates its registry record.
+func (m *Manager) StartSession(ctx context.Context, rec SessionRecord) error {
+	if !ValidSessionID(rec.ID) {
+		return fmt.Errorf("invalid session ID %q", rec.ID)
+	}
+	if rec.Role == "" {
+		rec.Role = RoleSender
+	}
+	if !rec.Role.Valid() {
+		return fmt.Errorf("invalid role %q", rec.Role)
+	}
+	if rec.CreatedAt.IsZero() {
+		rec.CreatedAt = time.Now()
+	}
+
+	if m.Registry != nil {
+		if err := m.Registry.SaveSession(ctx, &rec); err != nil {
+			return fmt.Errorf("failed to save session: %w", err)
+		}
+	}
+
+	if _, running := m.GetClient(rec.ID); running {
+		return nil
+	}
+
+	if err := m.CreateClient(ctx, rec.ID, SessionDBPath(rec.ID), rec.Role); err != nil {
+		return err
+	}
+	if err := m.SetupEventHandlers(rec.ID); err != nil {
+		return err
+	}
+
+	go func() {
+		// Use background context for long running connection
+		if err := m.Connect(context.Background(), rec.ID); err != nil {
+			fmt.Printf("❌ [%s] Failed to connect: %v\n", rec.ID, err)
+		}
+	}()
+	return nil
+}
+
+// LogoutSession unlinks the device from WhatsApp and restarts the session so a new QR code can be scanned
+func (m *Manager) LogoutSession(ctx context.Context, id string) error {
+	client, ok := m.GetClient(id)
+	if !ok {
+		return fmt.Errorf("session %s is not running", id)
+	}
+	role := client.Role
+
+	if client.WAClient.Store.ID != nil {
+		if err := client.WAClient.Logout(ctx); err != nil {
+			return fmt.Errorf("failed to log out: %w", err)
+		}
+	}
+	if err := m.DestroyClient(id); err != nil {
+		return err
+	}
+	m.statusChan <- StatusUpdate{Client: id, Ready: false, Reason: "logged_out"}
+
+	rec := SessionRecord{ID: id, Role: role}
+	if m.Registry != nil {
+		if records, err := m.Registry.ListSessions(ctx); err == nil {
+			for _, r := range records {
+				if r.ID == id {
+					rec = r
+				}
+			}
+		}
+	}
+	return m.StartSession(ctx, rec)
+}
+
+// DeleteSession logs out and stops a session, removes it from the registry and deletes its session database
+func (m *Manager) DeleteSession(ctx context.Context, id string) error {
+	if client, ok := m.GetClient(id); ok {
+		if client.IsReady() && client.WAClient.Store.ID != nil {
+			if err := client.WAClient.Logout(ctx); err != nil {
+				fmt.Printf("⚠️ [%s] Logout before delete failed: %v\n", id, err)
+			}
+		}
+		if err := m.DestroyClient(id); err != nil {
+			return err
+		}
+	}
+
+	if m.Registry != nil {
+		if err := m.Registry.DeleteSession(ctx, id); err != nil {
+			return fmt.Errorf("failed to delete session: %w", err)
+		}
+	}
+
+	dbPath := SessionDBPath(id)
+	for _, path := range []string{dbPath, dbPath + "-wal", dbPath + "-shm"} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			fmt.Printf("⚠️ [%s] Failed to remove %s: %v\n", id, path, err)
+		}
+	}
+	return nil
+}
+
+// Sessions returns all registered sessions with their live state.
+// Running clients that are not registered are included too.
+func (m *Manager) Sessions(ctx context.Context) ([]SessionInfo, error) {
+	var records []SessionRecord
+	if m.Registry != nil {
+		var err error
+		records, err = m.Registry.ListSessions(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	seen := make(map[string]bool)
+	sessions := make([]SessionInfo, 0, len(records))
+	for _, rec := range records {
+		seen[rec.ID] = true
+		sessions = append(sessions, m.sessionInfo(rec))
+	}
+
+	m.mu.RLock()
+	for id, client := range m.clients {
+		if !seen[id] {
+			sessions = append(sessions, m.clientInfo(SessionRecord{ID: id, Role: client.Role}, client))
+		}
+	}
+	m.mu.RUnlock()
+
+	return sessions, nil
+}
+
+func (m *Manager) sessionInfo(rec SessionRecord) SessionInfo {
+	client, ok := m.GetClient(rec.ID)
+	if !ok {
+		return SessionInfo{SessionRecord: rec}
+	}
+	return m.clientInfo(rec, client)
+}
+
+func (m *Manager) clientInfo(rec SessionRecord, client *Client) SessionInfo {
+	info := SessionInfo{
+		SessionRecord: rec,
+		Running:       true,
+		Ready:         client.IsReady(),
+		QR:            client.GetQRCode(),
+	}
+	if id := client.WAClient.Store.ID; id != nil {
+		info.Phone = id.User
+	}
+	return info
+}
@@ -22,17 +22,29 @@ type QRImageEvent struct {
 
 // NewMessageEvent represents an incoming message event
 type NewMessageEvent struct {
-	Client    string `json:"client"`
-	ID        string `json:"id"`
-	From      string `json:"from"`
-	To        string `json:"to"`
-	Body      string `json:"body"`
-	Timestamp int64  `json:"timestamp"`
-	FromMe    bool   `json:"fromMe"`
-	ChatID    string `json:"chatId"`
-	ChatName  string `json:"chatName"`
-	HasMedia  bool   `json:"hasMedia"`
-	Type      string `json:"type"`
+	Client     string `json:"client"`
+	ID         string `json:"id"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+	Body       string `json:"body"`
+	Timestamp  int64  `json:"timestamp"`
+	FromMe     bool   `json:"fromMe"`
+	ChatID     string `json:"chatId"`
+	ChatName   string `json:"chatName"`
+	SenderName string `json:"senderName,omitempty"`
+	HasMedia   bool   `json:"hasMedia"`
+	Type       string `json:"type"`
+}
+
+// ReceiptEvent represents a delivery/read receipt for messages we sent
+type ReceiptEvent struct {
+	Client     string   `json:"client"`
+	MessageIDs []string `json:"messageIds"`
+	ChatID     string   `json:"chatId"`
+	Sender     string   `json:"sender"`
+	Type       string   `json:"type"` // delivered, read, played
+	Ack        int      `json:"ack"`
+	Timestamp  int64    `json:"timestamp"`
 }
 
 // Helper function to encode bytes to base64